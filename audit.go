@@ -0,0 +1,220 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// audit.go defines AuditingClient, an AIClient wrapper that hands every interaction to
+// an AuditSink for a durable compliance trail. Unlike debug logging, records are
+// complete (full conversation, full response, usage) and never truncated.
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one interaction as seen by an AuditingClient.
+type AuditRecord struct {
+	// Timestamp is when the call started
+	Timestamp time.Time `json:"timestamp"`
+	// TraceID is the call's trace ID when one was attached with WithTrace
+	TraceID string `json:"trace_id,omitempty"`
+	// Client and Model identify the wrapped client
+	Client string `json:"client"`
+	Model  string `json:"model"`
+	// Messages is the conversation sent, including the prompt
+	Messages []Message `json:"messages"`
+	// Response is the returned content; for streams, the assembled chunks
+	Response string `json:"response"`
+	// Metadata carries usage and the model used, when the call returned it
+	Metadata *ResponseMetadata `json:"metadata,omitempty"`
+	// Streamed is set for StreamPrompt and StreamConversation calls
+	Streamed bool `json:"streamed,omitempty"`
+	// Error is the failure message, if the call failed
+	Error string `json:"error,omitempty"`
+	// LatencyMs is the wall-clock duration of the call
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// AuditSink persists AuditRecords. Implementations must be safe for concurrent use.
+// Record is called synchronously after each call completes, so slow sinks add latency.
+type AuditSink interface {
+	Record(ctx context.Context, record AuditRecord) error
+}
+
+// JSONLinesAuditSink writes each record as one JSON line to an io.Writer, such as an
+// append-only file.
+type JSONLinesAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesAuditSink creates a sink writing JSON lines to w.
+func NewJSONLinesAuditSink(w io.Writer) *JSONLinesAuditSink {
+	return &JSONLinesAuditSink{w: w}
+}
+
+// Record writes record as a single line.
+func (s *JSONLinesAuditSink) Record(_ context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// AuditingClient wraps an AIClient and records every call to an AuditSink. Calls are
+// forwarded unchanged and sink failures never fail a call; they are passed to the
+// handler set with OnSinkError.
+type AuditingClient struct {
+	inner       AIClient
+	sink        AuditSink
+	onSinkError func(error)
+}
+
+// NewAuditingClient creates an AuditingClient recording inner's calls to sink.
+func NewAuditingClient(inner AIClient, sink AuditSink) *AuditingClient {
+	return &AuditingClient{inner: inner, sink: sink}
+}
+
+// OnSinkError sets a handler for errors returned by the sink.
+func (a *AuditingClient) OnSinkError(handler func(error)) *AuditingClient {
+	a.onSinkError = handler
+	return a
+}
+
+// begin starts a record for a call sending messages.
+func (a *AuditingClient) begin(ctx context.Context, messages []Message, streamed bool) AuditRecord {
+	return AuditRecord{
+		Timestamp: time.Now(),
+		TraceID:   TraceID(ctx),
+		Client:    a.inner.Name(),
+		Model:     a.inner.Model(),
+		Messages:  append([]Message(nil), messages...),
+		Streamed:  streamed,
+	}
+}
+
+// finish completes record with the outcome of the call and hands it to the sink.
+func (a *AuditingClient) finish(ctx context.Context, record AuditRecord, content string, metadata *ResponseMetadata, err error) {
+	record.Response = content
+	if metadata != nil {
+		copied := *metadata
+		record.Metadata = &copied
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	record.LatencyMs = time.Since(record.Timestamp).Milliseconds()
+
+	if sinkErr := a.sink.Record(ctx, record); sinkErr != nil && a.onSinkError != nil {
+		a.onSinkError(sinkErr)
+	}
+}
+
+// SendPrompt forwards to the inner client and records the call.
+func (a *AuditingClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	record := a.begin(ctx, promptConversation(prompt).Messages, false)
+	content, err := a.inner.SendPrompt(ctx, prompt)
+	a.finish(ctx, record, content, nil, err)
+	return content, err
+}
+
+// SendPromptWithMetadata forwards to the inner client and records the call.
+func (a *AuditingClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	record := a.begin(ctx, promptConversation(prompt).Messages, false)
+	response, err := a.inner.SendPromptWithMetadata(ctx, prompt)
+	a.finishResponse(ctx, record, response, err)
+	return response, err
+}
+
+// SendConversation forwards to the inner client and records the call.
+func (a *AuditingClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	record := a.begin(ctx, conversation.Messages, false)
+	content, err := a.inner.SendConversation(ctx, conversation)
+	a.finish(ctx, record, content, nil, err)
+	return content, err
+}
+
+// SendConversationWithMetadata forwards to the inner client and records the call.
+func (a *AuditingClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	record := a.begin(ctx, conversation.Messages, false)
+	response, err := a.inner.SendConversationWithMetadata(ctx, conversation)
+	a.finishResponse(ctx, record, response, err)
+	return response, err
+}
+
+// finishResponse is finish for calls returning an AiResponse.
+func (a *AuditingClient) finishResponse(ctx context.Context, record AuditRecord, response *AiResponse, err error) {
+	if response == nil {
+		a.finish(ctx, record, "", nil, err)
+		return
+	}
+	a.finish(ctx, record, response.Content, &response.Metadata, err)
+}
+
+// StreamPrompt forwards to the inner client and records the assembled stream.
+func (a *AuditingClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	record := a.begin(ctx, promptConversation(prompt).Messages, true)
+	ch, err := a.inner.StreamPrompt(ctx, prompt)
+	return a.recordStream(ctx, record, ch, err)
+}
+
+// StreamConversation forwards to the inner client and records the assembled stream.
+func (a *AuditingClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
+	record := a.begin(ctx, conversation.Messages, true)
+	ch, err := a.inner.StreamConversation(ctx, conversation)
+	return a.recordStream(ctx, record, ch, err)
+}
+
+// recordStream forwards src unchanged and records the assembled content once the
+// stream ends.
+func (a *AuditingClient) recordStream(ctx context.Context, record AuditRecord, src <-chan StreamChunk, err error) (<-chan StreamChunk, error) {
+	if err != nil {
+		a.finish(ctx, record, "", nil, err)
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 10)
+	go func() {
+		defer close(out)
+		var content strings.Builder
+		var metadata *ResponseMetadata
+		var streamErr error
+		for chunk := range src {
+			content.WriteString(chunk.Content)
+			if chunk.Finished {
+				metadata, streamErr = chunk.Metadata, chunk.Err
+			}
+			out <- chunk
+		}
+		a.finish(ctx, record, content.String(), metadata, streamErr)
+	}()
+	return out, nil
+}
+
+// SupportsStreaming delegates to the inner client.
+func (a *AuditingClient) SupportsStreaming() bool { return a.inner.SupportsStreaming() }
+
+// SupportsConversations delegates to the inner client.
+func (a *AuditingClient) SupportsConversations() bool { return a.inner.SupportsConversations() }
+
+// Name delegates to the inner client.
+func (a *AuditingClient) Name() string { return a.inner.Name() }
+
+// Model delegates to the inner client.
+func (a *AuditingClient) Model() string { return a.inner.Model() }
+
+// WithOptions applies opts to the inner client and wraps the result the same way.
+func (a *AuditingClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	inner, err := ClientWithOptions(a.inner, opts...)
+	if err != nil {
+		return nil, err
+	}
+	clone := *a
+	clone.inner = inner
+	return &clone, nil
+}
@@ -0,0 +1,105 @@
+package chatdelta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAuditSink keeps records in memory.
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+	err     error
+}
+
+func (s *memoryAuditSink) Record(_ context.Context, record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return s.err
+}
+
+func TestAuditingClient_RecordsEveryCall(t *testing.T) {
+	mock := NewMockClient("mock", "mock-model")
+	mock.QueueResponse("first")
+	mock.QueueResponse("second")
+	mock.QueueError(NewServerError(500, "boom"))
+	mock.QueueResponse("streamed reply")
+	sink := &memoryAuditSink{}
+	client := NewAuditingClient(mock, sink)
+
+	ctx := WithTrace(context.Background(), "trace-1")
+	got, err := client.SendPrompt(ctx, "one")
+	require.NoError(t, err)
+	assert.Equal(t, "first", got)
+
+	conv := NewConversation()
+	conv.AddSystemMessage("Be brief.")
+	conv.AddUserMessage("two")
+	response, err := client.SendConversationWithMetadata(context.Background(), conv)
+	require.NoError(t, err)
+	assert.Equal(t, "second", response.Content)
+
+	_, err = client.SendPrompt(context.Background(), "three")
+	require.Error(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "four")
+	require.NoError(t, err)
+	content, _ := collectStream(t, ch)
+	assert.Equal(t, "streamed reply", content)
+
+	require.Len(t, sink.records, 4)
+
+	first := sink.records[0]
+	assert.Equal(t, "trace-1", first.TraceID)
+	assert.Equal(t, "mock", first.Client)
+	assert.Equal(t, "mock-model", first.Model)
+	assert.Equal(t, []Message{{Role: "user", Content: "one"}}, first.Messages)
+	assert.Equal(t, "first", first.Response)
+	assert.False(t, first.Timestamp.IsZero())
+
+	second := sink.records[1]
+	require.Len(t, second.Messages, 2)
+	assert.Equal(t, "second", second.Response)
+	require.NotNil(t, second.Metadata)
+
+	assert.Contains(t, sink.records[2].Error, "boom")
+	assert.Empty(t, sink.records[2].Response)
+
+	assert.True(t, sink.records[3].Streamed)
+	assert.Equal(t, "streamed reply", sink.records[3].Response)
+}
+
+func TestAuditingClient_SinkErrorDoesNotFailCall(t *testing.T) {
+	mock := NewMockClient("mock", "mock-model")
+	mock.QueueResponse("ok")
+	var reported error
+	client := NewAuditingClient(mock, &memoryAuditSink{err: errors.New("disk full")}).
+		OnSinkError(func(err error) { reported = err })
+
+	got, err := client.SendPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", got)
+	assert.EqualError(t, reported, "disk full")
+}
+
+func TestJSONLinesAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLinesAuditSink(&buf)
+	require.NoError(t, sink.Record(context.Background(), AuditRecord{Client: "a", Response: "x"}))
+	require.NoError(t, sink.Record(context.Background(), AuditRecord{Client: "b", Response: "y"}))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var record AuditRecord
+	require.NoError(t, json.Unmarshal(lines[1], &record))
+	assert.Equal(t, "b", record.Client)
+	assert.Equal(t, "y", record.Response)
+}
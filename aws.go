@@ -0,0 +1,310 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// aws.go holds the pieces of the AWS protocol the Bedrock client needs, implemented
+// on the standard library: static credential resolution, Signature Version 4 request
+// signing, and decoding of the binary event stream used for streaming responses.
+package chatdelta
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are the static credentials used to sign AWS requests.
+type AWSCredentials struct {
+	// AccessKeyID is the access key, e.g. "AKIA..."
+	AccessKeyID string
+	// SecretAccessKey is the secret paired with AccessKeyID
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials, e.g. from an assumed role
+	SessionToken string
+}
+
+// awsRegionFromEnv returns AWS_REGION, falling back to AWS_DEFAULT_REGION.
+func awsRegionFromEnv() string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// defaultAWSCredentials resolves static credentials the way the AWS CLI does: the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment
+// variables, then the AWS_PROFILE (or "default") profile of the shared credentials
+// file. Instance roles, SSO, and credential processes are not supported.
+func defaultAWSCredentials() (*AWSCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return &AWSCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, NewMissingConfigError("AWS credentials")
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, NewMissingConfigError("AWS credentials")
+	}
+	defer file.Close()
+
+	credentials, err := readAWSCredentialsProfile(file, profile)
+	if err != nil {
+		return nil, err
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, NewMissingConfigError(fmt.Sprintf("AWS credentials for profile %q", profile))
+	}
+	return credentials, nil
+}
+
+// readAWSCredentialsProfile reads the keys of profile from a shared credentials file.
+func readAWSCredentialsProfile(r io.Reader, profile string) (*AWSCredentials, error) {
+	credentials := &AWSCredentials{}
+	inProfile := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inProfile || !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = value
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = value
+		case "aws_session_token":
+			credentials.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, NewMissingConfigError("AWS credentials: " + err.Error())
+	}
+	return credentials, nil
+}
+
+// awsSignedHeaders are the headers covered by the signature when present. Other
+// headers, such as ExtraHeaders, are left unsigned so proxies may rewrite them.
+var awsSignedHeaders = []string{"content-type", "host", "x-amz-date", "x-amz-security-token"}
+
+// signAWSRequest signs req in place with AWS Signature Version 4, adding the
+// X-Amz-Date, X-Amz-Security-Token, and Authorization headers. body must be the
+// exact request payload.
+func signAWSRequest(req *http.Request, body []byte, credentials *AWSCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	var canonicalHeaders strings.Builder
+	var signed []string
+	for _, name := range awsSignedHeaders {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = host
+		}
+		if value == "" {
+			continue
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+		signed = append(signed, name)
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsCanonicalURI(req.URL.EscapedPath()),
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = awsHMAC(key, part)
+	}
+	signature := hex.EncodeToString(awsHMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func awsHMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsCanonicalURI encodes each segment of an already-escaped path a second time, as
+// SigV4 requires for every service except S3.
+func awsCanonicalURI(escapedPath string) string {
+	if escapedPath == "" {
+		return "/"
+	}
+	segments := strings.Split(escapedPath, "/")
+	for i, segment := range segments {
+		segments[i] = awsURIEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsCanonicalQuery returns the query parameters sorted and encoded for signing.
+func awsCanonicalQuery(query map[string][]string) string {
+	var pairs []string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, awsURIEncode(key)+"="+awsURIEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode percent-encodes every byte except the RFC 3986 unreserved characters.
+func awsURIEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// awsEventMessage is one message of an application/vnd.amazon.eventstream body.
+type awsEventMessage struct {
+	// Headers holds the string-valued headers, e.g. ":event-type"
+	Headers map[string]string
+	Payload []byte
+}
+
+// errAWSEventStreamCorrupt reports a message that fails its length or CRC checks.
+var errAWSEventStreamCorrupt = errors.New("corrupt event stream message")
+
+// awsEventStreamReader decodes event stream messages. Each message is framed as
+// total length, headers length, prelude CRC, headers, payload, and message CRC.
+type awsEventStreamReader struct {
+	r io.Reader
+}
+
+// maxAWSEventMessage bounds the size of a single message, as the service does.
+const maxAWSEventMessage = 16 << 20
+
+// next returns the next message, or io.EOF at a clean end of stream.
+func (s *awsEventStreamReader) next() (*awsEventMessage, error) {
+	var prelude [12]byte
+	if _, err := io.ReadFull(s.r, prelude[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errAWSEventStreamCorrupt
+		}
+		return nil, err
+	}
+
+	totalLength := binary.BigEndian.Uint32(prelude[0:4])
+	headersLength := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[:8]) != binary.BigEndian.Uint32(prelude[8:12]) ||
+		totalLength < 16 || totalLength > maxAWSEventMessage || headersLength > totalLength-16 {
+		return nil, errAWSEventStreamCorrupt
+	}
+
+	message := make([]byte, totalLength)
+	copy(message, prelude[:])
+	if _, err := io.ReadFull(s.r, message[12:]); err != nil {
+		return nil, errAWSEventStreamCorrupt
+	}
+	if crc32.ChecksumIEEE(message[:totalLength-4]) != binary.BigEndian.Uint32(message[totalLength-4:]) {
+		return nil, errAWSEventStreamCorrupt
+	}
+
+	headers, err := parseAWSEventHeaders(message[12 : 12+headersLength])
+	if err != nil {
+		return nil, err
+	}
+	return &awsEventMessage{Headers: headers, Payload: message[12+headersLength : totalLength-4]}, nil
+}
+
+// awsEventHeaderSizes gives the value size of the fixed-width header types, indexed
+// by type: bool true, bool false, byte, short, integer, long, (bytes), (string),
+// timestamp, and UUID.
+var awsEventHeaderSizes = [10]int{0, 0, 1, 2, 4, 8, -1, -1, 8, 16}
+
+// parseAWSEventHeaders decodes a header block, keeping only string values.
+func parseAWSEventHeaders(data []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	for len(data) > 0 {
+		nameLength := int(data[0])
+		if len(data) < 1+nameLength+1 {
+			return nil, errAWSEventStreamCorrupt
+		}
+		name := string(data[1 : 1+nameLength])
+		valueType := data[1+nameLength]
+		data = data[2+nameLength:]
+
+		if int(valueType) >= len(awsEventHeaderSizes) {
+			return nil, errAWSEventStreamCorrupt
+		}
+		size := awsEventHeaderSizes[valueType]
+		if size < 0 {
+			if len(data) < 2 {
+				return nil, errAWSEventStreamCorrupt
+			}
+			size = int(binary.BigEndian.Uint16(data[:2]))
+			data = data[2:]
+		}
+		if len(data) < size {
+			return nil, errAWSEventStreamCorrupt
+		}
+		if valueType == 7 {
+			headers[name] = string(data[:size])
+		}
+		data = data[size:]
+	}
+	return headers, nil
+}
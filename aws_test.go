@@ -0,0 +1,136 @@
+package chatdelta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// awsEventFrame encodes one event stream message with string headers.
+func awsEventFrame(headers map[string]string, payload []byte) []byte {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headerBlock bytes.Buffer
+	for _, name := range names {
+		headerBlock.WriteByte(byte(len(name)))
+		headerBlock.WriteString(name)
+		headerBlock.WriteByte(7)
+		_ = binary.Write(&headerBlock, binary.BigEndian, uint16(len(headers[name])))
+		headerBlock.WriteString(headers[name])
+	}
+
+	total := 12 + headerBlock.Len() + len(payload) + 4
+	message := make([]byte, 12, total)
+	binary.BigEndian.PutUint32(message[0:4], uint32(total))
+	binary.BigEndian.PutUint32(message[4:8], uint32(headerBlock.Len()))
+	binary.BigEndian.PutUint32(message[8:12], crc32.ChecksumIEEE(message[:8]))
+	message = append(message, headerBlock.Bytes()...)
+	message = append(message, payload...)
+	return binary.BigEndian.AppendUint32(message, crc32.ChecksumIEEE(message))
+}
+
+// TestSignAWSRequest_Vanilla checks the get-vanilla case of the AWS SigV4 test suite.
+func TestSignAWSRequest_Vanilla(t *testing.T) {
+	req, err := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+	credentials := &AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signAWSRequest(req, nil, credentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestSignAWSRequest_SessionTokenAndPath(t *testing.T) {
+	req, err := http.NewRequest("POST", "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-v2%3A1/invoke", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	signAWSRequest(req, []byte(`{}`), &AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "token"},
+		"us-east-1", "bedrock", time.Now())
+
+	assert.Equal(t, "token", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token,")
+	assert.Equal(t, "/model/anthropic.claude-v2%253A1/invoke", awsCanonicalURI(req.URL.EscapedPath()))
+}
+
+func TestAWSEventStreamReader(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(awsEventFrame(map[string]string{":event-type": "chunk", ":message-type": "event"}, []byte(`{"bytes":"e30="}`)))
+	stream.Write(awsEventFrame(nil, nil))
+
+	reader := &awsEventStreamReader{r: &stream}
+	message, err := reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, "chunk", message.Headers[":event-type"])
+	assert.Equal(t, `{"bytes":"e30="}`, string(message.Payload))
+
+	message, err = reader.next()
+	require.NoError(t, err)
+	assert.Empty(t, message.Headers)
+	assert.Empty(t, message.Payload)
+
+	_, err = reader.next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestAWSEventStreamReader_Corrupt(t *testing.T) {
+	frame := awsEventFrame(map[string]string{":event-type": "chunk"}, []byte("payload"))
+	frame[len(frame)-5] ^= 0xff
+
+	_, err := (&awsEventStreamReader{r: bytes.NewReader(frame)}).next()
+	assert.ErrorIs(t, err, errAWSEventStreamCorrupt)
+
+	_, err = (&awsEventStreamReader{r: bytes.NewReader(frame[:20])}).next()
+	assert.ErrorIs(t, err, errAWSEventStreamCorrupt)
+}
+
+func TestDefaultAWSCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	credentials, err := defaultAWSCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, &AWSCredentials{AccessKeyID: "env-id", SecretAccessKey: "env-secret"}, credentials)
+
+	path := filepath.Join(t.TempDir(), "credentials")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+		"[default]",
+		"aws_access_key_id = default-id",
+		"aws_secret_access_key = default-secret",
+		"",
+		"# work account",
+		"[work]",
+		"aws_access_key_id=work-id",
+		"aws_secret_access_key=work-secret",
+		"aws_session_token=work-token",
+	}, "\n")), 0o600))
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_PROFILE", "work")
+
+	credentials, err = defaultAWSCredentials()
+	require.NoError(t, err)
+	assert.Equal(t, &AWSCredentials{AccessKeyID: "work-id", SecretAccessKey: "work-secret", SessionToken: "work-token"}, credentials)
+
+	t.Setenv("AWS_PROFILE", "missing")
+	_, err = defaultAWSCredentials()
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// azure.go adds Azure OpenAI support. Azure serves the OpenAI chat protocol from a
+// per-resource host, addresses models by deployment name, requires an api-version
+// query parameter, and authenticates with an api-key header instead of Bearer auth.
+package chatdelta
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when none is configured.
+const DefaultAzureAPIVersion = "2024-06-01"
+
+// AzureConfig identifies an Azure OpenAI deployment.
+type AzureConfig struct {
+	// Resource is the Azure OpenAI resource name, i.e. the {resource} in
+	// https://{resource}.openai.azure.com
+	Resource string
+	// Deployment is the model deployment name. When empty, CreateClient uses its
+	// model argument.
+	Deployment string
+	// APIVersion is the api-version query parameter; defaults to DefaultAzureAPIVersion
+	APIVersion string
+}
+
+// NewAzureOpenAIClient creates a client for an Azure OpenAI deployment. It uses the
+// OpenAI request and streaming code, so metadata and streaming behave exactly as for
+// NewOpenAIClient. ClientConfig.BaseURL, when set, replaces the resource host (for
+// example to go through a gateway).
+func NewAzureOpenAIClient(apiKey string, azure AzureConfig, config *ClientConfig) (*OpenAIClient, error) {
+	if apiKey == "" {
+		return nil, NewInvalidAPIKeyError()
+	}
+	if azure.Resource == "" {
+		return nil, NewMissingConfigError("Azure OpenAI resource name")
+	}
+	if azure.Deployment == "" {
+		return nil, NewMissingConfigError("Azure OpenAI deployment name")
+	}
+	if azure.APIVersion == "" {
+		azure.APIVersion = DefaultAzureAPIVersion
+	}
+
+	client, err := NewOpenAIClient(apiKey, azure.Deployment, config)
+	if err != nil {
+		return nil, err
+	}
+	client.endpoint = openAIEndpoint{
+		name:       "Azure OpenAI",
+		provider:   ProviderOpenAI,
+		baseURL:    fmt.Sprintf("https://%s.openai.azure.com", url.PathEscape(azure.Resource)),
+		path:       fmt.Sprintf("/openai/deployments/%s/chat/completions?api-version=%s", url.PathEscape(azure.Deployment), url.QueryEscape(azure.APIVersion)),
+		authHeader: "api-key",
+	}
+	return client, nil
+}
+
+// newAzureClientFromConfig builds an Azure client for CreateClient from config.Azure,
+// using model as the deployment when none is configured.
+func newAzureClientFromConfig(apiKey, model string, config *ClientConfig) (AIClient, error) {
+	if config.Azure == nil {
+		return nil, NewMissingConfigError("Azure OpenAI settings (use ClientConfig.SetAzure)")
+	}
+	azure := *config.Azure
+	if azure.Deployment == "" {
+		azure.Deployment = model
+	}
+	return NewAzureOpenAIClient(apiKey, azure, config)
+}
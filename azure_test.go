@@ -0,0 +1,65 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureOpenAIClient_RequestShape(t *testing.T) {
+	transcript, err := os.ReadFile(filepath.Join("testdata", "openai_stream.sse"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/gpt4o-prod/chat/completions", r.URL.Path)
+		assert.Equal(t, "2024-10-21", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "azure-key", r.Header.Get("api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		_, _ = w.Write(transcript)
+	}))
+	defer server.Close()
+
+	client, err := NewAzureOpenAIClient("azure-key", AzureConfig{
+		Resource:   "contoso",
+		Deployment: "gpt4o-prod",
+		APIVersion: "2024-10-21",
+	}, NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+	assert.Equal(t, "Azure OpenAI", client.Name())
+	assert.Equal(t, "gpt4o-prod", client.Model())
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "Hello there!", content)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, 15, last.Metadata.TotalTokens)
+}
+
+func TestAzureOpenAIClient_DefaultURL(t *testing.T) {
+	client, err := NewAzureOpenAIClient("azure-key", AzureConfig{Resource: "contoso", Deployment: "chat"}, nil)
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://contoso.openai.azure.com/openai/deployments/chat/chat/completions?api-version="+DefaultAzureAPIVersion, prepared.URL)
+	assert.Equal(t, redactedValue, prepared.Header.Get("api-key"))
+}
+
+func TestCreateClient_Azure(t *testing.T) {
+	_, err := CreateClient("azure", "azure-key", "chat", nil)
+	assert.Error(t, err, "Azure settings are required")
+
+	client, err := CreateClient("azure", "azure-key", "chat", NewClientConfig().SetAzure("contoso", "", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "chat", client.Model(), "the model argument names the deployment")
+
+	_, err = CreateClient("azure", "azure-key", "", NewClientConfig().SetAzure("contoso", "", ""))
+	assert.Error(t, err, "a deployment is required")
+}
@@ -0,0 +1,164 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// batch.go implements typed, bounded-concurrency helpers for embedding AI calls into
+// larger pipelines (for example alongside golang.org/x/sync/errgroup) without the
+// ParallelResult indirection.
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// MapError reports which input of a MapPrompts call failed.
+type MapError struct {
+	// Index of the failing input
+	Index int
+	// Err is the client or parse error
+	Err error
+}
+
+func (e *MapError) Error() string {
+	return fmt.Sprintf("input %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error so errors.Is/As and the Is*Error helpers work.
+func (e *MapError) Unwrap() error {
+	return e.Err
+}
+
+// PromptFunc adapts a single prompt into the func() error shape used by errgroup.Go.
+// The response is written to out on success.
+//
+//	g, ctx := errgroup.WithContext(ctx)
+//	var summary string
+//	g.Go(chatdelta.PromptFunc(ctx, client, "Summarize ...", &summary))
+func PromptFunc(ctx context.Context, client AIClient, prompt string, out *string) func() error {
+	return func() error {
+		result, err := client.SendPrompt(ctx, prompt)
+		if err != nil {
+			return err
+		}
+		*out = result
+		return nil
+	}
+}
+
+// MapPrompts renders each input into a prompt, sends it with at most concurrency
+// requests in flight, and parses each response into R. Results are returned in input
+// order.
+//
+// MapPrompts fails fast: the first error (by completion time) cancels the context
+// passed to in-flight and pending requests, and is returned as a *MapError after all
+// started requests have finished. The results slice is nil on error. Use
+// MapPromptsAll to process every input regardless of failures.
+//
+// A non-positive concurrency means no limit.
+func MapPrompts[T, R any](ctx context.Context, client AIClient, inputs []T, render func(T) string, parse func(string) (R, error), concurrency int) ([]R, error) {
+	results := make([]R, len(inputs))
+	errs := runBounded(ctx, len(inputs), concurrency, true, func(ctx context.Context, i int) error {
+		r, err := mapOne(ctx, client, inputs[i], render, parse)
+		results[i] = r
+		return err
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// MapPromptsAll is like MapPrompts but processes every input even when some fail.
+// Results for failed inputs hold the zero value of R; the returned error joins one
+// *MapError per failed input, in input order, and is nil if all succeeded.
+func MapPromptsAll[T, R any](ctx context.Context, client AIClient, inputs []T, render func(T) string, parse func(string) (R, error), concurrency int) ([]R, error) {
+	results := make([]R, len(inputs))
+	errs := runBounded(ctx, len(inputs), concurrency, false, func(ctx context.Context, i int) error {
+		r, err := mapOne(ctx, client, inputs[i], render, parse)
+		results[i] = r
+		return err
+	})
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return results, errors.Join(failed...)
+}
+
+// mapOne sends a single rendered input and parses the response.
+func mapOne[T, R any](ctx context.Context, client AIClient, input T, render func(T) string, parse func(string) (R, error)) (R, error) {
+	var zero R
+	response, err := client.SendPrompt(ctx, render(input))
+	if err != nil {
+		return zero, err
+	}
+	return parse(response)
+}
+
+// runBounded calls fn for indices 0..n-1 with at most concurrency calls in flight
+// and returns a *MapError per failed index. Indices not started because ctx ended
+// report ctx.Err(). When failFast is set, the first failure cancels the context
+// given to fn, no further indices are started, and only that failure is reported.
+func runBounded(ctx context.Context, n, concurrency int, failFast bool, fn func(ctx context.Context, i int) error) []error {
+	if concurrency <= 0 || concurrency > n {
+		concurrency = n
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, n)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr = -1
+	)
+	sem := make(chan struct{}, concurrency)
+
+	dispatched := 0
+dispatch:
+	for ; dispatched < n; dispatched++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(ctx, i); err != nil {
+				errs[i] = &MapError{Index: i, Err: err}
+				if failFast {
+					once.Do(func() {
+						firstErr = i
+						cancel()
+					})
+				}
+			}
+		}(dispatched)
+	}
+	wg.Wait()
+
+	if firstErr >= 0 {
+		only := make([]error, n)
+		only[firstErr] = errs[firstErr]
+		return only
+	}
+	// The parent context ended before every input was started.
+	for i := dispatched; i < n; i++ {
+		errs[i] = &MapError{Index: i, Err: ctx.Err()}
+	}
+	return errs
+}
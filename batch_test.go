@@ -0,0 +1,128 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoClient replies to "n=<k>" with "<k*2>".
+func echoClient(n int) *MockClient {
+	client := NewMockClient("mock", "")
+	for i := 0; i < n; i++ {
+		client.ScriptReply(fmt.Sprintf("n=%d", i), strconv.Itoa(i*2))
+	}
+	return client
+}
+
+func renderN(i int) string { return fmt.Sprintf("n=%d", i) }
+
+func TestMapPrompts_TypedOrderedResults(t *testing.T) {
+	client := echoClient(20)
+	inputs := make([]int, 20)
+	for i := range inputs {
+		inputs[i] = i
+	}
+
+	results, err := MapPrompts(context.Background(), client, inputs, renderN, strconv.Atoi, 4)
+	require.NoError(t, err)
+	require.Len(t, results, 20)
+	for i, r := range results {
+		assert.Equal(t, i*2, r)
+	}
+	assert.Equal(t, 20, client.CallCount())
+}
+
+func TestMapPrompts_FailFast(t *testing.T) {
+	client := echoClient(50)
+	client.SetLatency(5 * time.Millisecond)
+	boom := NewServerError(500, "boom")
+	client.ScriptError("n=3", boom)
+
+	inputs := make([]int, 50)
+	for i := range inputs {
+		inputs[i] = i
+	}
+
+	results, err := MapPrompts(context.Background(), client, inputs, renderN, strconv.Atoi, 2)
+	assert.Nil(t, results)
+
+	var mapErr *MapError
+	require.True(t, errors.As(err, &mapErr))
+	assert.Equal(t, 3, mapErr.Index)
+	assert.True(t, errors.Is(err, boom))
+	assert.Less(t, client.CallCount(), 50, "remaining inputs must not be dispatched")
+}
+
+func TestMapPrompts_ParseError(t *testing.T) {
+	client := NewMockClient("mock", "")
+	client.SetDefaultResponse("not a number", nil)
+
+	_, err := MapPrompts(context.Background(), client, []int{0}, renderN, strconv.Atoi, 1)
+	var numErr *strconv.NumError
+	assert.True(t, errors.As(err, &numErr))
+}
+
+func TestMapPromptsAll_JoinsErrors(t *testing.T) {
+	client := echoClient(6)
+	client.ScriptError("n=1", errors.New("first"))
+	client.ScriptError("n=4", errors.New("second"))
+
+	results, err := MapPromptsAll(context.Background(), client, []int{0, 1, 2, 3, 4, 5}, renderN, strconv.Atoi, 0)
+	require.Error(t, err)
+	assert.Equal(t, []int{0, 0, 4, 6, 0, 10}, results)
+	assert.Equal(t, "input 1: first\ninput 4: second", err.Error())
+	assert.Equal(t, 6, client.CallCount())
+}
+
+func TestMapPrompts_ConcurrencyLimit(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	client := &countingClient{MockClient: NewMockClient("mock", ""), inFlight: &inFlight, peak: &peak}
+
+	_, err := MapPrompts(context.Background(), client, make([]int, 12), renderN, func(string) (string, error) { return "", nil }, 3)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+}
+
+func TestMapPrompts_CanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := MapPrompts(ctx, echoClient(3), []int{0, 1, 2}, renderN, strconv.Atoi, 1)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestPromptFunc(t *testing.T) {
+	client := NewMockClient("mock", "")
+	client.QueueResponse("done")
+
+	var out string
+	require.NoError(t, PromptFunc(context.Background(), client, "go", &out)())
+	assert.Equal(t, "done", out)
+}
+
+// countingClient tracks the peak number of concurrent SendPrompt calls.
+type countingClient struct {
+	*MockClient
+	inFlight, peak *atomic.Int32
+}
+
+func (c *countingClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		p := c.peak.Load()
+		if n <= p || c.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(2 * time.Millisecond)
+	return c.MockClient.SendPrompt(ctx, prompt)
+}
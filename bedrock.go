@@ -0,0 +1,510 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// bedrock.go adds Anthropic models hosted on AWS Bedrock. Requests use the Claude
+// Messages format with anthropic_version in the body instead of a header, are signed
+// with SigV4, and stream over Bedrock's binary event stream rather than SSE.
+package chatdelta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// bedrockAnthropicVersion is the anthropic_version Bedrock requires in the body.
+const bedrockAnthropicVersion = "bedrock-2023-05-31"
+
+// BedrockConfig selects the AWS region and credentials used by the "bedrock" provider.
+type BedrockConfig struct {
+	// Region is the AWS region, e.g. "us-east-1". When empty, AWS_REGION and then
+	// AWS_DEFAULT_REGION are used.
+	Region string
+	// Credentials sign requests. When nil, the AWS_ACCESS_KEY_ID family of
+	// environment variables and then the shared credentials file are used.
+	Credentials *AWSCredentials
+}
+
+// BedrockClient implements the AIClient interface for Anthropic models on AWS Bedrock
+type BedrockClient struct {
+	model       string
+	region      string
+	credentials *AWSCredentials
+	config      *ClientConfig
+	httpClient  *http.Client
+	// format builds Claude Messages payloads for the model
+	format *ClaudeClient
+}
+
+// bedrockClaudeRequest is claudeRequest as Bedrock expects it: the model is in the
+// URL, streaming is chosen by endpoint, and the API version is in the body.
+type bedrockClaudeRequest struct {
+	AnthropicVersion string            `json:"anthropic_version"`
+	Messages         []claudeMessage   `json:"messages"`
+	System           string            `json:"system,omitempty"`
+	Temperature      *float64          `json:"temperature,omitempty"`
+	MaxTokens        int               `json:"max_tokens"`
+	TopP             *float64          `json:"top_p,omitempty"`
+	TopK             *int              `json:"top_k,omitempty"`
+	StopSequences    []string          `json:"stop_sequences,omitempty"`
+	Tools            []claudeTool      `json:"tools,omitempty"`
+	ToolChoice       *claudeToolChoice `json:"tool_choice,omitempty"`
+}
+
+// bedrockChunk is the payload of a streaming "chunk" event; Bytes holds one Claude
+// stream event, base64-encoded on the wire.
+type bedrockChunk struct {
+	Bytes []byte `json:"bytes"`
+}
+
+// bedrockError is the body of a Bedrock error response or stream exception.
+type bedrockError struct {
+	Message string `json:"message"`
+}
+
+// NewBedrockClient creates a client for an Anthropic model on AWS Bedrock. The region
+// and credentials come from config.Bedrock, falling back to the environment.
+func NewBedrockClient(model string, config *ClientConfig) (*BedrockClient, error) {
+	if model == "" {
+		model = "anthropic.claude-3-haiku-20240307-v1:0"
+	}
+
+	if config == nil {
+		config = NewClientConfig()
+	}
+
+	var settings BedrockConfig
+	if config.Bedrock != nil {
+		settings = *config.Bedrock
+	}
+	if settings.Region == "" {
+		settings.Region = awsRegionFromEnv()
+	}
+	if settings.Region == "" {
+		return nil, NewMissingConfigError("AWS region (set AWS_REGION or use ClientConfig.SetBedrock)")
+	}
+	if settings.Credentials == nil {
+		credentials, err := defaultAWSCredentials()
+		if err != nil {
+			return nil, err
+		}
+		settings.Credentials = credentials
+	}
+
+	return &BedrockClient{
+		model:       model,
+		region:      settings.Region,
+		credentials: settings.Credentials,
+		config:      config,
+		httpClient:  newHTTPClient(config),
+		format:      &ClaudeClient{model: model, config: config},
+	}, nil
+}
+
+// SendPrompt sends a single prompt to Bedrock
+func (c *BedrockClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	conversation := NewConversation()
+	conversation.AddUserMessage(prompt)
+	return c.SendConversation(ctx, conversation)
+}
+
+// SendConversation sends a conversation to Bedrock
+func (c *BedrockClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	response, err := c.SendConversationWithMetadata(ctx, conversation)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendPromptWithMetadata sends a prompt and returns the response with metadata.
+func (c *BedrockClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	conversation := NewConversation()
+	conversation.AddUserMessage(prompt)
+	return c.SendConversationWithMetadata(ctx, conversation)
+}
+
+// SendConversationWithMetadata sends a conversation and returns the response with metadata.
+func (c *BedrockClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	if c.config.keepAliveViaStreaming() {
+		return sendViaStream(ctx, c.config, conversation, c.streamRequest)
+	}
+
+	var result *AiResponse
+	operation := func() error {
+		response, err := c.sendRequest(ctx, conversation)
+		if err != nil {
+			return err
+		}
+		result, err = claudeAiResponse(response)
+		if err != nil {
+			return err
+		}
+		result.Raw = response.info.raw(c.config)
+		result.Content, err = postProcessContent(c.config, conversation, result.Content, &result.Metadata)
+		return err
+	}
+
+	if err := c.config.retry(ctx, operation); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StreamPrompt streams a response for a single prompt
+func (c *BedrockClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	conversation := NewConversation()
+	conversation.AddUserMessage(prompt)
+	return c.StreamConversation(ctx, conversation)
+}
+
+// StreamConversation streams a response for a conversation
+func (c *BedrockClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
+	resultChan := make(chan StreamChunk, 10)
+
+	go func() {
+		defer close(resultChan)
+
+		ctx, cancel := c.config.withOperationTimeout(ctx)
+		defer cancel()
+
+		sink := newStreamSink(ctx, resultChan, c.config)
+		operation := func() error {
+			return c.streamRequest(ctx, conversation, sink)
+		}
+
+		if err := c.config.retry(ctx, operation); err != nil {
+			sink.fail(ctx, c.config, err)
+		}
+	}()
+
+	return resultChan, nil
+}
+
+// buildRequest converts a conversation into a Bedrock request body.
+func (c *BedrockClient) buildRequest(conversation *Conversation) bedrockClaudeRequest {
+	request := c.format.buildRequest(conversation, false)
+	return bedrockClaudeRequest{
+		AnthropicVersion: bedrockAnthropicVersion,
+		Messages:         request.Messages,
+		System:           request.System,
+		Temperature:      request.Temperature,
+		MaxTokens:        request.MaxTokens,
+		TopP:             request.TopP,
+		TopK:             request.TopK,
+		StopSequences:    request.StopSequences,
+		Tools:            request.Tools,
+		ToolChoice:       request.ToolChoice,
+	}
+}
+
+// newHTTPRequest validates and marshals conversation into an unsigned invoke request.
+func (c *BedrockClient) newHTTPRequest(ctx context.Context, conversation *Conversation, stream bool) (*http.Request, []byte, error) {
+	conversation, err := prepareConversation(c.config, conversation)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := conversation.ValidateAlternation(); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateImages(ProviderClaude, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateVision(ProviderBedrock, c.model, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateMaxTokens(ProviderClaude, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateStopSequences(ProviderClaude, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateTools(c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateResponseFormat(c.config); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := marshalRequestBody(c.config, ProviderBedrock, c.model, c.buildRequest(conversation))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	action := "/invoke"
+	if stream {
+		action = "/invoke-with-response-stream"
+	}
+	defaultBase := "https://bedrock-runtime." + c.region + ".amazonaws.com"
+	url := endpointURL(c.config, defaultBase, "/model/"+awsURIEncode(c.model)+action)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, NewConnectionError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if stream {
+		req.Header.Set("Accept", "application/vnd.amazon.eventstream")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	applyExtraHeaders(req, c.config, "Authorization", "X-Amz-Date", "X-Amz-Security-Token")
+
+	return req, body, nil
+}
+
+// newSignedRequest builds the invoke request and signs it for sending.
+func (c *BedrockClient) newSignedRequest(ctx context.Context, conversation *Conversation, stream bool) (*http.Request, error) {
+	req, body, err := c.newHTTPRequest(ctx, conversation, stream)
+	if err != nil {
+		return nil, err
+	}
+	signAWSRequest(req, body, c.credentials, c.region, "bedrock", c.config.clock().Now())
+	return req, nil
+}
+
+// DryRun returns the request that would be sent for conversation without sending it.
+// The request is shown before SigV4 signing, so it carries no credentials.
+func (c *BedrockClient) DryRun(conversation *Conversation, stream bool) (*PreparedRequest, error) {
+	req, body, err := c.newHTTPRequest(context.Background(), conversation, stream)
+	if err != nil {
+		return nil, err
+	}
+	return newPreparedRequest(req, body), nil
+}
+
+// sendRequest sends a request to the invoke endpoint
+func (c *BedrockClient) sendRequest(ctx context.Context, conversation *Conversation) (*claudeResponse, error) {
+	req, err := c.newSignedRequest(ctx, conversation, false)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, NewTimeoutError(c.config.Timeout)
+		}
+		return nil, NewConnectionError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, NewConnectionError(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.errorFromBody(resp.StatusCode, resp.Header, body)
+	}
+
+	var response claudeResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, NewJSONParseError(err)
+	}
+	response.info = responseInfo{requestID: requestIDFromHeader(resp.Header, response.ID), latency: time.Since(start), body: body}
+	return &response, nil
+}
+
+// streamRequest sends a request to the invoke-with-response-stream endpoint and
+// forwards the Claude events carried in its chunk messages.
+func (c *BedrockClient) streamRequest(ctx context.Context, conversation *Conversation, sink *streamSink) error {
+	req, err := c.newSignedRequest(ctx, conversation, true)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return NewTimeoutError(c.config.Timeout)
+		}
+		return NewConnectionError(err)
+	}
+	defer resp.Body.Close()
+	defer closeOnDone(ctx, resp.Body)()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return c.errorFromBody(resp.StatusCode, resp.Header, body)
+	}
+
+	state := newClaudeStreamState(c.model, start, requestIDFromHeader(resp.Header, ""))
+	reader := &awsEventStreamReader{r: resp.Body}
+	for {
+		if ctx.Err() != nil {
+			return streamReadError(ctx, nil)
+		}
+		message, err := reader.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return streamReadError(ctx, err)
+		}
+
+		switch message.Headers[":message-type"] {
+		case "exception":
+			// Errors after the response has started arrive in-band
+			var detail bedrockError
+			_ = json.Unmarshal(message.Payload, &detail)
+			errorType := message.Headers[":exception-type"]
+			return c.parseAPIError(bedrockErrorStatus(errorType), nil, errorType, detail.Message)
+		case "error":
+			return NewServerError(http.StatusInternalServerError, message.Headers[":error-message"])
+		}
+		if message.Headers[":event-type"] != "chunk" {
+			continue
+		}
+
+		var chunk bedrockChunk
+		if err := json.Unmarshal(message.Payload, &chunk); err != nil {
+			continue // Skip malformed chunks
+		}
+		var event claudeResponse
+		if err := json.Unmarshal(chunk.Bytes, &event); err != nil {
+			continue
+		}
+		sink.event(string(chunk.Bytes))
+		if event.Type == "error" && event.Error != nil {
+			return c.format.parseAPIError(claudeErrorStatus(event.Error.Type), nil, event.Error)
+		}
+		if state.handle(&event, sink) {
+			return nil
+		}
+	}
+}
+
+// errorFromBody maps a Bedrock error response, whose type is in X-Amzn-ErrorType.
+func (c *BedrockClient) errorFromBody(statusCode int, header http.Header, body []byte) *ClientError {
+	var detail bedrockError
+	if err := json.Unmarshal(body, &detail); err != nil || detail.Message == "" {
+		detail.Message = string(body)
+	}
+	// The header may carry a namespace suffix: "ValidationException:http://..."
+	errorType, _, _ := strings.Cut(header.Get("X-Amzn-ErrorType"), ":")
+	return c.parseAPIError(statusCode, header, errorType, detail.Message)
+}
+
+// bedrockErrorStatus returns the HTTP status Bedrock uses for an exception type, so
+// exceptions reported inside a stream map to the same ClientError as the response.
+func bedrockErrorStatus(errorType string) int {
+	switch strings.ToLower(errorType) {
+	case "validationexception":
+		return http.StatusBadRequest
+	case "accessdeniedexception", "unrecognizedclientexception", "expiredtokenexception":
+		return http.StatusForbidden
+	case "resourcenotfoundexception":
+		return http.StatusNotFound
+	case "throttlingexception", "servicequotaexceededexception":
+		return http.StatusTooManyRequests
+	case "serviceunavailableexception":
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseAPIError maps a Bedrock status and exception type to a ClientError
+func (c *BedrockClient) parseAPIError(statusCode int, header http.Header, errorType, message string) *ClientError {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		switch strings.ToLower(errorType) {
+		case "accessdeniedexception":
+			return NewPermissionDeniedError("Bedrock API")
+		case "expiredtokenexception":
+			return NewExpiredTokenError()
+		}
+		return NewInvalidAPIKeyError()
+	case http.StatusNotFound:
+		return NewInvalidModelError(c.model)
+	case http.StatusTooManyRequests:
+		return NewRateLimitError(parseRetryAfter(header, c.config.clock().Now()))
+	case http.StatusBadRequest:
+		if isContextOverflowMessage(message) {
+			return NewContextLengthError(message)
+		}
+		if strings.Contains(strings.ToLower(message), "model") {
+			return NewInvalidModelError(c.model)
+		}
+		return NewBadRequestError(message)
+	default:
+		return NewServerError(statusCode, message)
+	}
+}
+
+// SupportsStreaming returns true (Bedrock supports streaming)
+func (c *BedrockClient) SupportsStreaming() bool {
+	return true
+}
+
+// SupportsAssistantPrefill returns true (Claude continues a trailing assistant message)
+func (c *BedrockClient) SupportsAssistantPrefill() bool {
+	return true
+}
+
+// SupportsSeed returns false (Claude does not support seeded sampling)
+func (c *BedrockClient) SupportsSeed() bool {
+	return false
+}
+
+// SupportsConversations returns true (Bedrock supports conversations)
+func (c *BedrockClient) SupportsConversations() bool {
+	return true
+}
+
+// Name returns the client name
+func (c *BedrockClient) Name() string {
+	return c.config.nameOr("Bedrock")
+}
+
+// Model returns the model identifier
+func (c *BedrockClient) Model() string {
+	return c.model
+}
+
+// clientConfig returns the client's configuration.
+func (c *BedrockClient) clientConfig() *ClientConfig {
+	return c.config
+}
+
+// WithOptions returns a copy of the client with opts applied over its config.
+func (c *BedrockClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	config, err := applyRequestOptions(c.config, opts)
+	if err != nil {
+		return nil, err
+	}
+	clone := *c
+	clone.config = config
+	format := *c.format
+	format.config = config
+	clone.format = &format
+	return &clone, nil
+}
+
+// bedrockModelID matches Bedrock IDs such as "us.anthropic.claude-3-5-sonnet-20241022-v2:0",
+// capturing the Anthropic model name.
+var bedrockModelID = regexp.MustCompile(`^(?:[a-z]+\.)?anthropic\.(.+?)(?:-v\d+(?::\d+)?)?$`)
+
+// ModelInfo returns registry data for the Anthropic model behind the Bedrock ID.
+func (c *BedrockClient) ModelInfo() (ModelInfo, bool) {
+	match := bedrockModelID.FindStringSubmatch(c.model)
+	if match == nil {
+		return ModelInfo{}, false
+	}
+	return LookupModelInfo(ProviderClaude, match[1])
+}
+
+// EstimatePromptTokens estimates the prompt size of conversation with the token
+// estimator registered for the configured model.
+func (c *BedrockClient) EstimatePromptTokens(conversation *Conversation) int {
+	return estimateConversationTokens(conversation.Messages, TokenEstimatorForModel(c.model))
+}
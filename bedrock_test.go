@@ -0,0 +1,195 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bedrockChunkFrame wraps a Claude stream event as a Bedrock "chunk" message.
+func bedrockChunkFrame(event string) []byte {
+	payload := `{"bytes":"` + base64.StdEncoding.EncodeToString([]byte(event)) + `"}`
+	return awsEventFrame(map[string]string{
+		":event-type":   "chunk",
+		":content-type": "application/json",
+		":message-type": "event",
+	}, []byte(payload))
+}
+
+func newTestBedrockClient(t *testing.T, baseURL string) *BedrockClient {
+	t.Helper()
+	config := NewClientConfig().
+		SetBedrock("us-west-2", &AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret"}).
+		SetRetries(0)
+	if baseURL != "" {
+		config.SetBaseURL(baseURL)
+	}
+	client, err := NewBedrockClient("anthropic.claude-3-haiku-20240307-v1:0", config)
+	require.NoError(t, err)
+	return client
+}
+
+func TestBedrockClient_SendConversationWithMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/model/anthropic.claude-3-haiku-20240307-v1%3A0/invoke", r.URL.EscapedPath())
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-west-2/bedrock/aws4_request")
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"anthropic_version":"bedrock-2023-05-31","max_tokens":1024,
+			"messages":[{"role":"user","content":"Hello!"}]}`, string(body))
+
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-haiku-20240307",
+			"content":[{"type":"text","text":"Hi there"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":8,"output_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	response, err := newTestBedrockClient(t, server.URL).SendPromptWithMetadata(context.Background(), "Hello!")
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there", response.Content)
+	assert.Equal(t, "msg_1", response.Metadata.RequestID)
+	assert.Equal(t, 11, response.Metadata.TotalTokens)
+	assert.Equal(t, FinishReasonStop, response.Metadata.NormalizedFinishReason)
+}
+
+func TestBedrockClient_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/model/anthropic.claude-3-haiku-20240307-v1:0/invoke-with-response-stream", r.URL.Path)
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","model":"claude-3-haiku-20240307","usage":{"input_tokens":8,"output_tokens":1}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there!"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`,
+			`{"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":8,"outputTokenCount":4}}`,
+		} {
+			_, _ = w.Write(bedrockChunkFrame(event))
+		}
+	}))
+	defer server.Close()
+
+	ch, err := newTestBedrockClient(t, server.URL).StreamPrompt(context.Background(), "Hello!")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "Hello there!", content)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, "msg_1", last.Metadata.RequestID)
+	assert.Equal(t, 12, last.Metadata.TotalTokens)
+	assert.Equal(t, FinishReasonStop, last.Metadata.NormalizedFinishReason)
+}
+
+func TestBedrockClient_StreamException(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bedrockChunkFrame(`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":8}}}`))
+		_, _ = w.Write(awsEventFrame(map[string]string{
+			":message-type":   "exception",
+			":exception-type": "throttlingException",
+		}, []byte(`{"message":"Too many requests"}`)))
+	}))
+	defer server.Close()
+
+	ch, err := newTestBedrockClient(t, server.URL).StreamPrompt(context.Background(), "Hello!")
+	require.NoError(t, err)
+	var streamErr error
+	for chunk := range ch {
+		if chunk.Err != nil {
+			streamErr = chunk.Err
+		}
+	}
+	var clientErr *ClientError
+	require.True(t, errors.As(streamErr, &clientErr))
+	assert.Equal(t, "rate_limit", clientErr.Code)
+}
+
+func TestBedrockClient_ErrorMapping(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		errorType string
+		body      string
+		wantCode  string
+	}{
+		{"bad signature", 403, "UnrecognizedClientException", `{"message":"The security token included in the request is invalid."}`, "invalid_api_key"},
+		{"no model access", 403, "AccessDeniedException:http://internal.amazon.com/coral/com.amazon.bedrock/", `{"message":"You don't have access to the model"}`, "permission_denied"},
+		{"unknown model", 400, "ValidationException", `{"message":"The provided model identifier is invalid."}`, "invalid_model"},
+		{"throttled", 429, "ThrottlingException", `{"message":"Too many requests"}`, "rate_limit"},
+		{"unavailable", 503, "ServiceUnavailableException", `{"message":"Service unavailable"}`, "server_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestBedrockClient(t, "")
+			client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: tt.status,
+					Header:     http.Header{"X-Amzn-Errortype": []string{tt.errorType}},
+					Body:       io.NopCloser(strings.NewReader(tt.body)),
+					Request:    req,
+				}, nil
+			})
+
+			_, err := client.SendPrompt(context.Background(), "Hello!")
+			var clientErr *ClientError
+			require.True(t, errors.As(err, &clientErr))
+			assert.Equal(t, tt.wantCode, clientErr.Code)
+		})
+	}
+}
+
+func TestCreateClient_Bedrock(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-central-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "env-id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	client, err := CreateClient("bedrock", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Bedrock", client.Name())
+	assert.Equal(t, "anthropic.claude-3-haiku-20240307-v1:0", client.Model())
+	assert.Contains(t, GetAvailableProviders(), "bedrock")
+
+	prepared, err := client.(DryRunner).DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://bedrock-runtime.eu-central-1.amazonaws.com/model/anthropic.claude-3-haiku-20240307-v1%3A0/invoke", prepared.URL)
+
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	_, err = CreateClient("bedrock", "", "", nil)
+	var clientErr *ClientError
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, "missing_config", clientErr.Code)
+}
+
+func TestBedrockClient_ModelInfo(t *testing.T) {
+	config := NewClientConfig().SetBedrock("us-east-1", &AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret"})
+
+	for _, model := range []string{"anthropic.claude-3-haiku-20240307-v1:0", "us.anthropic.claude-3-5-sonnet-20241022-v2:0"} {
+		client, err := NewBedrockClient(model, config)
+		require.NoError(t, err)
+		info, ok := client.ModelInfo()
+		require.True(t, ok, model)
+		assert.Equal(t, ProviderClaude, info.Provider)
+		assert.Equal(t, 200000, info.ContextWindow)
+	}
+
+	client, err := NewBedrockClient("meta.llama3-8b-instruct-v1:0", config)
+	require.NoError(t, err)
+	_, ok := client.ModelInfo()
+	assert.False(t, ok)
+}
+
+func TestBedrockClient_BuildRequestTopK(t *testing.T) {
+	client := newTestBedrockClient(t, "")
+	client.config.SetTopK(40)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi")))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"top_k":40`)
+}
@@ -0,0 +1,83 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// bytestream.go implements helpers for streaming raw (non-SSE) text bodies. Reads from
+// the network can end in the middle of a multi-byte UTF-8 sequence, so incomplete
+// runes are held back until the rest of their bytes arrive.
+package chatdelta
+
+import (
+	"context"
+	"io"
+	"unicode/utf8"
+)
+
+// UTF8Decoder converts a sequence of byte slices into strings that never split a
+// multi-byte rune. It is NOT safe for concurrent use.
+type UTF8Decoder struct {
+	pending []byte
+}
+
+// Decode appends p to any bytes held from the previous call and returns the longest
+// prefix made of complete runes. A trailing incomplete rune is kept for the next call.
+func (d *UTF8Decoder) Decode(p []byte) string {
+	buf := append(d.pending, p...)
+	cut := incompleteSuffix(buf)
+	d.pending = append([]byte(nil), buf[cut:]...)
+	return string(buf[:cut])
+}
+
+// Flush returns any held bytes. At end of stream these can only be a truncated rune,
+// which is emitted as-is so no input is lost.
+func (d *UTF8Decoder) Flush() string {
+	s := string(d.pending)
+	d.pending = nil
+	return s
+}
+
+// incompleteSuffix returns the index at which a trailing, not-yet-complete UTF-8
+// sequence starts, or len(buf) if buf ends on a rune boundary.
+func incompleteSuffix(buf []byte) int {
+	// A rune is at most utf8.UTFMax bytes, so only the last few bytes can be pending.
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(buf[i]) {
+			continue
+		}
+		if utf8.FullRune(buf[i:]) {
+			return len(buf)
+		}
+		return i
+	}
+	return len(buf)
+}
+
+// StreamReader reads r in pieces of up to bufSize bytes and delivers the text as
+// StreamChunks, never splitting a multi-byte rune across chunks. The channel ends
+// with a Finished chunk when r is exhausted, on read error, or when ctx is done.
+// A non-positive bufSize defaults to 4096.
+func StreamReader(ctx context.Context, r io.Reader, bufSize int) <-chan StreamChunk {
+	if bufSize <= 0 {
+		bufSize = 4096
+	}
+	out := make(chan StreamChunk, 10)
+	go func() {
+		defer close(out)
+		var decoder UTF8Decoder
+		buf := make([]byte, bufSize)
+		for {
+			if ctx.Err() != nil {
+				out <- StreamChunk{Content: "", Finished: true}
+				return
+			}
+			n, err := r.Read(buf)
+			if n > 0 {
+				if text := decoder.Decode(buf[:n]); text != "" {
+					out <- StreamChunk{Content: text, Finished: false}
+				}
+			}
+			if err != nil {
+				out <- StreamChunk{Content: decoder.Flush(), Finished: true}
+				return
+			}
+		}
+	}()
+	return out
+}
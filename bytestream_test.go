@@ -0,0 +1,58 @@
+package chatdelta
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUTF8Decoder_SplitEmoji(t *testing.T) {
+	emoji := []byte("hi 😀!")
+	// The emoji occupies bytes 3..6; split it after its second byte.
+	var d UTF8Decoder
+	first := d.Decode(emoji[:5])
+	second := d.Decode(emoji[5:])
+
+	assert.Equal(t, "hi ", first)
+	assert.Equal(t, "😀!", second)
+	assert.True(t, utf8.ValidString(first))
+	assert.Equal(t, "", d.Flush())
+}
+
+func TestUTF8Decoder_ByteAtATime(t *testing.T) {
+	input := "naïve café — 日本語 😀"
+	var d UTF8Decoder
+	var out strings.Builder
+	for _, b := range []byte(input) {
+		piece := d.Decode([]byte{b})
+		assert.True(t, utf8.ValidString(piece))
+		out.WriteString(piece)
+	}
+	out.WriteString(d.Flush())
+	assert.Equal(t, input, out.String())
+}
+
+func TestUTF8Decoder_FlushTruncatedRune(t *testing.T) {
+	var d UTF8Decoder
+	assert.Equal(t, "a", d.Decode([]byte{'a', 0xF0, 0x9F}))
+	assert.Equal(t, string([]byte{0xF0, 0x9F}), d.Flush())
+}
+
+func TestStreamReader_ReassemblesSplitRunes(t *testing.T) {
+	input := "stream 😀 with émojis 🎉"
+	ch := StreamReader(context.Background(), iotest.OneByteReader(strings.NewReader(input)), 0)
+
+	var out strings.Builder
+	var last StreamChunk
+	for chunk := range ch {
+		assert.True(t, utf8.ValidString(chunk.Content), "chunk %q is not valid UTF-8", chunk.Content)
+		out.WriteString(chunk.Content)
+		last = chunk
+	}
+	assert.True(t, last.Finished)
+	assert.Equal(t, input, out.String())
+}
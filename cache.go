@@ -0,0 +1,280 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// cache.go defines the Cache interface for storing responses and CachingClient, an
+// AIClient wrapper that answers repeated requests from a Cache.
+package chatdelta
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+)
+
+// Cache stores responses by key. Implementations must be safe for concurrent use.
+// Failures to read or write are treated as misses; a cache never fails a request.
+type Cache interface {
+	// Get returns the response stored under key, if present and not expired
+	Get(key string) (*AiResponse, bool)
+	// Set stores response under key
+	Set(key string, response *AiResponse)
+	// Delete removes the entry for key, if any
+	Delete(key string)
+}
+
+// PurgeableCache is a Cache that can remove every entry at once. MemoryCache and
+// FileCache implement it.
+type PurgeableCache interface {
+	Cache
+	// PurgeAll removes every entry
+	PurgeAll()
+}
+
+// CacheStats counts lookups made by a CachingClient. Expired entries count as misses.
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// HitRatio returns the fraction of lookups answered from the cache, or 0 before any
+// lookup.
+func (s CacheStats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// cacheCounters holds the live counters behind CacheStats. Clients derived with
+// WithOptions share them with the client they came from.
+type cacheCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// CachingClient wraps an AIClient and serves repeated requests from a Cache.
+// Responses served from the cache carry a ServedViaCache segment; cached streams are
+// replayed without contacting the provider.
+type CachingClient struct {
+	inner    AIClient
+	cache    Cache
+	counters *cacheCounters
+}
+
+// NewCachingClient creates a CachingClient answering from cache before calling inner.
+func NewCachingClient(inner AIClient, cache Cache) *CachingClient {
+	return &CachingClient{inner: inner, cache: cache, counters: &cacheCounters{}}
+}
+
+// Stats returns the number of cache hits and misses so far. It is safe to call
+// while requests are in flight.
+func (c *CachingClient) Stats() CacheStats {
+	return CacheStats{Hits: c.counters.hits.Load(), Misses: c.counters.misses.Load()}
+}
+
+// Purge removes the entry stored under key, as returned by CacheKey, so the next
+// matching request goes to the provider.
+func (c *CachingClient) Purge(key string) {
+	c.cache.Delete(key)
+}
+
+// PurgeAll removes every entry from the cache. It returns a config error if the
+// cache does not implement PurgeableCache.
+func (c *CachingClient) PurgeAll() error {
+	purgeable, ok := c.cache.(PurgeableCache)
+	if !ok {
+		return NewInvalidParameterError("cache", "cache does not support PurgeAll")
+	}
+	purgeable.PurgeAll()
+	return nil
+}
+
+// lookup reads key from the cache and counts the outcome.
+func (c *CachingClient) lookup(key string) (*AiResponse, bool) {
+	cached, ok := c.cache.Get(key)
+	if ok {
+		c.counters.hits.Add(1)
+	} else {
+		c.counters.misses.Add(1)
+	}
+	return cached, ok
+}
+
+// CacheKey returns the cache key for sending conversation to client. It covers the
+// client's name and model and every message, so any change to the history is a miss.
+// For the built-in provider clients it also covers the request-shaping settings of
+// their ClientConfig, such as the system message, sampling parameters, and tools.
+func CacheKey(client AIClient, conversation *Conversation) string {
+	var settings *cacheKeySettings
+	if configured, ok := client.(configuredClient); ok {
+		settings = newCacheKeySettings(configured.clientConfig())
+	}
+	payload, _ := json.Marshal(struct {
+		Name     string            `json:"name"`
+		Model    string            `json:"model"`
+		Messages []Message         `json:"messages"`
+		Settings *cacheKeySettings `json:"settings,omitempty"`
+	}{client.Name(), client.Model(), conversation.Messages, settings})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// configuredClient is implemented by the provider clients, exposing their config.
+type configuredClient interface {
+	clientConfig() *ClientConfig
+}
+
+// cacheKeySettings is the part of a ClientConfig that changes what a request asks
+// for. Transport settings such as timeouts and retries are left out.
+type cacheKeySettings struct {
+	SystemMessage    *string         `json:"system_message,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	MaxTokens        *int            `json:"max_tokens,omitempty"`
+	TopP             *float64        `json:"top_p,omitempty"`
+	TopK             *int            `json:"top_k,omitempty"`
+	StopSequences    []string        `json:"stop_sequences,omitempty"`
+	FrequencyPenalty *float64        `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64        `json:"presence_penalty,omitempty"`
+	LogitBias        map[int]int     `json:"logit_bias,omitempty"`
+	Logprobs         bool            `json:"logprobs,omitempty"`
+	TopLogprobs      *int            `json:"top_logprobs,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	Tools            []Tool          `json:"tools,omitempty"`
+	ToolChoice       ToolChoice      `json:"tool_choice,omitempty"`
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	BaseURL          *string         `json:"base_url,omitempty"`
+}
+
+func newCacheKeySettings(config *ClientConfig) *cacheKeySettings {
+	return &cacheKeySettings{
+		SystemMessage:    config.SystemMessage,
+		Temperature:      config.Temperature,
+		MaxTokens:        config.MaxTokens,
+		TopP:             config.TopP,
+		TopK:             config.TopK,
+		StopSequences:    config.StopSequences,
+		FrequencyPenalty: config.FrequencyPenalty,
+		PresencePenalty:  config.PresencePenalty,
+		LogitBias:        config.LogitBias,
+		Logprobs:         config.Logprobs,
+		TopLogprobs:      config.TopLogprobs,
+		Seed:             config.Seed,
+		Tools:            config.Tools,
+		ToolChoice:       config.ToolChoice,
+		ResponseFormat:   config.ResponseFormat,
+		BaseURL:          config.BaseURL,
+	}
+}
+
+// SendPrompt returns a cached response for prompt or sends it to the inner client.
+func (c *CachingClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	response, err := c.SendConversationWithMetadata(ctx, promptConversation(prompt))
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendPromptWithMetadata returns a cached response for prompt or sends it to the inner client.
+func (c *CachingClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	return c.SendConversationWithMetadata(ctx, promptConversation(prompt))
+}
+
+// SendConversation returns a cached response for conversation or sends it to the inner client.
+func (c *CachingClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	response, err := c.SendConversationWithMetadata(ctx, conversation)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendConversationWithMetadata returns a cached response for conversation or sends it
+// to the inner client, caching successful responses.
+func (c *CachingClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	key := CacheKey(c.inner, conversation)
+	if cached, ok := c.lookup(key); ok {
+		response := *cached
+		response.Metadata.Notices = append([]string(nil), cached.Metadata.Notices...)
+		AppendServedVia(&response.Metadata, ServedViaCache)
+		return &response, nil
+	}
+
+	response, err := c.inner.SendConversationWithMetadata(ctx, conversation)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Set(key, response)
+	return response, nil
+}
+
+// StreamPrompt replays a cached response for prompt or streams it from the inner client.
+func (c *CachingClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	return c.StreamConversation(ctx, promptConversation(prompt))
+}
+
+// StreamConversation replays a cached response for conversation as a single content
+// chunk followed by the final chunk, or streams it from the inner client. A stream
+// that finishes without error or truncation is cached under the same key as a
+// non-streaming call, so either kind of call can answer the other.
+func (c *CachingClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
+	key := CacheKey(c.inner, conversation)
+	if cached, ok := c.lookup(key); ok {
+		metadata := cached.Metadata
+		metadata.Notices = append([]string(nil), cached.Metadata.Notices...)
+		AppendServedVia(&metadata, ServedViaCache)
+		ch := make(chan StreamChunk, 2)
+		if cached.Content != "" {
+			ch <- StreamChunk{Content: cached.Content}
+		}
+		ch <- StreamChunk{Finished: true, Metadata: &metadata}
+		close(ch)
+		return ch, nil
+	}
+
+	src, err := c.inner.StreamConversation(ctx, conversation)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan StreamChunk, 10)
+	go func() {
+		defer close(out)
+		var content strings.Builder
+		for chunk := range src {
+			content.WriteString(chunk.Content)
+			if chunk.Finished && chunk.Err == nil && !chunk.Truncated {
+				response := &AiResponse{Content: content.String()}
+				if chunk.Metadata != nil {
+					response.Metadata = *chunk.Metadata
+				}
+				c.cache.Set(key, response)
+			}
+			out <- chunk
+		}
+	}()
+	return out, nil
+}
+
+// SupportsStreaming delegates to the inner client.
+func (c *CachingClient) SupportsStreaming() bool { return c.inner.SupportsStreaming() }
+
+// SupportsConversations delegates to the inner client.
+func (c *CachingClient) SupportsConversations() bool { return c.inner.SupportsConversations() }
+
+// Name delegates to the inner client.
+func (c *CachingClient) Name() string { return c.inner.Name() }
+
+// Model delegates to the inner client.
+func (c *CachingClient) Model() string { return c.inner.Model() }
+
+// WithOptions applies opts to the inner client and wraps the result the same way.
+func (c *CachingClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	inner, err := ClientWithOptions(c.inner, opts...)
+	if err != nil {
+		return nil, err
+	}
+	clone := *c
+	clone.inner = inner
+	return &clone, nil
+}
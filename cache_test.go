@@ -0,0 +1,181 @@
+package chatdelta
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachingClient_ReplaysCachedStream(t *testing.T) {
+	inner := NewMockClient("mock", "")
+	inner.SetChunkSize(4)
+	inner.QueueResponse("streamed answer")
+	client := NewCachingClient(inner, NewMemoryCache(MemoryCacheOptions{}))
+
+	ch, err := client.StreamPrompt(context.Background(), "question")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "streamed answer", content)
+	assert.Equal(t, ServedViaPrimary, last.Metadata.ServedVia)
+
+	ch, err = client.StreamPrompt(context.Background(), "question")
+	require.NoError(t, err)
+	content, last = collectStream(t, ch)
+	assert.Equal(t, "streamed answer", content)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, ServedViaCache, last.Metadata.ServedVia)
+	assert.Equal(t, 1, inner.CallCount())
+
+	// A streamed response also answers the equivalent non-streaming call.
+	response, err := client.SendPromptWithMetadata(context.Background(), "question")
+	require.NoError(t, err)
+	assert.Equal(t, "streamed answer", response.Content)
+	assert.Equal(t, 1, inner.CallCount())
+}
+
+func TestCachingClient_StreamFromNonStreamingEntry(t *testing.T) {
+	inner := NewMockClient("mock", "")
+	inner.QueueResponse("plain answer")
+	client := NewCachingClient(inner, NewMemoryCache(MemoryCacheOptions{}))
+
+	_, err := client.SendPrompt(context.Background(), "question")
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "question")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "plain answer", content)
+	assert.True(t, last.Finished)
+	assert.Equal(t, 1, inner.CallCount())
+}
+
+func TestCachingClient_FailedStreamIsNotCached(t *testing.T) {
+	cache := NewMemoryCache(MemoryCacheOptions{})
+	inner := NewMockClient("mock", "")
+	inner.QueueError(NewServerError(503, "unavailable"))
+	client := NewCachingClient(inner, cache)
+
+	_, err := client.StreamPrompt(context.Background(), "question")
+	require.Error(t, err)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestCachingClient_ExpiredEntryIsRefetched(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewMemoryCache(MemoryCacheOptions{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+	inner := NewMockClient("mock", "")
+	inner.QueueResponse("first")
+	inner.QueueResponse("second")
+	client := NewCachingClient(inner, cache)
+
+	response, err := client.SendPrompt(context.Background(), "question")
+	require.NoError(t, err)
+	assert.Equal(t, "first", response)
+
+	now = now.Add(2 * time.Minute)
+	response, err = client.SendPrompt(context.Background(), "question")
+	require.NoError(t, err)
+	assert.Equal(t, "second", response)
+	assert.Equal(t, 2, inner.CallCount())
+	assert.Equal(t, CacheStats{Hits: 0, Misses: 2}, client.Stats())
+}
+
+func TestCachingClient_EntryExpiresExactlyAtTTL(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := NewMemoryCache(MemoryCacheOptions{TTL: time.Minute})
+	cache.now = func() time.Time { return now }
+	inner := NewMockClient("mock", "answer")
+	client := NewCachingClient(inner, cache)
+
+	_, err := client.SendPrompt(context.Background(), "question")
+	require.NoError(t, err)
+	now = now.Add(time.Minute - time.Nanosecond)
+	_, err = client.SendPrompt(context.Background(), "question")
+	require.NoError(t, err)
+	assert.Equal(t, 1, inner.CallCount())
+
+	now = now.Add(time.Nanosecond)
+	assert.Equal(t, 1, cache.Len(), "expired entries stay until they are next read")
+	_, err = client.SendPrompt(context.Background(), "question")
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.CallCount())
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2}, client.Stats())
+}
+
+func TestCachingClient_Purge(t *testing.T) {
+	inner := NewMockClient("mock", "answer")
+	cache := NewMemoryCache(MemoryCacheOptions{})
+	client := NewCachingClient(inner, cache)
+	ctx := context.Background()
+
+	for _, prompt := range []string{"one", "two", "one", "two"} {
+		_, err := client.SendPrompt(ctx, prompt)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, inner.CallCount())
+
+	client.Purge(CacheKey(inner, promptConversation("one")))
+	_, err := client.SendPrompt(ctx, "one")
+	require.NoError(t, err)
+	_, err = client.SendPrompt(ctx, "two")
+	require.NoError(t, err)
+	assert.Equal(t, 3, inner.CallCount(), "only the purged entry is refetched")
+
+	require.NoError(t, client.PurgeAll())
+	assert.Zero(t, cache.Len())
+	_, err = client.SendPrompt(ctx, "two")
+	require.NoError(t, err)
+	assert.Equal(t, 4, inner.CallCount())
+
+	stats := client.Stats()
+	assert.Equal(t, CacheStats{Hits: 3, Misses: 4}, stats)
+	assert.InDelta(t, 3.0/7.0, stats.HitRatio(), 1e-9)
+}
+
+// deleteOnlyCache is a Cache without PurgeAll.
+type deleteOnlyCache struct{ Cache }
+
+func TestCachingClient_PurgeAllUnsupported(t *testing.T) {
+	client := NewCachingClient(NewMockClient("mock", "answer"), deleteOnlyCache{NewMemoryCache(MemoryCacheOptions{})})
+	err := client.PurgeAll()
+	require.Error(t, err)
+	assert.True(t, isConfigError(err))
+}
+
+func TestCachingClient_StatsAreConcurrencySafe(t *testing.T) {
+	inner := NewMockClient("mock", "answer")
+	client := NewCachingClient(inner, NewMemoryCache(MemoryCacheOptions{}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = client.SendPrompt(context.Background(), fmt.Sprintf("question %d", i%4))
+		}(i)
+	}
+	wg.Wait()
+
+	stats := client.Stats()
+	assert.Equal(t, uint64(20), stats.Hits+stats.Misses)
+	assert.Equal(t, uint64(inner.CallCount()), stats.Misses)
+}
+
+func TestCreateClient_ConfigCacheWrapsClient(t *testing.T) {
+	cache := NewMemoryCache(MemoryCacheOptions{})
+	client, err := CreateClient("openai", "key", "gpt-4o", NewClientConfig().SetCache(cache))
+	require.NoError(t, err)
+	caching, ok := client.(*CachingClient)
+	require.True(t, ok)
+	assert.Equal(t, "gpt-4o", caching.Model())
+
+	plain, err := CreateClient("openai", "key", "gpt-4o", nil)
+	require.NoError(t, err)
+	assert.IsType(t, &OpenAIClient{}, plain)
+}
@@ -89,6 +89,8 @@
 //	}
 package chatdelta
 
+import "context"
+
 const (
 	// Version of the chatdelta-go library
 	Version = "1.1.0"
@@ -104,12 +106,26 @@ const (
 
 // QuickPrompt is a convenience function for sending a quick prompt to a provider
 // without needing to manage client instances. It uses environment variables
-// for API keys and default configurations.
+// for API keys and default configurations, and gives up after the default
+// 30 second timeout.
 func QuickPrompt(provider, prompt string) (string, error) {
-	client, err := CreateClient(provider, "", "", nil)
+	return QuickPromptWithConfig(provider, prompt, nil)
+}
+
+// QuickPromptWithConfig is QuickPrompt with a caller-supplied configuration; a nil
+// config uses NewClientConfig. The whole call, retries included, is bounded by
+// config.Timeout through a context deadline, so a hung connection cannot block it
+// even if the transport does not time out.
+func QuickPromptWithConfig(provider, prompt string, config *ClientConfig) (string, error) {
+	if config == nil {
+		config = NewClientConfig()
+	}
+	client, err := CreateClient(provider, "", "", config)
 	if err != nil {
 		return "", err
 	}
 
-	return client.SendPrompt(nil, prompt)
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	return client.SendPrompt(ctx, prompt)
 }
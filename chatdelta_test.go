@@ -2,6 +2,10 @@ package chatdelta
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -67,59 +71,146 @@ func TestClientConfig_BuilderPattern(t *testing.T) {
 	assert.Equal(t, "Test message", *config.SystemMessage)
 }
 
+func TestAiResponse_ToMessage(t *testing.T) {
+	response := &AiResponse{Content: "Paris.", Metadata: ResponseMetadata{ModelUsed: "gpt-4o"}}
+	assert.Equal(t, Message{Role: "assistant", Content: "Paris."}, response.ToMessage())
+
+	calls := []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: []byte(`{"city":"Paris"}`)}}
+	response = &AiResponse{ToolCalls: calls}
+	message := response.ToMessage()
+	assert.Equal(t, "assistant", message.Role)
+	assert.Equal(t, calls, message.ToolCalls)
+
+	conversation := NewConversation()
+	conversation.AddUserMessage("What is the capital of France?")
+	conversation.Messages = append(conversation.Messages, (&AiResponse{Content: "Paris."}).ToMessage())
+	assert.NoError(t, conversation.ValidateAlternation())
+}
+
 func TestValidateConfig(t *testing.T) {
 	tests := []struct {
 		name    string
 		config  *ClientConfig
-		wantErr bool
+		wantErr string
 	}{
 		{
-			name:    "valid config",
-			config:  NewClientConfig(),
-			wantErr: false,
+			name:   "valid config",
+			config: NewClientConfig(),
 		},
 		{
 			name:    "negative timeout",
 			config:  NewClientConfig().SetTimeout(-1 * time.Second),
-			wantErr: true,
+			wantErr: "invalid parameter timeout: -1s",
 		},
 		{
 			name:    "negative retries",
 			config:  NewClientConfig().SetRetries(-1),
-			wantErr: true,
+			wantErr: "invalid parameter retries: -1",
 		},
 		{
 			name:    "invalid temperature",
 			config:  NewClientConfig().SetTemperature(-1),
-			wantErr: true,
+			wantErr: "invalid parameter temperature: -1",
+		},
+		{
+			name:    "fractional temperature",
+			config:  NewClientConfig().SetTemperature(2.5),
+			wantErr: "invalid parameter temperature: 2.5",
 		},
 		{
 			name:    "invalid max tokens",
 			config:  NewClientConfig().SetMaxTokens(0),
-			wantErr: true,
+			wantErr: "invalid parameter max_tokens: 0",
 		},
 		{
 			name:    "invalid top_p",
 			config:  NewClientConfig().SetTopP(1.5),
-			wantErr: true,
+			wantErr: "invalid parameter top_p: 1.5",
+		},
+		{
+			name:    "negative top_k",
+			config:  NewClientConfig().SetTopK(-1),
+			wantErr: "invalid parameter top_k: -1",
+		},
+		{
+			name:    "invalid frequency penalty",
+			config:  NewClientConfig().SetFrequencyPenalty(-2.5),
+			wantErr: "invalid parameter frequency_penalty: -2.5",
+		},
+		{
+			name:    "invalid presence penalty",
+			config:  NewClientConfig().SetPresencePenalty(3),
+			wantErr: "invalid parameter presence_penalty: 3",
+		},
+		{
+			name:    "zero completions",
+			config:  NewClientConfig().SetN(0),
+			wantErr: "invalid parameter n: 0",
+		},
+		{
+			name:    "logit bias out of range",
+			config:  NewClientConfig().SetLogitBias(map[int]int{10: 100, 20: -101}),
+			wantErr: "invalid parameter logit_bias: bias -101 for token 20 is outside -100..100",
+		},
+		{
+			name:    "logit bias negative token",
+			config:  NewClientConfig().SetLogitBias(map[int]int{-1: 5}),
+			wantErr: "invalid parameter logit_bias: token ID -1 is negative",
+		},
+		{
+			name:    "top logprobs out of range",
+			config:  NewClientConfig().SetTopLogprobs(21),
+			wantErr: "invalid parameter top_logprobs: 21",
+		},
+		{
+			name:    "top logprobs without logprobs",
+			config:  NewClientConfig().SetTopLogprobs(2).SetLogprobs(false),
+			wantErr: "invalid parameter top_logprobs: requires logprobs",
+		},
+		{
+			name:    "unknown retry strategy",
+			config:  NewClientConfig().SetRetryStrategy("fibonacci"),
+			wantErr: "invalid parameter retry_strategy: fibonacci",
+		},
+		{
+			name:    "negative base retry delay",
+			config:  NewClientConfig().SetBaseRetryDelay(-time.Second),
+			wantErr: "invalid parameter base_retry_delay: -1s",
+		},
+		{
+			name:    "negative max retry delay",
+			config:  NewClientConfig().SetMaxRetryDelay(-time.Second),
+			wantErr: "invalid parameter max_retry_delay: -1s",
 		},
 		{
-			name:    "valid temperature range",
-			config:  NewClientConfig().SetTemperature(1.0),
-			wantErr: false,
+			name:    "max retry delay below base",
+			config:  NewClientConfig().SetBaseRetryDelay(2 * time.Second).SetMaxRetryDelay(time.Second),
+			wantErr: "invalid parameter max_retry_delay: 1s is less than base_retry_delay 2s",
 		},
 		{
-			name:    "valid top_p range",
-			config:  NewClientConfig().SetTopP(0.8),
-			wantErr: false,
+			name:   "max retry delay without base",
+			config: NewClientConfig().SetMaxRetryDelay(500 * time.Millisecond),
+		},
+		{
+			name:   "valid temperature range",
+			config: NewClientConfig().SetTemperature(1.0),
+		},
+		{
+			name:   "valid top_p range",
+			config: NewClientConfig().SetTopP(0.8),
+		},
+		{
+			name:   "jitter retry strategy",
+			config: NewClientConfig().SetRetryStrategy(RetryStrategyExponentialWithJitter),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			err := ValidateConfig(tt.config)
-			if tt.wantErr {
-				assert.Error(t, err)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
 			} else {
 				assert.NoError(t, err)
 			}
@@ -224,6 +315,57 @@ func TestCreateClient(t *testing.T) {
 	}
 }
 
+func TestCreateClientFromEnv(t *testing.T) {
+	clearProviderEnv(t)
+	_, err := CreateClientFromEnv(nil)
+	var clientErr *ClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, "missing_config", clientErr.Code)
+
+	t.Setenv("GEMINI_API_KEY", "g-test")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant")
+	client, err := CreateClientFromEnv(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Claude", client.Name(), "claude is preferred over gemini")
+	assert.Equal(t, "claude-3-haiku-20240307", client.Model())
+}
+
+func TestCreateAllAvailableClients(t *testing.T) {
+	clearProviderEnv(t)
+	t.Setenv("GEMINI_API_KEY", "g-test")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant")
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	clients, err := CreateAllAvailableClients(NewClientConfig())
+	require.NoError(t, err)
+	var names []string
+	for _, client := range clients {
+		names = append(names, client.Name())
+	}
+	assert.Equal(t, []string{"OpenAI", "Claude", "Gemini"}, names, "aliases are not duplicated")
+
+	clearProviderEnv(t)
+	_, err = CreateAllAvailableClients(nil)
+	assert.Error(t, err)
+}
+
+func TestQuickPromptWithConfig_BoundedByTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	// Every attempt times out and is retried; the deadline stops the sequence
+	config := NewClientConfig().SetBaseURL(server.URL).SetTimeout(100 * time.Millisecond).
+		SetRetries(3).SetBaseRetryDelay(time.Millisecond)
+	start := time.Now()
+	_, err := QuickPromptWithConfig("openai", "hi", config)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 300*time.Millisecond)
+}
+
 func TestGetDefaultModel(t *testing.T) {
 	tests := []struct {
 		provider string
@@ -269,52 +411,322 @@ func TestMergeStreamChunks(t *testing.T) {
 }
 
 func TestExecuteParallel(t *testing.T) {
-	// Create mock clients for testing
-	var clients []AIClient
+	m1 := NewMockClient("OpenAI", "")
+	m1.QueueResponse("answer one")
+	m2 := NewMockClient("Claude", "")
+	m2.QueueError(NewInvalidAPIKeyError())
+
+	results := ExecuteParallel(context.Background(), []AIClient{m1, m2}, "Test prompt")
 
-	client1, err := CreateClient("openai", "test-key", "", nil)
+	require.Len(t, results, 2)
+	assert.Equal(t, "OpenAI", results[0].ClientName)
+	assert.Equal(t, "answer one", results[0].Result)
+	assert.NoError(t, results[0].Error)
+
+	assert.Equal(t, "Claude", results[1].ClientName)
+	assert.True(t, IsAuthenticationError(results[1].Error))
+
+	assert.Equal(t, "Test prompt", m1.Conversations()[0].Messages[0].Content)
+}
+
+func TestExecuteParallelWithMetadata(t *testing.T) {
+	openai, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetRetries(0))
 	require.NoError(t, err)
-	clients = append(clients, client1)
+	openai.httpClient.Transport = cannedResponse(http.StatusOK, `{"model": "gpt-4o-2024-08-06",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "answer one"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}}`)
+	slow := NewMockClient("slow", "mock-model")
+	slow.SetLatency(30 * time.Millisecond)
+	slow.QueueResponse("answer two")
+	failing := NewMockClient("failing", "")
+	failing.QueueError(NewServerError(500, "down"))
+
+	results := ExecuteParallelWithMetadata(context.Background(), []AIClient{openai, slow, failing}, "Test prompt")
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "OpenAI", results[0].ClientName)
+	assert.Equal(t, "gpt-4o", results[0].Model)
+	require.NoError(t, results[0].Error)
+	require.NotNil(t, results[0].Response)
+	assert.Equal(t, "answer one", results[0].Response.Content)
+	assert.Equal(t, 15, results[0].Response.Metadata.TotalTokens)
+
+	assert.Equal(t, "mock-model", results[1].Model)
+	assert.Equal(t, "answer two", results[1].Response.Content)
+	assert.GreaterOrEqual(t, results[1].LatencyMs, int64(30), "latency is measured per client")
+
+	assert.Nil(t, results[2].Response)
+	assert.Error(t, results[2].Error)
+}
 
-	client2, err := CreateClient("claude", "test-key", "", nil)
+func TestExecuteParallelWithMetadata_ReturnsWhenContextDone(t *testing.T) {
+	fast := NewMockClient("fast", "")
+	fast.QueueResponse("fast answer")
+	// The stuck client's transport ignores the request context
+	release := make(chan struct{})
+	defer close(release)
+	stuck, err := NewOpenAIClient("key", "", NewClientConfig().SetRetries(0))
 	require.NoError(t, err)
-	clients = append(clients, client2)
+	stuck.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-release
+		return nil, errors.New("released")
+	})
 
-	// Note: This will fail with invalid API keys, but tests the structure
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
+	start := time.Now()
+	results := ExecuteParallelWithMetadata(ctx, []AIClient{fast, stuck}, "Test prompt")
+
+	assert.Less(t, time.Since(start), time.Second)
+	require.Len(t, results, 2)
+	require.NoError(t, results[0].Error)
+	assert.Equal(t, "fast answer", results[0].Response.Content)
+	assert.Nil(t, results[1].Response)
+	assert.ErrorIs(t, results[1].Error, context.DeadlineExceeded)
+}
 
-	results := ExecuteParallel(ctx, clients, "Test prompt")
+func TestExecuteParallel_MetadataAndLatency(t *testing.T) {
+	openai, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetRetries(0))
+	require.NoError(t, err)
+	openai.httpClient.Transport = cannedResponse(http.StatusOK, `{"model": "gpt-4o-2024-08-06",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "answer one"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}}`)
+	slow := NewMockClient("slow", "mock-model")
+	slow.SetLatency(30 * time.Millisecond)
+	slow.QueueResponse("answer two")
+	failing := NewMockClient("failing", "")
+	failing.QueueError(NewServerError(500, "down"))
+
+	results := ExecuteParallel(context.Background(), []AIClient{openai, slow, failing}, "Test prompt")
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "gpt-4o", results[0].Model)
+	require.NotNil(t, results[0].Metadata)
+	assert.Equal(t, 15, results[0].Metadata.TotalTokens)
+	assert.Equal(t, "gpt-4o-2024-08-06", results[0].Metadata.ModelUsed)
+
+	assert.Equal(t, "answer two", results[1].Result)
+	assert.GreaterOrEqual(t, results[1].Latency, 30*time.Millisecond, "latency is measured per client")
+
+	assert.Error(t, results[2].Error)
+	assert.Nil(t, results[2].Metadata, "failed clients have no metadata")
+	assert.Positive(t, results[2].Latency)
+}
 
-	assert.Len(t, results, 2)
-	assert.Equal(t, "OpenAI", results[0].ClientName)
-	assert.Equal(t, "Claude", results[1].ClientName)
+func TestExecuteParallel_CustomClientNames(t *testing.T) {
+	body := `{"choices": [{"index": 0, "message": {"role": "assistant", "content": "ok"}, "finish_reason": "stop"}]}`
+	primary, err := NewOpenAIClient("key-1", "gpt-4o", NewClientConfig().SetName("openai-primary"))
+	require.NoError(t, err)
+	primary.httpClient.Transport = cannedResponse(http.StatusOK, body)
+	fallback, err := NewOpenAIClient("key-2", "gpt-4o", NewClientConfig().SetName("openai-fallback"))
+	require.NoError(t, err)
+	fallback.httpClient.Transport = cannedResponse(http.StatusOK, body)
+	unnamed, err := NewClaudeClient("key-3", "", nil)
+	require.NoError(t, err)
 
-	// Results should contain errors due to invalid API keys
-	assert.Error(t, results[0].Error)
-	assert.Error(t, results[1].Error)
+	assert.Equal(t, "openai-primary", GetClientInfo(primary).Name)
+	assert.Equal(t, "Claude", unnamed.Name(), "without a name the provider's is reported")
+
+	results := ExecuteParallel(context.Background(), []AIClient{primary, fallback}, "Test prompt")
+	require.Len(t, results, 2)
+	assert.Equal(t, "openai-primary", results[0].ClientName)
+	assert.Equal(t, "openai-fallback", results[1].ClientName)
 }
 
-func TestExecuteParallelConversation(t *testing.T) {
-	// Create mock clients for testing
-	var clients []AIClient
+func TestExecuteParallelStream_CompletionOrder(t *testing.T) {
+	slow := NewMockClient("slow", "")
+	slow.SetLatency(150 * time.Millisecond)
+	slow.QueueResponse("slow answer")
+	fast := NewMockClient("fast", "")
+	fast.SetLatency(10 * time.Millisecond)
+	fast.QueueResponse("fast answer")
+	medium := NewMockClient("medium", "")
+	medium.SetLatency(80 * time.Millisecond)
+	medium.QueueError(NewServerError(500, "down"))
+
+	var names []string
+	for result := range ExecuteParallelStream(context.Background(), []AIClient{slow, fast, medium}, "Test prompt") {
+		names = append(names, result.ClientName)
+		switch result.ClientName {
+		case "fast":
+			assert.Equal(t, "fast answer", result.Result)
+		case "medium":
+			assert.Error(t, result.Error)
+		}
+	}
+	assert.Equal(t, []string{"fast", "medium", "slow"}, names)
+}
+
+func TestExecuteParallelStream_ReportsCancelledClients(t *testing.T) {
+	fast := NewMockClient("fast", "")
+	fast.QueueResponse("fast answer")
+	stuck := NewMockClient("stuck", "")
+	stuck.SetLatency(time.Hour)
 
-	client1, err := CreateClient("openai", "test-key", "", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var results []ParallelResult
+	for result := range ExecuteParallelStream(ctx, []AIClient{fast, stuck}, "Test prompt") {
+		results = append(results, result)
+	}
+	require.Len(t, results, 2)
+	assert.Equal(t, "fast", results[0].ClientName)
+	assert.Equal(t, "fast answer", results[0].Result)
+	assert.Equal(t, "stuck", results[1].ClientName)
+	assert.ErrorIs(t, results[1].Error, context.DeadlineExceeded)
+}
+
+func TestExecuteParallel_ReturnsWhenContextDone(t *testing.T) {
+	stuck := NewMockClient("stuck", "")
+	stuck.SetLatency(time.Hour)
+	fast := NewMockClient("fast", "")
+	fast.QueueResponse("fast answer")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results := ExecuteParallel(ctx, []AIClient{stuck, fast}, "Test prompt")
+
+	require.Len(t, results, 2)
+	assert.Equal(t, "stuck", results[0].ClientName, "results keep the order of clients")
+	assert.ErrorIs(t, results[0].Error, context.DeadlineExceeded)
+	assert.Equal(t, "fast answer", results[1].Result)
+}
+
+func TestExecuteFastest_CancelsLosers(t *testing.T) {
+	aborted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client hanging up once the body is consumed
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer server.Close()
+	slow, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetBaseURL(server.URL).SetRetries(0))
 	require.NoError(t, err)
-	clients = append(clients, client1)
+
+	fast := NewMockClient("fast", "")
+	fast.SetLatency(20 * time.Millisecond)
+	fast.QueueResponse("fast answer")
+
+	result, err := ExecuteFastest(context.Background(), []AIClient{slow, fast}, "Test prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "fast", result.ClientName)
+	assert.Equal(t, "fast answer", result.Result)
+
+	select {
+	case <-aborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the losing HTTP request was not cancelled")
+	}
+}
+
+func TestExecuteFastest_AllFail(t *testing.T) {
+	down := NewServerError(503, "down")
+	a := NewMockClient("a", "")
+	a.QueueError(down)
+	b := NewMockClient("b", "")
+	b.SetLatency(10 * time.Millisecond)
+	badKey := NewInvalidAPIKeyError()
+	b.QueueError(badKey)
+
+	_, err := ExecuteFastest(context.Background(), []AIClient{a, b}, "Test prompt")
+	var fastestErr *FastestError
+	require.ErrorAs(t, err, &fastestErr)
+	require.Len(t, fastestErr.Failures, 2)
+	assert.Equal(t, "a", fastestErr.Failures[0].ClientName)
+	assert.Equal(t, "b", fastestErr.Failures[1].ClientName)
+	assert.ErrorIs(t, err, down)
+	assert.ErrorIs(t, err, badKey)
+	assert.Contains(t, err.Error(), "all 2 clients failed")
+
+	_, err = ExecuteFastest(context.Background(), nil, "Test prompt")
+	assert.Error(t, err)
+}
+
+func TestExecuteParallelConversation(t *testing.T) {
+	m := NewMockClient("OpenAI", "")
+	m.ScriptReply("Test message", "scripted answer")
 
 	conversation := NewConversation()
+	conversation.AddSystemMessage("Be brief.")
 	conversation.AddUserMessage("Test message")
 
-	// Note: This will fail with invalid API keys, but tests the structure
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
-	results := ExecuteParallelConversation(ctx, clients, conversation)
+	results := ExecuteParallelConversation(context.Background(), []AIClient{m}, conversation)
 
-	assert.Len(t, results, 1)
+	require.Len(t, results, 1)
 	assert.Equal(t, "OpenAI", results[0].ClientName)
+	assert.Equal(t, "scripted answer", results[0].Result)
+	assert.NoError(t, results[0].Error)
+	assert.Len(t, m.Conversations()[0].Messages, 2)
+}
+
+// promptOnlyClient is a MockClient that does not support conversations.
+type promptOnlyClient struct {
+	*MockClient
+}
+
+func (promptOnlyClient) SupportsConversations() bool { return false }
+
+func fallbackConversation() *Conversation {
+	conversation := NewConversation()
+	conversation.AddSystemMessage("Be brief.")
+	conversation.AddUserMessage("What is Go?")
+	conversation.AddAssistantMessage("A programming language.")
+	conversation.AddUserMessage("Who made it?")
+	return conversation
+}
 
-	// Result should contain error due to invalid API key
-	assert.Error(t, results[0].Error)
+func TestExecuteParallelConversation_FallbackLastMessage(t *testing.T) {
+	m := NewMockClient("prompt-only", "")
+	m.QueueResponse("Google.")
+
+	results := ExecuteParallelConversation(context.Background(), []AIClient{promptOnlyClient{m}}, fallbackConversation())
+
+	require.NoError(t, results[0].Error)
+	assert.Equal(t, "Google.", results[0].Result)
+	require.Len(t, m.Conversations()[0].Messages, 1)
+	assert.Equal(t, "Who made it?", m.Conversations()[0].Messages[0].Content)
+
+	conversation := fallbackConversation()
+	conversation.AddAssistantMessage("Google.")
+	results = ExecuteParallelConversation(context.Background(), []AIClient{promptOnlyClient{m}}, conversation)
+	assert.True(t, isConfigError(results[0].Error), "the last message must be from the user")
+}
+
+func TestExecuteParallelConversation_FallbackFlatten(t *testing.T) {
+	m := NewMockClient("prompt-only", "")
+	m.QueueResponse("Google.")
+	opts := ParallelConversationOptions{Fallback: ConversationFallbackFlatten}
+
+	results := ExecuteParallelConversationWithOptions(context.Background(), []AIClient{promptOnlyClient{m}}, fallbackConversation(), opts)
+
+	require.NoError(t, results[0].Error)
+	assert.Equal(t, "Google.", results[0].Result)
+	require.Len(t, m.Conversations()[0].Messages, 1)
+	assert.Equal(t, "System: Be brief.\n\nUser: What is Go?\n\nAssistant: A programming language.\n\nUser: Who made it?",
+		m.Conversations()[0].Messages[0].Content)
+}
+
+func TestExecuteParallelConversation_FallbackError(t *testing.T) {
+	promptOnly := NewMockClient("prompt-only", "")
+	full := NewMockClient("full", "")
+	full.QueueResponse("Google.")
+	opts := ParallelConversationOptions{Fallback: ConversationFallbackError}
+
+	results := ExecuteParallelConversationWithOptions(context.Background(), []AIClient{promptOnlyClient{promptOnly}, full}, fallbackConversation(), opts)
+
+	assert.True(t, isConfigError(results[0].Error))
+	assert.Zero(t, promptOnly.CallCount(), "nothing is sent to the client")
+	require.NoError(t, results[1].Error)
+	assert.Len(t, full.Conversations()[0].Messages, 4, "clients that support conversations get all of it")
+}
+
+func TestExecuteParallelConversation_FallbackEmptyConversation(t *testing.T) {
+	for _, fallback := range []ConversationFallback{ConversationFallbackLastMessage, ConversationFallbackFlatten, ConversationFallbackError} {
+		m := NewMockClient("prompt-only", "")
+		results := ExecuteParallelConversationWithOptions(context.Background(), []AIClient{promptOnlyClient{m}}, NewConversation(),
+			ParallelConversationOptions{Fallback: fallback})
+		assert.True(t, isConfigError(results[0].Error), "fallback %d", fallback)
+		assert.Zero(t, m.CallCount())
+	}
 }
@@ -0,0 +1,114 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// chunks.go splits long documents into token-bounded pieces for map-reduce style
+// pipelines such as summarizing a document that does not fit one request.
+package chatdelta
+
+import (
+	"regexp"
+	"strings"
+)
+
+// paragraphBreak matches a blank line, optionally containing whitespace.
+var paragraphBreak = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+// SplitIntoChunks splits text into pieces that each fit in maxTokens according to
+// the token estimator for model. Paragraphs are packed together while they fit; a
+// paragraph that is too large on its own is split between sentences, a sentence that
+// is too large between words, and only a single oversized word is cut mid-word.
+// Paragraphs within a chunk stay separated by a blank line, and surrounding
+// whitespace is trimmed from every chunk.
+// A non-positive maxTokens returns the trimmed text as a single chunk.
+func SplitIntoChunks(text string, maxTokens int, model string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if maxTokens <= 0 {
+		return []string{text}
+	}
+
+	estimator := TokenEstimatorForModel(model)
+	var chunks []string
+	var current string
+	flush := func() {
+		if current != "" {
+			chunks = append(chunks, current)
+			current = ""
+		}
+	}
+
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if current != "" && estimator.EstimateTokens(current+"\n\n"+paragraph) <= maxTokens {
+			current += "\n\n" + paragraph
+			continue
+		}
+		flush()
+		if estimator.EstimateTokens(paragraph) <= maxTokens {
+			current = paragraph
+			continue
+		}
+		chunks = append(chunks, splitParagraph(paragraph, maxTokens, estimator)...)
+	}
+	flush()
+	return chunks
+}
+
+// splitParagraph packs the sentences of an oversized paragraph into chunks.
+func splitParagraph(paragraph string, maxTokens int, estimator TokenEstimator) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if chunk := strings.TrimSpace(current.String()); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+	}
+
+	for _, sentence := range splitSentences(paragraph) {
+		if estimator.EstimateTokens(strings.TrimSpace(current.String()+sentence)) <= maxTokens {
+			current.WriteString(sentence)
+			continue
+		}
+		flush()
+		if estimator.EstimateTokens(strings.TrimSpace(sentence)) <= maxTokens {
+			current.WriteString(sentence)
+			continue
+		}
+		chunks = append(chunks, splitSentence(sentence, maxTokens, estimator)...)
+	}
+	flush()
+	return chunks
+}
+
+// splitSentence packs the words of an oversized sentence into chunks, cutting any
+// single word that exceeds maxTokens at a rune boundary.
+func splitSentence(sentence string, maxTokens int, estimator TokenEstimator) []string {
+	var chunks []string
+	current := ""
+	for _, word := range strings.Fields(sentence) {
+		if current != "" && estimator.EstimateTokens(current+" "+word) <= maxTokens {
+			current += " " + word
+			continue
+		}
+		if current != "" {
+			chunks = append(chunks, current)
+		}
+		for estimator.EstimateTokens(word) > maxTokens {
+			head := cutRunes(word, maxTokens, estimator, false)
+			if head == "" {
+				break
+			}
+			chunks = append(chunks, head)
+			word = word[len(head):]
+		}
+		current = word
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
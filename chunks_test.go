@@ -0,0 +1,64 @@
+package chatdelta
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitIntoChunks_StaysUnderLimit(t *testing.T) {
+	var paragraphs []string
+	for i := 0; i < 30; i++ {
+		paragraphs = append(paragraphs, strings.Repeat("The quick brown fox jumps over the lazy dog. ", 1+i%7))
+	}
+	text := strings.Join(paragraphs, "\n\n")
+
+	chunks := SplitIntoChunks(text, 50, "gpt-4o")
+	require.NotEmpty(t, chunks)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, DefaultTokenEstimator.EstimateTokens(chunk), 50)
+		assert.Equal(t, strings.TrimSpace(chunk), chunk)
+	}
+	assert.Equal(t, strings.Fields(text), strings.Fields(strings.Join(chunks, " ")), "no text is lost or reordered")
+}
+
+func TestSplitIntoChunks_PacksParagraphs(t *testing.T) {
+	text := "First paragraph.\n\nSecond paragraph.\n  \nThird paragraph that is a good deal longer than the others."
+
+	chunks := SplitIntoChunks(text, 16, "")
+	assert.Equal(t, []string{
+		"First paragraph.\n\nSecond paragraph.",
+		"Third paragraph that is a good deal longer than the others.",
+	}, chunks)
+}
+
+func TestSplitIntoChunks_SplitsOnSentences(t *testing.T) {
+	text := "Alpha sentence here. Beta sentence here. Gamma sentence here."
+
+	chunks := SplitIntoChunks(text, 6, "")
+	assert.Equal(t, []string{"Alpha sentence here.", "Beta sentence here.", "Gamma sentence here."}, chunks)
+}
+
+func TestSplitIntoChunks_SplitsLongSentenceBetweenWords(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve"
+
+	chunks := SplitIntoChunks(text, 5, "")
+	assert.Equal(t, []string{"one two three four", "five six seven eight", "nine ten eleven", "twelve"}, chunks)
+}
+
+func TestSplitIntoChunks_CutsOversizedWord(t *testing.T) {
+	text := strings.Repeat("x", 100)
+
+	chunks := SplitIntoChunks(text, 5, "")
+	require.Len(t, chunks, 5)
+	for _, chunk := range chunks {
+		assert.Len(t, chunk, 20)
+	}
+}
+
+func TestSplitIntoChunks_EdgeCases(t *testing.T) {
+	assert.Nil(t, SplitIntoChunks("   \n\n ", 10, ""))
+	assert.Equal(t, []string{"whole text"}, SplitIntoChunks(" whole text ", 0, ""))
+}
@@ -8,8 +8,12 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
+// claudeBaseURL is the default Anthropic API endpoint; ClientConfig.BaseURL overrides it.
+const claudeBaseURL = "https://api.anthropic.com/v1"
+
 // ClaudeClient implements the AIClient interface for Anthropic's Claude API
 type ClaudeClient struct {
 	apiKey     string
@@ -20,8 +24,40 @@ type ClaudeClient struct {
 
 // Claude API request/response structures
 type claudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a string, or []claudeBlock for tool use, tool results, and images
+	Content interface{} `json:"content"`
+}
+
+// claudeBlock is a structured content block in a request message.
+type claudeBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	Source    *claudeSource   `json:"source,omitempty"`
+}
+
+// claudeSource is the source of an image block: inline base64 data or a URL.
+type claudeSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      []byte `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type claudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type claudeToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 type claudeRequest struct {
@@ -32,16 +68,31 @@ type claudeRequest struct {
 	Temperature *float64        `json:"temperature,omitempty"`
 	MaxTokens   int             `json:"max_tokens"`
 	TopP        *float64        `json:"top_p,omitempty"`
+	TopK        *int            `json:"top_k,omitempty"`
+	// StopSequences end generation; the one hit is reported as stop_sequence
+	StopSequences []string          `json:"stop_sequences,omitempty"`
+	Tools         []claudeTool      `json:"tools,omitempty"`
+	ToolChoice    *claudeToolChoice `json:"tool_choice,omitempty"`
 }
 
 type claudeContent struct {
 	Type string `json:"type"`
 	Text string `json:"text"`
+	// ID, Name, and Input are set on tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
 }
 
+// claudeEmptySchema is sent for tools declared without parameters, since Claude
+// requires an input_schema.
+var claudeEmptySchema = json.RawMessage(`{"type":"object","properties":{}}`)
+
 type claudeDelta struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	StopReason   string `json:"stop_reason,omitempty"`
+	StopSequence string `json:"stop_sequence,omitempty"`
 }
 
 type claudeResponse struct {
@@ -56,6 +107,14 @@ type claudeResponse struct {
 	} `json:"usage,omitempty"`
 	Delta      *claudeDelta `json:"delta,omitempty"`
 	StopReason *string      `json:"stop_reason,omitempty"`
+	// StopSequence is the stop sequence that ended generation, if any
+	StopSequence *string `json:"stop_sequence,omitempty"`
+	// Message carries the response envelope in streaming message_start events
+	Message *claudeResponse `json:"message,omitempty"`
+	// Error is set on streaming error events
+	Error *claudeErrorDetail `json:"error,omitempty"`
+	// info is filled in by sendRequest
+	info responseInfo
 }
 
 type claudeErrorDetail struct {
@@ -83,12 +142,10 @@ func NewClaudeClient(apiKey, model string, config *ClientConfig) (*ClaudeClient,
 	}
 
 	return &ClaudeClient{
-		apiKey: apiKey,
-		model:  model,
-		config: config,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		apiKey:     apiKey,
+		model:      model,
+		config:     config,
+		httpClient: newHTTPClient(config),
 	}, nil
 }
 
@@ -102,6 +159,17 @@ func (c *ClaudeClient) SendPrompt(ctx context.Context, prompt string) (string, e
 
 // SendConversation sends a conversation to Claude
 func (c *ClaudeClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	if c.config.keepAliveViaStreaming() {
+		response, err := sendViaStream(ctx, c.config, conversation, c.streamRequest)
+		if err != nil {
+			return "", err
+		}
+		return response.Content, nil
+	}
+
 	var result string
 	var lastErr error
 
@@ -117,11 +185,16 @@ func (c *ClaudeClient) SendConversation(ctx context.Context, conversation *Conve
 			return lastErr
 		}
 
-		result = response.Content[0].Text
-		return nil
+		text, _, err := claudeResponseContent(response)
+		if err != nil {
+			lastErr = err
+			return err
+		}
+		result, err = postProcessContent(c.config, conversation, text, nil)
+		return err
 	}
 
-	err := ExecuteWithRetry(ctx, c.config.Retries, operation)
+	err := c.config.retry(ctx, operation)
 	if err != nil {
 		return "", err
 	}
@@ -144,21 +217,44 @@ func (c *ClaudeClient) StreamConversation(ctx context.Context, conversation *Con
 	go func() {
 		defer close(resultChan)
 
+		ctx, cancel := c.config.withOperationTimeout(ctx)
+		defer cancel()
+
+		sink := newStreamSink(ctx, resultChan, c.config)
 		operation := func() error {
-			return c.streamRequest(ctx, conversation, resultChan)
+			return c.streamRequest(ctx, conversation, sink)
 		}
 
-		err := ExecuteWithRetry(ctx, c.config.Retries, operation)
+		err := c.config.retry(ctx, operation)
 		if err != nil {
-			resultChan <- StreamChunk{Content: "", Finished: true}
+			sink.fail(ctx, c.config, err)
 		}
 	}()
 
 	return resultChan, nil
 }
 
-// sendRequest sends a request to the Claude API
-func (c *ClaudeClient) sendRequest(ctx context.Context, conversation *Conversation, stream bool) (*claudeResponse, error) {
+// claudeContentBlocks maps a multimodal message to text and image blocks.
+func claudeContentBlocks(msg Message) []claudeBlock {
+	var blocks []claudeBlock
+	for _, part := range messageParts(msg) {
+		switch {
+		case part.Type == ContentPartImage && part.Image != nil:
+			source := &claudeSource{Type: "url", URL: part.Image.URL}
+			if part.Image.URL == "" {
+				source = &claudeSource{Type: "base64", MediaType: part.Image.MIMEType, Data: part.Image.Data}
+			}
+			blocks = append(blocks, claudeBlock{Type: "image", Source: source})
+		case part.Type == ContentPartText:
+			blocks = append(blocks, claudeBlock{Type: "text", Text: part.Text})
+		}
+	}
+	return blocks
+}
+
+// buildRequest converts a conversation and the client configuration into a Claude request body.
+// Claude has no seed parameter, so ClientConfig.Seed is ignored.
+func (c *ClaudeClient) buildRequest(conversation *Conversation, stream bool) claudeRequest {
 	// Separate system messages from conversation messages
 	var systemMessage string
 	var messages []claudeMessage
@@ -169,14 +265,40 @@ func (c *ClaudeClient) sendRequest(ctx context.Context, conversation *Conversati
 	}
 
 	for _, msg := range conversation.Messages {
-		if msg.Role == "system" {
+		switch {
+		case msg.Role == "system":
 			// Append system messages to the system prompt
 			if systemMessage != "" {
 				systemMessage += "\n\n" + msg.Content
 			} else {
 				systemMessage = msg.Content
 			}
-		} else {
+		case msg.Role == "tool":
+			// Tool results are user turns; results of parallel calls share one message
+			block := claudeBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content}
+			if n := len(messages); n > 0 && messages[n-1].Role == "user" {
+				if blocks, ok := messages[n-1].Content.([]claudeBlock); ok {
+					messages[n-1].Content = append(blocks, block)
+					continue
+				}
+			}
+			messages = append(messages, claudeMessage{Role: "user", Content: []claudeBlock{block}})
+		case len(msg.ToolCalls) > 0:
+			var blocks []claudeBlock
+			if msg.Content != "" {
+				blocks = append(blocks, claudeBlock{Type: "text", Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				input := call.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, claudeBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			messages = append(messages, claudeMessage{Role: msg.Role, Content: blocks})
+		case len(msg.Parts) > 0:
+			messages = append(messages, claudeMessage{Role: msg.Role, Content: claudeContentBlocks(msg)})
+		default:
 			messages = append(messages, claudeMessage{
 				Role:    msg.Role,
 				Content: msg.Content,
@@ -184,35 +306,213 @@ func (c *ClaudeClient) sendRequest(ctx context.Context, conversation *Conversati
 		}
 	}
 
+	// Claude has no JSON mode; ask for JSON in the system prompt instead
+	if c.config.ResponseFormat != nil {
+		systemMessage = strings.TrimPrefix(systemMessage+"\n\n"+c.config.ResponseFormat.instruction(), "\n\n")
+	}
+
 	maxTokens := 1024
 	if c.config.MaxTokens != nil {
 		maxTokens = *c.config.MaxTokens
 	}
 
 	request := claudeRequest{
-		Model:       c.model,
-		Messages:    messages,
-		System:      systemMessage,
-		Stream:      stream,
-		Temperature: c.config.Temperature,
-		MaxTokens:   maxTokens,
-		TopP:        c.config.TopP,
+		Model:         c.model,
+		Messages:      messages,
+		System:        systemMessage,
+		Stream:        stream,
+		Temperature:   c.config.Temperature,
+		MaxTokens:     maxTokens,
+		TopP:          c.config.TopP,
+		TopK:          c.config.TopK,
+		StopSequences: c.config.StopSequences,
+	}
+	if len(c.config.Tools) > 0 {
+		request.Tools, request.ToolChoice = c.buildTools()
+	}
+	return request
+}
+
+// buildTools converts the configured tools and tool choice into Claude's schema.
+func (c *ClaudeClient) buildTools() ([]claudeTool, *claudeToolChoice) {
+	tools := make([]claudeTool, len(c.config.Tools))
+	for i, tool := range c.config.Tools {
+		schema := tool.Parameters
+		if len(schema) == 0 {
+			schema = claudeEmptySchema
+		}
+		tools[i] = claudeTool{Name: tool.Name, Description: tool.Description, InputSchema: schema}
 	}
 
-	jsonData, err := json.Marshal(request)
+	var choice *claudeToolChoice
+	switch name, forced := c.config.ToolChoice.function(); {
+	case forced:
+		choice = &claudeToolChoice{Type: "tool", Name: name}
+	case c.config.ToolChoice == ToolChoiceRequired:
+		choice = &claudeToolChoice{Type: "any"}
+	case c.config.ToolChoice != "":
+		choice = &claudeToolChoice{Type: string(c.config.ToolChoice)}
+	}
+	return tools, choice
+}
+
+// claudeResponseContent joins the text blocks of response and collects its tool_use
+// blocks.
+func claudeResponseContent(response *claudeResponse) (string, []ToolCall, error) {
+	var text strings.Builder
+	var calls []ToolCall
+	for _, block := range response.Content {
+		switch block.Type {
+		case "tool_use":
+			arguments, err := toolArguments(block.Input)
+			if err != nil {
+				return "", nil, err
+			}
+			calls = append(calls, ToolCall{ID: block.ID, Name: block.Name, Arguments: arguments})
+		default:
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String(), calls, nil
+}
+
+// claudeAiResponse converts a complete Messages API response into an AiResponse.
+func claudeAiResponse(response *claudeResponse) (*AiResponse, error) {
+	if len(response.Content) == 0 {
+		return nil, NewMissingFieldError("content")
+	}
+	text, toolCalls, err := claudeResponseContent(response)
 	if err != nil {
-		return nil, NewJSONParseError(err)
+		return nil, err
+	}
+	finishReason, stopSequence := "", ""
+	if response.StopReason != nil {
+		finishReason = *response.StopReason
+	}
+	if response.StopSequence != nil {
+		stopSequence = *response.StopSequence
+	}
+	return &AiResponse{
+		Content:   text,
+		ToolCalls: toolCalls,
+		Metadata: ResponseMetadata{
+			ModelUsed:              response.Model,
+			PromptTokens:           response.Usage.InputTokens,
+			CompletionTokens:       response.Usage.OutputTokens,
+			TotalTokens:            response.Usage.InputTokens + response.Usage.OutputTokens,
+			FinishReason:           finishReason,
+			NormalizedFinishReason: NormalizeFinishReason(ProviderClaude, finishReason),
+			StopSequence:           stopSequence,
+			RequestID:              response.info.requestID,
+			LatencyMs:              response.info.latency.Milliseconds(),
+			ServedVia:              ServedViaPrimary,
+		},
+	}, nil
+}
+
+// newHTTPRequest builds the HTTP request for conversation, applying any configured
+// request mutators to the body. The marshaled body is returned alongside the request.
+func (c *ClaudeClient) newHTTPRequest(ctx context.Context, conversation *Conversation, stream bool) (*http.Request, []byte, error) {
+	conversation, err := prepareConversation(c.config, conversation)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := conversation.ValidateAlternation(); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateImages(ProviderClaude, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateVision(ProviderClaude, c.model, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateMaxTokens(ProviderClaude, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateStopSequences(ProviderClaude, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateTools(c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateResponseFormat(c.config); err != nil {
+		return nil, nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+	body, err := marshalRequestBody(c.config, ProviderClaude, c.model, c.buildRequest(conversation, stream))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := c.newRawHTTPRequest(ctx, body, stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, body, nil
+}
+
+// newRawHTTPRequest builds the messages request for an already-marshaled body.
+func (c *ClaudeClient) newRawHTTPRequest(ctx context.Context, body []byte, stream bool) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL(c.config, claudeBaseURL, "/messages"), bytes.NewReader(body))
 	if err != nil {
 		return nil, NewConnectionError(err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	c.setAuthHeaders(req)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	applyExtraHeaders(req, c.config, "x-api-key")
+
+	return req, nil
+}
+
+// setAuthHeaders adds the API key and version headers.
+func (c *ClaudeClient) setAuthHeaders(req *http.Request) {
 	req.Header.Set("x-api-key", c.apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
+}
+
+// SendRaw posts body verbatim to the Claude chat endpoint using the client's
+// credentials, extra headers, and retry policy, and returns the raw response body.
+// Request mutators are not applied.
+func (c *ClaudeClient) SendRaw(ctx context.Context, body json.RawMessage) (json.RawMessage, error) {
+	if err := checkRawBody(c.config, c.model, body); err != nil {
+		return nil, err
+	}
+	return sendRaw(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		return c.newRawHTTPRequest(ctx, body, false)
+	}, c.errorFromBody)
+}
+
+// StreamEvents streams conversation and delivers each Messages API event (message_start,
+// content_block_delta, ping, error, and any others) exactly as Claude sends it.
+func (c *ClaudeClient) StreamEvents(ctx context.Context, conversation *Conversation) (<-chan StreamEvent, error) {
+	return streamEvents(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		req, _, err := c.newHTTPRequest(ctx, conversation, true)
+		return req, err
+	}, c.errorFromBody), nil
+}
+
+// DryRun returns the request that would be sent for conversation without sending it.
+// Credentials are redacted.
+func (c *ClaudeClient) DryRun(conversation *Conversation, stream bool) (*PreparedRequest, error) {
+	req, body, err := c.newHTTPRequest(context.Background(), conversation, stream)
+	if err != nil {
+		return nil, err
+	}
+	return newPreparedRequest(req, body), nil
+}
 
+// sendRequest sends a request to the Claude API
+func (c *ClaudeClient) sendRequest(ctx context.Context, conversation *Conversation, stream bool) (*claudeResponse, error) {
+	req, _, err := c.newHTTPRequest(ctx, conversation, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
@@ -228,78 +528,26 @@ func (c *ClaudeClient) sendRequest(ctx context.Context, conversation *Conversati
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errorResp claudeErrorResponse
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, c.parseAPIError(resp.StatusCode, &errorResp.Error)
-		}
-		return nil, NewServerError(resp.StatusCode, string(body))
+		return nil, c.errorFromBody(resp.StatusCode, resp.Header, body)
 	}
 
 	var response claudeResponse
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, NewJSONParseError(err)
 	}
+	response.info = responseInfo{requestID: requestIDFromHeader(resp.Header, response.ID), latency: time.Since(start), body: body}
 
 	return &response, nil
 }
 
 // streamRequest handles streaming requests
-func (c *ClaudeClient) streamRequest(ctx context.Context, conversation *Conversation, resultChan chan<- StreamChunk) error {
-	// Separate system messages from conversation messages
-	var systemMessage string
-	var messages []claudeMessage
-
-	// Start with system message from config if available
-	if c.config.SystemMessage != nil {
-		systemMessage = *c.config.SystemMessage
-	}
-
-	for _, msg := range conversation.Messages {
-		if msg.Role == "system" {
-			// Append system messages to the system prompt
-			if systemMessage != "" {
-				systemMessage += "\n\n" + msg.Content
-			} else {
-				systemMessage = msg.Content
-			}
-		} else {
-			messages = append(messages, claudeMessage{
-				Role:    msg.Role,
-				Content: msg.Content,
-			})
-		}
-	}
-
-	maxTokens := 1024
-	if c.config.MaxTokens != nil {
-		maxTokens = *c.config.MaxTokens
-	}
-
-	request := claudeRequest{
-		Model:       c.model,
-		Messages:    messages,
-		System:      systemMessage,
-		Stream:      true,
-		Temperature: c.config.Temperature,
-		MaxTokens:   maxTokens,
-		TopP:        c.config.TopP,
-	}
-
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return NewJSONParseError(err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
+func (c *ClaudeClient) streamRequest(ctx context.Context, conversation *Conversation, sink *streamSink) error {
+	req, _, err := c.newHTTPRequest(ctx, conversation, true)
 	if err != nil {
-		return NewConnectionError(err)
+		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", c.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-	req.Header.Set("Accept", "text/event-stream")
-
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
@@ -308,23 +556,24 @@ func (c *ClaudeClient) streamRequest(ctx context.Context, conversation *Conversa
 		return NewConnectionError(err)
 	}
 	defer resp.Body.Close()
+	defer closeOnDone(ctx, resp.Body)()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		var errorResp claudeErrorResponse
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return c.parseAPIError(resp.StatusCode, &errorResp.Error)
-		}
-		return NewServerError(resp.StatusCode, string(body))
+		return c.errorFromBody(resp.StatusCode, resp.Header, body)
 	}
 
+	state := newClaudeStreamState(c.model, start, requestIDFromHeader(resp.Header, ""))
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
 		line := scanner.Text()
 		if strings.HasPrefix(line, "data: ") {
 			data := strings.TrimPrefix(line, "data: ")
 			if data == "[DONE]" {
-				resultChan <- StreamChunk{Content: "", Finished: true}
+				state.finish(sink)
 				return nil
 			}
 
@@ -332,38 +581,131 @@ func (c *ClaudeClient) streamRequest(ctx context.Context, conversation *Conversa
 			if err := json.Unmarshal([]byte(data), &response); err != nil {
 				continue // Skip malformed chunks
 			}
+			sink.event(data)
 
-			// Handle different event types
-			switch response.Type {
-			case "content_block_delta":
-				if response.Delta != nil && response.Delta.Type == "text_delta" {
-					resultChan <- StreamChunk{
-						Content:  response.Delta.Text,
-						Finished: false,
-					}
-				}
-			case "message_stop":
-				resultChan <- StreamChunk{Content: "", Finished: true}
+			// Errors after the response has started arrive in-band
+			if response.Type == "error" && response.Error != nil {
+				return c.parseAPIError(claudeErrorStatus(response.Error.Type), nil, response.Error)
+			}
+			if state.handle(&response, sink) {
 				return nil
 			}
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return NewStreamReadError(err)
+	if err := streamReadError(ctx, scanner.Err()); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// claudeStreamState accumulates response metadata across Messages API stream events.
+// Bedrock's event stream carries the same events, so both transports share it.
+type claudeStreamState struct {
+	metadata *ResponseMetadata
+	start    time.Time
+}
+
+// newClaudeStreamState starts the metadata for a stream. requestID is the transport's
+// request ID header; when empty, the message ID is used instead.
+func newClaudeStreamState(model string, start time.Time, requestID string) *claudeStreamState {
+	return &claudeStreamState{
+		metadata: &ResponseMetadata{ModelUsed: model, RequestID: requestID, ServedVia: ServedViaPrimary},
+		start:    start,
+	}
+}
+
+// handle applies one stream event, forwarding text deltas to sink. It returns true
+// once the terminal chunk has been sent. "error" events are left to the caller.
+func (s *claudeStreamState) handle(event *claudeResponse, sink *streamSink) bool {
+	metadata := s.metadata
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			if metadata.RequestID == "" {
+				metadata.RequestID = event.Message.ID
+			}
+			if event.Message.Model != "" {
+				metadata.ModelUsed = event.Message.Model
+			}
+			metadata.PromptTokens = event.Message.Usage.InputTokens
+			metadata.CompletionTokens = event.Message.Usage.OutputTokens
+		}
+	case "content_block_delta":
+		if event.Delta != nil && event.Delta.Type == "text_delta" {
+			sink.send(StreamChunk{
+				Content:  event.Delta.Text,
+				Finished: false,
+			})
+		}
+	case "message_delta":
+		// Usage in message_delta is cumulative for the output side
+		if event.Delta != nil && event.Delta.StopReason != "" {
+			metadata.FinishReason = event.Delta.StopReason
+			metadata.StopSequence = event.Delta.StopSequence
+		}
+		if event.Usage.OutputTokens > 0 {
+			metadata.CompletionTokens = event.Usage.OutputTokens
+		}
+	case "message_stop":
+		s.finish(sink)
+		return true
+	}
+	return false
+}
+
+// finish sends the terminal chunk carrying the accumulated metadata.
+func (s *claudeStreamState) finish(sink *streamSink) {
+	metadata := s.metadata
+	metadata.NormalizedFinishReason = NormalizeFinishReason(ProviderClaude, metadata.FinishReason)
+	metadata.TotalTokens = metadata.PromptTokens + metadata.CompletionTokens
+	metadata.LatencyMs = time.Since(s.start).Milliseconds()
+	sink.send(StreamChunk{Content: "", Finished: true, Metadata: metadata})
+}
+
+// errorFromBody maps a non-200 response body onto a ClientError. Bodies that are not
+// Claude's JSON error envelope are used verbatim as the message.
+func (c *ClaudeClient) errorFromBody(statusCode int, header http.Header, body []byte) *ClientError {
+	var errorResp claudeErrorResponse
+	if err := json.Unmarshal(body, &errorResp); err == nil {
+		return c.parseAPIError(statusCode, header, &errorResp.Error)
+	}
+	return c.parseAPIError(statusCode, header, &claudeErrorDetail{Message: string(body)})
+}
+
+// claudeErrorStatus returns the HTTP status Claude uses for an error type, so errors
+// reported inside a stream map to the same ClientError as the equivalent response.
+func claudeErrorStatus(errorType string) int {
+	switch errorType {
+	case "invalid_request_error":
+		return http.StatusBadRequest
+	case "authentication_error":
+		return http.StatusUnauthorized
+	case "permission_error":
+		return http.StatusForbidden
+	case "not_found_error":
+		return http.StatusNotFound
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "overloaded_error":
+		return 529
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // parseAPIError parses Claude API errors
-func (c *ClaudeClient) parseAPIError(statusCode int, error *claudeErrorDetail) *ClientError {
+func (c *ClaudeClient) parseAPIError(statusCode int, header http.Header, error *claudeErrorDetail) *ClientError {
 	switch statusCode {
 	case http.StatusUnauthorized:
 		return NewInvalidAPIKeyError()
 	case http.StatusTooManyRequests:
-		return NewRateLimitError(nil)
+		return NewRateLimitError(parseRetryAfter(header, c.config.clock().Now()))
 	case http.StatusBadRequest:
+		if isContextOverflowMessage(error.Message) {
+			return NewContextLengthError(error.Message)
+		}
 		if strings.Contains(strings.ToLower(error.Message), "model") {
 			return NewInvalidModelError(c.model)
 		}
@@ -380,6 +722,16 @@ func (c *ClaudeClient) SupportsStreaming() bool {
 	return true
 }
 
+// SupportsSeed returns false (Claude does not support seeded sampling)
+func (c *ClaudeClient) SupportsSeed() bool {
+	return false
+}
+
+// SupportsAssistantPrefill returns true (Claude continues a trailing assistant message)
+func (c *ClaudeClient) SupportsAssistantPrefill() bool {
+	return true
+}
+
 // SupportsConversations returns true (Claude supports conversations)
 func (c *ClaudeClient) SupportsConversations() bool {
 	return true
@@ -394,6 +746,13 @@ func (c *ClaudeClient) SendPromptWithMetadata(ctx context.Context, prompt string
 
 // SendConversationWithMetadata sends a conversation and returns the response with metadata.
 func (c *ClaudeClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	if c.config.keepAliveViaStreaming() {
+		return sendViaStream(ctx, c.config, conversation, c.streamRequest)
+	}
+
 	var result *AiResponse
 	var lastErr error
 
@@ -403,29 +762,17 @@ func (c *ClaudeClient) SendConversationWithMetadata(ctx context.Context, convers
 			lastErr = err
 			return err
 		}
-		if len(response.Content) == 0 {
-			lastErr = NewMissingFieldError("content")
-			return lastErr
-		}
-		finishReason := ""
-		if response.StopReason != nil {
-			finishReason = *response.StopReason
-		}
-		result = &AiResponse{
-			Content: response.Content[0].Text,
-			Metadata: ResponseMetadata{
-				ModelUsed:        response.Model,
-				PromptTokens:     response.Usage.InputTokens,
-				CompletionTokens: response.Usage.OutputTokens,
-				TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
-				FinishReason:     finishReason,
-				RequestID:        response.ID,
-			},
+		result, err = claudeAiResponse(response)
+		if err != nil {
+			lastErr = err
+			return err
 		}
-		return nil
+		result.Raw = response.info.raw(c.config)
+		result.Content, err = postProcessContent(c.config, conversation, result.Content, &result.Metadata)
+		return err
 	}
 
-	if err := ExecuteWithRetry(ctx, c.config.Retries, operation); err != nil {
+	if err := c.config.retry(ctx, operation); err != nil {
 		return nil, err
 	}
 	_ = lastErr
@@ -434,10 +781,71 @@ func (c *ClaudeClient) SendConversationWithMetadata(ctx context.Context, convers
 
 // Name returns the client name
 func (c *ClaudeClient) Name() string {
-	return "Claude"
+	return c.config.nameOr("Claude")
 }
 
 // Model returns the model identifier
 func (c *ClaudeClient) Model() string {
 	return c.model
 }
+
+// clientConfig returns the client's configuration.
+func (c *ClaudeClient) clientConfig() *ClientConfig {
+	return c.config
+}
+
+// WithOptions returns a copy of the client with opts applied over its config.
+func (c *ClaudeClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	config, err := applyRequestOptions(c.config, opts)
+	if err != nil {
+		return nil, err
+	}
+	clone := *c
+	clone.config = config
+	return &clone, nil
+}
+
+// ModelInfo returns registry data for the configured model.
+func (c *ClaudeClient) ModelInfo() (ModelInfo, bool) {
+	return LookupModelInfo(ProviderClaude, c.model)
+}
+
+// EstimatePromptTokens estimates the prompt size of conversation with the token
+// estimator registered for the configured model.
+func (c *ClaudeClient) EstimatePromptTokens(conversation *Conversation) int {
+	return estimateConversationTokens(conversation.Messages, TokenEstimatorForModel(c.model))
+}
+
+// claudeModelList is the GET /models response. Claude reports no limits.
+type claudeModelList struct {
+	Data []struct {
+		ID          string `json:"id"`
+		DisplayName string `json:"display_name"`
+	} `json:"data"`
+}
+
+// ListModels returns the models visible to the API key with their display names.
+func (c *ClaudeClient) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	body, err := sendRaw(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", endpointURL(c.config, claudeBaseURL, "/models?limit=1000"), nil)
+		if err != nil {
+			return nil, NewConnectionError(err)
+		}
+		c.setAuthHeaders(req)
+		applyExtraHeaders(req, c.config, "x-api-key")
+		return req, nil
+	}, c.errorFromBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var list claudeModelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, NewJSONParseError(err)
+	}
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ModelInfo{Provider: ProviderClaude, Model: m.ID, DisplayName: m.DisplayName})
+	}
+	return models, nil
+}
@@ -0,0 +1,71 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaudeClient_BuildRequestIgnoresSeed(t *testing.T) {
+	client, err := NewClaudeClient("test-key", "", NewClientConfig().SetSeed(42))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "seed")
+}
+
+func TestClaudeClient_BuildRequestTopK(t *testing.T) {
+	client, err := NewClaudeClient("test-key", "", NewClientConfig().SetTopK(40))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"top_k":40`)
+}
+
+func TestClaudeClient_StreamFinalChunkMetadata(t *testing.T) {
+	server := transcriptServer(t, "claude_stream.sse")
+	client, err := NewClaudeClient("test-key", "", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+
+	assert.Equal(t, "Hello there!", content)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, "claude-3-5-sonnet-20241022", last.Metadata.ModelUsed)
+	assert.Equal(t, "end_turn", last.Metadata.FinishReason)
+	assert.Equal(t, 25, last.Metadata.PromptTokens)
+	assert.Equal(t, 7, last.Metadata.CompletionTokens)
+	assert.Equal(t, 32, last.Metadata.TotalTokens)
+	assert.Equal(t, "msg_01XYZ", last.Metadata.RequestID)
+}
+
+func TestClaudeClient_MetadataFields(t *testing.T) {
+	server := metadataServer(t, http.Header{"Request-Id": {"req_011"}}, `{
+		"id": "msg_01", "type": "message", "role": "assistant", "model": "claude-3-5-haiku-20241022",
+		"content": [{"type": "text", "text": "Hi"}],
+		"stop_reason": "max_tokens",
+		"usage": {"input_tokens": 10, "output_tokens": 2}
+	}`)
+	client, err := NewClaudeClient("test-key", "claude-3-5-haiku-latest", NewClientConfig().SetBaseURL(server.URL).SetRetries(0))
+	require.NoError(t, err)
+
+	resp, err := client.SendPromptWithMetadata(context.Background(), "hi")
+	require.NoError(t, err)
+	meta := resp.Metadata
+	assert.Equal(t, "claude-3-5-haiku-20241022", meta.ModelUsed)
+	assert.Equal(t, 10, meta.PromptTokens)
+	assert.Equal(t, 2, meta.CompletionTokens)
+	assert.Equal(t, 12, meta.TotalTokens)
+	assert.Equal(t, "max_tokens", meta.FinishReason)
+	assert.Equal(t, FinishReasonLength, meta.NormalizedFinishReason)
+	assert.Equal(t, "req_011", meta.RequestID)
+	assert.GreaterOrEqual(t, meta.LatencyMs, int64(5))
+}
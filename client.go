@@ -1,12 +1,14 @@
 package chatdelta
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 )
 
 // SupportedProviders lists all supported AI providers
-var SupportedProviders = []string{"openai", "anthropic", "claude", "google", "gemini"}
+var SupportedProviders = []string{"openai", "anthropic", "claude", "google", "gemini", "ollama", "azure", "openai-compatible", "xai", "grok", "bedrock", "vertex"}
 
 // CreateClient creates a new AI client based on the provider string
 func CreateClient(provider, apiKey, model string, config *ClientConfig) (AIClient, error) {
@@ -26,7 +28,7 @@ func CreateClient(provider, apiKey, model string, config *ClientConfig) (AIClien
 		apiKey = getAPIKeyFromEnv(provider)
 	}
 
-	if apiKey == "" {
+	if apiKey == "" && !keylessProvider(provider) {
 		return nil, NewMissingConfigError("API key for provider: " + provider)
 	}
 
@@ -35,6 +37,18 @@ func CreateClient(provider, apiKey, model string, config *ClientConfig) (AIClien
 		model = getDefaultModel(provider)
 	}
 
+	client, err := createProviderClient(provider, apiKey, model, config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Cache != nil {
+		return NewCachingClient(client, config.Cache), nil
+	}
+	return client, nil
+}
+
+// createProviderClient constructs the client for a normalized provider name.
+func createProviderClient(provider, apiKey, model string, config *ClientConfig) (AIClient, error) {
 	switch provider {
 	case "openai":
 		return NewOpenAIClient(apiKey, model, config)
@@ -42,7 +56,22 @@ func CreateClient(provider, apiKey, model string, config *ClientConfig) (AIClien
 		return NewClaudeClient(apiKey, model, config)
 	case "google", "gemini":
 		return NewGeminiClient(apiKey, model, config)
+	case "ollama":
+		return NewOllamaClient(model, config)
+	case "azure":
+		return newAzureClientFromConfig(apiKey, model, config)
+	case "openai-compatible":
+		return newOpenAICompatibleClientFromConfig(apiKey, model, config)
+	case "xai", "grok":
+		return NewXAIClient(apiKey, model, config)
+	case "bedrock":
+		return NewBedrockClient(model, config)
+	case "vertex":
+		return NewVertexClient(model, config)
 	default:
+		if p, ok := lookupProvider(provider); ok {
+			return p.factory(apiKey, model, config)
+		}
 		return nil, NewInvalidParameterError("provider", provider)
 	}
 }
@@ -65,11 +94,52 @@ func getAPIKeyFromEnv(provider string) string {
 			return key
 		}
 		return os.Getenv("GEMINI_API_KEY")
+	case "azure":
+		return os.Getenv("AZURE_OPENAI_API_KEY")
+	case "openai-compatible":
+		return os.Getenv("OPENAI_COMPATIBLE_API_KEY")
+	case "xai", "grok":
+		return os.Getenv("XAI_API_KEY")
 	default:
+		if p, ok := lookupProvider(provider); ok {
+			return p.apiKeyFromEnv()
+		}
 		return ""
 	}
 }
 
+// keylessProvider reports whether provider can be created without an API key.
+func keylessProvider(provider string) bool {
+	switch provider {
+	case "ollama", "openai-compatible", "bedrock", "vertex":
+		return true
+	}
+	if p, ok := lookupProvider(provider); ok {
+		return p.keyless
+	}
+	return false
+}
+
+// keylessProviderConfigured reports whether a keyless built-in provider has the
+// environment it needs to be usable.
+func keylessProviderConfigured(provider string) bool {
+	switch provider {
+	case "ollama":
+		return os.Getenv("OLLAMA_HOST") != ""
+	case "openai-compatible":
+		return os.Getenv(openAICompatibleBaseURLEnv) != ""
+	case "bedrock":
+		if awsRegionFromEnv() == "" {
+			return false
+		}
+		_, err := defaultAWSCredentials()
+		return err == nil
+	case "vertex":
+		return os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != ""
+	}
+	return false
+}
+
 // getDefaultModel returns the default model for a provider
 func getDefaultModel(provider string) string {
 	switch provider {
@@ -79,17 +149,33 @@ func getDefaultModel(provider string) string {
 		return "claude-3-haiku-20240307"
 	case "google", "gemini":
 		return "gemini-1.5-flash"
+	case "ollama":
+		return "llama3"
+	case "xai", "grok":
+		return "grok-2-latest"
+	case "bedrock":
+		return "anthropic.claude-3-haiku-20240307-v1:0"
+	case "vertex":
+		return "gemini-1.5-flash"
 	default:
+		if p, ok := lookupProvider(provider); ok {
+			return p.defaultModel
+		}
 		return ""
 	}
 }
 
-// GetAvailableProviders returns a list of providers with available API keys
+// GetAvailableProviders returns a list of providers with available API keys.
+// Ollama needs no key and is listed when OLLAMA_HOST is set; openai-compatible is
+// listed when OPENAI_COMPATIBLE_BASE_URL is set; bedrock is listed when an AWS region
+// and credentials are found, and vertex when GOOGLE_APPLICATION_CREDENTIALS is set.
+// Built-in providers come first, followed by registered providers in registration order.
 func GetAvailableProviders() []string {
 	var available []string
 
-	for _, provider := range SupportedProviders {
-		if getAPIKeyFromEnv(provider) != "" {
+	providers := append(append([]string(nil), SupportedProviders...), registeredProviderNames()...)
+	for _, provider := range providers {
+		if getAPIKeyFromEnv(provider) != "" || keylessProviderConfigured(provider) {
 			available = append(available, provider)
 		}
 	}
@@ -97,20 +183,105 @@ func GetAvailableProviders() []string {
 	return available
 }
 
+// envProviderPriority is the order CreateClientFromEnv and CreateAllAvailableClients
+// try the built-in providers in: hosted APIs with a plain API key first, then cloud
+// platforms, then self-hosted endpoints.
+var envProviderPriority = []string{"openai", "claude", "gemini", "xai", "azure", "bedrock", "vertex", "openai-compatible", "ollama"}
+
+// availableProviders returns the providers configured in the environment, each once
+// and in envProviderPriority order, followed by registered providers in
+// registration order.
+func availableProviders() []string {
+	var available []string
+	providers := append(append([]string(nil), envProviderPriority...), registeredProviderNames()...)
+	for _, provider := range providers {
+		if getAPIKeyFromEnv(provider) != "" || keylessProviderConfigured(provider) {
+			available = append(available, provider)
+		}
+	}
+	return available
+}
+
+// noProviderError is returned when the environment configures no provider.
+func noProviderError() *ClientError {
+	return NewMissingConfigError("API key for any provider (set OPENAI_API_KEY, ANTHROPIC_API_KEY, GOOGLE_API_KEY, XAI_API_KEY or another provider's environment)")
+}
+
+// CreateClientFromEnv creates a client, with its default model, for the first provider
+// configured in the environment. Providers are tried in this order: openai, claude,
+// gemini, xai, azure, bedrock, vertex, openai-compatible, ollama, then registered
+// providers in registration order. It returns a missing-config error if none is set.
+func CreateClientFromEnv(config *ClientConfig) (AIClient, error) {
+	available := availableProviders()
+	if len(available) == 0 {
+		return nil, noProviderError()
+	}
+	return CreateClient(available[0], "", "", config)
+}
+
+// CreateAllAvailableClients creates a client, with its default model, for every
+// provider configured in the environment, in CreateClientFromEnv's order, ready for
+// ExecuteParallel. Aliases such as "anthropic" are not duplicated. Providers whose
+// client cannot be created are left out and their errors joined into the returned
+// error; it is a missing-config error if no provider is configured at all.
+func CreateAllAvailableClients(config *ClientConfig) ([]AIClient, error) {
+	available := availableProviders()
+	if len(available) == 0 {
+		return nil, noProviderError()
+	}
+
+	var clients []AIClient
+	var failed []error
+	for _, provider := range available {
+		client, err := CreateClient(provider, "", "", config)
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", provider, err))
+			continue
+		}
+		clients = append(clients, client)
+	}
+	return clients, errors.Join(failed...)
+}
+
 // ClientInfo holds information about a client
 type ClientInfo struct {
-	Name                  string `json:"name"`
-	Model                 string `json:"model"`
-	SupportsStreaming     bool   `json:"supports_streaming"`
-	SupportsConversations bool   `json:"supports_conversations"`
+	Name                        string `json:"name"`
+	Model                       string `json:"model"`
+	SupportsStreaming           bool   `json:"supports_streaming"`
+	SupportsConversations       bool   `json:"supports_conversations"`
+	SupportsSeed                bool   `json:"supports_seed"`
+	SupportsMultipleCompletions bool   `json:"supports_multiple_completions"`
+	SupportsLogprobs            bool   `json:"supports_logprobs"`
+}
+
+// SupportsSeed reports whether client honours ClientConfig.Seed. Clients that do
+// not implement a SupportsSeed method are assumed not to support seeding.
+func SupportsSeed(client AIClient) bool {
+	if s, ok := client.(interface{ SupportsSeed() bool }); ok {
+		return s.SupportsSeed()
+	}
+	return false
+}
+
+// SupportsLogprobs reports whether client returns token log probabilities when
+// ClientConfig.Logprobs is set. Clients that do not implement a SupportsLogprobs
+// method, including Claude and Gemini, ignore the setting.
+func SupportsLogprobs(client AIClient) bool {
+	if s, ok := client.(interface{ SupportsLogprobs() bool }); ok {
+		return s.SupportsLogprobs()
+	}
+	return false
 }
 
 // GetClientInfo returns information about a client
 func GetClientInfo(client AIClient) ClientInfo {
 	return ClientInfo{
-		Name:                  client.Name(),
-		Model:                 client.Model(),
-		SupportsStreaming:     client.SupportsStreaming(),
-		SupportsConversations: client.SupportsConversations(),
+		Name:                        client.Name(),
+		Model:                       client.Model(),
+		SupportsStreaming:           client.SupportsStreaming(),
+		SupportsConversations:       client.SupportsConversations(),
+		SupportsSeed:                SupportsSeed(client),
+		SupportsMultipleCompletions: SupportsMultipleCompletions(client),
+		SupportsLogprobs:            SupportsLogprobs(client),
 	}
 }
@@ -0,0 +1,65 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// client_middleware.go composes AIClient wrappers. A ClientMiddleware takes a client
+// and returns one with added behavior, the way an http.RoundTripper wraps another;
+// Chain applies several in order. The built-in wrappers (caching, logging, auditing,
+// tracing) are available as ClientMiddleware so they stack in a single call.
+package chatdelta
+
+import (
+	"log"
+)
+
+// ClientMiddleware wraps an AIClient with cross-cutting behavior.
+type ClientMiddleware func(AIClient) AIClient
+
+// Chain wraps client with middleware. The first middleware is the outermost, so it
+// sees each call first and each response last.
+func Chain(client AIClient, middleware ...ClientMiddleware) AIClient {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		client = middleware[i](client)
+	}
+	return client
+}
+
+// WithCache returns a ClientMiddleware that answers repeated requests from cache;
+// see CachingClient. Use NewMemoryCache for an in-process cache.
+func WithCache(cache Cache) ClientMiddleware {
+	return func(inner AIClient) AIClient {
+		return NewCachingClient(inner, cache)
+	}
+}
+
+// WithLogging returns a ClientMiddleware that logs each prompt and response with
+// LoggingMiddleware. Pass nil to use the default logger.
+func WithLogging(logger *log.Logger) ClientMiddleware {
+	return WithMiddleware(LoggingMiddleware(logger))
+}
+
+// WithMiddleware returns a ClientMiddleware applying prompt-level Middleware; see
+// MiddlewareClient for how conversations and streams are handled.
+func WithMiddleware(mw ...Middleware) ClientMiddleware {
+	return func(inner AIClient) AIClient {
+		return NewMiddlewareClient(inner, mw...)
+	}
+}
+
+// WithAudit returns a ClientMiddleware recording every call to sink; see AuditingClient.
+func WithAudit(sink AuditSink) ClientMiddleware {
+	return func(inner AIClient) AIClient {
+		return NewAuditingClient(inner, sink)
+	}
+}
+
+// WithTracing returns a ClientMiddleware opening a span per call; see TracingClient.
+func WithTracing(tracer Tracer) ClientMiddleware {
+	return func(inner AIClient) AIClient {
+		return NewTracingClient(inner, tracer)
+	}
+}
+
+// WithRateLimit returns a ClientMiddleware applying limiter; see RateLimitedClient.
+func WithRateLimit(limiter *RateLimiter) ClientMiddleware {
+	return func(inner AIClient) AIClient {
+		return NewRateLimitedClient(inner, limiter)
+	}
+}
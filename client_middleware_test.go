@@ -0,0 +1,67 @@
+package chatdelta
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tagMiddleware appends tag to every prompt-level response, to observe chain order.
+func tagMiddleware(tag string) ClientMiddleware {
+	return WithMiddleware(func(ctx context.Context, prompt string, next func(context.Context, string) (string, error)) (string, error) {
+		response, err := next(ctx, prompt)
+		return response + tag, err
+	})
+}
+
+func TestChain_FirstMiddlewareIsOutermost(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	mock.QueueResponse("reply")
+
+	client := Chain(mock, tagMiddleware("[outer]"), tagMiddleware("[inner]"))
+	response, err := client.SendPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "reply[inner][outer]", response)
+	assert.Equal(t, "mock", client.Name())
+}
+
+func TestChain_NoMiddlewareReturnsClient(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	assert.Same(t, AIClient(mock), Chain(mock))
+}
+
+func TestChain_LoggingAndCache(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	mock.QueueResponse("cached answer")
+	var logs bytes.Buffer
+
+	client := Chain(mock,
+		WithLogging(log.New(&logs, "", 0)),
+		WithCache(NewMemoryCache(MemoryCacheOptions{})),
+	)
+	for i := 0; i < 2; i++ {
+		response, err := client.SendPrompt(context.Background(), "question")
+		require.NoError(t, err)
+		assert.Equal(t, "cached answer", response)
+	}
+
+	assert.Equal(t, 1, mock.CallCount())
+	assert.Equal(t, 2, bytes.Count(logs.Bytes(), []byte("request prompt=")))
+}
+
+func TestCacheKey_CoversRequestSettings(t *testing.T) {
+	conv := promptConversation("hi")
+	cool, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetTemperature(0.2))
+	require.NoError(t, err)
+	warm, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetTemperature(0.9))
+	require.NoError(t, err)
+	slow, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetTemperature(0.2).SetRetries(7))
+	require.NoError(t, err)
+
+	assert.NotEqual(t, CacheKey(cool, conv), CacheKey(warm, conv))
+	assert.Equal(t, CacheKey(cool, conv), CacheKey(slow, conv), "transport settings do not affect the key")
+}
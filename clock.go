@@ -0,0 +1,29 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// clock.go defines the Clock abstraction used for retry waits and Retry-After
+// arithmetic, so tests can observe and skip sleeps instead of waiting in real time.
+package chatdelta
+
+import "time"
+
+// Clock provides the current time and timers. Set ClientConfig.Clock to a fake
+// implementation in tests to control retry delays.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After returns a channel that receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the real-time Clock used by default.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clock returns the configured Clock, or the system clock if none is set.
+func (c *ClientConfig) clock() Clock {
+	if c.Clock != nil {
+		return c.Clock
+	}
+	return systemClock{}
+}
@@ -13,7 +13,7 @@ import (
 
 func main() {
 	var (
-		provider    = flag.String("provider", "openai", "AI provider (openai, claude, gemini)")
+		provider    = flag.String("provider", "openai", "AI provider (openai, claude, gemini, ollama, xai, bedrock, vertex)")
 		model       = flag.String("model", "", "Model to use (defaults to provider default)")
 		prompt      = flag.String("prompt", "Hello! How are you?", "Prompt to send")
 		temperature = flag.Float64("temperature", 0.7, "Temperature parameter")
@@ -21,17 +21,36 @@ func main() {
 		stream      = flag.Bool("stream", false, "Use streaming response")
 		parallel    = flag.Bool("parallel", false, "Execute on all available providers in parallel")
 		timeout     = flag.Duration("timeout", 30*time.Second, "Request timeout")
+		cacheDir    = flag.String("cache-dir", "", "Cache responses on disk in this directory across runs")
+		cacheTTL    = flag.Duration("cache-ttl", 24*time.Hour, "How long cached responses stay valid")
 	)
 	flag.Parse()
 
+	var cache chatdelta.Cache
+	if *cacheDir != "" {
+		fileCache, err := chatdelta.NewFileCache(*cacheDir, chatdelta.FileCacheOptions{TTL: *cacheTTL, MaxBytes: 64 << 20})
+		if err != nil {
+			log.Fatalf("Failed to open cache: %v", err)
+		}
+		cache = fileCache
+	}
+
 	if *parallel {
-		runParallel(*prompt, *timeout)
+		runParallel(*prompt, *timeout, cache)
 	} else {
-		runSingle(*provider, *model, *prompt, *temperature, *maxTokens, *stream, *timeout)
+		runSingle(*provider, *model, *prompt, *temperature, *maxTokens, *stream, *timeout, cache)
 	}
 }
 
-func runSingle(provider, model, prompt string, temperature float64, maxTokens int, useStreaming bool, timeout time.Duration) {
+// withCache wraps client in a CachingClient when a cache is configured.
+func withCache(client chatdelta.AIClient, cache chatdelta.Cache) chatdelta.AIClient {
+	if cache == nil {
+		return client
+	}
+	return chatdelta.NewCachingClient(client, cache)
+}
+
+func runSingle(provider, model, prompt string, temperature float64, maxTokens int, useStreaming bool, timeout time.Duration, cache chatdelta.Cache) {
 	// Create configuration
 	config := chatdelta.NewClientConfig().
 		SetTimeout(timeout).
@@ -44,6 +63,7 @@ func runSingle(provider, model, prompt string, temperature float64, maxTokens in
 	if err != nil {
 		log.Fatalf("Failed to create %s client: %v", provider, err)
 	}
+	client = withCache(client, cache)
 
 	fmt.Printf("Using %s with model %s\n", client.Name(), client.Model())
 	fmt.Printf("Prompt: %s\n", prompt)
@@ -70,44 +90,43 @@ func runSingle(provider, model, prompt string, temperature float64, maxTokens in
 			fmt.Printf("Note: %s doesn't support streaming, using regular response\n", client.Name())
 		}
 
-		response, err := client.SendPrompt(ctx, prompt)
+		response, err := client.SendPromptWithMetadata(ctx, prompt)
 		if err != nil {
 			log.Fatalf("Failed to send prompt: %v", err)
 		}
 
-		fmt.Printf("Response: %s\n", response)
+		fmt.Printf("Response: %s\n", response.Content)
+		if cache != nil {
+			fmt.Printf("Served via: %s\n", response.Metadata.ServedVia)
+		}
 	}
 }
 
-func runParallel(prompt string, timeout time.Duration) {
-	available := chatdelta.GetAvailableProviders()
-	if len(available) == 0 {
-		fmt.Println("No AI providers available.")
+func runParallel(prompt string, timeout time.Duration, cache chatdelta.Cache) {
+	// Create clients for all available providers
+	clients, err := chatdelta.CreateAllAvailableClients(chatdelta.NewClientConfig().SetCache(cache))
+	if len(clients) == 0 {
+		fmt.Printf("No AI providers available: %v\n", err)
 		fmt.Println("Set one of these environment variables:")
 		fmt.Println("  OPENAI_API_KEY or CHATGPT_API_KEY")
 		fmt.Println("  ANTHROPIC_API_KEY or CLAUDE_API_KEY")
 		fmt.Println("  GOOGLE_API_KEY or GEMINI_API_KEY")
+		fmt.Println("  XAI_API_KEY")
+		fmt.Println("  AWS_REGION (with AWS credentials, for bedrock)")
+		fmt.Println("  GOOGLE_APPLICATION_CREDENTIALS (for vertex)")
 		os.Exit(1)
 	}
-
-	fmt.Printf("Available providers: %v\n", available)
-	fmt.Printf("Prompt: %s\n", prompt)
-	fmt.Println("---")
-
-	// Create clients for all available providers
-	var clients []chatdelta.AIClient
-	for _, provider := range available {
-		client, err := chatdelta.CreateClient(provider, "", "", nil)
-		if err != nil {
-			fmt.Printf("Warning: Failed to create %s client: %v\n", provider, err)
-			continue
-		}
-		clients = append(clients, client)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
 	}
 
-	if len(clients) == 0 {
-		log.Fatal("No clients could be created")
+	names := make([]string, len(clients))
+	for i, client := range clients {
+		names[i] = client.Name()
 	}
+	fmt.Printf("Available providers: %v\n", names)
+	fmt.Printf("Prompt: %s\n", prompt)
+	fmt.Println("---")
 
 	// Execute in parallel
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -117,7 +136,7 @@ func runParallel(prompt string, timeout time.Duration) {
 
 	// Display results
 	for _, result := range results {
-		fmt.Printf("\n=== %s ===\n", result.ClientName)
+		fmt.Printf("\n=== %s (%s) ===\n", result.ClientName, result.Latency.Round(time.Millisecond))
 		if result.Error != nil {
 			fmt.Printf("Error: %v\n", result.Error)
 		} else {
@@ -0,0 +1,38 @@
+// Command goldengen regenerates the golden request fixtures used by golden_test.go.
+// Run it from the repository root after an intentional change to request shaping:
+//
+//	go run ./cmd/goldengen
+//
+// Review the resulting diff under testdata/golden before committing.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/chatdelta/chatdelta-go/internal/golden"
+)
+
+func main() {
+	dir := flag.String("dir", golden.Dir, "Directory to write fixtures to")
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *dir, err)
+	}
+
+	for _, c := range golden.Cases() {
+		data, err := golden.Render(c)
+		if err != nil {
+			log.Fatalf("Failed to render %s: %v", c.Name(), err)
+		}
+		path := filepath.Join(*dir, c.Name()+".json")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+		fmt.Println("wrote", path)
+	}
+}
@@ -0,0 +1,115 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// compaction.go implements ChatSession compaction: once the history grows past a
+// token threshold, the middle of the conversation is replaced with a recap written by
+// the session's client, keeping the system messages and the most recent messages
+// verbatim. Unlike TrimToTokenLimit, the gist of the dropped turns is retained.
+package chatdelta
+
+import (
+	"context"
+	"strings"
+)
+
+// DefaultCompactionPrompt instructs the model writing a compaction recap.
+const DefaultCompactionPrompt = "Summarize the conversation transcript below so it can replace the original messages. " +
+	"Keep decisions, facts, names, numbers, open questions, and the user's preferences. Write in the third person and be concise."
+
+// compactionRecapPrefix marks the recap message in the history.
+const compactionRecapPrefix = "Summary of the earlier conversation:\n"
+
+// CompactionOptions configures ChatSession compaction.
+type CompactionOptions struct {
+	// Threshold is the estimated conversation size, in tokens, above which the
+	// history is compacted before the next request
+	Threshold int
+	// KeepLast is the number of most recent non-system messages kept verbatim,
+	// including the message being sent. Defaults to 4.
+	KeepLast int
+	// Prompt instructs the model writing the recap; defaults to DefaultCompactionPrompt
+	Prompt string
+	// Estimator sizes the conversation; nil uses DefaultTokenEstimator
+	Estimator TokenEstimator
+}
+
+// SetCompaction enables compaction for the session. Before each request whose
+// conversation exceeds opts.Threshold, the messages between the system messages and
+// the last opts.KeepLast messages are replaced with a single assistant message
+// recapping them, generated by the session's client. If the recap would still leave
+// the conversation over the threshold it is shortened to fit; the kept messages are
+// never altered. A non-positive threshold disables compaction.
+func (s *ChatSession) SetCompaction(opts CompactionOptions) *ChatSession {
+	if opts.Threshold <= 0 {
+		s.compaction = nil
+		return s
+	}
+	if opts.KeepLast <= 0 {
+		opts.KeepLast = 4
+	}
+	if opts.Prompt == "" {
+		opts.Prompt = DefaultCompactionPrompt
+	}
+	if opts.Estimator == nil {
+		opts.Estimator = DefaultTokenEstimator
+	}
+	s.compaction = &opts
+	return s
+}
+
+// compactIfNeeded compacts conversation when compaction is enabled and it is over
+// the threshold.
+func (s *ChatSession) compactIfNeeded(ctx context.Context, conversation *Conversation) error {
+	opts := s.compaction
+	if opts == nil || estimateConversationTokens(conversation.Messages, opts.Estimator) <= opts.Threshold {
+		return nil
+	}
+
+	var system, rest []Message
+	for _, msg := range conversation.Messages {
+		if msg.Role == "system" {
+			system = append(system, msg)
+		} else {
+			rest = append(rest, msg)
+		}
+	}
+	if len(rest) <= opts.KeepLast {
+		return nil
+	}
+	middle, kept := rest[:len(rest)-opts.KeepLast], rest[len(rest)-opts.KeepLast:]
+
+	recapRequest := NewConversation()
+	recapRequest.AddSystemMessage(opts.Prompt)
+	recapRequest.AddUserMessage(formatTranscript(middle))
+	recap, err := s.client.SendConversation(ctx, recapRequest)
+	if err != nil {
+		return err
+	}
+
+	recapMessage := Message{Role: "assistant", Content: compactionRecapPrefix + strings.TrimSpace(recap)}
+	budget := opts.Threshold - estimateConversationTokens(system, opts.Estimator) -
+		estimateConversationTokens(kept, opts.Estimator) - messageTokenOverhead
+	if budget < 0 {
+		budget = 0
+	}
+	recapMessage, _ = truncateMessage(recapMessage, budget, TruncateHead, opts.Estimator)
+
+	messages := make([]Message, 0, len(system)+1+len(kept))
+	messages = append(messages, system...)
+	messages = append(messages, recapMessage)
+	messages = append(messages, kept...)
+	conversation.Messages = messages
+	return nil
+}
+
+// formatTranscript renders messages as "role: content" blocks for a recap request.
+func formatTranscript(messages []Message) string {
+	var b strings.Builder
+	for i, msg := range messages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(msg.Role)
+		b.WriteString(": ")
+		b.WriteString(msg.Content)
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// compare.go streams one prompt to two clients at once and reports, as the chunks
+// arrive, where their answers first diverge. The divergence point is maintained
+// incrementally so each event costs time proportional to the new content only.
+package chatdelta
+
+import (
+	"context"
+	"sync"
+	"unicode/utf8"
+)
+
+// CompareSide identifies which client a CompareEvent came from.
+type CompareSide int
+
+const (
+	// CompareSideA is the first client passed to StreamCompare
+	CompareSideA CompareSide = iota
+	// CompareSideB is the second client passed to StreamCompare
+	CompareSideB
+)
+
+// CompareEvent is one update from StreamCompare.
+type CompareEvent struct {
+	// Side is the client that produced the event
+	Side CompareSide
+	// Client is that client's name
+	Client string
+	// Content is the text Side produced since its previous event
+	Content string
+	// Divergence is the byte offset of the first character at which the two
+	// accumulated texts differ, or -1 while they still agree. Once set it never
+	// changes.
+	Divergence int
+	// Done is set on Side's last event
+	Done bool
+	// Err is set when Side's stream failed; the other side keeps streaming
+	Err error
+	// Metadata is Side's response metadata, when its final chunk carried it
+	Metadata *ResponseMetadata
+}
+
+// StreamCompare streams prompt to a and b concurrently and merges their chunks into
+// one channel of CompareEvents. A side that fails to start or errors mid-stream gets a
+// Done event with Err set; the other side is unaffected. The channel is closed after
+// both sides are done and must be drained by the caller.
+func StreamCompare(ctx context.Context, a, b AIClient, prompt string) <-chan CompareEvent {
+	events := make(chan CompareEvent, 10)
+	chunks := make(chan CompareEvent, 10)
+
+	var wg sync.WaitGroup
+	for side, client := range []AIClient{a, b} {
+		wg.Add(1)
+		go func(side CompareSide, c AIClient) {
+			defer wg.Done()
+			forwardCompareSide(ctx, side, c, prompt, chunks)
+		}(CompareSide(side), client)
+	}
+	go func() {
+		wg.Wait()
+		close(chunks)
+	}()
+
+	go func() {
+		defer close(events)
+		var state divergenceTracker
+		for event := range chunks {
+			event.Divergence = state.update(event.Side, event.Content, event.Done)
+			events <- event
+		}
+	}()
+	return events
+}
+
+// forwardCompareSide streams prompt to c and sends one event per chunk to out,
+// ending with a Done event.
+func forwardCompareSide(ctx context.Context, side CompareSide, c AIClient, prompt string, out chan<- CompareEvent) {
+	stream, err := c.StreamPrompt(ctx, prompt)
+	if err != nil {
+		out <- CompareEvent{Side: side, Client: c.Name(), Done: true, Err: err}
+		return
+	}
+	for chunk := range stream {
+		event := CompareEvent{Side: side, Client: c.Name(), Content: chunk.Content}
+		if chunk.Finished {
+			event.Done, event.Err, event.Metadata = true, chunk.Err, chunk.Metadata
+			out <- event
+			return
+		}
+		out <- event
+	}
+	out <- CompareEvent{Side: side, Client: c.Name(), Done: true, Err: NewStreamClosedError()}
+}
+
+// divergenceTracker keeps both accumulated texts and the length of their verified
+// common prefix, so each update only compares the newly overlapping bytes.
+type divergenceTracker struct {
+	text       [2][]byte
+	done       [2]bool
+	agreed     int
+	divergence int
+	diverged   bool
+}
+
+// update appends content to side's text and returns the current divergence offset.
+func (t *divergenceTracker) update(side CompareSide, content string, done bool) int {
+	t.text[side] = append(t.text[side], content...)
+	t.done[side] = t.done[side] || done
+	if t.diverged {
+		return t.divergence
+	}
+
+	a, b := t.text[CompareSideA], t.text[CompareSideB]
+	overlap := min(len(a), len(b))
+	for ; t.agreed < overlap; t.agreed++ {
+		if a[t.agreed] != b[t.agreed] {
+			return t.diverge(t.agreed)
+		}
+	}
+	// Both finished and one is a strict prefix of the other: they differ at its end.
+	if t.done[CompareSideA] && t.done[CompareSideB] && len(a) != len(b) {
+		return t.diverge(overlap)
+	}
+	return -1
+}
+
+// diverge fixes the divergence at offset, moved back to the start of its character.
+func (t *divergenceTracker) diverge(offset int) int {
+	a := t.text[CompareSideA]
+	for offset > 0 && offset < len(a) && !utf8.RuneStart(a[offset]) {
+		offset--
+	}
+	t.diverged, t.divergence = true, offset
+	return offset
+}
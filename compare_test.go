@@ -0,0 +1,97 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkStreamClient streams a fixed sequence of chunks.
+type chunkStreamClient struct {
+	*MockClient
+	chunks []StreamChunk
+}
+
+func (c *chunkStreamClient) StreamPrompt(context.Context, string) (<-chan StreamChunk, error) {
+	ch := make(chan StreamChunk, len(c.chunks))
+	for _, chunk := range c.chunks {
+		ch <- chunk
+	}
+	close(ch)
+	return ch, nil
+}
+
+// collectCompare drains events and returns each side's assembled text and last event.
+func collectCompare(t *testing.T, events <-chan CompareEvent) (texts [2]string, last [2]CompareEvent) {
+	t.Helper()
+	for event := range events {
+		require.False(t, last[event.Side].Done, "no events after Done")
+		texts[event.Side] += event.Content
+		last[event.Side] = event
+	}
+	return texts, last
+}
+
+func TestDivergenceTracker(t *testing.T) {
+	var tracker divergenceTracker
+	assert.Equal(t, -1, tracker.update(CompareSideA, "The cat", false))
+	assert.Equal(t, -1, tracker.update(CompareSideB, "The", false))
+	assert.Equal(t, -1, tracker.update(CompareSideB, " c", false))
+	assert.Equal(t, 5, tracker.update(CompareSideB, "ow", false))
+	assert.Equal(t, 5, tracker.update(CompareSideA, " sat", true), "divergence is fixed once found")
+
+	tracker = divergenceTracker{}
+	tracker.update(CompareSideA, "café", false)
+	assert.Equal(t, 3, tracker.update(CompareSideB, "cafè", false), "offset is moved to the start of the character")
+
+	tracker = divergenceTracker{}
+	tracker.update(CompareSideA, "Yes", true)
+	assert.Equal(t, -1, tracker.update(CompareSideB, "Yes", false))
+	assert.Equal(t, 3, tracker.update(CompareSideB, "!", true), "a finished prefix diverges at its end")
+}
+
+func TestStreamCompare(t *testing.T) {
+	a := NewMockClient("a", "")
+	a.SetChunkSize(3)
+	a.QueueResponse("Hello world")
+	b := NewMockClient("b", "")
+	b.SetChunkSize(2)
+	b.QueueResponse("Hello there")
+
+	texts, last := collectCompare(t, StreamCompare(context.Background(), a, b, "hi"))
+	assert.Equal(t, [2]string{"Hello world", "Hello there"}, texts)
+	for side, client := range []string{"a", "b"} {
+		assert.True(t, last[side].Done)
+		assert.NoError(t, last[side].Err)
+		assert.Equal(t, client, last[side].Client)
+		assert.NotNil(t, last[side].Metadata)
+	}
+	// Whichever side finished last saw the divergence.
+	assert.Equal(t, 6, max(last[0].Divergence, last[1].Divergence))
+}
+
+func TestStreamCompare_OneSideFails(t *testing.T) {
+	good := NewMockClient("good", "")
+	good.QueueResponse("fine")
+	failing := NewMockClient("failing", "")
+	failing.QueueError(NewServerError(500, "down"))
+
+	texts, last := collectCompare(t, StreamCompare(context.Background(), failing, good, "hi"))
+	assert.Equal(t, "fine", texts[CompareSideB])
+	assert.NoError(t, last[CompareSideB].Err)
+	assert.ErrorContains(t, last[CompareSideA].Err, "down")
+
+	midStream := &chunkStreamClient{MockClient: NewMockClient("mid", ""), chunks: []StreamChunk{
+		{Content: "fi"},
+		{Finished: true, Err: errors.New("connection reset")},
+	}}
+	good.QueueResponse("fine")
+	texts, last = collectCompare(t, StreamCompare(context.Background(), good, midStream, "hi"))
+	assert.Equal(t, [2]string{"fine", "fi"}, texts)
+	assert.NoError(t, last[CompareSideA].Err)
+	assert.EqualError(t, last[CompareSideB].Err, "connection reset")
+	assert.Equal(t, 2, max(last[0].Divergence, last[1].Divergence))
+}
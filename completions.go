@@ -0,0 +1,94 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// completions.go requests several completions for the same prompt. OpenAI-style APIs
+// return them from one request via the n parameter; for other providers the request
+// is repeated concurrently.
+//
+// Usage:
+//
+//	client, _ := chatdelta.CreateClient("openai", "", "", chatdelta.NewClientConfig().SetN(3))
+//	names, err := chatdelta.SendPromptN(ctx, client, "Suggest a name for a cat")
+package chatdelta
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MultiCompletionClient is implemented by clients that return several completions
+// from a single request.
+type MultiCompletionClient interface {
+	// SendConversationN returns ClientConfig.N completions of conversation
+	SendConversationN(ctx context.Context, conversation *Conversation) ([]*AiResponse, error)
+}
+
+// SupportsMultipleCompletions reports whether client returns ClientConfig.N
+// completions from one request. SendConversationN works with other provider
+// clients too, by sending N requests.
+func SupportsMultipleCompletions(client AIClient) bool {
+	if s, ok := client.(interface{ SupportsMultipleCompletions() bool }); ok {
+		return s.SupportsMultipleCompletions()
+	}
+	return false
+}
+
+// SendPromptN returns the text of ClientConfig.N completions of prompt; see
+// SendConversationN.
+func SendPromptN(ctx context.Context, client AIClient, prompt string) ([]string, error) {
+	responses, err := SendConversationN(ctx, client, promptConversation(prompt))
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(responses))
+	for i, response := range responses {
+		texts[i] = response.Content
+	}
+	return texts, nil
+}
+
+// SendConversationN returns ClientConfig.N completions of conversation, one when N
+// is unset. Clients implementing MultiCompletionClient make a single request; the
+// other provider clients send N concurrent requests and fail if any of them does.
+// Clients without a ClientConfig, such as wrappers, return a config error.
+func SendConversationN(ctx context.Context, client AIClient, conversation *Conversation) ([]*AiResponse, error) {
+	if multi, ok := client.(MultiCompletionClient); ok {
+		return multi.SendConversationN(ctx, conversation)
+	}
+	configured, ok := client.(configuredClient)
+	if !ok {
+		return nil, NewInvalidParameterError("client", fmt.Sprintf("%s does not support multiple completions", client.Name()))
+	}
+	n := 1
+	if config := configured.clientConfig(); config.N != nil {
+		n = *config.N
+	}
+
+	// The first failure cancels the remaining requests and is the one reported
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	responses := make([]*AiResponse, n)
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := client.SendConversationWithMetadata(ctx, conversation)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			responses[i] = response
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return responses, nil
+}
@@ -0,0 +1,115 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendPromptN_OpenAIReturnsAllChoices(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetN(3).SetRetries(0))
+	require.NoError(t, err)
+	var bodies []map[string]any
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		data, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(data, &body))
+		bodies = append(bodies, body)
+		return cannedResponse(http.StatusOK, `{"model":"gpt-4o","choices":[
+			{"index":0,"message":{"role":"assistant","content":"Tom"},"finish_reason":"stop"},
+			{"index":1,"message":{"role":"assistant","content":"Felix"},"finish_reason":"stop"},
+			{"index":2,"message":{"role":"assistant","content":"Luna"},"finish_reason":"length"}],
+			"usage":{"prompt_tokens":5,"completion_tokens":6,"total_tokens":11}}`)(req)
+	})
+
+	names, err := SendPromptN(context.Background(), client, "Name a cat")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Tom", "Felix", "Luna"}, names)
+	require.Len(t, bodies, 1, "one request carries every choice")
+	assert.Equal(t, 3.0, bodies[0]["n"])
+
+	responses, err := client.SendConversationN(context.Background(), promptConversation("Name a cat"))
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+	assert.Equal(t, FinishReasonLength, responses[2].Metadata.NormalizedFinishReason)
+	assert.Equal(t, 11, responses[2].Metadata.TotalTokens)
+
+	// Single-completion calls never ask for extra choices.
+	response, err := client.SendPrompt(context.Background(), "Name a cat")
+	require.NoError(t, err)
+	assert.Equal(t, "Tom", response)
+	assert.NotContains(t, bodies[len(bodies)-1], "n")
+}
+
+func TestSendConversationN_LoopsForProvidersWithoutN(t *testing.T) {
+	client, err := NewClaudeClient("key", "", NewClientConfig().SetN(3).SetRetries(0))
+	require.NoError(t, err)
+	var calls atomic.Int32
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		data, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), `"n"`)
+		calls.Add(1)
+		return cannedResponse(http.StatusOK, `{"id":"msg_1","type":"message","role":"assistant",
+			"content":[{"type":"text","text":"Whiskers"}],"stop_reason":"end_turn"}`)(req)
+	})
+
+	assert.False(t, SupportsMultipleCompletions(client))
+	responses, err := SendConversationN(context.Background(), client, promptConversation("Name a cat"))
+	require.NoError(t, err)
+	assert.Len(t, responses, 3)
+	assert.EqualValues(t, 3, calls.Load())
+	for _, response := range responses {
+		assert.Equal(t, "Whiskers", response.Content)
+	}
+}
+
+func TestSendConversationN_LoopFailsIfAnyRequestFails(t *testing.T) {
+	client, err := NewClaudeClient("key", "", NewClientConfig().SetN(2).SetRetries(0))
+	require.NoError(t, err)
+	var calls atomic.Int32
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			return cannedResponse(http.StatusBadRequest, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`)(req)
+		}
+		return cannedResponse(http.StatusOK, `{"content":[{"type":"text","text":"ok"}]}`)(req)
+	})
+
+	_, err = SendConversationN(context.Background(), client, promptConversation("hi"))
+	var ce *ClientError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, "bad_request", ce.Code)
+}
+
+func TestSendConversationN_DefaultsToOne(t *testing.T) {
+	client, err := NewClaudeClient("key", "", NewClientConfig().SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = roundTripFunc(cannedResponse(http.StatusOK, `{"content":[{"type":"text","text":"ok"}]}`))
+
+	texts, err := SendPromptN(context.Background(), client, "hi")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ok"}, texts)
+}
+
+func TestSendConversationN_UnsupportedClient(t *testing.T) {
+	_, err := SendPromptN(context.Background(), NewMockClient("mock", ""), "hi")
+	var ce *ClientError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, ErrorTypeConfig, ce.Type)
+	assert.Contains(t, ce.Message, "multiple completions")
+}
+
+func TestSupportsMultipleCompletions(t *testing.T) {
+	openai, err := NewOpenAIClient("key", "", NewClientConfig())
+	require.NoError(t, err)
+	assert.True(t, SupportsMultipleCompletions(openai))
+	assert.True(t, GetClientInfo(openai).SupportsMultipleCompletions)
+	assert.False(t, SupportsMultipleCompletions(NewMockClient("mock", "")))
+}
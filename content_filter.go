@@ -0,0 +1,109 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// content_filter.go applies ClientConfig.ContentFilter to responses before they reach
+// the caller. Non-streaming responses are screened once; streams are screened chunk by
+// chunk, holding back a small window of already-approved text so that a phrase split
+// across chunk boundaries is seen whole by the next call.
+package chatdelta
+
+import "unicode/utf8"
+
+// ContentFilter screens response text. chunk is the text about to be released and
+// accumulated is everything already released to the caller in this response. It
+// returns allow=true to release chunk unchanged, or false to release replacement
+// instead ("" suppresses the chunk).
+//
+// The filter runs synchronously on the goroutine delivering the response and only
+// ever sees text from that one response.
+type ContentFilter func(chunk string, accumulated string) (allow bool, replacement string)
+
+// DefaultContentFilterWindow is the number of bytes held back from a stream when
+// ClientConfig.ContentFilterWindow is zero.
+const DefaultContentFilterWindow = 64
+
+// contentFilteredNotice is recorded in ResponseMetadata.Notices when the filter
+// replaced or suppressed content.
+const contentFilteredNotice = "content_filtered"
+
+// postProcessContent applies the echo guard, the response filters, and then the
+// content filter to a complete response, recording notices in metadata (which may be
+// nil). It fails only when a response filter rejects the content.
+func postProcessContent(config *ClientConfig, conversation *Conversation, content string, metadata *ResponseMetadata) (string, error) {
+	content = applyEchoGuard(config, conversation, content, metadata)
+	content, err := runTextFilters("response", config.ResponseFilters, content)
+	if err != nil {
+		return "", err
+	}
+	if config.ContentFilter == nil {
+		return content, nil
+	}
+	allow, replacement := config.ContentFilter(content, "")
+	if allow {
+		return content, nil
+	}
+	if metadata != nil {
+		metadata.Notices = append(metadata.Notices, contentFilteredNotice)
+	}
+	return replacement, nil
+}
+
+// streamFilter is the per-stream state of a ContentFilter.
+type streamFilter struct {
+	filter ContentFilter
+	window int
+	// pending is text received but not yet released
+	pending string
+	// released is the text already sent to the caller
+	released string
+	filtered bool
+}
+
+func newStreamFilter(config *ClientConfig) *streamFilter {
+	if config.ContentFilter == nil {
+		return nil
+	}
+	window := config.ContentFilterWindow
+	if window <= 0 {
+		window = DefaultContentFilterWindow
+	}
+	return &streamFilter{filter: config.ContentFilter, window: window}
+}
+
+// push screens content together with the held-back text and returns the text that
+// can be released now. The last window bytes of approved text are held back unless
+// flush is set.
+func (f *streamFilter) push(content string, flush bool) string {
+	f.pending += content
+	if f.pending == "" {
+		return ""
+	}
+
+	allow, replacement := f.filter(f.pending, f.released)
+	if !allow {
+		f.filtered = true
+		f.pending = ""
+		f.released += replacement
+		return replacement
+	}
+
+	cut := len(f.pending)
+	if !flush {
+		cut -= f.window
+		for cut > 0 && !utf8.RuneStart(f.pending[cut]) {
+			cut--
+		}
+		if cut <= 0 {
+			return ""
+		}
+	}
+	out := f.pending[:cut]
+	f.pending = f.pending[cut:]
+	f.released += out
+	return out
+}
+
+// finish records a notice in metadata (which may be nil) when anything was filtered.
+func (f *streamFilter) finish(metadata *ResponseMetadata) {
+	if f.filtered && metadata != nil {
+		metadata.Notices = append(metadata.Notices, contentFilteredNotice)
+	}
+}
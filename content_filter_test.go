@@ -0,0 +1,83 @@
+package chatdelta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockPhrase returns a ContentFilter that replaces any chunk containing phrase.
+func blockPhrase(phrase, replacement string) ContentFilter {
+	return func(chunk, accumulated string) (bool, string) {
+		if strings.Contains(strings.ToLower(chunk), phrase) {
+			return false, replacement
+		}
+		return true, ""
+	}
+}
+
+func TestContentFilter_NonStreaming(t *testing.T) {
+	config := NewClientConfig().SetRetries(0).SetContentFilter(blockPhrase("hunter2", "[removed]"), 0)
+	client, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`{"choices":[{"message":{"role":"assistant","content":"The password is hunter2."},"finish_reason":"stop"}]}`)
+
+	response, err := client.SendPromptWithMetadata(context.Background(), "What is the password?")
+	require.NoError(t, err)
+	assert.Equal(t, "[removed]", response.Content)
+	assert.Contains(t, response.Metadata.Notices, contentFilteredNotice)
+
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`{"choices":[{"message":{"role":"assistant","content":"I can't share that."},"finish_reason":"stop"}]}`)
+	response, err = client.SendPromptWithMetadata(context.Background(), "What is the password?")
+	require.NoError(t, err)
+	assert.Equal(t, "I can't share that.", response.Content)
+	assert.Empty(t, response.Metadata.Notices)
+}
+
+func TestContentFilter_StreamPhraseAcrossChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, piece := range []string{"Sure. The pass", "word is hun", "ter2, keep it safe."} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", piece)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	config := NewClientConfig().SetBaseURL(server.URL).SetContentFilter(blockPhrase("hunter2", "[removed]"), 8)
+	client, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "What is the password?")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+
+	assert.NotContains(t, content, "hun")
+	assert.Contains(t, content, "[removed]")
+	require.NotNil(t, last.Metadata)
+	assert.Contains(t, last.Metadata.Notices, contentFilteredNotice)
+}
+
+func TestStreamFilter_HoldsWindowAndSeesAccumulated(t *testing.T) {
+	var calls [][2]string
+	filter := &streamFilter{window: 4, filter: func(chunk, accumulated string) (bool, string) {
+		calls = append(calls, [2]string{chunk, accumulated})
+		return true, ""
+	}}
+
+	assert.Equal(t, "ab", filter.push("abcdef", false))
+	assert.Equal(t, "cd", filter.push("gh", false))
+	assert.Equal(t, "efghé", filter.push("é", true))
+	assert.Equal(t, [][2]string{{"abcdef", ""}, {"cdefgh", "ab"}, {"efghé", "abcd"}}, calls)
+
+	// The window never splits a multi-byte rune.
+	filter = &streamFilter{window: 1, filter: func(string, string) (bool, string) { return true, "" }}
+	assert.Equal(t, "a", filter.push("aé", false))
+}
@@ -0,0 +1,57 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// continuation.go resumes responses that stopped because they hit the max_tokens
+// limit (finish reason "length").
+package chatdelta
+
+import (
+	"context"
+	"strings"
+)
+
+// continuePrompt asks a model without assistant prefill to pick up its previous
+// answer where it stopped.
+const continuePrompt = "Continue exactly where your previous response stopped. Do not repeat any of it or add a preamble."
+
+// SupportsAssistantPrefill reports whether client continues a conversation that ends
+// with an assistant message, instead of answering it afresh. Clients that do not
+// implement a SupportsAssistantPrefill method are assumed not to.
+func SupportsAssistantPrefill(client AIClient) bool {
+	if s, ok := client.(interface{ SupportsAssistantPrefill() bool }); ok {
+		return s.SupportsAssistantPrefill()
+	}
+	return false
+}
+
+// ContinueGeneration resumes previousPartial, a response to conversation that was cut
+// off, and returns the partial joined with its continuation. conversation is not
+// modified.
+//
+// Clients with assistant prefill (see SupportsAssistantPrefill), such as Claude, are
+// sent the partial as a trailing assistant message and carry on from it directly;
+// trailing whitespace is trimmed from the partial because Claude rejects it there.
+// Other clients are sent the partial followed by a user message asking them to
+// continue. Clients without conversation support receive it flattened into a single
+// prompt.
+func ContinueGeneration(ctx context.Context, client AIClient, conversation *Conversation, previousPartial string) (string, error) {
+	continued := &Conversation{Messages: append([]Message(nil), conversation.Messages...)}
+	prefill := SupportsAssistantPrefill(client)
+	if prefill {
+		previousPartial = strings.TrimRight(previousPartial, " \t\r\n")
+	}
+	continued.AddAssistantMessage(previousPartial)
+	if !prefill {
+		continued.AddUserMessage(continuePrompt)
+	}
+
+	var continuation string
+	var err error
+	if client.SupportsConversations() {
+		continuation, err = client.SendConversation(ctx, continued)
+	} else {
+		continuation, err = client.SendPrompt(ctx, FlattenConversation(continued))
+	}
+	if err != nil {
+		return "", err
+	}
+	return previousPartial + continuation, nil
+}
@@ -0,0 +1,67 @@
+package chatdelta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefillMock is a MockClient that continues trailing assistant messages, like Claude.
+type prefillMock struct {
+	*MockClient
+}
+
+func (prefillMock) SupportsAssistantPrefill() bool { return true }
+
+func TestContinueGeneration_AsksToContinue(t *testing.T) {
+	client := NewMockClient("mock", "")
+	client.QueueResponse("The quick brown fox jumps")
+	client.QueueResponse(" over the lazy dog.")
+
+	conversation := NewConversation()
+	conversation.AddUserMessage("Write a pangram")
+	partial, err := client.SendConversation(context.Background(), conversation)
+	require.NoError(t, err)
+
+	full, err := ContinueGeneration(context.Background(), client, conversation, partial)
+	require.NoError(t, err)
+	assert.Equal(t, "The quick brown fox jumps over the lazy dog.", full)
+	assert.Len(t, conversation.Messages, 1, "the caller's conversation is not modified")
+
+	sent := client.Conversations()[1].Messages
+	require.Len(t, sent, 3)
+	assert.Equal(t, Message{Role: "assistant", Content: partial}, sent[1])
+	assert.Equal(t, "user", sent[2].Role)
+	assert.Equal(t, continuePrompt, sent[2].Content)
+}
+
+func TestContinueGeneration_NativePrefill(t *testing.T) {
+	client := prefillMock{NewMockClient("mock", "")}
+	client.QueueResponse(" over the lazy dog.")
+
+	conversation := NewConversation()
+	conversation.AddUserMessage("Write a pangram")
+
+	full, err := ContinueGeneration(context.Background(), client, conversation, "The quick brown fox jumps \n")
+	require.NoError(t, err)
+	assert.Equal(t, "The quick brown fox jumps over the lazy dog.", full)
+
+	sent := client.Conversations()[0].Messages
+	require.Len(t, sent, 2)
+	assert.Equal(t, Message{Role: "assistant", Content: "The quick brown fox jumps"}, sent[1])
+}
+
+func TestContinueGeneration_ClaudeAcceptsTrailingAssistant(t *testing.T) {
+	client, err := NewClaudeClient("test-key", "", NewClientConfig())
+	require.NoError(t, err)
+	assert.True(t, SupportsAssistantPrefill(client))
+	assert.False(t, SupportsAssistantPrefill(NewMockClient("mock", "")))
+
+	conversation := NewConversation()
+	conversation.AddUserMessage("Write a pangram")
+	conversation.AddAssistantMessage("The quick brown fox")
+	_, err = client.DryRun(conversation, false)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,77 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// conversation_builder.go provides ConversationBuilder, a fluent way to write out a
+// conversation in one expression, mainly for tests and scripts.
+//
+// Usage:
+//
+//	conv, err := chatdelta.NewConversationBuilder().
+//	    System("You are terse.").
+//	    User("What is 2+2?").
+//	    Assistant("4").
+//	    User("And 3+3?").
+//	    Build()
+package chatdelta
+
+// ConversationBuilder accumulates messages and checks their order on Build.
+type ConversationBuilder struct {
+	messages []Message
+}
+
+// NewConversationBuilder creates an empty ConversationBuilder.
+func NewConversationBuilder() *ConversationBuilder {
+	return &ConversationBuilder{}
+}
+
+// System appends a system message.
+func (b *ConversationBuilder) System(content string) *ConversationBuilder {
+	return b.add(Message{Role: "system", Content: content})
+}
+
+// User appends a user message.
+func (b *ConversationBuilder) User(content string) *ConversationBuilder {
+	return b.add(Message{Role: "user", Content: content})
+}
+
+// UserImage appends a user message holding caption followed by image.
+func (b *ConversationBuilder) UserImage(caption string, image ImagePart) *ConversationBuilder {
+	return b.add(Message{
+		Role:    "user",
+		Content: caption,
+		Parts:   []ContentPart{{Type: ContentPartImage, Image: &image}},
+	})
+}
+
+// Assistant appends an assistant message.
+func (b *ConversationBuilder) Assistant(content string) *ConversationBuilder {
+	return b.add(Message{Role: "assistant", Content: content})
+}
+
+func (b *ConversationBuilder) add(msg Message) *ConversationBuilder {
+	b.messages = append(b.messages, msg)
+	return b
+}
+
+// Build returns the conversation after checking its role order with
+// ValidateSystemOrder and Conversation.ValidateAlternation: system messages come
+// first, the first other turn is from the user, and no two assistant messages are
+// adjacent. The builder can be reused; later calls do not affect the result.
+func (b *ConversationBuilder) Build() (*Conversation, error) {
+	conversation := &Conversation{Messages: append(make([]Message, 0, len(b.messages)), b.messages...)}
+	if err := ValidateSystemOrder(conversation); err != nil {
+		return nil, err
+	}
+	if err := conversation.ValidateAlternation(); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// MustBuild is like Build but panics if the order is invalid. It is intended for
+// tests and fixed conversations known to be valid.
+func (b *ConversationBuilder) MustBuild() *Conversation {
+	conversation, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return conversation
+}
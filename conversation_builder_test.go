@@ -0,0 +1,70 @@
+package chatdelta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversationBuilder_MatchesFluentCalls(t *testing.T) {
+	image := ImagePart{URL: "https://example.com/cat.png"}
+	conv, err := NewConversationBuilder().
+		System("be terse").
+		User("2+2?").
+		Assistant("4").
+		UserImage("what is this?", image).
+		Build()
+	require.NoError(t, err)
+
+	want := NewConversation()
+	want.AddSystemMessage("be terse")
+	want.AddUserMessage("2+2?")
+	want.AddAssistantMessage("4")
+	want.AddUserImageMessage("what is this?", image)
+	assert.Equal(t, want.Messages, conv.Messages)
+}
+
+func TestConversationBuilder_BuildIsIndependentOfBuilder(t *testing.T) {
+	builder := NewConversationBuilder().User("first")
+	conv := builder.MustBuild()
+	builder.Assistant("reply")
+
+	assert.Len(t, conv.Messages, 1)
+	assert.Len(t, builder.MustBuild().Messages, 2)
+}
+
+func TestConversationBuilder_InvalidOrdering(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		builder *ConversationBuilder
+		message string
+	}{
+		{"system after user", NewConversationBuilder().User("hi").System("late"), "system messages must come first"},
+		{"assistant first", NewConversationBuilder().System("s").Assistant("hello"), "first turn must be from the user"},
+		{"two assistant turns", NewConversationBuilder().User("a").Assistant("b").Assistant("c"), "must alternate"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conv, err := tc.builder.Build()
+			assert.Nil(t, conv)
+			var ce *ClientError
+			require.ErrorAs(t, err, &ce)
+			assert.Equal(t, ErrorTypeConfig, ce.Type)
+			assert.Contains(t, ce.Message, tc.message)
+		})
+	}
+}
+
+func TestConversationBuilder_MustBuildPanicsOnInvalidOrdering(t *testing.T) {
+	assert.Panics(t, func() { NewConversationBuilder().Assistant("hi").MustBuild() })
+}
+
+func TestConversationBuilder_EmptyAndSystemOnly(t *testing.T) {
+	conv, err := NewConversationBuilder().Build()
+	require.NoError(t, err)
+	assert.Empty(t, conv.Messages)
+
+	conv, err = NewConversationBuilder().System("a").System("b").Build()
+	require.NoError(t, err)
+	assert.Len(t, conv.Messages, 2)
+}
@@ -0,0 +1,58 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// conversation_validation.go checks a conversation's shape before it is sent, so a
+// malformed history fails with a clear config error instead of an obscure provider
+// response.
+package chatdelta
+
+import "fmt"
+
+// knownRoles are the message roles the clients know how to send.
+var knownRoles = map[string]bool{"system": true, "user": true, "assistant": true, "tool": true}
+
+// Validate reports problems no provider accepts: a conversation without messages and
+// messages with a role other than "system", "user", "assistant", or "tool". Every
+// client runs it before sending.
+func (c *Conversation) Validate() error {
+	if len(c.Messages) == 0 {
+		return NewInvalidParameterError("messages", "conversation is empty")
+	}
+	for i, msg := range c.Messages {
+		if !knownRoles[msg.Role] {
+			return NewInvalidParameterError("messages",
+				fmt.Sprintf("message at index %d has unknown role %q; use system, user, assistant, or tool", i, msg.Role))
+		}
+	}
+	return nil
+}
+
+// ValidateAlternation checks the turn order required by providers such as Claude:
+// ignoring system messages, the first turn is from the user and user and assistant
+// turns alternate. Consecutive user-side messages (user messages and tool results,
+// such as a caption followed by an image) are sent as one turn and are allowed.
+// Claude and Bedrock run it before sending.
+func (c *Conversation) ValidateAlternation() error {
+	previous := ""
+	for i, msg := range c.Messages {
+		side := turnSide(msg.Role)
+		switch {
+		case side == "system":
+			continue
+		case previous == "" && msg.Role != "user":
+			return NewInvalidParameterError("messages",
+				fmt.Sprintf("message at index %d is from %q; the first turn must be from the user", i, msg.Role))
+		case side == "assistant" && previous == "assistant":
+			return NewInvalidParameterError("messages",
+				fmt.Sprintf("message at index %d follows another assistant message; user and assistant turns must alternate", i))
+		}
+		previous = side
+	}
+	return nil
+}
+
+// turnSide maps a role to the side of the exchange it is sent as.
+func turnSide(role string) string {
+	if role == "tool" {
+		return "user"
+	}
+	return role
+}
@@ -0,0 +1,127 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conversationOf(messages ...Message) *Conversation {
+	return &Conversation{Messages: messages}
+}
+
+func TestConversation_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		conv    *Conversation
+		message string
+	}{
+		{"empty", NewConversation(), "conversation is empty"},
+		{"unknown role", conversationOf(Message{Role: "user", Content: "hi"}, Message{Role: "model", Content: "hello"}), `unknown role "model"`},
+		{"missing role", conversationOf(Message{Content: "hi"}), `unknown role ""`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.conv.Validate()
+			var ce *ClientError
+			require.ErrorAs(t, err, &ce)
+			assert.Equal(t, ErrorTypeConfig, ce.Type)
+			assert.Contains(t, ce.Message, tc.message)
+		})
+	}
+
+	valid := conversationOf(
+		Message{Role: "system", Content: "s"},
+		Message{Role: "assistant", Content: "greeting"},
+		Message{Role: "user", Content: "hi"},
+	)
+	assert.NoError(t, valid.Validate(), "ordering is left to ValidateAlternation")
+}
+
+func TestConversation_ValidateAlternation(t *testing.T) {
+	toolTurn := Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "a", Name: "f"}, {ID: "b", Name: "f"}}}
+	for _, tc := range []struct {
+		name    string
+		conv    *Conversation
+		message string
+	}{
+		{"alternating", conversationOf(
+			Message{Role: "system", Content: "s"},
+			Message{Role: "user", Content: "hi"},
+			Message{Role: "assistant", Content: "hello"},
+			Message{Role: "user", Content: "bye"},
+		), ""},
+		{"caption then image", conversationOf(
+			Message{Role: "user", Content: "what is this?"},
+			Message{Role: "user", Parts: []ContentPart{{Type: ContentPartImage, Image: &ImagePart{URL: "https://example.com/a.png"}}}},
+		), ""},
+		{"parallel tool results", conversationOf(
+			Message{Role: "user", Content: "weather?"},
+			toolTurn,
+			Message{Role: "tool", ToolCallID: "a", Content: "sunny"},
+			Message{Role: "tool", ToolCallID: "b", Content: "warm"},
+		), ""},
+		{"assistant first", conversationOf(
+			Message{Role: "system", Content: "s"},
+			Message{Role: "assistant", Content: "hello"},
+		), "first turn must be from the user"},
+		{"tool result first", conversationOf(Message{Role: "tool", ToolCallID: "a", Content: "x"}), "first turn must be from the user"},
+		{"repeated assistant", conversationOf(
+			Message{Role: "user", Content: "hi"},
+			Message{Role: "assistant", Content: "one"},
+			Message{Role: "assistant", Content: "two"},
+		), "must alternate"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.conv.ValidateAlternation()
+			if tc.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var ce *ClientError
+			require.ErrorAs(t, err, &ce)
+			assert.Equal(t, ErrorTypeConfig, ce.Type)
+			assert.Contains(t, ce.Message, tc.message)
+		})
+	}
+}
+
+func TestSendConversation_RejectsMalformedBeforeDispatch(t *testing.T) {
+	calls := 0
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return cannedResponse(http.StatusOK, `{}`)(req)
+	})
+	openai, err := NewOpenAIClient("key", "", NewClientConfig())
+	require.NoError(t, err)
+	openai.httpClient.Transport = transport
+	claude, err := NewClaudeClient("key", "", NewClientConfig())
+	require.NoError(t, err)
+	claude.httpClient.Transport = transport
+
+	ctx := context.Background()
+	_, err = openai.SendConversation(ctx, NewConversation())
+	assert.True(t, isConfigError(err), "empty conversation: %v", err)
+
+	_, err = openai.SendConversation(ctx, conversationOf(Message{Role: "bot", Content: "hi"}))
+	assert.True(t, isConfigError(err), "unknown role: %v", err)
+
+	assistantFirst := conversationOf(Message{Role: "assistant", Content: "hello"}, Message{Role: "user", Content: "hi"})
+	_, err = claude.SendConversation(ctx, assistantFirst)
+	assert.True(t, isConfigError(err), "Claude requires a user turn first: %v", err)
+	ch, err := claude.StreamConversation(ctx, assistantFirst)
+	if err == nil {
+		_, last := collectStream(t, ch)
+		err = last.Err
+	}
+	assert.True(t, isConfigError(err), "streaming is validated too: %v", err)
+
+	assert.Zero(t, calls, "nothing reached the network")
+}
+
+func isConfigError(err error) bool {
+	ce, ok := err.(*ClientError)
+	return ok && ce.Type == ErrorTypeConfig
+}
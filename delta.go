@@ -0,0 +1,242 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// delta.go measures the delta between the answers of a parallel execution: line and
+// word diffs and a similarity score for every pair of successful results, and the
+// consensus answer when most of them agree. Summarize asks a judge model to describe
+// the agreements and disagreements in prose.
+package chatdelta
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DiffOp is the kind of a DiffSegment.
+type DiffOp int
+
+const (
+	// DiffEqual marks text both answers share
+	DiffEqual DiffOp = iota
+	// DiffDelete marks text only the first answer of the pair has
+	DiffDelete
+	// DiffInsert marks text only the second answer of the pair has
+	DiffInsert
+)
+
+// DiffSegment is a run of lines or words with the same DiffOp. Lines are joined with
+// "\n" and words with a single space.
+type DiffSegment struct {
+	Op   DiffOp
+	Text string
+}
+
+// PairwiseDelta compares the answers of two clients.
+type PairwiseDelta struct {
+	// A and B are the names of the clients compared
+	A, B string
+	// Similarity is the word-level similarity of the two answers, from 0 (nothing in
+	// common) to 1 (the same words in the same order), ignoring case and spacing
+	Similarity float64
+	// LineDiff turns A's answer into B's line by line
+	LineDiff []DiffSegment
+	// WordDiff turns A's answer into B's word by word
+	WordDiff []DiffSegment
+}
+
+// Comparison is the result of CompareResults.
+type Comparison struct {
+	// Pairs holds one PairwiseDelta for every pair of successful results, in the
+	// order of the results
+	Pairs []PairwiseDelta
+	// Consensus is the answer a majority of the successful results agree on once
+	// normalized, as written by the first of them; empty when there is none
+	Consensus string
+	// ConsensusClients names the clients whose answers make up the Consensus
+	ConsensusClients []string
+	// Failed names the clients whose results had an error and were left out
+	Failed []string
+}
+
+// CompareResults computes the delta between the answers in results. Failed results
+// are listed in Comparison.Failed and otherwise ignored. Answers agree for the
+// consensus when they are equal after lowercasing, collapsing whitespace, and
+// trimming surrounding punctuation; the consensus needs at least two answers and more
+// than half of the successful ones.
+func CompareResults(results []ParallelResult) *Comparison {
+	comparison := &Comparison{}
+	var answered []ParallelResult
+	for _, r := range results {
+		if r.Error != nil {
+			comparison.Failed = append(comparison.Failed, r.ClientName)
+			continue
+		}
+		answered = append(answered, r)
+	}
+
+	for i := range answered {
+		for j := i + 1; j < len(answered); j++ {
+			comparison.Pairs = append(comparison.Pairs, comparePair(answered[i], answered[j]))
+		}
+	}
+
+	groups := make(map[string][]int)
+	var order []string
+	for i, r := range answered {
+		key := normalizeAnswer(r.Result)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	for _, key := range order {
+		members := groups[key]
+		if len(members) >= 2 && len(members)*2 > len(answered) {
+			comparison.Consensus = strings.TrimSpace(answered[members[0]].Result)
+			for _, i := range members {
+				comparison.ConsensusClients = append(comparison.ConsensusClients, answered[i].ClientName)
+			}
+			break
+		}
+	}
+	return comparison
+}
+
+// comparePair computes the delta between the answers of a and b.
+func comparePair(a, b ParallelResult) PairwiseDelta {
+	aWords, _ := wordSpans(a.Result)
+	bWords, _ := wordSpans(b.Result)
+	return PairwiseDelta{
+		A:          a.ClientName,
+		B:          b.ClientName,
+		Similarity: wordSimilarity(aWords, bWords),
+		LineDiff:   diffTokens(answerLines(a.Result), answerLines(b.Result), "\n"),
+		WordDiff:   diffTokens(strings.Fields(a.Result), strings.Fields(b.Result), " "),
+	}
+}
+
+// wordSimilarity is 1 minus the word-level edit distance between a and b relative to
+// the longer of the two.
+func wordSimilarity(a, b []string) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	distance := prefixEditDistances(a, b)[len(b)]
+	return 1 - float64(distance)/float64(longest)
+}
+
+// answerLines splits an answer into lines, ignoring a trailing newline.
+func answerLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// normalizeAnswer reduces an answer to the form compared for consensus.
+func normalizeAnswer(s string) string {
+	s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	return strings.TrimFunc(s, unicode.IsPunct)
+}
+
+// diffTokens returns the segments that turn a into b, from a longest common
+// subsequence of tokens. Consecutive tokens with the same op are joined with sep.
+func diffTokens(a, b []string, sep string) []DiffSegment {
+	// The shared prefix and suffix are kept out of the quadratic table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:]
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var builder diffBuilder
+	for _, token := range a[:prefix] {
+		builder.add(DiffEqual, token)
+	}
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			builder.add(DiffEqual, midA[i])
+			i, j = i+1, j+1
+		case j == len(midB) || (i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]):
+			builder.add(DiffDelete, midA[i])
+			i++
+		default:
+			builder.add(DiffInsert, midB[j])
+			j++
+		}
+	}
+	for _, token := range a[len(a)-suffix:] {
+		builder.add(DiffEqual, token)
+	}
+	return builder.segments(sep)
+}
+
+// diffBuilder groups consecutive tokens with the same op.
+type diffBuilder struct {
+	ops    []DiffOp
+	tokens [][]string
+}
+
+func (d *diffBuilder) add(op DiffOp, token string) {
+	if n := len(d.ops); n > 0 && d.ops[n-1] == op {
+		d.tokens[n-1] = append(d.tokens[n-1], token)
+		return
+	}
+	d.ops = append(d.ops, op)
+	d.tokens = append(d.tokens, []string{token})
+}
+
+func (d *diffBuilder) segments(sep string) []DiffSegment {
+	segments := make([]DiffSegment, len(d.ops))
+	for i, op := range d.ops {
+		segments[i] = DiffSegment{Op: op, Text: strings.Join(d.tokens[i], sep)}
+	}
+	return segments
+}
+
+// DefaultJudgePrompt is the instruction Summarize sends to the judge, followed by the
+// answers being compared.
+const DefaultJudgePrompt = "Several AI assistants answered the same question. Summarize where their answers agree and where they disagree, naming the assistants involved in each disagreement. Do not answer the question yourself."
+
+// Summarize asks judge to describe where the successful answers in results agree and
+// disagree, and returns its summary. Failed results are left out; if none
+// succeeded, a config error is returned without calling judge.
+func Summarize(ctx context.Context, judge AIClient, results []ParallelResult) (string, error) {
+	var b strings.Builder
+	b.WriteString(DefaultJudgePrompt)
+	answers := 0
+	for _, r := range results {
+		if r.Error != nil {
+			continue
+		}
+		answers++
+		fmt.Fprintf(&b, "\n\n### %s\n%s", r.ClientName, strings.TrimSpace(r.Result))
+	}
+	if answers == 0 {
+		return "", NewConfigError("no successful results to summarize")
+	}
+	return judge.SendPrompt(ctx, b.String())
+}
@@ -0,0 +1,125 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareResults_Consensus(t *testing.T) {
+	results := []ParallelResult{
+		{ClientName: "OpenAI", Result: "Paris"},
+		{ClientName: "Claude", Result: "paris."},
+		{ClientName: "Gemini", Result: "Lyon"},
+		{ClientName: "Ollama", Result: "  Paris  \n"},
+		{ClientName: "xAI", Error: errors.New("down")},
+	}
+
+	comparison := CompareResults(results)
+
+	assert.Equal(t, "Paris", comparison.Consensus)
+	assert.Equal(t, []string{"OpenAI", "Claude", "Ollama"}, comparison.ConsensusClients)
+	assert.Equal(t, []string{"xAI"}, comparison.Failed)
+	require.Len(t, comparison.Pairs, 6, "every pair of the four answers")
+	assert.Equal(t, "OpenAI", comparison.Pairs[0].A)
+	assert.Equal(t, "Claude", comparison.Pairs[0].B)
+}
+
+func TestCompareResults_NoConsensus(t *testing.T) {
+	comparison := CompareResults([]ParallelResult{
+		{ClientName: "a", Result: "yes"},
+		{ClientName: "b", Result: "yes"},
+		{ClientName: "c", Result: "no"},
+		{ClientName: "d", Result: "maybe"},
+	})
+	assert.Empty(t, comparison.Consensus, "two of four is not a majority")
+	assert.Empty(t, comparison.ConsensusClients)
+
+	single := CompareResults([]ParallelResult{{ClientName: "a", Result: "yes"}})
+	assert.Empty(t, single.Consensus, "one answer agrees with nobody")
+	assert.Empty(t, single.Pairs)
+}
+
+func TestCompareResults_Similarity(t *testing.T) {
+	comparison := CompareResults([]ParallelResult{
+		{ClientName: "a", Result: "The quick brown fox jumps"},
+		{ClientName: "b", Result: "the quick  red fox jumps"},
+		{ClientName: "c", Result: "The quick brown fox jumps"},
+		{ClientName: "d", Result: ""},
+	})
+
+	similarity := map[string]float64{}
+	for _, p := range comparison.Pairs {
+		similarity[p.A+p.B] = p.Similarity
+	}
+	assert.InDelta(t, 0.8, similarity["ab"], 1e-9, "one word of five differs")
+	assert.InDelta(t, 1.0, similarity["ac"], 1e-9)
+	assert.InDelta(t, 0.0, similarity["ad"], 1e-9)
+}
+
+func TestCompareResults_Diffs(t *testing.T) {
+	comparison := CompareResults([]ParallelResult{
+		{ClientName: "a", Result: "Step one\nStep two\nStep three\n"},
+		{ClientName: "b", Result: "Step one\nStep 2\nStep three\nStep four"},
+	})
+	require.Len(t, comparison.Pairs, 1)
+	pair := comparison.Pairs[0]
+
+	assert.Equal(t, []DiffSegment{
+		{Op: DiffEqual, Text: "Step one"},
+		{Op: DiffDelete, Text: "Step two"},
+		{Op: DiffInsert, Text: "Step 2"},
+		{Op: DiffEqual, Text: "Step three"},
+		{Op: DiffInsert, Text: "Step four"},
+	}, pair.LineDiff)
+
+	assert.Equal(t, []DiffSegment{
+		{Op: DiffEqual, Text: "Step one Step"},
+		{Op: DiffDelete, Text: "two"},
+		{Op: DiffInsert, Text: "2"},
+		{Op: DiffEqual, Text: "Step three"},
+		{Op: DiffInsert, Text: "Step four"},
+	}, pair.WordDiff)
+}
+
+func TestDiffTokens(t *testing.T) {
+	assert.Equal(t, []DiffSegment{{Op: DiffEqual, Text: "a b"}}, diffTokens([]string{"a", "b"}, []string{"a", "b"}, " "))
+	assert.Equal(t, []DiffSegment{{Op: DiffInsert, Text: "a b"}}, diffTokens(nil, []string{"a", "b"}, " "))
+	assert.Equal(t, []DiffSegment{
+		{Op: DiffDelete, Text: "x"},
+		{Op: DiffEqual, Text: "a"},
+		{Op: DiffInsert, Text: "y"},
+	}, diffTokens([]string{"x", "a"}, []string{"a", "y"}, " "))
+}
+
+func TestSummarize(t *testing.T) {
+	judge := NewMockClient("judge", "")
+	judge.QueueResponse("Both say Paris; Gemini disagrees.")
+
+	summary, err := Summarize(context.Background(), judge, []ParallelResult{
+		{ClientName: "OpenAI", Result: "Paris"},
+		{ClientName: "Gemini", Result: "Lyon\n"},
+		{ClientName: "xAI", Error: errors.New("down")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Both say Paris; Gemini disagrees.", summary)
+
+	require.Len(t, judge.Conversations(), 1)
+	prompt := judge.Conversations()[0].Messages[0].Content
+	assert.Contains(t, prompt, DefaultJudgePrompt)
+	assert.Contains(t, prompt, "### OpenAI\nParis")
+	assert.Contains(t, prompt, "### Gemini\nLyon")
+	assert.NotContains(t, prompt, "xAI", "failed results are left out")
+}
+
+func TestSummarize_NoResults(t *testing.T) {
+	judge := NewMockClient("judge", "")
+	_, err := Summarize(context.Background(), judge, []ParallelResult{{ClientName: "xAI", Error: errors.New("down")}})
+	var ce *ClientError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, ErrorTypeConfig, ce.Type)
+	assert.Zero(t, judge.CallCount())
+}
@@ -0,0 +1,181 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// drain.go defines DrainingClient, an AIClient wrapper for services that need a clean
+// shutdown: it tracks in-flight calls and streams, and Drain waits for them up to a
+// deadline before cancelling whatever is still running.
+package chatdelta
+
+import (
+	"context"
+	"sync"
+)
+
+// DrainingClient wraps an AIClient and tracks its in-flight calls. Once Drain is
+// called, new calls fail with a client_draining error while running ones are allowed
+// to finish. A stream counts as in flight until its channel is closed.
+type DrainingClient struct {
+	inner AIClient
+
+	// shutdown is cancelled when a drain deadline passes, cancelling every call
+	shutdown context.Context
+	cancel   context.CancelFunc
+
+	mu       sync.Mutex
+	draining bool
+	inFlight sync.WaitGroup
+	count    int
+}
+
+// NewDrainingClient creates a DrainingClient around inner.
+func NewDrainingClient(inner AIClient) *DrainingClient {
+	shutdown, cancel := context.WithCancel(context.Background())
+	return &DrainingClient{inner: inner, shutdown: shutdown, cancel: cancel}
+}
+
+// InFlight returns the number of calls and streams currently running.
+func (d *DrainingClient) InFlight() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.count
+}
+
+// Drain stops accepting new calls and waits for in-flight ones to finish. If ctx ends
+// first, the remaining calls are cancelled and Drain returns ctx's error once they
+// have returned. Calling Drain again waits for the same calls.
+func (d *DrainingClient) Drain(ctx context.Context) error {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// begin registers a call and returns its context, which is also cancelled by a drain
+// deadline, and the function that unregisters it.
+func (d *DrainingClient) begin(ctx context.Context) (context.Context, func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return nil, nil, NewClientDrainingError()
+	}
+	d.inFlight.Add(1)
+	d.count++
+
+	callCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(d.shutdown, cancel)
+	return callCtx, func() {
+		stop()
+		cancel()
+		d.mu.Lock()
+		d.count--
+		d.mu.Unlock()
+		d.inFlight.Done()
+	}, nil
+}
+
+// SendPrompt forwards to the inner client while tracking the call.
+func (d *DrainingClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	ctx, end, err := d.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer end()
+	return d.inner.SendPrompt(ctx, prompt)
+}
+
+// SendPromptWithMetadata forwards to the inner client while tracking the call.
+func (d *DrainingClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	ctx, end, err := d.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+	return d.inner.SendPromptWithMetadata(ctx, prompt)
+}
+
+// SendConversation forwards to the inner client while tracking the call.
+func (d *DrainingClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	ctx, end, err := d.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer end()
+	return d.inner.SendConversation(ctx, conversation)
+}
+
+// SendConversationWithMetadata forwards to the inner client while tracking the call.
+func (d *DrainingClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	ctx, end, err := d.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer end()
+	return d.inner.SendConversationWithMetadata(ctx, conversation)
+}
+
+// StreamPrompt forwards to the inner client; the stream is tracked until it closes.
+func (d *DrainingClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	ctx, end, err := d.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := d.inner.StreamPrompt(ctx, prompt)
+	return d.trackStream(ctx, ch, err, end)
+}
+
+// StreamConversation forwards to the inner client; the stream is tracked until it closes.
+func (d *DrainingClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
+	ctx, end, err := d.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := d.inner.StreamConversation(ctx, conversation)
+	return d.trackStream(ctx, ch, err, end)
+}
+
+// trackStream forwards src unchanged and calls end once it closes. If ctx, the call's
+// context, ends while a chunk goes unread, the rest of src is drained unforwarded so an
+// abandoned stream does not keep Drain waiting.
+func (d *DrainingClient) trackStream(ctx context.Context, src <-chan StreamChunk, err error, end func()) (<-chan StreamChunk, error) {
+	if err != nil {
+		end()
+		return nil, err
+	}
+	out := make(chan StreamChunk, 10)
+	go func() {
+		defer close(out)
+		defer end()
+		for chunk := range src {
+			if !deliverChunk(ctx, out, chunk) {
+				for range src {
+				}
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// SupportsStreaming delegates to the inner client.
+func (d *DrainingClient) SupportsStreaming() bool { return d.inner.SupportsStreaming() }
+
+// SupportsConversations delegates to the inner client.
+func (d *DrainingClient) SupportsConversations() bool { return d.inner.SupportsConversations() }
+
+// Name delegates to the inner client.
+func (d *DrainingClient) Name() string { return d.inner.Name() }
+
+// Model delegates to the inner client.
+func (d *DrainingClient) Model() string { return d.inner.Model() }
@@ -0,0 +1,136 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingClient holds each SendPrompt until release is closed or its context ends.
+type blockingClient struct {
+	*MockClient
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingClient() *blockingClient {
+	return &blockingClient{MockClient: NewMockClient("blocking", ""), started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (c *blockingClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	c.started <- struct{}{}
+	select {
+	case <-c.release:
+		return "done: " + prompt, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// launch starts n SendPrompt calls and waits until all are in flight.
+func launch(t *testing.T, client *DrainingClient, inner *blockingClient, n int) (*sync.WaitGroup, []error) {
+	t.Helper()
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = client.SendPrompt(context.Background(), "job")
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		<-inner.started
+	}
+	return &wg, errs
+}
+
+func TestDrainingClient_WaitsForInFlight(t *testing.T) {
+	inner := newBlockingClient()
+	client := NewDrainingClient(inner)
+	wg, errs := launch(t, client, inner, 3)
+	assert.Equal(t, 3, client.InFlight())
+
+	drained := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		drained <- client.Drain(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return client.draining
+	}, time.Second, time.Millisecond)
+	_, err := client.SendPrompt(context.Background(), "late")
+	assert.True(t, errors.Is(err, NewClientDrainingError()), "new calls are rejected while draining")
+
+	close(inner.release)
+	require.NoError(t, <-drained)
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 0, client.InFlight())
+}
+
+func TestDrainingClient_CancelsAfterDeadline(t *testing.T) {
+	inner := newBlockingClient()
+	client := NewDrainingClient(inner)
+	wg, errs := launch(t, client, inner, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := client.Drain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	wg.Wait()
+	for _, err := range errs {
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+	assert.Equal(t, 0, client.InFlight())
+}
+
+func TestDrainingClient_TracksStreamsUntilClosed(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	mock.QueueResponse("streamed")
+	client := NewDrainingClient(mock)
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, 1, client.InFlight())
+
+	content, _ := collectStream(t, ch)
+	assert.Equal(t, "streamed", content)
+	require.NoError(t, client.Drain(context.Background()))
+}
+
+func TestDrainingClient_AbandonedStreamDoesNotBlockDrain(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	mock.SetChunkSize(1)
+	mock.QueueResponse(strings.Repeat("x", 100))
+	client := NewDrainingClient(mock)
+
+	// The caller never reads, so the forwarding buffer fills up
+	_, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	drained := make(chan error, 1)
+	go func() { drained <- client.Drain(ctx) }()
+	select {
+	case err := <-drained:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Drain still waiting for the abandoned stream")
+	}
+	assert.Equal(t, 0, client.InFlight())
+}
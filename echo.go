@@ -0,0 +1,136 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// echo.go implements the opt-in prompt echo guard. Smaller models sometimes repeat
+// the user's message before answering; when enabled, a near-verbatim copy of the
+// final user message at the start of a non-streaming response is removed.
+package chatdelta
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Default EchoGuard settings used by SetEchoGuard when zero values are passed.
+const (
+	DefaultEchoThreshold = 0.9
+	DefaultEchoMinLength = 40
+)
+
+// EchoGuard configures detection of responses that begin by repeating the prompt.
+type EchoGuard struct {
+	// Threshold is the word-level similarity (0-1) above which a response prefix is
+	// treated as an echo of the prompt.
+	Threshold float64
+	// MinLength is the minimum prompt length, in characters after whitespace
+	// normalization, for the guard to apply. Short prompts are never checked since
+	// repeating them is usually intentional or harmless.
+	MinLength int
+}
+
+// echoNotice is recorded in ResponseMetadata.Notices when an echo is removed.
+const echoNotice = "prompt_echo_stripped"
+
+// applyEchoGuard strips a prompt echo from content when the config enables it and
+// records a notice in metadata (which may be nil).
+func applyEchoGuard(config *ClientConfig, conversation *Conversation, content string, metadata *ResponseMetadata) string {
+	if config.EchoGuard == nil {
+		return content
+	}
+	stripped, ok := StripPromptEcho(lastUserMessage(conversation), content, *config.EchoGuard)
+	if !ok {
+		return content
+	}
+	if metadata != nil {
+		metadata.Notices = append(metadata.Notices, echoNotice)
+	}
+	return stripped
+}
+
+// StripPromptEcho removes a near-verbatim copy of prompt from the start of response.
+// It reports whether anything was removed. The response is left untouched when the
+// prompt is shorter than guard.MinLength, when the best-matching prefix is less
+// similar than guard.Threshold, or when nothing but the echo would remain (the user
+// most likely asked for the text to be repeated).
+func StripPromptEcho(prompt, response string, guard EchoGuard) (string, bool) {
+	promptWords := strings.Fields(strings.ToLower(prompt))
+	if len(strings.Join(promptWords, " ")) < guard.MinLength || len(promptWords) == 0 {
+		return response, false
+	}
+
+	words, ends := wordSpans(response)
+	// Only prefixes within the tolerated edit distance of the prompt can match.
+	slack := int(float64(len(promptWords))*(1-guard.Threshold)) + 1
+	limit := len(promptWords) + slack
+	if limit > len(words) {
+		limit = len(words)
+	}
+
+	distances := prefixEditDistances(promptWords, words[:limit])
+	best, bestScore := 0, 0.0
+	for j := 1; j <= limit; j++ {
+		longest := j
+		if len(promptWords) > longest {
+			longest = len(promptWords)
+		}
+		score := 1 - float64(distances[j])/float64(longest)
+		if score > bestScore {
+			best, bestScore = j, score
+		}
+	}
+	if best == 0 || bestScore < guard.Threshold {
+		return response, false
+	}
+
+	rest := strings.TrimLeftFunc(response[ends[best-1]:], unicode.IsSpace)
+	if rest == "" {
+		return response, false
+	}
+	return rest, true
+}
+
+// wordSpans splits s into lowercase words and returns each word's end byte offset in s.
+func wordSpans(s string) ([]string, []int) {
+	var words []string
+	var ends []int
+	start := -1
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				words = append(words, strings.ToLower(s[start:i]))
+				ends = append(ends, i)
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, strings.ToLower(s[start:]))
+		ends = append(ends, len(s))
+	}
+	return words, ends
+}
+
+// prefixEditDistances returns, for each j in 0..len(b), the word-level edit distance
+// between a and b[:j].
+func prefixEditDistances(a, b []string) []int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	// Row 0: distance from the empty prefix of a to b[:j].
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev
+}
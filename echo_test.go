@@ -0,0 +1,93 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var defaultGuard = EchoGuard{Threshold: DefaultEchoThreshold, MinLength: DefaultEchoMinLength}
+
+const echoPrompt = "Summarize the following paragraph about the history of the printing press in two sentences."
+
+func TestStripPromptEcho_VerbatimEcho(t *testing.T) {
+	response := echoPrompt + "\n\nThe printing press spread rapidly. It transformed Europe."
+	out, ok := StripPromptEcho(echoPrompt, response, defaultGuard)
+	assert.True(t, ok)
+	assert.Equal(t, "The printing press spread rapidly. It transformed Europe.", out)
+}
+
+func TestStripPromptEcho_NearVerbatimEcho(t *testing.T) {
+	// Whitespace and case differences plus one changed word still count as an echo.
+	response := "summarize  the following paragraph about the\nhistory of the printing press in 2 sentences.\nThe press spread rapidly."
+	out, ok := StripPromptEcho(echoPrompt, response, defaultGuard)
+	assert.True(t, ok)
+	assert.Equal(t, "The press spread rapidly.", out)
+}
+
+func TestStripPromptEcho_NoEcho(t *testing.T) {
+	response := "The printing press, invented around 1440, spread rapidly across Europe."
+	out, ok := StripPromptEcho(echoPrompt, response, defaultGuard)
+	assert.False(t, ok)
+	assert.Equal(t, response, out)
+}
+
+func TestStripPromptEcho_ShortPromptIgnored(t *testing.T) {
+	// Short prompts fall under the minimum length and are never stripped.
+	out, ok := StripPromptEcho("Say hello world", "Say hello world. Hello world!", defaultGuard)
+	assert.False(t, ok)
+	assert.Equal(t, "Say hello world. Hello world!", out)
+}
+
+func TestStripPromptEcho_RequestedRepetitionKept(t *testing.T) {
+	// When the whole response is the repeated text, the user most likely asked for it.
+	prompt := "Repeat after me exactly: the quick brown fox jumps over the lazy dog near the river bank"
+	response := "Repeat after me exactly: the quick brown fox jumps over the lazy dog near the river bank"
+	out, ok := StripPromptEcho(prompt, response, defaultGuard)
+	assert.False(t, ok)
+	assert.Equal(t, response, out)
+
+	// A quoted repetition that does not copy the instruction is not an echo either.
+	out, ok = StripPromptEcho(prompt, "the quick brown fox jumps over the lazy dog near the river bank", defaultGuard)
+	assert.False(t, ok)
+	assert.Equal(t, "the quick brown fox jumps over the lazy dog near the river bank", out)
+}
+
+func TestStripPromptEcho_Threshold(t *testing.T) {
+	response := "Summarize this paragraph on printing history in two sentences please.\nAnswer."
+	_, ok := StripPromptEcho(echoPrompt, response, defaultGuard)
+	assert.False(t, ok)
+
+	_, ok = StripPromptEcho(echoPrompt, response, EchoGuard{Threshold: 0.3, MinLength: DefaultEchoMinLength})
+	assert.True(t, ok)
+}
+
+func TestEchoGuard_AppliedByClient(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "", NewClientConfig().SetEchoGuard(0, 0))
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`{"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"`+echoPrompt+` Two sentences follow."},"finish_reason":"stop"}]}`)
+
+	resp, err := client.SendPromptWithMetadata(context.Background(), echoPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "Two sentences follow.", resp.Content)
+	assert.Equal(t, []string{"prompt_echo_stripped"}, resp.Metadata.Notices)
+
+	text, err := client.SendPrompt(context.Background(), echoPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "Two sentences follow.", text)
+}
+
+func TestEchoGuard_DisabledByDefault(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "", nil)
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`{"choices":[{"index":0,"message":{"role":"assistant","content":"`+echoPrompt+` Answer."},"finish_reason":"stop"}]}`)
+
+	text, err := client.SendPrompt(context.Background(), echoPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, echoPrompt+" Answer.", text)
+}
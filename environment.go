@@ -0,0 +1,117 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// environment.go reports which providers the environment is configured for and,
+// optionally, whether their credentials are accepted, for startup diagnostics.
+//
+// Usage:
+//
+//	for _, status := range chatdelta.ValidateEnvironment(ctx, chatdelta.EnvironmentOptions{Ping: true}) {
+//		if status.Present && !status.Valid {
+//			log.Printf("%s: %v", status.Provider, status.Error)
+//		}
+//	}
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// environmentProviders lists each built-in provider once, without aliases.
+var environmentProviders = []string{"openai", "claude", "gemini", "ollama", "azure", "openai-compatible", "xai", "bedrock", "vertex"}
+
+// defaultPingTimeout bounds each ping when EnvironmentOptions.Timeout is zero.
+const defaultPingTimeout = 10 * time.Second
+
+// EnvironmentOptions configures ValidateEnvironment.
+type EnvironmentOptions struct {
+	// Ping sends a request to every present provider to check that its credentials
+	// are accepted. Without it only the environment is inspected.
+	Ping bool
+	// Timeout bounds each ping; zero uses 10 seconds
+	Timeout time.Duration
+	// Configs holds the ClientConfig used to create a provider's client for pinging,
+	// keyed by provider name. Providers without one use NewClientConfig. Pings are
+	// never retried or answered from a cache, whatever the config says.
+	Configs map[string]*ClientConfig
+}
+
+// ProviderStatus reports how one provider is configured in the environment.
+type ProviderStatus struct {
+	// Provider is the provider name accepted by CreateClient
+	Provider string `json:"provider"`
+	// Present reports whether the provider's API key, or for keyless providers the
+	// settings they need, are found in the environment
+	Present bool `json:"present"`
+	// Pinged reports whether a request was sent to verify the credentials
+	Pinged bool `json:"pinged"`
+	// Valid reports whether the ping succeeded
+	Valid bool `json:"valid"`
+	// Error is why the client could not be created or the ping failed
+	Error error `json:"-"`
+}
+
+// ValidateEnvironment reports, for each built-in provider and then each registered
+// provider, whether the environment holds its credentials. With opts.Ping, present
+// providers are also pinged concurrently: clients that can list models are asked to,
+// and the others are sent a one-token prompt, which may be billed.
+func ValidateEnvironment(ctx context.Context, opts EnvironmentOptions) []ProviderStatus {
+	providers := append(append([]string(nil), environmentProviders...), registeredProviderNames()...)
+	statuses := make([]ProviderStatus, len(providers))
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		statuses[i] = ProviderStatus{
+			Provider: provider,
+			Present:  getAPIKeyFromEnv(provider) != "" || keylessProviderConfigured(provider),
+		}
+		if !opts.Ping || !statuses[i].Present {
+			continue
+		}
+		wg.Add(1)
+		go func(status *ProviderStatus) {
+			defer wg.Done()
+			status.Pinged = true
+			status.Error = pingProvider(ctx, status.Provider, opts)
+			status.Valid = status.Error == nil
+		}(&statuses[i])
+	}
+	wg.Wait()
+	return statuses
+}
+
+// pingProvider creates a client for provider from the environment and sends it the
+// cheapest request it supports.
+func pingProvider(ctx context.Context, provider string, opts EnvironmentOptions) error {
+	config := NewClientConfig()
+	if opts.Configs[provider] != nil {
+		copied := *opts.Configs[provider]
+		config = &copied
+	}
+	config.Retries = 0
+	config.Cache = nil
+	config.SetMaxTokens(1)
+
+	client, err := CreateClient(provider, "", "", config)
+	if err != nil {
+		return err
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultPingTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if lister, ok := client.(ModelLister); ok {
+		_, err := lister.ListModels(ctx)
+		var ce *ClientError
+		if !errors.As(err, &ce) || ce.Type != ErrorTypeConfig {
+			return err
+		}
+		// The endpoint cannot list models; fall back to a prompt
+	}
+	_, err = client.SendPrompt(ctx, "ping")
+	return err
+}
@@ -0,0 +1,118 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearProviderEnv unsets every environment variable the built-in providers read.
+func clearProviderEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"OPENAI_API_KEY", "CHATGPT_API_KEY", "ANTHROPIC_API_KEY", "CLAUDE_API_KEY",
+		"GOOGLE_API_KEY", "GEMINI_API_KEY", "AZURE_OPENAI_API_KEY", "OPENAI_COMPATIBLE_API_KEY",
+		openAICompatibleBaseURLEnv, "XAI_API_KEY", "OLLAMA_HOST", "AWS_REGION",
+		"AWS_DEFAULT_REGION", "GOOGLE_APPLICATION_CREDENTIALS",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+// statusFor returns the status reported for provider.
+func statusFor(t *testing.T, statuses []ProviderStatus, provider string) ProviderStatus {
+	t.Helper()
+	for _, status := range statuses {
+		if status.Provider == provider {
+			return status
+		}
+	}
+	t.Fatalf("no status for %s", provider)
+	return ProviderStatus{}
+}
+
+func TestValidateEnvironment_Presence(t *testing.T) {
+	clearProviderEnv(t)
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("GEMINI_API_KEY", "g-test")
+
+	statuses := ValidateEnvironment(context.Background(), EnvironmentOptions{})
+
+	assert.Equal(t, "openai", statuses[0].Provider)
+	assert.Equal(t, ProviderStatus{Provider: "openai", Present: true}, statusFor(t, statuses, "openai"))
+	assert.True(t, statusFor(t, statuses, "gemini").Present)
+	assert.False(t, statusFor(t, statuses, "claude").Present)
+	for _, status := range statuses {
+		assert.False(t, status.Pinged, "%s: nothing is pinged without Ping", status.Provider)
+		assert.NotEqual(t, "anthropic", status.Provider, "aliases are not reported")
+	}
+}
+
+func TestValidateEnvironment_Ping(t *testing.T) {
+	clearProviderEnv(t)
+	t.Setenv("OPENAI_API_KEY", "sk-good")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-revoked")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models", r.URL.Path)
+		if r.Header.Get("x-api-key") == "sk-revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`))
+			return
+		}
+		assert.Equal(t, "Bearer sk-good", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"object": "list", "data": [{"id": "gpt-4o"}]}`))
+	}))
+	defer server.Close()
+	config := NewClientConfig().SetBaseURL(server.URL).SetRetries(3)
+
+	statuses := ValidateEnvironment(context.Background(), EnvironmentOptions{
+		Ping:    true,
+		Configs: map[string]*ClientConfig{"openai": config, "claude": config},
+	})
+
+	openai := statusFor(t, statuses, "openai")
+	assert.True(t, openai.Pinged)
+	assert.True(t, openai.Valid)
+	assert.NoError(t, openai.Error)
+
+	claude := statusFor(t, statuses, "claude")
+	assert.True(t, claude.Pinged)
+	assert.False(t, claude.Valid)
+	assert.True(t, IsAuthenticationError(claude.Error))
+
+	gemini := statusFor(t, statuses, "gemini")
+	assert.False(t, gemini.Present)
+	assert.False(t, gemini.Pinged, "absent providers are not pinged")
+	assert.Equal(t, 3, config.Retries, "the caller's config is not modified")
+}
+
+func TestValidateEnvironment_PingFallsBackToPrompt(t *testing.T) {
+	clearProviderEnv(t)
+	t.Setenv("AZURE_OPENAI_API_KEY", "az-key")
+
+	var maxTokens interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/gpt-4o/chat/completions", r.URL.Path)
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		maxTokens = body["max_tokens"]
+		_, _ = w.Write([]byte(`{"choices": [{"index": 0, "message": {"role": "assistant", "content": "p"}, "finish_reason": "length"}]}`))
+	}))
+	defer server.Close()
+
+	statuses := ValidateEnvironment(context.Background(), EnvironmentOptions{
+		Ping:    true,
+		Configs: map[string]*ClientConfig{"azure": NewClientConfig().SetAzure("contoso", "gpt-4o", "").SetBaseURL(server.URL)},
+	})
+
+	status := statusFor(t, statuses, "azure")
+	require.True(t, status.Pinged)
+	assert.True(t, status.Valid, "%v", status.Error)
+	assert.EqualValues(t, 1, maxTokens)
+}
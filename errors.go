@@ -1,9 +1,13 @@
 package chatdelta
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,6 +29,12 @@ type ClientError struct {
 	Code    string    `json:"code,omitempty"`
 	Message string    `json:"message"`
 	Cause   error     `json:"-"`
+	// RetryAfter is the server-requested delay before retrying, taken from the
+	// Retry-After header of a rate-limited response. Zero when not provided.
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+	// StatusCode is the HTTP status of the response that produced the error, when
+	// known. Zero otherwise.
+	StatusCode int `json:"status_code,omitempty"`
 }
 
 // Error implements the error interface
@@ -84,14 +94,42 @@ func NewDNSError(hostname string, err error) *ClientError {
 // NewRateLimitError creates a new rate limit error
 func NewRateLimitError(retryAfter *time.Duration) *ClientError {
 	message := "rate limit exceeded"
+	var delay time.Duration
 	if retryAfter != nil {
 		message = fmt.Sprintf("rate limit exceeded, retry after %v", *retryAfter)
+		delay = *retryAfter
 	}
 	return &ClientError{
-		Type:    ErrorTypeAPI,
-		Code:    "rate_limit",
-		Message: message,
+		Type:       ErrorTypeAPI,
+		Code:       "rate_limit",
+		Message:    message,
+		RetryAfter: delay,
+	}
+}
+
+// parseRetryAfter reads the Retry-After header, which holds either a number of
+// seconds or an HTTP date. It returns nil when the header is absent or malformed;
+// dates in the past yield a zero delay.
+func parseRetryAfter(header http.Header, now time.Time) *time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return nil
 	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return nil
+		}
+		d := time.Duration(seconds) * time.Second
+		return &d
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := at.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return &d
+	}
+	return nil
 }
 
 // NewQuotaExceededError creates a new quota exceeded error
@@ -112,12 +150,75 @@ func NewInvalidModelError(model string) *ClientError {
 	}
 }
 
+// NewContextLengthError creates an error for a request whose prompt exceeds the
+// model's context window
+func NewContextLengthError(message string) *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeAPI,
+		Code:    "context_length_exceeded",
+		Message: message,
+	}
+}
+
+// ErrContextTooLarge matches, with errors.Is, the error CheckContextWindow returns for
+// a conversation estimated not to fit the model's context window.
+var ErrContextTooLarge = &ClientError{
+	Type:    ErrorTypeConfig,
+	Code:    "context_too_large",
+	Message: "conversation exceeds the model's context window",
+}
+
+// NewContextTooLargeError creates an error for a conversation estimated at estimated
+// tokens, more than the limit available for the prompt, found before sending it
+func NewContextTooLargeError(estimated, limit int) *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeConfig,
+		Code:    "context_too_large",
+		Message: fmt.Sprintf("conversation is estimated at %d tokens, more than the %d available", estimated, limit),
+	}
+}
+
+// contextOverflowPhrases are fragments of the messages providers return when a prompt
+// exceeds the context window: OpenAI, xAI, Claude, Bedrock, and Gemini respectively.
+var contextOverflowPhrases = []string{
+	"maximum context length",
+	"maximum prompt length",
+	"prompt is too long",
+	"input is too long",
+	"exceeds the maximum number of tokens",
+}
+
+// isContextOverflowMessage reports whether a 400 error message describes a context
+// window overflow.
+func isContextOverflowMessage(message string) bool {
+	lower := strings.ToLower(message)
+	for _, phrase := range contextOverflowPhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
 // NewServerError creates a new server error
 func NewServerError(statusCode int, message string) *ClientError {
 	return &ClientError{
-		Type:    ErrorTypeAPI,
-		Code:    "server_error",
-		Message: fmt.Sprintf("server returned status %d: %s", statusCode, message),
+		Type:       ErrorTypeAPI,
+		Code:       "server_error",
+		Message:    fmt.Sprintf("server returned status %d: %s", statusCode, message),
+		StatusCode: statusCode,
+	}
+}
+
+// isTransientServerStatus reports whether a 5xx status is worth retrying. 501 Not
+// Implemented and 505 HTTP Version Not Supported will fail the same way every time;
+// an unknown (zero) status is treated as transient.
+func isTransientServerStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	default:
+		return true
 	}
 }
 
@@ -179,6 +280,56 @@ func NewMissingConfigError(config string) *ClientError {
 	}
 }
 
+// NewRequestMutatorError creates an error for a RequestMutator that rejected a request
+func NewRequestMutatorError(index int, err error) *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeConfig,
+		Code:    "request_mutator_failed",
+		Message: fmt.Sprintf("request mutator %d failed", index),
+		Cause:   err,
+	}
+}
+
+// NewContentRejectedError creates an error for a prompt or response filter that
+// rejected content
+func NewContentRejectedError(stage string, index int, err error) *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeConfig,
+		Code:    "content_rejected",
+		Message: fmt.Sprintf("%s filter %d rejected the content", stage, index),
+		Cause:   err,
+	}
+}
+
+// NewImageLimitError creates an error for a request exceeding a provider's image limits
+func NewImageLimitError(message string) *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeConfig,
+		Code:    "image_limit_exceeded",
+		Message: message,
+	}
+}
+
+// NewVisionUnsupportedError creates an error for images sent to a model that does
+// not accept image input
+func NewVisionUnsupportedError(provider Provider, model string) *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeConfig,
+		Code:    "vision_unsupported",
+		Message: fmt.Sprintf("%s model %s does not accept image input", provider, model),
+	}
+}
+
+// NewClientDrainingError creates an error for a call made after a DrainingClient
+// started draining
+func NewClientDrainingError() *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeConfig,
+		Code:    "client_draining",
+		Message: "client is shutting down and accepts no new requests",
+	}
+}
+
 // Parse Error constructors
 
 // NewJSONParseError creates a new JSON parsing error
@@ -191,6 +342,17 @@ func NewJSONParseError(err error) *ClientError {
 	}
 }
 
+// NewResponseDecodeError creates an error for a response that is not the JSON the
+// caller asked for; the cause is a *ParseError holding text
+func NewResponseDecodeError(text string, err error) *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeParse,
+		Code:    "response_decode_error",
+		Message: "failed to decode response as JSON",
+		Cause:   &ParseError{Text: text, Err: err},
+	}
+}
+
 // NewMissingFieldError creates a new missing field error
 func NewMissingFieldError(field string) *ClientError {
 	return &ClientError{
@@ -221,6 +383,16 @@ func NewStreamReadError(err error) *ClientError {
 	}
 }
 
+// NewStreamTruncatedError creates an error reporting that a stream timed out and
+// only partial content was received
+func NewStreamTruncatedError() *ClientError {
+	return &ClientError{
+		Type:    ErrorTypeStream,
+		Code:    "stream_truncated",
+		Message: "stream timed out; response is incomplete",
+	}
+}
+
 // Helper functions to classify errors
 
 // IsNetworkError checks if the error is a network-related error
@@ -249,7 +421,14 @@ func IsRetryableError(err error) bool {
 		case ErrorTypeNetwork:
 			return true
 		case ErrorTypeAPI:
-			return ce.Code == "rate_limit" || ce.Code == "server_error"
+			switch ce.Code {
+			case "rate_limit":
+				return true
+			case "server_error":
+				return isTransientServerStatus(ce.StatusCode)
+			default:
+				return false
+			}
 		default:
 			return false
 		}
@@ -263,6 +442,14 @@ func IsRetryableError(err error) bool {
 	return false
 }
 
+// IsContextLengthError reports whether err is a provider's context window overflow, or
+// CheckContextWindow's ErrContextTooLarge, so callers can trim or summarize the
+// conversation and try again.
+func IsContextLengthError(err error) bool {
+	var ce *ClientError
+	return errors.As(err, &ce) && (ce.Code == "context_length_exceeded" || ce.Code == "context_too_large")
+}
+
 // IsAuthenticationError checks if the error is authentication-related
 func IsAuthenticationError(err error) bool {
 	if ce, ok := err.(*ClientError); ok {
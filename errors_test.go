@@ -1,12 +1,22 @@
 package chatdelta
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"math/rand"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClientError_Error(t *testing.T) {
@@ -126,6 +136,7 @@ func TestAPIErrorConstructors(t *testing.T) {
 		assert.Equal(t, "server_error", err.Code)
 		assert.Contains(t, err.Message, "500")
 		assert.Contains(t, err.Message, "Internal Server Error")
+		assert.Equal(t, 500, err.StatusCode)
 	})
 
 	t.Run("bad request error", func(t *testing.T) {
@@ -266,6 +277,19 @@ func TestIsRetryableError(t *testing.T) {
 		assert.True(t, IsRetryableError(err))
 	})
 
+	t.Run("server error by status", func(t *testing.T) {
+		for status, want := range map[int]bool{
+			http.StatusInternalServerError:     true,
+			http.StatusNotImplemented:          false,
+			http.StatusBadGateway:              true,
+			http.StatusServiceUnavailable:      true,
+			http.StatusGatewayTimeout:          true,
+			http.StatusHTTPVersionNotSupported: false,
+		} {
+			assert.Equal(t, want, IsRetryableError(NewServerError(status, "boom")), "status %d", status)
+		}
+	})
+
 	t.Run("auth error", func(t *testing.T) {
 		err := &ClientError{Type: ErrorTypeAuth}
 		assert.False(t, IsRetryableError(err))
@@ -303,3 +327,416 @@ func TestIsAuthenticationError(t *testing.T) {
 		assert.False(t, IsAuthenticationError(err))
 	})
 }
+
+func TestIsContextLengthError_ProviderBodies(t *testing.T) {
+	config := func() *ClientConfig { return NewClientConfig().SetRetries(0) }
+	openai, _ := NewOpenAIClient("key", "gpt-4o", config())
+	xai, _ := NewXAIClient("key", "", config())
+	claude, _ := NewClaudeClient("key", "", config())
+	gemini, _ := NewGeminiClient("key", "", config())
+
+	cases := []struct {
+		name   string
+		client AIClient
+		body   string
+	}{
+		{"openai", openai, `{"error":{"message":"This model's maximum context length is 128000 tokens. However, your messages resulted in 130412 tokens. Please reduce the length of the messages.","type":"invalid_request_error","param":"messages","code":"context_length_exceeded"}}`},
+		{"xai", xai, `{"error":{"message":"This model's maximum prompt length is 131072 but the request contains 140000 tokens.","type":"invalid_request_error"}}`},
+		{"claude", claude, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 208310 tokens > 200000 maximum"}}`},
+		{"gemini", gemini, `{"error":{"code":400,"message":"The input token count (1300000) exceeds the maximum number of tokens allowed (1048576).","status":"INVALID_ARGUMENT"}}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := cannedResponse(http.StatusBadRequest, tc.body)
+			switch c := tc.client.(type) {
+			case *OpenAIClient:
+				c.httpClient.Transport = transport
+			case *ClaudeClient:
+				c.httpClient.Transport = transport
+			case *GeminiClient:
+				c.httpClient.Transport = transport
+			}
+			_, err := tc.client.SendPrompt(context.Background(), "hi")
+			assert.True(t, IsContextLengthError(err), "%v", err)
+			assert.False(t, IsRetryableError(err))
+		})
+	}
+
+	t.Run("bedrock", func(t *testing.T) {
+		bedrock, err := NewBedrockClient("", config().SetBedrock("us-east-1", &AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}))
+		require.NoError(t, err)
+		header := http.Header{"X-Amzn-Errortype": {"ValidationException:http://internal.amazon.com/coral/com.amazon.bedrock/"}}
+		err = bedrock.errorFromBody(http.StatusBadRequest, header, []byte(`{"message":"Input is too long for requested model."}`))
+		assert.True(t, IsContextLengthError(err))
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		assert.False(t, IsContextLengthError(NewBadRequestError("temperature must be <= 2")))
+		assert.False(t, IsContextLengthError(errors.New("prompt is too long")))
+		assert.True(t, IsContextLengthError(fmt.Errorf("wrapped: %w", NewContextLengthError("too long"))))
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	header := func(v string) http.Header { return http.Header{"Retry-After": []string{v}} }
+
+	d := parseRetryAfter(header("120"), now)
+	require.NotNil(t, d)
+	assert.Equal(t, 2*time.Minute, *d)
+
+	d = parseRetryAfter(header(now.Add(30*time.Second).Format(http.TimeFormat)), now)
+	require.NotNil(t, d)
+	assert.Equal(t, 30*time.Second, *d)
+
+	d = parseRetryAfter(header(now.Add(-time.Minute).Format(http.TimeFormat)), now)
+	require.NotNil(t, d)
+	assert.Zero(t, *d)
+
+	assert.Nil(t, parseRetryAfter(http.Header{}, now))
+	assert.Nil(t, parseRetryAfter(header("soon"), now))
+	assert.Nil(t, parseRetryAfter(header("-5"), now))
+}
+
+func TestRateLimitError_RetryAfterFromClients(t *testing.T) {
+	rateLimited := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{"7"}},
+			Body:       io.NopCloser(strings.NewReader(`{"error":{"message":"slow down"}}`)),
+			Request:    req,
+		}, nil
+	})
+
+	config := func() *ClientConfig { return NewClientConfig().SetRetries(0) }
+	openai, _ := NewOpenAIClient("key", "", config())
+	openai.httpClient.Transport = rateLimited
+	claude, _ := NewClaudeClient("key", "", config())
+	claude.httpClient.Transport = rateLimited
+	gemini, _ := NewGeminiClient("key", "", config())
+	gemini.httpClient.Transport = rateLimited
+
+	for _, client := range []AIClient{openai, claude, gemini} {
+		_, err := client.SendPrompt(context.Background(), "hi")
+		var ce *ClientError
+		require.True(t, errors.As(err, &ce), client.Name())
+		assert.Equal(t, "rate_limit", ce.Code, client.Name())
+		assert.Equal(t, 7*time.Second, ce.RetryAfter, client.Name())
+	}
+
+	// Streaming requests surface the same hint on the terminal chunk.
+	ch, err := openai.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	var last StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	var ce *ClientError
+	require.True(t, errors.As(last.Err, &ce))
+	assert.Equal(t, 7*time.Second, ce.RetryAfter)
+}
+
+func TestExecuteWithRetry_HonorsRetryAfter(t *testing.T) {
+	hint := 60 * time.Millisecond
+	attempts := 0
+	start := time.Now()
+	err := ExecuteWithExponentialBackoff(context.Background(), 1, time.Millisecond, func() error {
+		attempts++
+		if attempts == 1 {
+			return NewRateLimitError(&hint)
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.GreaterOrEqual(t, time.Since(start), hint)
+	assert.Equal(t, 2*time.Second, honorRetryAfter(NewRateLimitError(&hint), 2*time.Second))
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base := 100 * time.Millisecond
+	for _, tc := range []struct {
+		strategy RetryStrategy
+		want     []time.Duration
+	}{
+		{RetryStrategyFixed, []time.Duration{base, base, base}},
+		{RetryStrategyLinear, []time.Duration{base, 2 * base, 3 * base}},
+		{RetryStrategyExponentialBackoff, []time.Duration{base, 2 * base, 4 * base}},
+	} {
+		for attempt, want := range tc.want {
+			assert.Equal(t, want, BackoffDelay(tc.strategy, attempt, base, 0, nil), "%s attempt %d", tc.strategy, attempt)
+		}
+	}
+	assert.Equal(t, 30*time.Second, BackoffDelay(RetryStrategyExponentialBackoff, 10, base, 0, nil))
+	assert.Equal(t, 30*time.Second, BackoffDelay(RetryStrategyExponentialBackoff, 200, base, 0, nil), "large attempts do not overflow")
+	assert.Equal(t, 3*time.Second, BackoffDelay(RetryStrategyExponentialBackoff, 10, base, 3*time.Second, nil))
+}
+
+func TestBackoffDelay_FullJitter(t *testing.T) {
+	base := 100 * time.Millisecond
+	first := rand.New(rand.NewSource(42))
+	second := rand.New(rand.NewSource(42))
+	distinct := map[time.Duration]bool{}
+	for attempt := 0; attempt < 8; attempt++ {
+		ceiling := BackoffDelay(RetryStrategyExponentialBackoff, attempt, base, 0, nil)
+		delay := BackoffDelay(RetryStrategyExponentialWithJitter, attempt, base, 0, first)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, ceiling)
+		assert.Equal(t, delay, BackoffDelay(RetryStrategyExponentialWithJitter, attempt, base, 0, second),
+			"a seeded source makes the waits repeatable")
+		distinct[delay] = true
+	}
+	assert.Greater(t, len(distinct), 1)
+	assert.Zero(t, BackoffDelay(RetryStrategyExponentialWithJitter, 0, 0, 0, nil))
+}
+
+func TestExecuteWithJitteredBackoff(t *testing.T) {
+	attempts := 0
+	err := ExecuteWithJitteredBackoff(context.Background(), 3, time.Millisecond, rand.New(rand.NewSource(1)), func() error {
+		attempts++
+		return NewServerError(http.StatusServiceUnavailable, "busy")
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, 4, attempts)
+
+	attempts = 0
+	err = ExecuteWithJitteredBackoff(context.Background(), 3, time.Millisecond, nil, func() error {
+		attempts++
+		return NewInvalidAPIKeyError()
+	})
+	assert.True(t, IsAuthenticationError(err))
+	assert.Equal(t, 1, attempts, "non-retryable errors are not retried")
+}
+
+// fakeClock is a Clock whose timers fire immediately and record the requested waits.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestRetryAfter_WaitUsesInjectedClock(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+	}{
+		{"seconds", "7", 7 * time.Second},
+		{"http_date", start.Add(12 * time.Second).Format(http.TimeFormat), 12 * time.Second},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := &fakeClock{now: start}
+			client, err := NewOpenAIClient("key", "", NewClientConfig().SetRetries(1).SetClock(clock))
+			require.NoError(t, err)
+
+			calls := 0
+			client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				calls++
+				if calls == 1 {
+					return &http.Response{
+						StatusCode: http.StatusTooManyRequests,
+						Header:     http.Header{"Retry-After": []string{tc.retryAfter}},
+						Body:       io.NopCloser(strings.NewReader(`{"error":{"message":"slow down"}}`)),
+						Request:    req,
+					}, nil
+				}
+				return cannedResponse(http.StatusOK, `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)(req)
+			})
+
+			result, err := client.SendPrompt(context.Background(), "hi")
+			require.NoError(t, err)
+			assert.Equal(t, "ok", result)
+			assert.Equal(t, 2, calls)
+			assert.Equal(t, []time.Duration{tc.want}, clock.waits)
+		})
+	}
+}
+
+func TestRetryDelays_BaseAndMax(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		config     *ClientConfig
+		retryAfter string
+		want       []time.Duration
+	}{
+		{"default", NewClientConfig(), "", []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second}},
+		{"base", NewClientConfig().SetBaseRetryDelay(200 * time.Millisecond), "",
+			[]time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 600 * time.Millisecond, 800 * time.Millisecond}},
+		{"base and max", NewClientConfig().SetBaseRetryDelay(200 * time.Millisecond).SetMaxRetryDelay(500 * time.Millisecond), "",
+			[]time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond}},
+		{"max", NewClientConfig().SetMaxRetryDelay(3 * time.Second), "",
+			[]time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{"linear is uncapped", NewClientConfig().SetBaseRetryDelay(20 * time.Second), "",
+			[]time.Duration{20 * time.Second, 40 * time.Second, 60 * time.Second, 80 * time.Second}},
+		{"exponential", NewClientConfig().SetRetryStrategy(RetryStrategyExponentialBackoff), "",
+			[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{"exponential default max", NewClientConfig().SetRetryStrategy(RetryStrategyExponentialBackoff).SetBaseRetryDelay(10 * time.Second), "",
+			[]time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}},
+		{"linear", NewClientConfig().SetRetryStrategy(RetryStrategyLinear).SetMaxRetryDelay(2500 * time.Millisecond), "",
+			[]time.Duration{time.Second, 2 * time.Second, 2500 * time.Millisecond, 2500 * time.Millisecond}},
+		{"retry after beats max", NewClientConfig().SetMaxRetryDelay(time.Second), "7",
+			[]time.Duration{7 * time.Second, 7 * time.Second, 7 * time.Second, 7 * time.Second}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+			client, err := NewOpenAIClient("key", "", tc.config.SetRetries(4).SetClock(clock))
+			require.NoError(t, err)
+			client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				status, header := http.StatusServiceUnavailable, http.Header{}
+				if tc.retryAfter != "" {
+					status = http.StatusTooManyRequests
+					header.Set("Retry-After", tc.retryAfter)
+				}
+				return &http.Response{
+					StatusCode: status,
+					Header:     header,
+					Body:       io.NopCloser(strings.NewReader(`{"error":{"message":"busy"}}`)),
+					Request:    req,
+				}, nil
+			})
+
+			_, err = client.SendPrompt(context.Background(), "hi")
+			require.Error(t, err)
+			assert.Equal(t, tc.want, clock.waits)
+		})
+	}
+}
+
+func TestRetryBackoff_DefaultsToLinear(t *testing.T) {
+	for _, strategy := range []RetryStrategy{"", NewClientConfig().RetryStrategy} {
+		backoff := retryBackoff{strategy: strategy}
+		for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second} {
+			assert.Equal(t, want, backoff.delay(attempt), "strategy %q attempt %d", strategy, attempt)
+		}
+		assert.Equal(t, 50*time.Second, backoff.delay(49), "strategy %q: no cap unless one is set", strategy)
+	}
+}
+
+func TestRetryDelays_Jitter(t *testing.T) {
+	waits := func(seed int64) []time.Duration {
+		clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+		config := NewClientConfig().SetRetries(6).SetClock(clock).
+			SetRetryStrategy(RetryStrategyExponentialWithJitter).SetRetryRand(rand.New(rand.NewSource(seed)))
+		client, err := NewOpenAIClient("key", "", config)
+		require.NoError(t, err)
+		client.httpClient.Transport = cannedResponse(http.StatusServiceUnavailable, `{"error":{"message":"busy"}}`)
+
+		_, err = client.SendPrompt(context.Background(), "hi")
+		require.Error(t, err)
+		return clock.waits
+	}
+
+	first := waits(7)
+	require.Len(t, first, 6)
+	distinct := map[time.Duration]bool{}
+	for attempt, wait := range first {
+		assert.GreaterOrEqual(t, wait, time.Duration(0))
+		assert.Less(t, wait, BackoffDelay(RetryStrategyExponentialBackoff, attempt, time.Second, 0, nil))
+		distinct[wait] = true
+	}
+	assert.Greater(t, len(distinct), 1, "waits are randomized")
+	assert.Equal(t, first, waits(7), "a seeded source makes the waits repeatable")
+}
+
+func TestOperationTimeout_BoundsRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error":{"message":"busy"}}`))
+	}))
+	defer server.Close()
+
+	// Unbounded, ten retries would wait 50ms+100ms+...+500ms = 2.75s in total
+	config := NewClientConfig().SetBaseURL(server.URL).SetRetries(10).
+		SetBaseRetryDelay(50 * time.Millisecond).SetOperationTimeout(200 * time.Millisecond)
+	client, err := NewOpenAIClient("key", "", config)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = client.SendPrompt(context.Background(), "hi")
+	elapsed := time.Since(start)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, elapsed, time.Second)
+	assert.Less(t, calls.Load(), int32(11), "the deadline stops further attempts")
+
+	assert.Error(t, ValidateConfig(NewClientConfig().SetOperationTimeout(-time.Second)))
+}
+
+func TestOpenAIClient_InsufficientQuotaNotRetried(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	client, err := NewOpenAIClient("key", "", NewClientConfig().SetRetries(3).SetClock(clock))
+	require.NoError(t, err)
+
+	calls := 0
+	quota := cannedResponse(http.StatusTooManyRequests, `{"error":{"message":"You exceeded your current quota, please check your plan and billing details.","type":"insufficient_quota","param":null,"code":"insufficient_quota"}}`)
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return quota(req)
+	})
+
+	_, err = client.SendPrompt(context.Background(), "hi")
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, "quota_exceeded", ce.Code)
+	assert.False(t, IsRetryableError(err))
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, clock.waits)
+}
+
+func TestServerErrorRetries_ByStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		status    int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"501 is not retried", http.StatusNotImplemented, 1, true},
+		{"503 is retried", http.StatusServiceUnavailable, 2, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+			client, err := NewOpenAIClient("key", "", NewClientConfig().SetRetries(2).SetClock(clock))
+			require.NoError(t, err)
+
+			calls := 0
+			client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				calls++
+				if calls == 1 {
+					return cannedResponse(tc.status, `{"error":{"message":"upstream trouble"}}`)(req)
+				}
+				return cannedResponse(http.StatusOK, `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)(req)
+			})
+
+			_, err = client.SendPrompt(context.Background(), "hi")
+			assert.Equal(t, tc.wantCalls, calls)
+			if !tc.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			var ce *ClientError
+			require.ErrorAs(t, err, &ce)
+			assert.Equal(t, "server_error", ce.Code)
+			assert.Equal(t, tc.status, ce.StatusCode)
+		})
+	}
+}
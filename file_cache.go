@@ -0,0 +1,220 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// file_cache.go implements FileCache, a Cache persisted as one JSON file per entry so
+// that responses survive process restarts.
+//
+// Writes go to a temporary file that is renamed into place, so readers never observe
+// a partial entry and concurrent writers from several processes resolve as
+// last-writer-wins. Entries that cannot be parsed are treated as misses and removed.
+// Least-recently-used order is tracked through file modification times, which Get
+// refreshes on every hit.
+package chatdelta
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileCacheExt is the extension of committed entries; temporary files use another
+// prefix and are ignored by reads and eviction.
+const (
+	fileCacheExt       = ".json"
+	fileCacheTmpPrefix = ".tmp-"
+)
+
+// FileCacheOptions configures a FileCache.
+type FileCacheOptions struct {
+	// TTL is how long an entry stays valid after it is written. Zero means entries
+	// never expire.
+	TTL time.Duration
+	// MaxBytes caps the total size of all entries. When a write takes the cache over
+	// the cap, least-recently-used entries are removed. Zero means no cap.
+	MaxBytes int64
+}
+
+// FileCache is a Cache stored as individual JSON files under a directory.
+// It is safe for concurrent use, including by several processes sharing a directory.
+type FileCache struct {
+	dir  string
+	opts FileCacheOptions
+	// mu serializes eviction within this process; other processes may evict
+	// concurrently, which only ever removes files.
+	mu sync.Mutex
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// fileCacheEntry is the on-disk form of an entry.
+type fileCacheEntry struct {
+	Key       string      `json:"key"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+	Response  *AiResponse `json:"response"`
+}
+
+// NewFileCache creates a FileCache rooted at dir, creating the directory if needed.
+func NewFileCache(dir string, opts FileCacheOptions) (*FileCache, error) {
+	if dir == "" {
+		return nil, NewInvalidParameterError("dir", "empty cache directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, NewInvalidParameterError("dir", err.Error())
+	}
+	return &FileCache{dir: dir, opts: opts, now: time.Now}, nil
+}
+
+// path returns the entry file for key. Keys are hashed so any string is a safe name.
+func (c *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+fileCacheExt)
+}
+
+// Get returns the response stored under key. Expired or corrupted entries are
+// removed and reported as misses.
+func (c *FileCache) Get(key string) (*AiResponse, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry fileCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || entry.Response == nil {
+		_ = os.Remove(path)
+		return nil, false
+	}
+
+	now := c.now()
+	if entry.ExpiresAt != nil && !now.Before(*entry.ExpiresAt) {
+		_ = os.Remove(path)
+		return nil, false
+	}
+
+	// Mark as recently used for eviction.
+	_ = os.Chtimes(path, now, now)
+	return entry.Response, true
+}
+
+// Set stores response under key, replacing any existing entry, then evicts
+// least-recently-used entries if the cache exceeds MaxBytes.
+func (c *FileCache) Set(key string, response *AiResponse) {
+	if response == nil {
+		return
+	}
+	entry := fileCacheEntry{Key: key, Response: response}
+	now := c.now()
+	if c.opts.TTL > 0 {
+		expires := now.Add(c.opts.TTL)
+		entry.ExpiresAt = &expires
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	if err := c.writeAtomic(c.path(key), data, now); err != nil {
+		return
+	}
+	if c.opts.MaxBytes > 0 {
+		c.evict()
+	}
+}
+
+// Delete removes the entry for key, if any.
+func (c *FileCache) Delete(key string) {
+	_ = os.Remove(c.path(key))
+}
+
+// PurgeAll removes every committed entry. Temporary files belong to writes in
+// progress and are left alone.
+func (c *FileCache) PurgeAll() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, fileCacheExt) || strings.HasPrefix(name, fileCacheTmpPrefix) {
+			continue
+		}
+		_ = os.Remove(filepath.Join(c.dir, name))
+	}
+}
+
+// writeAtomic writes data to a temporary file in the cache directory and renames it
+// over path, so the entry is either fully old or fully new.
+func (c *FileCache) writeAtomic(path string, data []byte, now time.Time) error {
+	tmp, err := os.CreateTemp(c.dir, fileCacheTmpPrefix+"*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	_ = os.Chtimes(tmpName, now, now)
+	return os.Rename(tmpName, path)
+}
+
+// evict removes least-recently-used entries until the total size is within MaxBytes.
+func (c *FileCache) evict() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	var total int64
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, fileCacheExt) || strings.HasPrefix(name, fileCacheTmpPrefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// Removed by another process since ReadDir.
+			continue
+		}
+		files = append(files, file{filepath.Join(c.dir, name), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	if total <= c.opts.MaxBytes {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if total <= c.opts.MaxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		total -= f.size
+	}
+}
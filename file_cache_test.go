@@ -0,0 +1,169 @@
+package chatdelta
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileCache(t *testing.T, dir string, opts FileCacheOptions, now *time.Time) *FileCache {
+	t.Helper()
+	cache, err := NewFileCache(dir, opts)
+	require.NoError(t, err)
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func TestFileCache_PersistsAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	first := newTestFileCache(t, dir, FileCacheOptions{}, &now)
+	first.Set("k", &AiResponse{Content: "cached", Metadata: ResponseMetadata{TotalTokens: 7}})
+
+	second := newTestFileCache(t, dir, FileCacheOptions{}, &now)
+	got, ok := second.Get("k")
+	require.True(t, ok)
+	assert.Equal(t, "cached", got.Content)
+	assert.Equal(t, 7, got.Metadata.TotalTokens)
+
+	second.Delete("k")
+	_, ok = first.Get("k")
+	assert.False(t, ok)
+}
+
+func TestFileCache_TTL(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestFileCache(t, t.TempDir(), FileCacheOptions{TTL: time.Hour}, &now)
+	cache.Set("k", &AiResponse{Content: "v"})
+
+	now = now.Add(59 * time.Minute)
+	_, ok := cache.Get("k")
+	assert.True(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.Get("k")
+	assert.False(t, ok)
+	_, err := os.Stat(cache.path("k"))
+	assert.True(t, os.IsNotExist(err), "expired entries are removed")
+}
+
+func TestFileCache_PurgeAll(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cache := newTestFileCache(t, dir, FileCacheOptions{}, &now)
+	cache.Set("a", &AiResponse{Content: "a"})
+	cache.Set("b", &AiResponse{Content: "b"})
+	tmp := filepath.Join(dir, fileCacheTmpPrefix+"pending")
+	require.NoError(t, os.WriteFile(tmp, []byte("{}"), 0o644))
+
+	cache.PurgeAll()
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	_, err := os.Stat(tmp)
+	assert.NoError(t, err, "writes in progress are left alone")
+}
+
+func TestFileCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	probe := newTestFileCache(t, t.TempDir(), FileCacheOptions{}, &now)
+	probe.Set("a", &AiResponse{Content: "xxxxxxxxxx"})
+	info, err := os.Stat(probe.path("a"))
+	require.NoError(t, err)
+
+	// Room for three entries of roughly the probe's size.
+	cache := newTestFileCache(t, dir, FileCacheOptions{MaxBytes: 3*info.Size() + 2}, &now)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(key, &AiResponse{Content: "xxxxxxxxxx"})
+		now = now.Add(time.Second)
+	}
+
+	// Touch "a" so "b" becomes the least recently used.
+	_, ok := cache.Get("a")
+	require.True(t, ok)
+	now = now.Add(time.Second)
+
+	cache.Set("d", &AiResponse{Content: "xxxxxxxxxx"})
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		_, ok := cache.Get(key)
+		assert.Equal(t, want, ok, "entry %s", key)
+	}
+}
+
+func TestFileCache_CorruptedEntryIsMiss(t *testing.T) {
+	now := time.Now()
+	cache := newTestFileCache(t, t.TempDir(), FileCacheOptions{}, &now)
+	cache.Set("k", &AiResponse{Content: "v"})
+	require.NoError(t, os.WriteFile(cache.path("k"), []byte(`{"key":"k","respon`), 0o644))
+
+	_, ok := cache.Get("k")
+	assert.False(t, ok)
+	_, err := os.Stat(cache.path("k"))
+	assert.True(t, os.IsNotExist(err), "corrupted entries are removed")
+
+	cache.Set("k", &AiResponse{Content: "fresh"})
+	got, ok := cache.Get("k")
+	require.True(t, ok)
+	assert.Equal(t, "fresh", got.Content)
+}
+
+func TestFileCache_ConcurrentWritersSharingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	caches := make([]*FileCache, 2)
+	for i := range caches {
+		cache, err := NewFileCache(dir, FileCacheOptions{MaxBytes: 4 << 10})
+		require.NoError(t, err)
+		caches[i] = cache
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			cache := caches[w%2]
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("k%d", i%10)
+				cache.Set(key, &AiResponse{Content: fmt.Sprintf("writer %d", w)})
+				cache.Get(key)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"response"`, "%s holds a complete entry", filepath.Base(path))
+	}
+}
+
+func TestCachingClient_ServesRepeatsFromCache(t *testing.T) {
+	cache, err := NewFileCache(t.TempDir(), FileCacheOptions{TTL: time.Hour})
+	require.NoError(t, err)
+	inner := NewMockClient("mock", "")
+	inner.QueueResponse("first answer")
+
+	client := NewCachingClient(inner, cache)
+	response, err := client.SendPromptWithMetadata(context.Background(), "question")
+	require.NoError(t, err)
+	assert.Equal(t, ServedViaPrimary, response.Metadata.ServedVia)
+
+	response, err = client.SendPromptWithMetadata(context.Background(), "question")
+	require.NoError(t, err)
+	assert.Equal(t, "first answer", response.Content)
+	assert.Equal(t, ServedViaCache, response.Metadata.ServedVia)
+	assert.Equal(t, 1, inner.CallCount())
+}
@@ -0,0 +1,65 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// finish_reason.go maps provider-specific finish/stop reasons onto a common set so
+// callers can branch on why generation ended without knowing the provider.
+package chatdelta
+
+import "strings"
+
+// FinishReason is a provider-independent reason for the end of generation.
+type FinishReason string
+
+const (
+	// FinishReasonStop means the model finished naturally or hit a stop sequence
+	FinishReasonStop FinishReason = "stop"
+	// FinishReasonLength means the output was cut off by the token limit
+	FinishReasonLength FinishReason = "length"
+	// FinishReasonToolCalls means the model stopped to call a tool
+	FinishReasonToolCalls FinishReason = "tool_calls"
+	// FinishReasonContentFilter means output was withheld by a safety or content filter
+	FinishReasonContentFilter FinishReason = "content_filter"
+	// FinishReasonOther is any reason not covered above
+	FinishReasonOther FinishReason = "other"
+)
+
+// finishReasons maps each provider's raw reason (lower-cased) to a FinishReason.
+var finishReasons = map[Provider]map[string]FinishReason{
+	ProviderOpenAI: {
+		"stop":           FinishReasonStop,
+		"length":         FinishReasonLength,
+		"tool_calls":     FinishReasonToolCalls,
+		"function_call":  FinishReasonToolCalls,
+		"content_filter": FinishReasonContentFilter,
+	},
+	ProviderClaude: {
+		"end_turn":      FinishReasonStop,
+		"stop_sequence": FinishReasonStop,
+		"max_tokens":    FinishReasonLength,
+		"tool_use":      FinishReasonToolCalls,
+		"refusal":       FinishReasonContentFilter,
+	},
+	ProviderGemini: {
+		"stop":               FinishReasonStop,
+		"max_tokens":         FinishReasonLength,
+		"safety":             FinishReasonContentFilter,
+		"recitation":         FinishReasonContentFilter,
+		"blocklist":          FinishReasonContentFilter,
+		"prohibited_content": FinishReasonContentFilter,
+		"spii":               FinishReasonContentFilter,
+	},
+	ProviderOllama: {
+		"stop":   FinishReasonStop,
+		"length": FinishReasonLength,
+	},
+}
+
+// NormalizeFinishReason converts a provider's raw finish reason into a FinishReason.
+// An empty raw reason yields "" and an unrecognized one yields FinishReasonOther.
+func NormalizeFinishReason(provider Provider, raw string) FinishReason {
+	if raw == "" {
+		return ""
+	}
+	if reason, ok := finishReasons[provider][strings.ToLower(raw)]; ok {
+		return reason
+	}
+	return FinishReasonOther
+}
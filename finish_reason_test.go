@@ -0,0 +1,99 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeFinishReason(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		raw      string
+		want     FinishReason
+	}{
+		{ProviderOpenAI, "stop", FinishReasonStop},
+		{ProviderOpenAI, "length", FinishReasonLength},
+		{ProviderOpenAI, "tool_calls", FinishReasonToolCalls},
+		{ProviderOpenAI, "content_filter", FinishReasonContentFilter},
+		{ProviderClaude, "end_turn", FinishReasonStop},
+		{ProviderClaude, "stop_sequence", FinishReasonStop},
+		{ProviderClaude, "max_tokens", FinishReasonLength},
+		{ProviderClaude, "tool_use", FinishReasonToolCalls},
+		{ProviderGemini, "STOP", FinishReasonStop},
+		{ProviderGemini, "MAX_TOKENS", FinishReasonLength},
+		{ProviderGemini, "SAFETY", FinishReasonContentFilter},
+		{ProviderGemini, "RECITATION", FinishReasonContentFilter},
+		{ProviderGemini, "OTHER", FinishReasonOther},
+		{ProviderClaude, "something_new", FinishReasonOther},
+		{ProviderOpenAI, "", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizeFinishReason(tt.provider, tt.raw), "%s/%s", tt.provider, tt.raw)
+	}
+}
+
+// streamTerminal drains a stream and returns its final chunk.
+func streamTerminal(t *testing.T, client AIClient) StreamChunk {
+	t.Helper()
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	var last StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	require.True(t, last.Finished)
+	require.NotNil(t, last.Metadata)
+	return last
+}
+
+func TestStreamFinishReason_OpenAI(t *testing.T) {
+	client, err := NewOpenAIClient("key", "", nil)
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`data: {"choices":[{"index":0,"delta":{"content":"cut"},"finish_reason":null}]}`+"\n\n"+
+			`data: {"choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`+"\n\n"+
+			"data: [DONE]\n\n")
+
+	last := streamTerminal(t, client)
+	assert.Equal(t, "length", last.Metadata.FinishReason)
+	assert.Equal(t, FinishReasonLength, last.Metadata.NormalizedFinishReason)
+}
+
+func TestStreamFinishReason_Claude(t *testing.T) {
+	client, err := NewClaudeClient("key", "", nil)
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"calling"}}`+"\n\n"+
+			`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":3}}`+"\n\n"+
+			`data: {"type":"message_stop"}`+"\n\n")
+
+	last := streamTerminal(t, client)
+	assert.Equal(t, "tool_use", last.Metadata.FinishReason)
+	assert.Equal(t, FinishReasonToolCalls, last.Metadata.NormalizedFinishReason)
+}
+
+func TestStreamFinishReason_Gemini(t *testing.T) {
+	client, err := NewGeminiClient("key", "", nil)
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`data: {"candidates":[{"content":{"parts":[{"text":"blocked"}],"role":"model"},"finishReason":"SAFETY"}]}`+"\r\n\r\n")
+
+	last := streamTerminal(t, client)
+	assert.Equal(t, "SAFETY", last.Metadata.FinishReason)
+	assert.Equal(t, FinishReasonContentFilter, last.Metadata.NormalizedFinishReason)
+}
+
+func TestFinishReason_NonStreamingMatchesStreaming(t *testing.T) {
+	client, err := NewClaudeClient("key", "", nil)
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`{"id":"msg_1","type":"message","content":[{"type":"text","text":"done"}],"stop_reason":"end_turn"}`)
+
+	resp, err := client.SendPromptWithMetadata(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, FinishReasonStop, resp.Metadata.NormalizedFinishReason)
+}
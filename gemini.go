@@ -1,6 +1,7 @@
 package chatdelta
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -8,19 +9,70 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
+// geminiBaseURL is the default Gemini API endpoint; ClientConfig.BaseURL overrides it.
+const geminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
 // GeminiClient implements the AIClient interface for Google's Gemini API
 type GeminiClient struct {
 	apiKey     string
 	model      string
 	config     *ClientConfig
 	httpClient *http.Client
+	// vertex, when set, sends requests to Vertex AI with OAuth instead of an API key
+	vertex *vertexTarget
 }
 
 // Gemini API request/response structures
 type geminiPart struct {
-	Text string `json:"text"`
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+}
+
+// geminiBlob is inline media; Data is base64-encoded on the wire.
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"`
+}
+
+// geminiFileData references media by URI.
+type geminiFileData struct {
+	MimeType string `json:"mimeType"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 
 type geminiContent struct {
@@ -47,9 +99,15 @@ type geminiUsageMetadata struct {
 }
 
 type geminiGenerationConfig struct {
-	Temperature *float64 `json:"temperature,omitempty"`
-	TopP        *float64 `json:"topP,omitempty"`
-	MaxTokens   *int     `json:"maxOutputTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+	TopK          *int     `json:"topK,omitempty"`
+	MaxTokens     *int     `json:"maxOutputTokens,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+	// ResponseMimeType and ResponseSchema request JSON output
+	ResponseMimeType string          `json:"responseMimeType,omitempty"`
+	ResponseSchema   json.RawMessage `json:"responseSchema,omitempty"`
 }
 
 type geminiSystemInstruction struct {
@@ -60,6 +118,8 @@ type geminiRequest struct {
 	Contents          []geminiContent          `json:"contents"`
 	GenerationConfig  *geminiGenerationConfig  `json:"generationConfig,omitempty"`
 	SystemInstruction *geminiSystemInstruction `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool             `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig        `json:"toolConfig,omitempty"`
 }
 
 type geminiResponse struct {
@@ -68,6 +128,12 @@ type geminiResponse struct {
 	PromptFeedback struct {
 		SafetyRatings []geminiSafetyRating `json:"safetyRatings,omitempty"`
 	} `json:"promptFeedback,omitempty"`
+	// ModelVersion is the model that served the request
+	ModelVersion string `json:"modelVersion,omitempty"`
+	// ResponseID identifies the response; Gemini sends no request ID header
+	ResponseID string `json:"responseId,omitempty"`
+	// info is filled in by sendRequest
+	info responseInfo
 }
 
 type geminiErrorDetail struct {
@@ -95,12 +161,10 @@ func NewGeminiClient(apiKey, model string, config *ClientConfig) (*GeminiClient,
 	}
 
 	return &GeminiClient{
-		apiKey: apiKey,
-		model:  model,
-		config: config,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		apiKey:     apiKey,
+		model:      model,
+		config:     config,
+		httpClient: newHTTPClient(config),
 	}, nil
 }
 
@@ -117,6 +181,17 @@ func (c *GeminiClient) SendPrompt(ctx context.Context, prompt string) (string, e
 
 // SendConversation sends a conversation to Gemini
 func (c *GeminiClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	if c.config.keepAliveViaStreaming() {
+		response, err := sendViaStream(ctx, c.config, conversation, c.streamRequest)
+		if err != nil {
+			return "", err
+		}
+		return response.Content, nil
+	}
+
 	var result string
 	var lastErr error
 
@@ -138,11 +213,16 @@ func (c *GeminiClient) SendConversation(ctx context.Context, conversation *Conve
 			return lastErr
 		}
 
-		result = candidate.Content.Parts[0].Text
-		return nil
+		text, _, err := geminiCandidateContent(candidate)
+		if err != nil {
+			lastErr = err
+			return err
+		}
+		result, err = postProcessContent(c.config, conversation, text, nil)
+		return err
 	}
 
-	err := ExecuteWithRetry(ctx, c.config.Retries, operation)
+	err := c.config.retry(ctx, operation)
 	if err != nil {
 		return "", err
 	}
@@ -150,50 +230,59 @@ func (c *GeminiClient) SendConversation(ctx context.Context, conversation *Conve
 	return result, nil
 }
 
-// StreamPrompt streams a response for a single prompt (not implemented for Gemini yet)
+// StreamPrompt streams a response for a single prompt
 func (c *GeminiClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
-	// Gemini doesn't support streaming in this implementation
-	// Fall back to non-streaming and emit the result as a single chunk
-	resultChan := make(chan StreamChunk, 1)
-
-	go func() {
-		defer close(resultChan)
-
-		result, err := c.SendPrompt(ctx, prompt)
-		if err != nil {
-			resultChan <- StreamChunk{Content: "", Finished: true}
-			return
-		}
-
-		resultChan <- StreamChunk{Content: result, Finished: true}
-	}()
+	conversation := NewConversation()
+	if c.config.SystemMessage != nil {
+		conversation.AddSystemMessage(*c.config.SystemMessage)
+	}
+	conversation.AddUserMessage(prompt)
 
-	return resultChan, nil
+	return c.StreamConversation(ctx, conversation)
 }
 
-// StreamConversation streams a response for a conversation (not implemented for Gemini yet)
+// StreamConversation streams a response for a conversation
 func (c *GeminiClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
-	// Gemini doesn't support streaming in this implementation
-	// Fall back to non-streaming and emit the result as a single chunk
-	resultChan := make(chan StreamChunk, 1)
+	resultChan := make(chan StreamChunk, 10)
 
 	go func() {
 		defer close(resultChan)
 
-		result, err := c.SendConversation(ctx, conversation)
-		if err != nil {
-			resultChan <- StreamChunk{Content: "", Finished: true}
-			return
+		ctx, cancel := c.config.withOperationTimeout(ctx)
+		defer cancel()
+
+		sink := newStreamSink(ctx, resultChan, c.config)
+		operation := func() error {
+			return c.streamRequest(ctx, conversation, sink)
 		}
 
-		resultChan <- StreamChunk{Content: result, Finished: true}
+		err := c.config.retry(ctx, operation)
+		if err != nil {
+			sink.fail(ctx, c.config, err)
+		}
 	}()
 
 	return resultChan, nil
 }
 
-// sendRequest sends a request to the Gemini API
-func (c *GeminiClient) sendRequest(ctx context.Context, conversation *Conversation) (*geminiResponse, error) {
+// geminiContentParts maps a multimodal message to text, inlineData, and fileData parts.
+func geminiContentParts(msg Message) []geminiPart {
+	var parts []geminiPart
+	for _, part := range messageParts(msg) {
+		switch {
+		case part.Type == ContentPartImage && part.Image != nil && part.Image.URL != "":
+			parts = append(parts, geminiPart{FileData: &geminiFileData{MimeType: part.Image.guessMIMEType(), FileURI: part.Image.URL}})
+		case part.Type == ContentPartImage && part.Image != nil:
+			parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: part.Image.MIMEType, Data: part.Image.Data}})
+		case part.Type == ContentPartText:
+			parts = append(parts, geminiPart{Text: part.Text})
+		}
+	}
+	return parts
+}
+
+// buildRequest converts a conversation and the client configuration into a Gemini request body
+func (c *GeminiClient) buildRequest(conversation *Conversation) geminiRequest {
 	// Convert messages to Gemini format
 	var contents []geminiContent
 	var systemInstruction *geminiSystemInstruction
@@ -204,18 +293,44 @@ func (c *GeminiClient) sendRequest(ctx context.Context, conversation *Conversati
 		systemMessages = append(systemMessages, *c.config.SystemMessage)
 	}
 
-	for _, msg := range conversation.Messages {
-		if msg.Role == "system" {
+	for i, msg := range conversation.Messages {
+		switch {
+		case msg.Role == "system":
 			systemMessages = append(systemMessages, msg.Content)
-		} else {
+		case msg.Role == "tool":
+			// Results are keyed by function name; results of parallel calls share one turn
+			part := geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     toolCallName(conversation.Messages, i, msg.ToolCallID),
+				Response: geminiFunctionResponsePayload(msg.Content),
+			}}
+			if n := len(contents); n > 0 && contents[n-1].Role == "user" &&
+				len(contents[n-1].Parts) > 0 && contents[n-1].Parts[0].FunctionResponse != nil {
+				contents[n-1].Parts = append(contents[n-1].Parts, part)
+				continue
+			}
+			contents = append(contents, geminiContent{Parts: []geminiPart{part}, Role: "user"})
+		case len(msg.ToolCalls) > 0:
+			var parts []geminiPart
+			if msg.Content != "" {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: call.Name, Args: call.Arguments}})
+			}
+			contents = append(contents, geminiContent{Parts: parts, Role: "model"})
+		default:
 			// Map roles: "user" -> "user", "assistant" -> "model"
 			role := msg.Role
 			if role == "assistant" {
 				role = "model"
 			}
 
+			parts := []geminiPart{{Text: msg.Content}}
+			if len(msg.Parts) > 0 {
+				parts = geminiContentParts(msg)
+			}
 			contents = append(contents, geminiContent{
-				Parts: []geminiPart{{Text: msg.Content}},
+				Parts: parts,
 				Role:  role,
 			})
 		}
@@ -230,11 +345,18 @@ func (c *GeminiClient) sendRequest(ctx context.Context, conversation *Conversati
 
 	// Build generation config
 	var genConfig *geminiGenerationConfig
-	if c.config.Temperature != nil || c.config.TopP != nil || c.config.MaxTokens != nil {
+	if c.config.Temperature != nil || c.config.TopP != nil || c.config.TopK != nil || c.config.MaxTokens != nil || c.config.Seed != nil || len(c.config.StopSequences) > 0 || c.config.ResponseFormat != nil {
 		genConfig = &geminiGenerationConfig{
-			Temperature: c.config.Temperature,
-			TopP:        c.config.TopP,
-			MaxTokens:   c.config.MaxTokens,
+			Temperature:   c.config.Temperature,
+			TopP:          c.config.TopP,
+			TopK:          c.config.TopK,
+			MaxTokens:     c.config.MaxTokens,
+			Seed:          c.config.Seed,
+			StopSequences: c.config.StopSequences,
+		}
+		if format := c.config.ResponseFormat; format != nil {
+			genConfig.ResponseMimeType = "application/json"
+			genConfig.ResponseSchema = format.schema()
 		}
 	}
 
@@ -243,22 +365,209 @@ func (c *GeminiClient) sendRequest(ctx context.Context, conversation *Conversati
 		GenerationConfig:  genConfig,
 		SystemInstruction: systemInstruction,
 	}
+	if len(c.config.Tools) > 0 {
+		request.Tools, request.ToolConfig = c.buildTools()
+	}
+	return request
+}
+
+// buildTools converts the configured tools and tool choice into Gemini's schema.
+func (c *GeminiClient) buildTools() ([]geminiTool, *geminiToolConfig) {
+	declarations := make([]geminiFunctionDeclaration, len(c.config.Tools))
+	for i, tool := range c.config.Tools {
+		declarations[i] = geminiFunctionDeclaration{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters}
+	}
+
+	var mode geminiFunctionCallingConfig
+	switch name, forced := c.config.ToolChoice.function(); {
+	case forced:
+		mode = geminiFunctionCallingConfig{Mode: "ANY", AllowedFunctionNames: []string{name}}
+	case c.config.ToolChoice == ToolChoiceRequired:
+		mode.Mode = "ANY"
+	case c.config.ToolChoice != "":
+		mode.Mode = strings.ToUpper(string(c.config.ToolChoice))
+	default:
+		return []geminiTool{{FunctionDeclarations: declarations}}, nil
+	}
+	return []geminiTool{{FunctionDeclarations: declarations}}, &geminiToolConfig{FunctionCallingConfig: mode}
+}
+
+// geminiFunctionResponsePayload wraps a tool result in the JSON object Gemini
+// requires. Results that already are JSON objects are sent as-is.
+func geminiFunctionResponsePayload(content string) json.RawMessage {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &object); err == nil {
+		return json.RawMessage(content)
+	}
+	wrapped, _ := json.Marshal(map[string]string{"content": content})
+	return wrapped
+}
 
-	jsonData, err := json.Marshal(request)
+// geminiCandidateContent joins the text parts of candidate and collects its function
+// calls. Gemini assigns no call IDs, so they are generated from the part order.
+func geminiCandidateContent(candidate geminiCandidate) (string, []ToolCall, error) {
+	var text strings.Builder
+	var calls []ToolCall
+	for _, part := range candidate.Content.Parts {
+		if part.FunctionCall == nil {
+			text.WriteString(part.Text)
+			continue
+		}
+		arguments, err := toolArguments(part.FunctionCall.Args)
+		if err != nil {
+			return "", nil, err
+		}
+		calls = append(calls, ToolCall{
+			ID:        fmt.Sprintf("call_%d", len(calls)),
+			Name:      part.FunctionCall.Name,
+			Arguments: arguments,
+		})
+	}
+	return text.String(), calls, nil
+}
+
+// newHTTPRequest builds the HTTP request for conversation, applying any configured
+// request mutators to the body. The marshaled body is returned alongside the request.
+func (c *GeminiClient) newHTTPRequest(ctx context.Context, conversation *Conversation, stream bool) (*http.Request, []byte, error) {
+	conversation, err := prepareConversation(c.config, conversation)
 	if err != nil {
-		return nil, NewJSONParseError(err)
+		return nil, nil, err
+	}
+	if err := ValidateImages(ProviderGemini, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateVision(ProviderGemini, c.model, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateMaxTokens(ProviderGemini, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateStopSequences(ProviderGemini, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateTools(c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateResponseFormat(c.config); err != nil {
+		return nil, nil, err
 	}
 
-	// Build URL with API key
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", c.model, c.apiKey)
+	body, err := marshalRequestBody(c.config, c.provider(), c.model, c.buildRequest(conversation))
+	if err != nil {
+		return nil, nil, err
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	req, err := c.newRawHTTPRequest(ctx, body, stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, body, nil
+}
+
+// geminiMethod returns the model method for a request: generateContent, or
+// streamGenerateContent with server-sent events when stream is set.
+func geminiMethod(stream bool) string {
+	if stream {
+		return "streamGenerateContent?alt=sse"
+	}
+	return "generateContent"
+}
+
+// newRawHTTPRequest builds the generateContent (or streamGenerateContent) request for
+// an already-marshaled body.
+func (c *GeminiClient) newRawHTTPRequest(ctx context.Context, body []byte, stream bool) (*http.Request, error) {
+	// Build URL with API key; Vertex requests are authorized when sent
+	path := fmt.Sprintf("/models/%s:%s", c.model, geminiMethod(stream))
+	if stream {
+		path += "&key=" + c.apiKey
+	} else {
+		path += "?key=" + c.apiKey
+	}
+	url := endpointURL(c.config, geminiBaseURL, path)
+	if c.vertex != nil {
+		url = endpointURL(c.config, c.vertex.baseURL(), c.vertex.modelPath(c.model, stream))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, NewConnectionError(err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	applyExtraHeaders(req, c.config)
+
+	return req, nil
+}
 
+// authorize adds the Vertex access token to req; API-key requests carry the key in
+// the URL already.
+func (c *GeminiClient) authorize(ctx context.Context, req *http.Request) error {
+	if c.vertex == nil {
+		return nil
+	}
+	return c.vertex.authorize(ctx, req)
+}
+
+// provider identifies the API for request mutators.
+func (c *GeminiClient) provider() Provider {
+	if c.vertex != nil {
+		return ProviderVertex
+	}
+	return ProviderGemini
+}
+
+// SendRaw posts body verbatim to the Gemini chat endpoint using the client's
+// credentials, extra headers, and retry policy, and returns the raw response body.
+// Request mutators are not applied. The model is taken from the URL, so a "model" field is only checked, never used.
+func (c *GeminiClient) SendRaw(ctx context.Context, body json.RawMessage) (json.RawMessage, error) {
+	if err := checkRawBody(c.config, c.model, body); err != nil {
+		return nil, err
+	}
+	return sendRaw(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		req, err := c.newRawHTTPRequest(ctx, body, false)
+		if err != nil {
+			return nil, err
+		}
+		return req, c.authorize(ctx, req)
+	}, c.errorFromBody)
+}
+
+// StreamEvents streams conversation and delivers each streamGenerateContent response
+// exactly as Gemini sends it.
+func (c *GeminiClient) StreamEvents(ctx context.Context, conversation *Conversation) (<-chan StreamEvent, error) {
+	return streamEvents(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		req, _, err := c.newHTTPRequest(ctx, conversation, true)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.authorize(ctx, req); err != nil {
+			return nil, err
+		}
+		return req, nil
+	}, c.errorFromBody), nil
+}
+
+// DryRun returns the request that would be sent for conversation without sending it.
+// Credentials are redacted.
+func (c *GeminiClient) DryRun(conversation *Conversation, stream bool) (*PreparedRequest, error) {
+	req, body, err := c.newHTTPRequest(context.Background(), conversation, stream)
+	if err != nil {
+		return nil, err
+	}
+	return newPreparedRequest(req, body), nil
+}
+
+// sendRequest sends a request to the Gemini API
+func (c *GeminiClient) sendRequest(ctx context.Context, conversation *Conversation) (*geminiResponse, error) {
+	req, _, err := c.newHTTPRequest(ctx, conversation, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.authorize(ctx, req); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
@@ -274,35 +583,155 @@ func (c *GeminiClient) sendRequest(ctx context.Context, conversation *Conversati
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errorResp geminiErrorResponse
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, c.parseAPIError(resp.StatusCode, &errorResp.Error)
-		}
-		return nil, NewServerError(resp.StatusCode, string(body))
+		return nil, c.errorFromBody(resp.StatusCode, resp.Header, body)
 	}
 
 	var response geminiResponse
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, NewJSONParseError(err)
 	}
+	response.info = responseInfo{requestID: requestIDFromHeader(resp.Header, response.ResponseID), latency: time.Since(start), body: body}
 
 	return &response, nil
 }
 
+// metadata builds the response metadata from response, or from the last event of a
+// stream, with the finish reason reported by the candidate.
+func (c *GeminiClient) metadata(response *geminiResponse, finishReason string, calledTools bool) ResponseMetadata {
+	meta := ResponseMetadata{
+		ModelUsed:              c.model,
+		FinishReason:           finishReason,
+		NormalizedFinishReason: NormalizeFinishReason(ProviderGemini, finishReason),
+		RequestID:              response.info.requestID,
+		LatencyMs:              response.info.latency.Milliseconds(),
+		ServedVia:              ServedViaPrimary,
+	}
+	if response.ModelVersion != "" {
+		meta.ModelUsed = response.ModelVersion
+	}
+	// Gemini reports STOP when it calls a function
+	if calledTools && meta.NormalizedFinishReason == FinishReasonStop {
+		meta.NormalizedFinishReason = FinishReasonToolCalls
+	}
+	if response.UsageMetadata != nil {
+		meta.PromptTokens = response.UsageMetadata.PromptTokenCount
+		meta.CompletionTokens = response.UsageMetadata.CandidatesTokenCount
+		meta.TotalTokens = response.UsageMetadata.TotalTokenCount
+	}
+	return meta
+}
+
+// streamRequest handles streaming requests. Each server-sent event is a partial
+// generateContent response; the finish reason and usageMetadata arrive with the last
+// one, which becomes the Finished chunk's metadata. Function calls are only reported
+// by the non-streaming path.
+func (c *GeminiClient) streamRequest(ctx context.Context, conversation *Conversation, sink *streamSink) error {
+	req, _, err := c.newHTTPRequest(ctx, conversation, true)
+	if err != nil {
+		return err
+	}
+	if err := c.authorize(ctx, req); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return NewTimeoutError(c.config.Timeout)
+		}
+		return NewConnectionError(err)
+	}
+	defer resp.Body.Close()
+	defer closeOnDone(ctx, resp.Body)()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return c.errorFromBody(resp.StatusCode, resp.Header, body)
+	}
+
+	var last geminiResponse
+	var finishReason string
+	received := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var response geminiResponse
+		if err := json.Unmarshal([]byte(data), &response); err != nil {
+			continue // Skip malformed chunks
+		}
+		sink.event(data)
+		received = true
+
+		if response.ModelVersion != "" {
+			last.ModelVersion = response.ModelVersion
+		}
+		if response.ResponseID != "" {
+			last.ResponseID = response.ResponseID
+		}
+		if response.UsageMetadata != nil {
+			last.UsageMetadata = response.UsageMetadata
+		}
+		if len(response.Candidates) > 0 {
+			candidate := response.Candidates[0]
+			for _, part := range candidate.Content.Parts {
+				if part.Text != "" {
+					sink.send(StreamChunk{Content: part.Text, Finished: false})
+				}
+			}
+			if candidate.FinishReason != "" {
+				finishReason = candidate.FinishReason
+			}
+		}
+	}
+
+	if err := streamReadError(ctx, scanner.Err()); err != nil {
+		return err
+	}
+	if !received {
+		return NewMissingFieldError("candidates")
+	}
+
+	last.info = responseInfo{requestID: requestIDFromHeader(resp.Header, last.ResponseID), latency: time.Since(start)}
+	metadata := c.metadata(&last, finishReason, false)
+	sink.send(StreamChunk{Content: "", Finished: true, Metadata: &metadata})
+	return nil
+}
+
+// errorFromBody maps a non-200 response body onto a ClientError. Bodies that are not
+// Gemini's JSON error envelope are used verbatim as the message.
+func (c *GeminiClient) errorFromBody(statusCode int, header http.Header, body []byte) *ClientError {
+	var errorResp geminiErrorResponse
+	if err := json.Unmarshal(body, &errorResp); err == nil {
+		return c.parseAPIError(statusCode, header, &errorResp.Error)
+	}
+	return c.parseAPIError(statusCode, header, &geminiErrorDetail{Message: string(body)})
+}
+
 // parseAPIError parses Gemini API errors
-func (c *GeminiClient) parseAPIError(statusCode int, error *geminiErrorDetail) *ClientError {
+func (c *GeminiClient) parseAPIError(statusCode int, header http.Header, error *geminiErrorDetail) *ClientError {
 	switch statusCode {
 	case http.StatusUnauthorized:
 		return NewInvalidAPIKeyError()
 	case http.StatusTooManyRequests:
-		return NewRateLimitError(nil)
+		return NewRateLimitError(parseRetryAfter(header, c.config.clock().Now()))
 	case http.StatusBadRequest:
+		if isContextOverflowMessage(error.Message) {
+			return NewContextLengthError(error.Message)
+		}
 		if strings.Contains(strings.ToLower(error.Message), "model") {
 			return NewInvalidModelError(c.model)
 		}
 		return NewBadRequestError(error.Message)
 	case http.StatusForbidden:
-		return NewPermissionDeniedError("Gemini API")
+		return NewPermissionDeniedError(c.providerName() + " API")
 	default:
 		return NewServerError(statusCode, error.Message)
 	}
@@ -320,6 +749,13 @@ func (c *GeminiClient) SendPromptWithMetadata(ctx context.Context, prompt string
 
 // SendConversationWithMetadata sends a conversation and returns the response with metadata.
 func (c *GeminiClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	if c.config.keepAliveViaStreaming() {
+		return sendViaStream(ctx, c.config, conversation, c.streamRequest)
+	}
+
 	var result *AiResponse
 	var lastErr error
 
@@ -338,32 +774,37 @@ func (c *GeminiClient) SendConversationWithMetadata(ctx context.Context, convers
 			lastErr = NewMissingFieldError("parts")
 			return lastErr
 		}
-		meta := ResponseMetadata{
-			ModelUsed:    c.model,
-			FinishReason: candidate.FinishReason,
-		}
-		if response.UsageMetadata != nil {
-			meta.PromptTokens = response.UsageMetadata.PromptTokenCount
-			meta.CompletionTokens = response.UsageMetadata.CandidatesTokenCount
-			meta.TotalTokens = response.UsageMetadata.TotalTokenCount
+		text, toolCalls, err := geminiCandidateContent(candidate)
+		if err != nil {
+			lastErr = err
+			return err
 		}
+		meta := c.metadata(response, candidate.FinishReason, len(toolCalls) > 0)
 		result = &AiResponse{
-			Content:  candidate.Content.Parts[0].Text,
-			Metadata: meta,
+			Content:   text,
+			Metadata:  meta,
+			ToolCalls: toolCalls,
+			Raw:       response.info.raw(c.config),
 		}
-		return nil
+		result.Content, err = postProcessContent(c.config, conversation, result.Content, &result.Metadata)
+		return err
 	}
 
-	if err := ExecuteWithRetry(ctx, c.config.Retries, operation); err != nil {
+	if err := c.config.retry(ctx, operation); err != nil {
 		return nil, err
 	}
 	_ = lastErr
 	return result, nil
 }
 
-// SupportsStreaming returns false (Gemini streaming not implemented yet)
+// SupportsStreaming returns true (Gemini supports streaming)
 func (c *GeminiClient) SupportsStreaming() bool {
-	return false
+	return true
+}
+
+// SupportsSeed returns true (Gemini honours generationConfig.seed)
+func (c *GeminiClient) SupportsSeed() bool {
+	return true
 }
 
 // SupportsConversations returns true (Gemini supports conversations)
@@ -373,6 +814,14 @@ func (c *GeminiClient) SupportsConversations() bool {
 
 // Name returns the client name
 func (c *GeminiClient) Name() string {
+	return c.config.nameOr(c.providerName())
+}
+
+// providerName names the API the client talks to.
+func (c *GeminiClient) providerName() string {
+	if c.vertex != nil {
+		return "Vertex AI"
+	}
 	return "Gemini"
 }
 
@@ -380,3 +829,76 @@ func (c *GeminiClient) Name() string {
 func (c *GeminiClient) Model() string {
 	return c.model
 }
+
+// clientConfig returns the client's configuration.
+func (c *GeminiClient) clientConfig() *ClientConfig {
+	return c.config
+}
+
+// WithOptions returns a copy of the client with opts applied over its config.
+func (c *GeminiClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	config, err := applyRequestOptions(c.config, opts)
+	if err != nil {
+		return nil, err
+	}
+	clone := *c
+	clone.config = config
+	return &clone, nil
+}
+
+// ModelInfo returns registry data for the configured model.
+func (c *GeminiClient) ModelInfo() (ModelInfo, bool) {
+	return LookupModelInfo(ProviderGemini, c.model)
+}
+
+// EstimatePromptTokens estimates the prompt size of conversation with the token
+// estimator registered for the configured model.
+func (c *GeminiClient) EstimatePromptTokens(conversation *Conversation) int {
+	return estimateConversationTokens(conversation.Messages, TokenEstimatorForModel(c.model))
+}
+
+// geminiModelList is the GET /models response.
+type geminiModelList struct {
+	Models []struct {
+		Name             string `json:"name"`
+		DisplayName      string `json:"displayName"`
+		InputTokenLimit  int    `json:"inputTokenLimit"`
+		OutputTokenLimit int    `json:"outputTokenLimit"`
+	} `json:"models"`
+}
+
+// ListModels returns the models visible to the API key. Gemini reports each model's
+// input limit, used as ContextWindow, and output limit.
+func (c *GeminiClient) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	if c.vertex != nil {
+		return nil, NewInvalidParameterError("provider", "Vertex AI does not support ListModels")
+	}
+	body, err := sendRaw(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		url := endpointURL(c.config, geminiBaseURL, fmt.Sprintf("/models?pageSize=1000&key=%s", c.apiKey))
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, NewConnectionError(err)
+		}
+		applyExtraHeaders(req, c.config)
+		return req, nil
+	}, c.errorFromBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var list geminiModelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, NewJSONParseError(err)
+	}
+	models := make([]ModelInfo, 0, len(list.Models))
+	for _, m := range list.Models {
+		models = append(models, ModelInfo{
+			Provider:        ProviderGemini,
+			Model:           strings.TrimPrefix(m.Name, "models/"),
+			DisplayName:     m.DisplayName,
+			ContextWindow:   m.InputTokenLimit,
+			MaxOutputTokens: m.OutputTokenLimit,
+		})
+	}
+	return models, nil
+}
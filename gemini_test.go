@@ -0,0 +1,139 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiClient_BuildRequestSeed(t *testing.T) {
+	client, err := NewGeminiClient("test-key", "", NewClientConfig().SetSeed(42))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi")))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"generationConfig":{"seed":42}`)
+}
+
+func TestGeminiClient_BuildRequestTopK(t *testing.T) {
+	client, err := NewGeminiClient("test-key", "", NewClientConfig().SetTopK(40))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi")))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"generationConfig":{"topK":40}`)
+}
+
+func TestGeminiClient_BuildRequestToolResultAfterEmptyParts(t *testing.T) {
+	client, err := NewGeminiClient("test-key", "", nil)
+	require.NoError(t, err)
+
+	conv := NewConversation()
+	conv.Messages = append(conv.Messages,
+		Message{Role: "user", Parts: []ContentPart{{Type: ContentPartImage}}},
+		Message{Role: "tool", ToolCallID: "call_0", Content: "sunny"},
+	)
+	request := client.buildRequest(conv)
+	require.Len(t, request.Contents, 2, "the tool result is not merged into a turn without parts")
+	assert.NotNil(t, request.Contents[1].Parts[0].FunctionResponse)
+}
+
+func TestGeminiClient_MetadataFields(t *testing.T) {
+	server := metadataServer(t, nil, `{
+		"candidates": [{"content": {"role": "model", "parts": [{"text": "Hi"}]}, "finishReason": "MAX_TOKENS", "index": 0}],
+		"usageMetadata": {"promptTokenCount": 4, "candidatesTokenCount": 2, "totalTokenCount": 6},
+		"modelVersion": "gemini-1.5-flash-002",
+		"responseId": "resp-42"
+	}`)
+	client, err := NewGeminiClient("test-key", "gemini-1.5-flash", NewClientConfig().SetBaseURL(server.URL).SetRetries(0))
+	require.NoError(t, err)
+
+	resp, err := client.SendPromptWithMetadata(context.Background(), "hi")
+	require.NoError(t, err)
+	meta := resp.Metadata
+	assert.Equal(t, "gemini-1.5-flash-002", meta.ModelUsed)
+	assert.Equal(t, 4, meta.PromptTokens)
+	assert.Equal(t, 2, meta.CompletionTokens)
+	assert.Equal(t, 6, meta.TotalTokens)
+	assert.Equal(t, "MAX_TOKENS", meta.FinishReason)
+	assert.Equal(t, FinishReasonLength, meta.NormalizedFinishReason)
+	assert.Equal(t, "resp-42", meta.RequestID)
+	assert.GreaterOrEqual(t, meta.LatencyMs, int64(5))
+}
+
+func TestGeminiClient_Stream(t *testing.T) {
+	server := transcriptServer(t, "gemini_stream.sse")
+	client, err := NewGeminiClient("test-key", "gemini-1.5-flash", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+	assert.True(t, client.SupportsStreaming())
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	var chunks []string
+	var last StreamChunk
+	for chunk := range ch {
+		chunks = append(chunks, chunk.Content)
+		last = chunk
+	}
+
+	assert.Equal(t, []string{"Hello", " there", "!", ""}, chunks)
+	require.True(t, last.Finished)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, "gemini-1.5-flash-002", last.Metadata.ModelUsed)
+	assert.Equal(t, 4, last.Metadata.PromptTokens)
+	assert.Equal(t, 3, last.Metadata.CompletionTokens)
+	assert.Equal(t, 7, last.Metadata.TotalTokens)
+	assert.Equal(t, FinishReasonStop, last.Metadata.NormalizedFinishReason)
+	assert.Equal(t, "resp-42", last.Metadata.RequestID)
+}
+
+func TestGeminiClient_StreamMetadataMatchesSend(t *testing.T) {
+	streamServer := transcriptServer(t, "gemini_stream.sse")
+	sendServer := metadataServer(t, nil, `{
+		"candidates": [{"content": {"role": "model", "parts": [{"text": "Hello there!"}]}, "finishReason": "STOP", "index": 0}],
+		"usageMetadata": {"promptTokenCount": 4, "candidatesTokenCount": 3, "totalTokenCount": 7},
+		"modelVersion": "gemini-1.5-flash-002",
+		"responseId": "resp-42"
+	}`)
+	streamClient, err := NewGeminiClient("test-key", "gemini-1.5-flash", NewClientConfig().SetBaseURL(streamServer.URL))
+	require.NoError(t, err)
+	sendClient, err := NewGeminiClient("test-key", "gemini-1.5-flash", NewClientConfig().SetBaseURL(sendServer.URL))
+	require.NoError(t, err)
+
+	ch, err := streamClient.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	resp, err := sendClient.SendPromptWithMetadata(context.Background(), "hi")
+	require.NoError(t, err)
+
+	assert.Equal(t, resp.Content, content)
+	require.NotNil(t, last.Metadata, "the terminal chunk carries the response metadata")
+	streamed, sent := *last.Metadata, resp.Metadata
+	assert.GreaterOrEqual(t, streamed.LatencyMs, int64(0))
+	streamed.LatencyMs, sent.LatencyMs = 0, 0
+	assert.Equal(t, sent, streamed)
+	assert.Equal(t, ServedViaPrimary, streamed.ServedVia)
+}
+
+func TestGeminiClient_StreamURL(t *testing.T) {
+	client, err := NewGeminiClient("test-key", "gemini-1.5-flash", nil)
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(promptConversation("hi"), true)
+	require.NoError(t, err)
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:streamGenerateContent?alt=sse&key=REDACTED", prepared.URL)
+}
+
+func TestGeminiClient_KeepAliveViaStreaming(t *testing.T) {
+	server := transcriptServer(t, "gemini_stream.sse")
+	client, err := NewGeminiClient("test-key", "gemini-1.5-flash", NewClientConfig().SetBaseURL(server.URL).SetKeepAliveViaStreaming(true))
+	require.NoError(t, err)
+
+	resp, err := client.SendPromptWithMetadata(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there!", resp.Content)
+	assert.Equal(t, 7, resp.Metadata.TotalTokens)
+}
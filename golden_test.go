@@ -0,0 +1,30 @@
+package chatdelta_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chatdelta/chatdelta-go/internal/golden"
+)
+
+// TestGoldenRequests compares every provider × feature prepared request against its
+// checked-in fixture. After an intentional change, regenerate with
+// "go run ./cmd/goldengen" and review the fixture diff.
+func TestGoldenRequests(t *testing.T) {
+	for _, c := range golden.Cases() {
+		c := c
+		t.Run(c.Name(), func(t *testing.T) {
+			got, err := golden.Render(c)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			want, err := os.ReadFile(c.Path())
+			if err != nil {
+				t.Fatalf("missing fixture %s (run go run ./cmd/goldengen): %v", c.Path(), err)
+			}
+			if diff := golden.Diff(string(want), string(got)); diff != "" {
+				t.Errorf("request for %s differs from %s (-want +got):\n%s", c.Name(), c.Path(), diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// google_auth.go implements the subset of Google Application Default Credentials the
+// Vertex AI client needs, on the standard library: service account keys (signed JWT
+// grant) and gcloud user credentials (refresh token grant). Access tokens are cached
+// until shortly before they expire.
+package chatdelta
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// googleCloudPlatformScope is the OAuth scope used for Vertex AI.
+const googleCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// googleTokenURL is the default OAuth2 token endpoint.
+const googleTokenURL = "https://oauth2.googleapis.com/token"
+
+// TokenSource supplies OAuth2 access tokens. Implementations must be safe for
+// concurrent use; an adapter around golang.org/x/oauth2.TokenSource is a one-liner.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticTokenSource returns a TokenSource that always returns token, e.g. the output
+// of "gcloud auth print-access-token".
+func StaticTokenSource(token string) TokenSource {
+	return staticTokenSource(token)
+}
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(context.Context) (string, error) { return string(s), nil }
+
+// googleCredentialsFile is the JSON credentials file used by ADC.
+type googleCredentialsFile struct {
+	Type string `json:"type"`
+	// service_account fields
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+	// authorized_user fields
+	ClientID       string `json:"client_id"`
+	ClientSecret   string `json:"client_secret"`
+	RefreshToken   string `json:"refresh_token"`
+	QuotaProjectID string `json:"quota_project_id"`
+}
+
+// googleADCPath returns the credentials file used by Application Default Credentials:
+// GOOGLE_APPLICATION_CREDENTIALS, or the file written by
+// "gcloud auth application-default login".
+func googleADCPath() string {
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", "application_default_credentials.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+// defaultGoogleTokenSource loads Application Default Credentials and returns a caching
+// TokenSource for them along with the project they name, if any. The GCE metadata
+// server is not supported.
+func defaultGoogleTokenSource(httpClient *http.Client, clock Clock) (TokenSource, string, error) {
+	path := googleADCPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", NewMissingConfigError("Google credentials (set GOOGLE_APPLICATION_CREDENTIALS)")
+	}
+	return googleTokenSourceFromJSON(data, httpClient, clock)
+}
+
+// googleTokenSourceFromJSON builds a caching TokenSource from a credentials file.
+func googleTokenSourceFromJSON(data []byte, httpClient *http.Client, clock Clock) (TokenSource, string, error) {
+	var file googleCredentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, "", NewJSONParseError(err)
+	}
+
+	source := &googleTokenSource{httpClient: httpClient, clock: clock, tokenURL: googleTokenURL}
+	switch file.Type {
+	case "service_account":
+		key, err := parseRSAPrivateKey(file.PrivateKey)
+		if err != nil {
+			return nil, "", NewMissingConfigError("valid service account private_key: " + err.Error())
+		}
+		if file.TokenURI != "" {
+			source.tokenURL = file.TokenURI
+		}
+		source.form = func(now time.Time) (url.Values, error) {
+			assertion, err := signGoogleJWT(key, file.PrivateKeyID, file.ClientEmail, source.tokenURL, now)
+			if err != nil {
+				return nil, err
+			}
+			return url.Values{
+				"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+				"assertion":  {assertion},
+			}, nil
+		}
+		return source, file.ProjectID, nil
+	case "authorized_user":
+		source.form = func(time.Time) (url.Values, error) {
+			return url.Values{
+				"grant_type":    {"refresh_token"},
+				"client_id":     {file.ClientID},
+				"client_secret": {file.ClientSecret},
+				"refresh_token": {file.RefreshToken},
+			}, nil
+		}
+		return source, file.QuotaProjectID, nil
+	default:
+		return nil, "", NewMissingConfigError(fmt.Sprintf("supported Google credentials type (got %q)", file.Type))
+	}
+}
+
+// googleTokenSource exchanges a grant for access tokens and caches them.
+type googleTokenSource struct {
+	httpClient *http.Client
+	clock      Clock
+	tokenURL   string
+	// form returns the token request parameters for the grant
+	form func(now time.Time) (url.Values, error)
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// googleTokenRefreshMargin is how long before expiry a cached token is replaced.
+const googleTokenRefreshMargin = time.Minute
+
+// Token returns the cached access token, fetching a new one when it is missing or
+// about to expire.
+func (s *googleTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if s.token != "" && now.Add(googleTokenRefreshMargin).Before(s.expires) {
+		return s.token, nil
+	}
+
+	form, err := s.form(now)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", NewConnectionError(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", NewConnectionError(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", NewConnectionError(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode >= 500 {
+			return "", NewServerError(resp.StatusCode, string(body))
+		}
+		return "", NewInvalidAPIKeyError()
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", NewJSONParseError(err)
+	}
+	if token.AccessToken == "" {
+		return "", NewMissingFieldError("access_token")
+	}
+
+	s.token = token.AccessToken
+	s.expires = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// parseRSAPrivateKey decodes a PEM-encoded PKCS#8 or PKCS#1 RSA key.
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not RSA")
+	}
+	return key, nil
+}
+
+// signGoogleJWT returns an RS256-signed assertion requesting the cloud-platform scope
+// for an hour.
+func signGoogleJWT(key *rsa.PrivateKey, keyID, email, audience string, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", NewJSONParseError(err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   email,
+		"scope": googleCloudPlatformScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", NewJSONParseError(err)
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", NewMissingConfigError("usable service account key: " + err.Error())
+	}
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
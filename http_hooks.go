@@ -0,0 +1,177 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// http_hooks.go implements the OnRequest and OnResponse hooks, which observe every
+// HTTP exchange a provider client makes. The hooks are installed as a wrapping
+// http.RoundTripper only when one is configured, so clients without hooks use the
+// plain transport.
+package chatdelta
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPRequestEvent describes an HTTP request about to be sent to a provider.
+// Credential headers and the "key" query parameter are redacted.
+type HTTPRequestEvent struct {
+	// TraceID identifies the call; see WithTrace
+	TraceID string
+	// Method is the HTTP method, e.g. "POST"
+	Method string
+	// URL is the target endpoint
+	URL string
+	// Header holds the request headers
+	Header http.Header
+	// Body is the request payload, after ClientConfig.HTTPBodyRedactor
+	Body []byte
+}
+
+// HTTPResponseEvent describes a provider's response to an HTTP request. For
+// streaming responses it is delivered once the stream has been read to the end
+// or closed, and Body holds everything that was read.
+type HTTPResponseEvent struct {
+	// TraceID identifies the call; see WithTrace
+	TraceID string
+	// Method is the HTTP method of the request
+	Method string
+	// URL is the request endpoint, redacted as in HTTPRequestEvent
+	URL string
+	// StatusCode is the HTTP status, or 0 when Err is set
+	StatusCode int
+	// Header holds the response headers
+	Header http.Header
+	// Body is the response payload, after ClientConfig.HTTPBodyRedactor
+	Body []byte
+	// Latency is the time from sending the request to receiving the response headers
+	Latency time.Duration
+	// Err is the transport error, if the request failed before a response arrived
+	Err error
+}
+
+// hasHTTPHooks reports whether the config observes HTTP exchanges.
+func (c *ClientConfig) hasHTTPHooks() bool {
+	return c.OnRequest != nil || c.OnResponse != nil
+}
+
+// newHTTPClient returns the http.Client a provider client sends requests with,
+// wrapping its transport with the config's HTTP hooks when any are set.
+func newHTTPClient(config *ClientConfig) *http.Client {
+	client := &http.Client{Timeout: config.Timeout}
+	if config.hasHTTPHooks() {
+		client.Transport = &hookTransport{config: config}
+	}
+	return client
+}
+
+// hookTransport reports each round trip to the config's OnRequest and OnResponse hooks.
+type hookTransport struct {
+	config *ClientConfig
+	// base performs the round trip; nil means http.DefaultTransport
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *hookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	traceID := TraceID(req.Context())
+	url := redactURL(req)
+
+	if t.config.OnRequest != nil {
+		body, err := requestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		t.config.OnRequest(HTTPRequestEvent{
+			TraceID: traceID,
+			Method:  req.Method,
+			URL:     url,
+			Header:  redactHeader(req.Header),
+			Body:    t.redactBody(body),
+		})
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if t.config.OnResponse == nil {
+		return resp, err
+	}
+
+	event := HTTPResponseEvent{TraceID: traceID, Method: req.Method, URL: url, Latency: time.Since(start)}
+	if err != nil {
+		event.Err = err
+		t.config.OnResponse(event)
+		return resp, err
+	}
+	event.StatusCode = resp.StatusCode
+	event.Header = resp.Header.Clone()
+	resp.Body = &hookedBody{body: resp.Body, done: func(body []byte) {
+		event.Body = t.redactBody(body)
+		t.config.OnResponse(event)
+	}}
+	return resp, nil
+}
+
+// redactBody applies the config's HTTPBodyRedactor to body.
+func (t *hookTransport) redactBody(body []byte) []byte {
+	if t.config.HTTPBodyRedactor == nil || len(body) == 0 {
+		return body
+	}
+	return t.config.HTTPBodyRedactor(body)
+}
+
+// requestBody returns a copy of req's body without consuming it.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// hookedBody records what is read from a response body and hands it to done once,
+// when the body reaches EOF or is closed.
+type hookedBody struct {
+	body io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func(body []byte)
+}
+
+// Read implements io.Reader.
+func (b *hookedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (b *hookedBody) Close() error {
+	err := b.body.Close()
+	b.finish()
+	return err
+}
+
+func (b *hookedBody) finish() {
+	b.once.Do(func() { b.done(b.buf.Bytes()) })
+}
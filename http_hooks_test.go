@@ -0,0 +1,134 @@
+package chatdelta
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// httpRecorder collects the events delivered to the HTTP hooks.
+type httpRecorder struct {
+	mu        sync.Mutex
+	requests  []HTTPRequestEvent
+	responses []HTTPResponseEvent
+}
+
+func (r *httpRecorder) config() *ClientConfig {
+	return NewClientConfig().
+		SetRetries(0).
+		SetOnRequest(func(event HTTPRequestEvent) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.requests = append(r.requests, event)
+		}).
+		SetOnResponse(func(event HTTPResponseEvent) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.responses = append(r.responses, event)
+		})
+}
+
+func TestHTTPHooks_RedactCredentialHeaders(t *testing.T) {
+	server := metadataServer(t, nil, `{"id":"msg_1","model":"claude-3-5-haiku-latest","content":[{"type":"text","text":"Hello"}],"stop_reason":"end_turn"}`)
+	var recorder httpRecorder
+	client, err := NewClaudeClient("sk-secret", "claude-3-5-haiku-latest", recorder.config().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	_, err = client.SendPrompt(WithTrace(context.Background(), "trace-1"), "hi")
+	require.NoError(t, err)
+
+	require.Len(t, recorder.requests, 1)
+	request := recorder.requests[0]
+	assert.Equal(t, "trace-1", request.TraceID)
+	assert.Equal(t, http.MethodPost, request.Method)
+	assert.Equal(t, redactedValue, request.Header.Get("x-api-key"))
+	assert.Contains(t, string(request.Body), `"hi"`)
+
+	require.Len(t, recorder.responses, 1)
+	response := recorder.responses[0]
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, string(response.Body), "Hello")
+	assert.Positive(t, response.Latency)
+	assert.NoError(t, response.Err)
+}
+
+func TestHTTPHooks_RedactKeyQueryParameter(t *testing.T) {
+	server := metadataServer(t, nil, `{"candidates":[{"content":{"parts":[{"text":"Hello"}]},"finishReason":"STOP"}]}`)
+	var recorder httpRecorder
+	client, err := NewGeminiClient("secret-key", "gemini-1.5-flash", recorder.config().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	_, err = client.SendPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+
+	require.Len(t, recorder.requests, 1)
+	assert.NotContains(t, recorder.requests[0].URL, "secret-key")
+	assert.Contains(t, recorder.requests[0].URL, "key="+redactedValue)
+	assert.Equal(t, recorder.requests[0].URL, recorder.responses[0].URL)
+}
+
+func TestHTTPHooks_BodyRedactorLeavesWireBodyAlone(t *testing.T) {
+	var sent []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"reply to alice@example.com"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	var recorder httpRecorder
+	config := recorder.config().SetBaseURL(server.URL).SetHTTPBodyRedactor(func(body []byte) []byte {
+		return bytes.ReplaceAll(body, []byte("alice@example.com"), []byte("[email]"))
+	})
+	client, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+
+	reply, err := client.SendPrompt(context.Background(), "mail alice@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "reply to alice@example.com", reply)
+	assert.Contains(t, string(sent), "alice@example.com")
+	assert.NotContains(t, string(recorder.requests[0].Body), "alice@example.com")
+	assert.NotContains(t, string(recorder.responses[0].Body), "alice@example.com")
+}
+
+func TestHTTPHooks_StreamBodyReportedAfterStream(t *testing.T) {
+	server := sseServer(t, "Hel", "lo")
+	var recorder httpRecorder
+	client, err := NewOpenAIClient("key", "gpt-4o", recorder.config().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, _ := collectStream(t, ch)
+	assert.Equal(t, "Hello", content)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Len(t, recorder.responses, 1)
+	assert.Contains(t, string(recorder.responses[0].Body), "[DONE]")
+}
+
+func TestHTTPHooks_TransportError(t *testing.T) {
+	var recorder httpRecorder
+	client, err := NewOpenAIClient("key", "gpt-4o", recorder.config().SetBaseURL("http://127.0.0.1:1"))
+	require.NoError(t, err)
+
+	_, err = client.SendPrompt(context.Background(), "hi")
+	require.Error(t, err)
+
+	require.Len(t, recorder.responses, 1)
+	assert.Error(t, recorder.responses[0].Err)
+	assert.Zero(t, recorder.responses[0].StatusCode)
+}
+
+func TestHTTPHooks_NotInstalledWithoutHooks(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig())
+	require.NoError(t, err)
+	assert.Nil(t, client.httpClient.Transport)
+}
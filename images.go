@@ -0,0 +1,132 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// images.go enforces provider limits on the number and size of images in a request,
+// and rejects images sent to models known not to accept them, so that such
+// conversations fail fast with a descriptive error instead of a provider-specific 400.
+package chatdelta
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+)
+
+// ImageLimits describes how many images, and how large, a provider accepts per request.
+// A zero value for any field means that dimension is not checked.
+type ImageLimits struct {
+	// MaxImages is the maximum number of images across all messages in a request.
+	MaxImages int
+	// MaxImageBytes is the maximum decoded size of a single inline image.
+	MaxImageBytes int
+	// MaxTotalImageBytes is the maximum combined decoded size of all inline images.
+	MaxTotalImageBytes int
+}
+
+// providerImageLimits holds the published per-request image limits for each provider.
+// Images referenced by URL count toward MaxImages but not toward the byte limits,
+// since their size is only known to the provider.
+var providerImageLimits = map[Provider]ImageLimits{
+	ProviderOpenAI: {MaxImages: 500, MaxImageBytes: 20 << 20, MaxTotalImageBytes: 50 << 20},
+	ProviderClaude: {MaxImages: 100, MaxImageBytes: 5 << 20, MaxTotalImageBytes: 32 << 20},
+	ProviderGemini: {MaxImages: 3000, MaxImageBytes: 20 << 20, MaxTotalImageBytes: 20 << 20},
+	// xAI caps each image but publishes no limit on their number or combined size
+	ProviderXAI: {MaxImageBytes: 20 << 20},
+}
+
+// ImageLimitsFor returns the image limits for provider, if any are known.
+func ImageLimitsFor(provider Provider) (ImageLimits, bool) {
+	limits, ok := providerImageLimits[provider]
+	return limits, ok
+}
+
+// ValidateImages checks the images in conversation against the limits for provider.
+// Providers without a limits entry are not checked.
+func ValidateImages(provider Provider, conversation *Conversation) error {
+	limits, ok := ImageLimitsFor(provider)
+	if !ok {
+		return nil
+	}
+
+	count, total := 0, 0
+	for i, msg := range conversation.Messages {
+		for _, part := range msg.Parts {
+			if part.Type != ContentPartImage || part.Image == nil {
+				continue
+			}
+			count++
+			size := len(part.Image.Data)
+			total += size
+			if limits.MaxImageBytes > 0 && size > limits.MaxImageBytes {
+				return NewImageLimitError(fmt.Sprintf("message %d has a %d byte image; %s accepts at most %d bytes per image",
+					i, size, provider, limits.MaxImageBytes))
+			}
+		}
+	}
+
+	if limits.MaxImages > 0 && count > limits.MaxImages {
+		return NewImageLimitError(fmt.Sprintf("request has %d images; %s accepts at most %d", count, provider, limits.MaxImages))
+	}
+	if limits.MaxTotalImageBytes > 0 && total > limits.MaxTotalImageBytes {
+		return NewImageLimitError(fmt.Sprintf("request has %d bytes of images; %s accepts at most %d",
+			total, provider, limits.MaxTotalImageBytes))
+	}
+	return nil
+}
+
+// ValidateVision rejects a conversation containing images when the model registry
+// knows model and records that it does not accept image input. Unknown models are
+// not checked, since the provider may support images the registry does not list.
+func ValidateVision(provider Provider, model string, conversation *Conversation) error {
+	if !hasImages(conversation) {
+		return nil
+	}
+	if info, ok := LookupModelInfo(provider, model); ok && !info.SupportsVision {
+		return NewVisionUnsupportedError(provider, model)
+	}
+	return nil
+}
+
+// hasImages reports whether any message in conversation carries an image part.
+func hasImages(conversation *Conversation) bool {
+	for _, msg := range conversation.Messages {
+		for _, part := range msg.Parts {
+			if part.Type == ContentPartImage && part.Image != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// messageParts returns msg's content as parts: Content, when set, as a leading text
+// part followed by Parts. Providers call it only for messages that have Parts.
+func messageParts(msg Message) []ContentPart {
+	parts := make([]ContentPart, 0, len(msg.Parts)+1)
+	if msg.Content != "" {
+		parts = append(parts, ContentPart{Type: ContentPartText, Text: msg.Content})
+	}
+	return append(parts, msg.Parts...)
+}
+
+// dataURL returns the image as a URL: URL itself, or Data encoded as a data: URL.
+func (p *ImagePart) dataURL() string {
+	if p.URL != "" {
+		return p.URL
+	}
+	return "data:" + p.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// guessMIMEType returns MIMEType, or for URL images the type implied by the file
+// extension, defaulting to "image/jpeg".
+func (p *ImagePart) guessMIMEType() string {
+	if p.MIMEType != "" {
+		return p.MIMEType
+	}
+	if parsed, err := url.Parse(p.URL); err == nil {
+		if guessed := mime.TypeByExtension(path.Ext(parsed.Path)); guessed != "" {
+			return guessed
+		}
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,184 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// imageConversation returns a conversation with one user message holding count
+// inline images of size bytes each.
+func imageConversation(count, size int) *Conversation {
+	msg := Message{Role: "user", Content: "describe these"}
+	for i := 0; i < count; i++ {
+		msg.Parts = append(msg.Parts, ContentPart{
+			Type:  ContentPartImage,
+			Image: &ImagePart{Data: make([]byte, size), MIMEType: "image/png"},
+		})
+	}
+	return &Conversation{Messages: []Message{msg}}
+}
+
+func assertImageLimitError(t *testing.T, err error) {
+	t.Helper()
+	require.Error(t, err)
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, ErrorTypeConfig, ce.Type)
+	assert.Equal(t, "image_limit_exceeded", ce.Code)
+}
+
+func TestValidateImages_CountLimit(t *testing.T) {
+	for _, provider := range []Provider{ProviderOpenAI, ProviderClaude, ProviderGemini} {
+		t.Run(string(provider), func(t *testing.T) {
+			limits, ok := ImageLimitsFor(provider)
+			require.True(t, ok)
+
+			assert.NoError(t, ValidateImages(provider, imageConversation(limits.MaxImages, 1)))
+			err := ValidateImages(provider, imageConversation(limits.MaxImages+1, 1))
+			assertImageLimitError(t, err)
+			assert.Contains(t, err.Error(), string(provider))
+		})
+	}
+}
+
+func TestValidateImages_SizeLimit(t *testing.T) {
+	for _, provider := range []Provider{ProviderOpenAI, ProviderClaude, ProviderGemini} {
+		t.Run(string(provider), func(t *testing.T) {
+			limits, _ := ImageLimitsFor(provider)
+
+			assert.NoError(t, ValidateImages(provider, imageConversation(1, limits.MaxImageBytes)))
+			assertImageLimitError(t, ValidateImages(provider, imageConversation(1, limits.MaxImageBytes+1)))
+		})
+	}
+}
+
+func TestValidateImages_TotalSizeLimit(t *testing.T) {
+	limits, _ := ImageLimitsFor(ProviderClaude)
+	// Each image is within the per-image cap but together they exceed the request cap.
+	n := limits.MaxTotalImageBytes/limits.MaxImageBytes + 1
+	err := ValidateImages(ProviderClaude, imageConversation(n, limits.MaxImageBytes))
+	assertImageLimitError(t, err)
+	assert.Contains(t, err.Error(), "bytes of images")
+}
+
+func TestValidateImages_URLImagesCountOnly(t *testing.T) {
+	conv := NewConversation()
+	conv.Messages = append(conv.Messages, Message{Role: "user", Parts: []ContentPart{
+		{Type: ContentPartImage, Image: &ImagePart{URL: "https://example.com/a.png"}},
+		{Type: ContentPartText, Text: "what is this?"},
+	}})
+	assert.NoError(t, ValidateImages(ProviderClaude, conv))
+	assert.NoError(t, ValidateImages(Provider("unknown"), imageConversation(10000, 1)))
+}
+
+func TestClient_RejectsImagesBeforeSending(t *testing.T) {
+	client, err := NewClaudeClient("test-key", "", NewClientConfig().SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Fatal("request must not be sent")
+		return nil, nil
+	})
+
+	_, err = client.SendConversation(context.Background(), imageConversation(101, 1))
+	assertImageLimitError(t, err)
+}
+
+func TestConversation_AddImageHelpers(t *testing.T) {
+	conv := NewConversation()
+	conv.AddImageMessage("https://example.com/a.png")
+	conv.AddImageBytes([]byte{1, 2}, "image/png")
+
+	require.Len(t, conv.Messages, 2)
+	assert.Equal(t, "user", conv.Messages[0].Role)
+	assert.Equal(t, []ContentPart{{Type: ContentPartImage, Image: &ImagePart{URL: "https://example.com/a.png"}}}, conv.Messages[0].Parts)
+	assert.Equal(t, &ImagePart{Data: []byte{1, 2}, MIMEType: "image/png"}, conv.Messages[1].Parts[0].Image)
+}
+
+func TestImageURLs_MappedPerProvider(t *testing.T) {
+	conv := NewConversation()
+	conv.Messages = append(conv.Messages, Message{Role: "user", Content: "what is this?", Parts: []ContentPart{
+		{Type: ContentPartImage, Image: &ImagePart{URL: "https://example.com/a.webp"}},
+	}})
+
+	openai, err := NewOpenAIClient("key", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []openAIContentPart{
+		{Type: "text", Text: "what is this?"},
+		{Type: "image_url", ImageURL: &openAIImageURL{URL: "https://example.com/a.webp"}},
+	}, openai.buildRequest(conv, false).Messages[0].Content)
+
+	claude, err := NewClaudeClient("key", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []claudeBlock{
+		{Type: "text", Text: "what is this?"},
+		{Type: "image", Source: &claudeSource{Type: "url", URL: "https://example.com/a.webp"}},
+	}, claude.buildRequest(conv, false).Messages[0].Content)
+
+	gemini, err := NewGeminiClient("key", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []geminiPart{
+		{Text: "what is this?"},
+		{FileData: &geminiFileData{MimeType: "image/webp", FileURI: "https://example.com/a.webp"}},
+	}, gemini.buildRequest(conv).Contents[0].Parts)
+}
+
+func TestOllamaClient_RejectsImageURLs(t *testing.T) {
+	client, err := NewOllamaClient("llava", NewClientConfig().SetRetries(0))
+	require.NoError(t, err)
+	conv := NewConversation()
+	conv.AddImageMessage("https://example.com/a.png")
+
+	_, err = client.DryRun(conv, false)
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, "invalid_parameter", ce.Code)
+}
+
+func TestAddUserImageMessage(t *testing.T) {
+	conv := NewConversation()
+	conv.AddUserImageMessage("what is this?", ImagePart{Data: []byte{1, 2}, MIMEType: "image/png"})
+
+	require.Len(t, conv.Messages, 1)
+	msg := conv.Messages[0]
+	assert.Equal(t, "user", msg.Role)
+	assert.Equal(t, "what is this?", msg.Content)
+	require.Len(t, msg.Parts, 1)
+	assert.Equal(t, ContentPartImage, msg.Parts[0].Type)
+	assert.Equal(t, "image/png", msg.Parts[0].Image.MIMEType)
+}
+
+func TestValidateVision(t *testing.T) {
+	images := imageConversation(1, 1)
+	assert.NoError(t, ValidateVision(ProviderOpenAI, "gpt-4o", images))
+	assert.NoError(t, ValidateVision(ProviderOpenAI, "my-finetune", images), "unknown models are not checked")
+	assert.NoError(t, ValidateVision(ProviderOpenAI, "gpt-3.5-turbo", promptConversation("hi")))
+
+	err := ValidateVision(ProviderOpenAI, "gpt-3.5-turbo", images)
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, ErrorTypeConfig, ce.Type)
+	assert.Equal(t, "vision_unsupported", ce.Code)
+	assert.Contains(t, ce.Message, "gpt-3.5-turbo")
+}
+
+func TestClients_RejectImagesForTextOnlyModels(t *testing.T) {
+	conv := NewConversation()
+	conv.AddUserImageMessage("what is this?", ImagePart{URL: "https://example.com/a.png"})
+
+	openai, err := NewOpenAIClient("key", "gpt-3.5-turbo", nil)
+	require.NoError(t, err)
+	claude, err := NewClaudeClient("key", "claude-3-5-haiku-20241022", nil)
+	require.NoError(t, err)
+
+	for _, client := range []DryRunner{openai, claude} {
+		_, err := client.DryRun(conv, false)
+		var ce *ClientError
+		require.True(t, errors.As(err, &ce))
+		assert.Equal(t, "vision_unsupported", ce.Code)
+	}
+}
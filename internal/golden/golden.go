@@ -0,0 +1,300 @@
+// Package golden defines the provider × feature matrix used by the golden request
+// tests. Each case builds a client and a conversation, and its fixture is the
+// client's DryRun output rendered as indented JSON.
+//
+// Fixtures live in testdata/golden and are regenerated with:
+//
+//	go run ./cmd/goldengen
+//
+// Add a feature to Features whenever request shaping changes so every provider's
+// payload for it is pinned.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/chatdelta/chatdelta-go"
+)
+
+// Dir is the fixture directory relative to the repository root.
+const Dir = "testdata/golden"
+
+// Feature is one request-shaping scenario applied to every provider.
+type Feature struct {
+	// Name identifies the feature in fixture file names
+	Name string
+	// Config returns the client configuration for the scenario
+	Config func() *chatdelta.ClientConfig
+	// Conversation returns the conversation to send
+	Conversation func() *chatdelta.Conversation
+	// Stream selects the streaming request shape
+	Stream bool
+	// Models overrides the model per provider, for scenarios the default model
+	// cannot serve
+	Models map[string]string
+}
+
+// Case is a single provider × feature combination.
+type Case struct {
+	Provider string
+	Feature  Feature
+}
+
+// Name returns the case's fixture base name, e.g. "openai_seed".
+func (c Case) Name() string {
+	return c.Provider + "_" + c.Feature.Name
+}
+
+// Path returns the fixture path relative to the repository root.
+func (c Case) Path() string {
+	return filepath.Join(Dir, c.Name()+".json")
+}
+
+// Providers lists the built-in providers covered by the matrix.
+var Providers = []string{"openai", "claude", "gemini", "ollama", "azure", "openai-compatible", "xai", "bedrock", "vertex"}
+
+// providerSetup holds what a provider needs before CreateClient accepts it: extra
+// settings applied to each feature's config, and a model for providers without a
+// default.
+var providerSetup = map[string]struct {
+	config func(*chatdelta.ClientConfig)
+	model  string
+}{
+	"azure": {config: func(config *chatdelta.ClientConfig) {
+		config.SetAzure("golden-resource", "golden-deployment", "")
+	}},
+	"openai-compatible": {
+		config: func(config *chatdelta.ClientConfig) {
+			if config.BaseURL == nil {
+				config.SetBaseURL("http://localhost:8000/v1")
+			}
+		},
+		model: "golden-model",
+	},
+	"bedrock": {config: func(config *chatdelta.ClientConfig) {
+		config.SetBedrock("us-east-1", &chatdelta.AWSCredentials{AccessKeyID: "golden", SecretAccessKey: "golden"})
+	}},
+	"vertex": {config: func(config *chatdelta.ClientConfig) {
+		config.SetVertex("golden-project", "us-central1", chatdelta.StaticTokenSource("golden"))
+	}},
+}
+
+func prompt(text string) func() *chatdelta.Conversation {
+	return func() *chatdelta.Conversation {
+		conv := chatdelta.NewConversation()
+		conv.AddUserMessage(text)
+		return conv
+	}
+}
+
+// Features is the list of scenarios pinned by fixtures.
+var Features = []Feature{
+	{
+		Name:         "basic",
+		Config:       chatdelta.NewClientConfig,
+		Conversation: prompt("Hello!"),
+	},
+	{
+		Name:         "stream",
+		Config:       chatdelta.NewClientConfig,
+		Conversation: prompt("Hello!"),
+		Stream:       true,
+	},
+	{
+		Name: "system_merging",
+		Config: func() *chatdelta.ClientConfig {
+			return chatdelta.NewClientConfig().SetSystemMessage("You are terse.")
+		},
+		Conversation: func() *chatdelta.Conversation {
+			conv := chatdelta.NewConversation()
+			conv.AddSystemMessage("Answer in French.")
+			conv.AddUserMessage("What is 2 + 2?")
+			conv.AddAssistantMessage("Quatre.")
+			conv.AddUserMessage("And 3 + 3?")
+			return conv
+		},
+	},
+	{
+		Name: "sampling",
+		Config: func() *chatdelta.ClientConfig {
+			return chatdelta.NewClientConfig().
+				SetTemperature(0.2).
+				SetTopP(0.9).
+				SetMaxTokens(256).
+				SetFrequencyPenalty(0.5).
+				SetPresencePenalty(-0.5).
+				SetStopSequences("END").
+				SetLogitBias(map[int]int{50256: -100, 1734: 5}).
+				SetTopLogprobs(2)
+		},
+		Conversation: prompt("Write a haiku."),
+	},
+	{
+		Name: "seed",
+		Config: func() *chatdelta.ClientConfig {
+			return chatdelta.NewClientConfig().SetSeed(1234)
+		},
+		Conversation: prompt("Pick a number."),
+	},
+	{
+		Name: "base_url_and_headers",
+		Config: func() *chatdelta.ClientConfig {
+			return chatdelta.NewClientConfig().
+				SetBaseURL("https://gateway.example.com/v1").
+				SetHeader("Helicone-Auth", "Bearer gateway")
+		},
+		Conversation: prompt("Hello!"),
+	},
+	{
+		Name: "request_mutator",
+		Config: func() *chatdelta.ClientConfig {
+			return chatdelta.NewClientConfig().
+				AddRequestMutator(func(_ chatdelta.Provider, _ string, body map[string]any) error {
+					body["metadata"] = map[string]any{"team": "golden"}
+					return nil
+				})
+		},
+		Conversation: prompt("Hello!"),
+	},
+	{
+		Name: "tools",
+		Config: func() *chatdelta.ClientConfig {
+			return chatdelta.NewClientConfig().
+				SetTools(chatdelta.Tool{
+					Name:        "get_weather",
+					Description: "Get the current weather for a city.",
+					Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`),
+				}).
+				SetToolChoice(chatdelta.ToolChoiceRequired)
+		},
+		Conversation: func() *chatdelta.Conversation {
+			conv := chatdelta.NewConversation()
+			conv.AddUserMessage("Is it raining in Paris or Oslo?")
+			conv.AddToolCalls("Checking both.", []chatdelta.ToolCall{
+				{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+				{ID: "call_2", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Oslo"}`)},
+			})
+			conv.AddToolResult("call_1", `{"raining":false}`)
+			conv.AddToolResult("call_2", "Light rain")
+			return conv
+		},
+	},
+	{
+		Name: "json_schema",
+		Config: func() *chatdelta.ClientConfig {
+			return chatdelta.NewClientConfig().
+				SetResponseJSONSchema("city", json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`))
+		},
+		Conversation: prompt("Name a city in Norway."),
+	},
+	{
+		Name:   "images",
+		Config: chatdelta.NewClientConfig,
+		Models: map[string]string{"openai": "gpt-4o", "xai": "grok-2-vision", "ollama": "llava"},
+		Conversation: func() *chatdelta.Conversation {
+			conv := chatdelta.NewConversation()
+			conv.AddImageBytes([]byte("\x89PNG"), "image/png")
+			conv.Messages = append(conv.Messages, chatdelta.Message{
+				Role:    "user",
+				Content: "What differs between these two images?",
+				Parts: []chatdelta.ContentPart{{
+					Type:  chatdelta.ContentPartImage,
+					Image: &chatdelta.ImagePart{Data: []byte("GIF89a"), MIMEType: "image/gif"},
+				}},
+			})
+			return conv
+		},
+	},
+}
+
+// Cases returns the full provider × feature matrix.
+func Cases() []Case {
+	var cases []Case
+	for _, provider := range Providers {
+		for _, feature := range Features {
+			cases = append(cases, Case{Provider: provider, Feature: feature})
+		}
+	}
+	return cases
+}
+
+// Render builds the case's prepared request and returns it as indented JSON.
+func Render(c Case) ([]byte, error) {
+	config := c.Feature.Config()
+	setup := providerSetup[c.Provider]
+	if setup.config != nil {
+		setup.config(config)
+	}
+	model := setup.model
+	if override, ok := c.Feature.Models[c.Provider]; ok {
+		model = override
+	}
+	client, err := chatdelta.CreateClient(c.Provider, "golden-key", model, config)
+	if err != nil {
+		return nil, err
+	}
+	runner, ok := client.(chatdelta.DryRunner)
+	if !ok {
+		return nil, fmt.Errorf("%s client does not support DryRun", c.Provider)
+	}
+	prepared, err := runner.DryRun(c.Feature.Conversation(), c.Feature.Stream)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(prepared); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Diff returns a line diff of want and got, marking removed lines with "-" and
+// added lines with "+". It returns "" when they are equal.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,23 @@
+package golden
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	if d := Diff("a\nb\n", "a\nb\n"); d != "" {
+		t.Fatalf("expected no diff, got %q", d)
+	}
+	want := "  a\n- b\n+ B\n  c\n"
+	if d := Diff("a\nb\nc", "a\nB\nc"); d != want {
+		t.Fatalf("unexpected diff:\n%s", d)
+	}
+}
+
+func TestCaseNamesUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, c := range Cases() {
+		if seen[c.Name()] {
+			t.Fatalf("duplicate case %s", c.Name())
+		}
+		seen[c.Name()] = true
+	}
+}
@@ -0,0 +1,84 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// keepalive.go implements ClientConfig.KeepAliveViaStreaming: non-streaming calls are
+// served through the provider's streaming API and assembled into a single response,
+// so bytes keep flowing on connections that intermediaries would otherwise close as
+// idle during a long generation.
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// streamFunc is a client's streamRequest: it sends conversation with streaming
+// enabled and forwards the chunks to sink, returning an error for the attempt.
+type streamFunc func(ctx context.Context, conversation *Conversation, sink *streamSink) error
+
+// keepAliveViaStreaming reports whether non-streaming calls should be served by
+// streaming. Tool calls are only reported by the non-streaming path, so configs
+// with tools keep it.
+func (c *ClientConfig) keepAliveViaStreaming() bool {
+	return c.KeepAliveViaStreaming && len(c.Tools) == 0
+}
+
+// sendViaStream runs stream under the config's retry policy and assembles its chunks
+// into an AiResponse, with metadata taken from the terminal chunk. Each attempt starts
+// from an empty buffer. Stream read failures are reported as the timeout or connection
+// errors the non-streaming path would produce for the same failure.
+func sendViaStream(ctx context.Context, config *ClientConfig, conversation *Conversation, stream streamFunc) (*AiResponse, error) {
+	var result *AiResponse
+
+	operation := func() error {
+		ch := make(chan StreamChunk, 10)
+		errc := make(chan error, 1)
+		go func() {
+			defer close(ch)
+			errc <- stream(ctx, conversation, &streamSink{ch: ch, ctx: ctx, keepRaw: config.IncludeRawResponse})
+		}()
+
+		var content strings.Builder
+		var metadata *ResponseMetadata
+		var raw json.RawMessage
+		for chunk := range ch {
+			content.WriteString(chunk.Content)
+			if chunk.Finished {
+				metadata, raw = chunk.Metadata, chunk.Raw
+			}
+		}
+		if err := <-errc; err != nil {
+			return nonStreamingError(ctx, config, err)
+		}
+		if metadata == nil {
+			// The stream ended without its terminal event: the connection dropped.
+			return NewConnectionError(io.ErrUnexpectedEOF)
+		}
+
+		text, err := postProcessContent(config, conversation, content.String(), metadata)
+		if err != nil {
+			return err
+		}
+		result = &AiResponse{Content: text, Metadata: *metadata, Raw: raw}
+		return nil
+	}
+
+	if err := config.retry(ctx, operation); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// nonStreamingError converts a stream read error into the error a non-streaming
+// request would have returned; other errors are already shared by both paths.
+func nonStreamingError(ctx context.Context, config *ClientConfig, err error) error {
+	var ce *ClientError
+	if !errors.As(err, &ce) || ce.Code != "stream_read_error" {
+		return err
+	}
+	if ctx.Err() != nil || isTimeoutError(ce.Cause) {
+		return NewTimeoutError(config.Timeout)
+	}
+	return NewConnectionError(ce.Cause)
+}
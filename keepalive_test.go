@@ -0,0 +1,139 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamingOnlyServer replays testdata/name and fails the test for requests that do
+// not ask for streaming.
+func streamingOnlyServer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	transcript, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Contains(t, string(body), `"stream":true`)
+		_, _ = w.Write(transcript)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestKeepAliveViaStreaming_AssemblesResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		transcript string
+		client     func(config *ClientConfig) (AIClient, error)
+	}{
+		{"openai", "openai_stream.sse", func(config *ClientConfig) (AIClient, error) {
+			return NewOpenAIClient("key", "gpt-4o", config)
+		}},
+		{"claude", "claude_stream.sse", func(config *ClientConfig) (AIClient, error) {
+			return NewClaudeClient("key", "", config)
+		}},
+		{"ollama", "ollama_stream.ndjson", func(config *ClientConfig) (AIClient, error) {
+			return NewOllamaClient("llama3", config)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := streamingOnlyServer(t, tt.transcript)
+			client, err := tt.client(NewClientConfig().SetBaseURL(server.URL).SetKeepAliveViaStreaming(true))
+			require.NoError(t, err)
+
+			text, err := client.SendPrompt(context.Background(), "hi")
+			require.NoError(t, err)
+			assert.NotEmpty(t, text)
+
+			response, err := client.SendConversationWithMetadata(context.Background(), promptConversation("hi"))
+			require.NoError(t, err)
+			assert.Equal(t, text, response.Content)
+			assert.NotZero(t, response.Metadata.TotalTokens)
+			assert.Equal(t, FinishReasonStop, response.Metadata.NormalizedFinishReason)
+			assert.Equal(t, ServedViaPrimary, response.Metadata.ServedVia)
+		})
+	}
+}
+
+func TestKeepAliveViaStreaming_ClaudeInBandError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("event: message_start\n" +
+			`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":5}}}` + "\n\n" +
+			"event: content_block_delta\n" +
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}` + "\n\n" +
+			"event: error\n" +
+			`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	client, err := NewClaudeClient("key", "", NewClientConfig().SetBaseURL(server.URL).SetRetries(0).SetKeepAliveViaStreaming(true))
+	require.NoError(t, err)
+
+	_, err = client.SendPrompt(context.Background(), "hi")
+	var clientErr *ClientError
+	require.True(t, errors.As(err, &clientErr), "unexpected error: %v", err)
+	assert.Equal(t, "server_error", clientErr.Code)
+	assert.Contains(t, clientErr.Message, "status 529: Overloaded")
+}
+
+func TestKeepAliveViaStreaming_DroppedStreamRetriesFromScratch(t *testing.T) {
+	transcript, err := os.ReadFile(filepath.Join("testdata", "openai_stream.sse"))
+	require.NoError(t, err)
+	firstEvent := strings.SplitAfterN(string(transcript), "\n\n", 3)
+	require.Len(t, firstEvent, 3)
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			// Cut the connection after the first events, before any finish reason
+			_, _ = w.Write([]byte(firstEvent[0] + firstEvent[1]))
+			return
+		}
+		_, _ = w.Write(transcript)
+	}))
+	defer server.Close()
+
+	config := NewClientConfig().SetBaseURL(server.URL).SetRetries(1).SetClock(&fakeClock{}).SetKeepAliveViaStreaming(true)
+	client, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+
+	text, err := client.SendPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there!", text, "content from the failed attempt is discarded")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	config.SetRetries(0)
+	atomic.StoreInt32(&attempts, 0)
+	_, err = client.SendPrompt(context.Background(), "hi")
+	assert.True(t, IsNetworkError(err), "a dropped stream is a connection error: %v", err)
+}
+
+func TestKeepAliveViaStreaming_SkippedWithTools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.NotContains(t, string(body), `"stream":true`)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	config := NewClientConfig().SetBaseURL(server.URL).SetKeepAliveViaStreaming(true).SetTools(weatherTool)
+	client, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+
+	text, err := client.SendPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", text)
+}
@@ -0,0 +1,34 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// max_tokens.go enforces provider minimums for ClientConfig.MaxTokens so that a
+// misconfigured limit fails fast with a descriptive error instead of a provider 400.
+package chatdelta
+
+import "fmt"
+
+// providerMinMaxTokens holds the smallest max_tokens each provider accepts. Claude
+// requires the field on every request, so an unset value is sent as its default of
+// 1024 rather than omitted. Ollama treats negative num_predict values as "no limit"
+// and has no entry.
+var providerMinMaxTokens = map[Provider]int{
+	ProviderOpenAI: 1,
+	ProviderClaude: 1,
+	ProviderGemini: 1,
+	ProviderXAI:    1,
+}
+
+// MinMaxTokensFor returns the smallest max_tokens provider accepts, if it has one.
+func MinMaxTokensFor(provider Provider) (int, bool) {
+	minimum, ok := providerMinMaxTokens[provider]
+	return minimum, ok
+}
+
+// ValidateMaxTokens checks config.MaxTokens against the minimum for provider. An unset
+// MaxTokens always passes.
+func ValidateMaxTokens(provider Provider, config *ClientConfig) error {
+	minimum, ok := MinMaxTokensFor(provider)
+	if !ok || config.MaxTokens == nil || *config.MaxTokens >= minimum {
+		return nil
+	}
+	return NewInvalidParameterError("max_tokens",
+		fmt.Sprintf("%d is below the %s minimum of %d", *config.MaxTokens, provider, minimum))
+}
@@ -0,0 +1,80 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMaxTokensTestClient builds a provider client whose HTTP transport fails the test
+// if a request is sent.
+func newMaxTokensTestClient(t *testing.T, provider Provider, config *ClientConfig) interface {
+	AIClient
+	DryRun(*Conversation, bool) (*PreparedRequest, error)
+} {
+	t.Helper()
+	transport := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Errorf("%s: request sent despite invalid max_tokens", provider)
+		return nil, http.ErrHandlerTimeout
+	})
+
+	switch provider {
+	case ProviderOpenAI:
+		client, err := NewOpenAIClient("key", "gpt-4o", config)
+		require.NoError(t, err)
+		client.httpClient.Transport = transport
+		return client
+	case ProviderClaude:
+		client, err := NewClaudeClient("key", "claude-3-haiku-20240307", config)
+		require.NoError(t, err)
+		client.httpClient.Transport = transport
+		return client
+	case ProviderGemini:
+		client, err := NewGeminiClient("key", "gemini-1.5-flash", config)
+		require.NoError(t, err)
+		client.httpClient.Transport = transport
+		return client
+	}
+	t.Fatalf("unexpected provider %s", provider)
+	return nil
+}
+
+func TestValidateMaxTokens_BelowProviderMinimum(t *testing.T) {
+	for _, provider := range []Provider{ProviderOpenAI, ProviderClaude, ProviderGemini} {
+		for _, maxTokens := range []int{0, -5} {
+			config := NewClientConfig().SetMaxTokens(maxTokens)
+			client := newMaxTokensTestClient(t, provider, config)
+
+			_, err := client.SendPrompt(context.Background(), "hi")
+			require.Error(t, err, "%s max_tokens=%d", provider, maxTokens)
+			var clientErr *ClientError
+			require.ErrorAs(t, err, &clientErr)
+			assert.Equal(t, ErrorTypeConfig, clientErr.Type)
+			assert.Contains(t, err.Error(), "below the "+string(provider)+" minimum of 1")
+
+			_, err = client.DryRun(promptConversation("hi"), false)
+			assert.Error(t, err, "%s dry run", provider)
+		}
+	}
+}
+
+func TestValidateMaxTokens_AtMinimumOrUnset(t *testing.T) {
+	for _, provider := range []Provider{ProviderOpenAI, ProviderClaude, ProviderGemini} {
+		assert.NoError(t, ValidateMaxTokens(provider, NewClientConfig().SetMaxTokens(1)))
+		assert.NoError(t, ValidateMaxTokens(provider, NewClientConfig()))
+	}
+}
+
+func TestValidateMaxTokens_OllamaHasNoMinimum(t *testing.T) {
+	_, ok := MinMaxTokensFor(ProviderOllama)
+	assert.False(t, ok)
+
+	client, err := NewOllamaClient("llama3", NewClientConfig().SetMaxTokens(-1))
+	require.NoError(t, err)
+	prepared, err := client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Contains(t, string(prepared.Body), `"num_predict":-1`)
+}
@@ -0,0 +1,111 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// memory_cache.go implements MemoryCache, an in-process Cache with optional expiry
+// and a least-recently-used bound on the number of entries.
+package chatdelta
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MemoryCacheOptions configures a MemoryCache.
+type MemoryCacheOptions struct {
+	// TTL is how long an entry stays valid after it is written. Zero means entries
+	// never expire.
+	TTL time.Duration
+	// MaxEntries caps the number of entries; when a write exceeds it the
+	// least-recently-used entry is removed. Zero means no cap.
+	MaxEntries int
+}
+
+// MemoryCache is a Cache held in memory. It is safe for concurrent use.
+type MemoryCache struct {
+	opts MemoryCacheOptions
+	mu   sync.Mutex
+	// order holds *memoryCacheEntry values, most recently used first
+	order   *list.List
+	entries map[string]*list.Element
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// memoryCacheEntry is one stored response.
+type memoryCacheEntry struct {
+	key       string
+	response  *AiResponse
+	expiresAt time.Time
+}
+
+// NewMemoryCache creates an empty MemoryCache.
+func NewMemoryCache(opts MemoryCacheOptions) *MemoryCache {
+	return &MemoryCache{opts: opts, order: list.New(), entries: make(map[string]*list.Element), now: time.Now}
+}
+
+// Get returns the response stored under key. An entry expires once TTL has passed
+// since it was written and is removed by the first Get that finds it expired.
+func (c *MemoryCache) Get(key string) (*AiResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.response, true
+}
+
+// Set stores response under key, evicting the least-recently-used entry when the
+// cache is over MaxEntries.
+func (c *MemoryCache) Set(key string, response *AiResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &memoryCacheEntry{key: key, response: response}
+	if c.opts.TTL > 0 {
+		entry.expiresAt = c.now().Add(c.opts.TTL)
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.opts.MaxEntries > 0 && c.order.Len() > c.opts.MaxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// Delete removes the entry for key, if any.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// PurgeAll removes every entry.
+func (c *MemoryCache) PurgeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
+
+// Len returns the number of stored entries, including expired ones not yet removed.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove drops elem. The caller holds mu.
+func (c *MemoryCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*memoryCacheEntry).key)
+}
@@ -0,0 +1,57 @@
+package chatdelta
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCache_TTL(t *testing.T) {
+	cache := NewMemoryCache(MemoryCacheOptions{TTL: time.Minute})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("k", &AiResponse{Content: "v"})
+	got, ok := cache.Get("k")
+	assert.True(t, ok)
+	assert.Equal(t, "v", got.Content)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get("k")
+	assert.False(t, ok)
+	assert.Zero(t, cache.Len())
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemoryCache(MemoryCacheOptions{MaxEntries: 2})
+	cache.Set("a", &AiResponse{Content: "a"})
+	cache.Set("b", &AiResponse{Content: "b"})
+	cache.Get("a")
+	cache.Set("c", &AiResponse{Content: "c"})
+
+	_, ok := cache.Get("b")
+	assert.False(t, ok, "b was least recently used")
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+
+	cache.Delete("a")
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestMemoryCache_PurgeAll(t *testing.T) {
+	cache := NewMemoryCache(MemoryCacheOptions{MaxEntries: 2})
+	cache.Set("a", &AiResponse{Content: "a"})
+	cache.Set("b", &AiResponse{Content: "b"})
+	cache.PurgeAll()
+	assert.Zero(t, cache.Len())
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+
+	cache.Set("c", &AiResponse{Content: "c"})
+	got, ok := cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, "c", got.Content)
+}
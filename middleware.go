@@ -96,20 +96,24 @@ func (m *MiddlewareClient) SendPrompt(ctx context.Context, prompt string) (strin
 	return m.buildChain(m.inner.SendPrompt)(ctx, prompt)
 }
 
-// SendPromptWithMetadata applies the middleware chain and wraps the result with metadata.
+// SendPromptWithMetadata applies the middleware chain and returns the result with the
+// inner client's metadata (including ServedVia), or just the model when a middleware
+// short-circuits the call.
 func (m *MiddlewareClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	metadata := ResponseMetadata{ModelUsed: m.inner.Model()}
 	chain := m.buildChain(func(ctx context.Context, p string) (string, error) {
 		resp, err := m.inner.SendPromptWithMetadata(ctx, p)
 		if err != nil {
 			return "", err
 		}
+		metadata = resp.Metadata
 		return resp.Content, nil
 	})
 	content, err := chain(ctx, prompt)
 	if err != nil {
 		return nil, err
 	}
-	return &AiResponse{Content: content, Metadata: ResponseMetadata{ModelUsed: m.inner.Model()}}, nil
+	return &AiResponse{Content: content, Metadata: metadata}, nil
 }
 
 // SendConversation applies the middleware chain using the last user message as the
@@ -124,18 +128,20 @@ func (m *MiddlewareClient) SendConversation(ctx context.Context, conv *Conversat
 // SendConversationWithMetadata applies the middleware chain then delegates to the inner client.
 func (m *MiddlewareClient) SendConversationWithMetadata(ctx context.Context, conv *Conversation) (*AiResponse, error) {
 	proxy := lastUserMessage(conv)
+	metadata := ResponseMetadata{ModelUsed: m.inner.Model()}
 	chain := m.buildChain(func(ctx context.Context, _ string) (string, error) {
 		resp, err := m.inner.SendConversationWithMetadata(ctx, conv)
 		if err != nil {
 			return "", err
 		}
+		metadata = resp.Metadata
 		return resp.Content, nil
 	})
 	content, err := chain(ctx, proxy)
 	if err != nil {
 		return nil, err
 	}
-	return &AiResponse{Content: content, Metadata: ResponseMetadata{ModelUsed: m.inner.Model()}}, nil
+	return &AiResponse{Content: content, Metadata: metadata}, nil
 }
 
 // StreamPrompt forwards directly to the inner client. Use StreamToChannel to post-process.
@@ -166,18 +172,21 @@ func (m *MiddlewareClient) Model() string { return m.inner.Model() }
 
 // LoggingMiddleware returns a Middleware that logs each request prompt and the
 // corresponding response (or error) using logger. Pass nil to use the default
-// stdlib logger writing to stderr.
+// stdlib logger writing to stderr. Lines carry the call's trace ID, which is
+// generated and attached to the context when the caller did not supply one with
+// WithTrace, so they line up with the client's own ClientConfig.Logger output.
 func LoggingMiddleware(logger *log.Logger) Middleware {
 	if logger == nil {
 		logger = log.Default()
 	}
 	return func(ctx context.Context, prompt string, next func(context.Context, string) (string, error)) (string, error) {
-		logger.Printf("[chatdelta] request prompt=%q", truncate(prompt, 80))
+		ctx, traceID := ensureTrace(ctx)
+		logger.Printf("[chatdelta] trace_id=%s request prompt=%q", traceID, truncate(prompt, 80))
 		resp, err := next(ctx, prompt)
 		if err != nil {
-			logger.Printf("[chatdelta] response error=%v", err)
+			logger.Printf("[chatdelta] trace_id=%s response error=%v", traceID, err)
 		} else {
-			logger.Printf("[chatdelta] response content=%q", truncate(resp, 80))
+			logger.Printf("[chatdelta] trace_id=%s response content=%q", traceID, truncate(resp, 80))
 		}
 		return resp, err
 	}
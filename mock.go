@@ -1,13 +1,21 @@
 // Package chatdelta provides a unified interface for interacting with multiple AI APIs.
-// mock.go implements a mock AIClient for use in unit tests.
-// Responses are pre-loaded into a queue and dequeued in order; when the queue is
-// exhausted a default fallback response is returned.
+// mock.go implements a mock AIClient for use in unit tests, both inside this package
+// and in applications that consume AIClient.
+//
+// Responses are resolved in the following order:
+//  1. A scripted reply registered with ScriptReply/ScriptError whose key matches the
+//     content of the last user message in the request.
+//  2. The next item in the queue loaded with QueueResponse/QueueError.
+//  3. The default response (see SetDefaultResponse), or a generic fallback.
+//
+// Every call is recorded so tests can assert on exactly what the client received.
 package chatdelta
 
 import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // MockResponse is a pre-configured response held in a MockClient's queue.
@@ -21,10 +29,16 @@ type MockResponse struct {
 // MockClient implements AIClient using a pre-loaded response queue.
 // It is safe for concurrent use.
 type MockClient struct {
-	mu        sync.Mutex
-	name      string
-	model     string
-	responses []MockResponse
+	mu              sync.Mutex
+	name            string
+	model           string
+	responses       []MockResponse
+	scripted        map[string]MockResponse
+	defaultResponse *MockResponse
+	latency         time.Duration
+	chunkSize       int
+	usage           ResponseMetadata
+	conversations   []*Conversation
 }
 
 // NewMockClient creates a new MockClient with the given name and model.
@@ -37,6 +51,7 @@ func NewMockClient(name, model string) *MockClient {
 		name:      name,
 		model:     model,
 		responses: make([]MockResponse, 0),
+		scripted:  make(map[string]MockResponse),
 	}
 }
 
@@ -54,73 +69,216 @@ func (m *MockClient) QueueError(err error) {
 	m.responses = append(m.responses, MockResponse{Error: err})
 }
 
-// dequeue pops the next response, or returns a generic fallback when the queue is empty.
-func (m *MockClient) dequeue() MockResponse {
+// ScriptReply registers content as the reply whenever the last user message of a
+// request equals userMessage. Scripted replies are not consumed and take priority
+// over the queue, which makes them convenient for multi-turn session tests.
+func (m *MockClient) ScriptReply(userMessage, content string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if len(m.responses) == 0 {
-		return MockResponse{Content: fmt.Sprintf("mock response from %s", m.name)}
+	m.scripted[userMessage] = MockResponse{Content: content}
+}
+
+// ScriptError registers err as the result whenever the last user message of a
+// request equals userMessage.
+func (m *MockClient) ScriptError(userMessage string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scripted[userMessage] = MockResponse{Error: err}
+}
+
+// SetDefaultResponse sets the response returned when no scripted reply matches and
+// the queue is empty. Passing a non-nil err makes every unmatched call fail.
+func (m *MockClient) SetDefaultResponse(content string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.defaultResponse = &MockResponse{Content: content, Error: err}
+}
+
+// SetLatency adds an artificial delay before every response. The delay honours
+// context cancellation, so it can be used to exercise timeout handling.
+func (m *MockClient) SetLatency(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency = latency
+}
+
+// SetChunkSize controls how streamed responses are split. A positive size emits
+// the content in pieces of at most size runes; zero (the default) emits the whole
+// content as a single chunk.
+func (m *MockClient) SetChunkSize(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chunkSize = size
+}
+
+// SetUsage sets the token counts reported in the metadata of every response.
+func (m *MockClient) SetUsage(promptTokens, completionTokens int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = ResponseMetadata{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
 	}
-	resp := m.responses[0]
-	m.responses = m.responses[1:]
-	return resp
 }
 
-// SendPrompt returns the next queued response.
-func (m *MockClient) SendPrompt(_ context.Context, _ string) (string, error) {
-	resp := m.dequeue()
-	return resp.Content, resp.Error
+// Conversations returns copies of every conversation the client has received, in
+// call order. Prompt-based calls are recorded as a single user message.
+func (m *MockClient) Conversations() []*Conversation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*Conversation, len(m.conversations))
+	copy(out, m.conversations)
+	return out
 }
 
-// SendPromptWithMetadata returns the next queued response with basic metadata.
-func (m *MockClient) SendPromptWithMetadata(_ context.Context, _ string) (*AiResponse, error) {
-	resp := m.dequeue()
-	if resp.Error != nil {
-		return nil, resp.Error
+// CallCount returns the number of requests the client has received.
+func (m *MockClient) CallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.conversations)
+}
+
+// Reset clears the queue, scripted replies, and recorded conversations.
+func (m *MockClient) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses = m.responses[:0]
+	m.scripted = make(map[string]MockResponse)
+	m.conversations = nil
+}
+
+// record stores a copy of conv and resolves the response for it.
+func (m *MockClient) record(conv *Conversation) (MockResponse, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := &Conversation{Messages: append([]Message(nil), conv.Messages...)}
+	m.conversations = append(m.conversations, snapshot)
+
+	if resp, ok := m.scripted[lastUserMessage(conv)]; ok {
+		return resp, m.latency
 	}
-	return &AiResponse{
-		Content:  resp.Content,
-		Metadata: ResponseMetadata{ModelUsed: m.model},
-	}, nil
+	if len(m.responses) > 0 {
+		resp := m.responses[0]
+		m.responses = m.responses[1:]
+		return resp, m.latency
+	}
+	if m.defaultResponse != nil {
+		return *m.defaultResponse, m.latency
+	}
+	return MockResponse{Content: fmt.Sprintf("mock response from %s", m.name)}, m.latency
+}
+
+// respond records conv, waits for the configured latency and returns the resolved response.
+func (m *MockClient) respond(ctx context.Context, conv *Conversation) MockResponse {
+	resp, latency := m.record(conv)
+	if latency > 0 {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		select {
+		case <-ctx.Done():
+			return MockResponse{Error: ctx.Err()}
+		case <-time.After(latency):
+		}
+	}
+	return resp
 }
 
-// SendConversation returns the next queued response.
-func (m *MockClient) SendConversation(_ context.Context, _ *Conversation) (string, error) {
-	resp := m.dequeue()
+// metadata returns the metadata reported with every response.
+func (m *MockClient) metadata() ResponseMetadata {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	meta := m.usage
+	meta.ModelUsed = m.model
+	meta.ServedVia = ServedViaPrimary
+	meta.LatencyMs = m.latency.Milliseconds()
+	return meta
+}
+
+// promptConversation wraps prompt in a single-message conversation.
+func promptConversation(prompt string) *Conversation {
+	conv := NewConversation()
+	conv.AddUserMessage(prompt)
+	return conv
+}
+
+// SendPrompt returns the response resolved for prompt.
+func (m *MockClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	return m.SendConversation(ctx, promptConversation(prompt))
+}
+
+// SendPromptWithMetadata returns the response resolved for prompt with its metadata.
+func (m *MockClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	return m.SendConversationWithMetadata(ctx, promptConversation(prompt))
+}
+
+// SendConversation returns the response resolved for conv.
+func (m *MockClient) SendConversation(ctx context.Context, conv *Conversation) (string, error) {
+	resp := m.respond(ctx, conv)
 	return resp.Content, resp.Error
 }
 
-// SendConversationWithMetadata returns the next queued response with basic metadata.
-func (m *MockClient) SendConversationWithMetadata(_ context.Context, _ *Conversation) (*AiResponse, error) {
-	resp := m.dequeue()
+// SendConversationWithMetadata returns the response resolved for conv with its
+// metadata: the model, the configured latency, and any usage set with SetUsage.
+func (m *MockClient) SendConversationWithMetadata(ctx context.Context, conv *Conversation) (*AiResponse, error) {
+	resp := m.respond(ctx, conv)
 	if resp.Error != nil {
 		return nil, resp.Error
 	}
 	return &AiResponse{
 		Content:  resp.Content,
-		Metadata: ResponseMetadata{ModelUsed: m.model},
+		Metadata: m.metadata(),
 	}, nil
 }
 
-// StreamPrompt dequeues a response and delivers it as a two-chunk stream.
-// If the dequeued item is an error it is returned immediately.
-func (m *MockClient) StreamPrompt(_ context.Context, _ string) (<-chan StreamChunk, error) {
-	resp := m.dequeue()
+// StreamPrompt resolves a response for prompt and delivers it as a stream.
+// If the resolved item is an error it is returned immediately.
+func (m *MockClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	return m.StreamConversation(ctx, promptConversation(prompt))
+}
+
+// StreamConversation resolves a response for conv and delivers it as one or more
+// content chunks (see SetChunkSize) followed by an empty Finished chunk.
+func (m *MockClient) StreamConversation(ctx context.Context, conv *Conversation) (<-chan StreamChunk, error) {
+	resp := m.respond(ctx, conv)
 	if resp.Error != nil {
 		return nil, resp.Error
 	}
-	ch := make(chan StreamChunk, 2)
+	m.mu.Lock()
+	pieces := splitRunes(resp.Content, m.chunkSize)
+	m.mu.Unlock()
+	meta := m.metadata()
+
+	ch := make(chan StreamChunk, len(pieces)+1)
 	go func() {
 		defer close(ch)
-		ch <- StreamChunk{Content: resp.Content, Finished: false}
-		ch <- StreamChunk{Content: "", Finished: true}
+		for _, piece := range pieces {
+			ch <- StreamChunk{Content: piece, Finished: false}
+		}
+		ch <- StreamChunk{Content: "", Finished: true, Metadata: &meta}
 	}()
 	return ch, nil
 }
 
-// StreamConversation delegates to StreamPrompt.
-func (m *MockClient) StreamConversation(ctx context.Context, _ *Conversation) (<-chan StreamChunk, error) {
-	return m.StreamPrompt(ctx, "")
+// splitRunes splits s into pieces of at most size runes. A non-positive size
+// returns s as a single piece.
+func splitRunes(s string, size int) []string {
+	if size <= 0 {
+		return []string{s}
+	}
+	runes := []rune(s)
+	var pieces []string
+	for len(runes) > 0 {
+		n := size
+		if n > len(runes) {
+			n = len(runes)
+		}
+		pieces = append(pieces, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return pieces
 }
 
 // SupportsStreaming returns true.
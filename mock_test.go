@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -92,6 +93,25 @@ func TestMockClient_SendConversationWithMetadata(t *testing.T) {
 	assert.Equal(t, "mymodel", resp.Metadata.ModelUsed)
 }
 
+func TestMockClient_SetUsage(t *testing.T) {
+	m := NewMockClient("test", "model")
+	m.SetUsage(10, 4)
+	m.SetLatency(5 * time.Millisecond)
+
+	resp, err := m.SendPromptWithMetadata(context.Background(), "q")
+	require.NoError(t, err)
+	assert.Equal(t, 10, resp.Metadata.PromptTokens)
+	assert.Equal(t, 4, resp.Metadata.CompletionTokens)
+	assert.Equal(t, 14, resp.Metadata.TotalTokens)
+	assert.Equal(t, int64(5), resp.Metadata.LatencyMs)
+
+	ch, err := m.StreamPrompt(context.Background(), "q")
+	require.NoError(t, err)
+	_, last := collectStream(t, ch)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, 14, last.Metadata.TotalTokens)
+}
+
 func TestMockClient_StreamPrompt(t *testing.T) {
 	m := NewMockClient("test", "model")
 	m.QueueResponse("streamed")
@@ -117,3 +137,104 @@ func TestMockClient_StreamPrompt_Error(t *testing.T) {
 	assert.EqualError(t, err, "stream error")
 	assert.Nil(t, ch)
 }
+
+func TestMockClient_StreamPrompt_Chunked(t *testing.T) {
+	m := NewMockClient("test", "model")
+	m.SetChunkSize(3)
+	m.QueueResponse("héllo world")
+
+	ch, err := m.StreamPrompt(context.Background(), "q")
+	require.NoError(t, err)
+
+	var contents []string
+	var last StreamChunk
+	for c := range ch {
+		if !c.Finished {
+			contents = append(contents, c.Content)
+		}
+		last = c
+	}
+	assert.Equal(t, []string{"hél", "lo ", "wor", "ld"}, contents)
+	assert.True(t, last.Finished)
+}
+
+func TestMockClient_ScriptReply(t *testing.T) {
+	m := NewMockClient("test", "model")
+	m.ScriptReply("hi", "hello there")
+	m.ScriptError("fail", errors.New("scripted failure"))
+	m.QueueResponse("queued")
+
+	conv := NewConversation()
+	conv.AddUserMessage("hi")
+	r, err := m.SendConversation(context.Background(), conv)
+	require.NoError(t, err)
+	assert.Equal(t, "hello there", r)
+
+	// Scripted replies are not consumed.
+	r, err = m.SendPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "hello there", r)
+
+	_, err = m.SendPrompt(context.Background(), "fail")
+	assert.EqualError(t, err, "scripted failure")
+
+	// Unmatched messages fall through to the queue.
+	r, err = m.SendPrompt(context.Background(), "other")
+	require.NoError(t, err)
+	assert.Equal(t, "queued", r)
+}
+
+func TestMockClient_SetDefaultResponse(t *testing.T) {
+	m := NewMockClient("test", "model")
+	m.SetDefaultResponse("always", nil)
+
+	r, err := m.SendPrompt(context.Background(), "q")
+	require.NoError(t, err)
+	assert.Equal(t, "always", r)
+
+	m.SetDefaultResponse("", errors.New("down"))
+	_, err = m.SendPrompt(context.Background(), "q")
+	assert.EqualError(t, err, "down")
+}
+
+func TestMockClient_RecordsConversations(t *testing.T) {
+	m := NewMockClient("test", "model")
+
+	conv := NewConversation()
+	conv.AddSystemMessage("sys")
+	conv.AddUserMessage("first")
+	_, err := m.SendConversation(context.Background(), conv)
+	require.NoError(t, err)
+
+	// Mutating the caller's conversation must not affect the recording.
+	conv.AddAssistantMessage("reply")
+
+	_, err = m.SendPrompt(context.Background(), "second")
+	require.NoError(t, err)
+
+	recorded := m.Conversations()
+	require.Len(t, recorded, 2)
+	assert.Equal(t, 2, m.CallCount())
+	assert.Len(t, recorded[0].Messages, 2)
+	assert.Equal(t, "first", recorded[0].Messages[1].Content)
+	assert.Equal(t, []Message{{Role: "user", Content: "second"}}, recorded[1].Messages)
+
+	m.Reset()
+	assert.Equal(t, 0, m.CallCount())
+}
+
+func TestMockClient_Latency(t *testing.T) {
+	m := NewMockClient("test", "model")
+	m.SetLatency(20 * time.Millisecond)
+
+	start := time.Now()
+	_, err := m.SendPrompt(context.Background(), "q")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	m.SetLatency(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = m.SendPrompt(ctx, "q")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,248 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// models.go holds the model registry: context window, output limit, knowledge cutoff,
+// and vision and tool support for well-known models of every provider. Entries come from three layers,
+// merged field by field with later layers winning: the built-in table below, live data
+// fetched with RefreshModelInfo, and user overrides added with RegisterModelInfo.
+package chatdelta
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ModelInfo describes a model's limits. Zero fields are unknown.
+type ModelInfo struct {
+	// Provider serving the model
+	Provider Provider `json:"provider"`
+	// Model identifier as passed to the provider
+	Model string `json:"model"`
+	// DisplayName is a human-readable name, when the provider supplies one
+	DisplayName string `json:"display_name,omitempty"`
+	// ContextWindow is the maximum number of input plus output tokens
+	ContextWindow int `json:"context_window,omitempty"`
+	// MaxOutputTokens is the maximum number of tokens generated per response
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+	// KnowledgeCutoff is the end of the training data as "YYYY-MM"
+	KnowledgeCutoff string `json:"knowledge_cutoff,omitempty"`
+	// SupportsVision reports whether the model accepts image input
+	SupportsVision bool `json:"supports_vision,omitempty"`
+	// SupportsTools reports whether the model accepts tool declarations
+	SupportsTools bool `json:"supports_tools,omitempty"`
+}
+
+// merge overlays the non-zero fields of other onto i.
+func (i ModelInfo) merge(other ModelInfo) ModelInfo {
+	if other.DisplayName != "" {
+		i.DisplayName = other.DisplayName
+	}
+	if other.ContextWindow != 0 {
+		i.ContextWindow = other.ContextWindow
+	}
+	if other.MaxOutputTokens != 0 {
+		i.MaxOutputTokens = other.MaxOutputTokens
+	}
+	if other.KnowledgeCutoff != "" {
+		i.KnowledgeCutoff = other.KnowledgeCutoff
+	}
+	i.SupportsVision = i.SupportsVision || other.SupportsVision
+	i.SupportsTools = i.SupportsTools || other.SupportsTools
+	return i
+}
+
+// builtinModels lists published limits for well-known models.
+var builtinModels = []ModelInfo{
+	{Provider: ProviderOpenAI, Model: "gpt-4o", ContextWindow: 128000, MaxOutputTokens: 16384, KnowledgeCutoff: "2023-10", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderOpenAI, Model: "gpt-4o-mini", ContextWindow: 128000, MaxOutputTokens: 16384, KnowledgeCutoff: "2023-10", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderOpenAI, Model: "gpt-4-turbo", ContextWindow: 128000, MaxOutputTokens: 4096, KnowledgeCutoff: "2023-12", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderOpenAI, Model: "gpt-4", ContextWindow: 8192, MaxOutputTokens: 8192, KnowledgeCutoff: "2021-09", SupportsTools: true},
+	{Provider: ProviderOpenAI, Model: "gpt-3.5-turbo", ContextWindow: 16385, MaxOutputTokens: 4096, KnowledgeCutoff: "2021-09", SupportsTools: true},
+	{Provider: ProviderOpenAI, Model: "o1", ContextWindow: 200000, MaxOutputTokens: 100000, KnowledgeCutoff: "2023-10", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderOpenAI, Model: "o1-mini", ContextWindow: 128000, MaxOutputTokens: 65536, KnowledgeCutoff: "2023-10"},
+
+	{Provider: ProviderClaude, Model: "claude-3-5-sonnet-20241022", ContextWindow: 200000, MaxOutputTokens: 8192, KnowledgeCutoff: "2024-04", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderClaude, Model: "claude-3-5-sonnet-20240620", ContextWindow: 200000, MaxOutputTokens: 8192, KnowledgeCutoff: "2024-04", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderClaude, Model: "claude-3-5-haiku-20241022", ContextWindow: 200000, MaxOutputTokens: 8192, KnowledgeCutoff: "2024-07", SupportsTools: true},
+	{Provider: ProviderClaude, Model: "claude-3-opus-20240229", ContextWindow: 200000, MaxOutputTokens: 4096, KnowledgeCutoff: "2023-08", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderClaude, Model: "claude-3-sonnet-20240229", ContextWindow: 200000, MaxOutputTokens: 4096, KnowledgeCutoff: "2023-08", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderClaude, Model: "claude-3-haiku-20240307", ContextWindow: 200000, MaxOutputTokens: 4096, KnowledgeCutoff: "2023-08", SupportsVision: true, SupportsTools: true},
+
+	{Provider: ProviderGemini, Model: "gemini-2.0-flash", ContextWindow: 1048576, MaxOutputTokens: 8192, KnowledgeCutoff: "2024-08", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderGemini, Model: "gemini-1.5-pro", ContextWindow: 2097152, MaxOutputTokens: 8192, KnowledgeCutoff: "2023-11", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderGemini, Model: "gemini-1.5-flash", ContextWindow: 1048576, MaxOutputTokens: 8192, KnowledgeCutoff: "2023-11", SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderGemini, Model: "gemini-1.5-flash-8b", ContextWindow: 1048576, MaxOutputTokens: 8192, KnowledgeCutoff: "2023-11", SupportsVision: true, SupportsTools: true},
+
+	{Provider: ProviderXAI, Model: "grok-2", ContextWindow: 131072, SupportsTools: true},
+	{Provider: ProviderXAI, Model: "grok-2-vision", ContextWindow: 32768, SupportsVision: true, SupportsTools: true},
+	{Provider: ProviderXAI, Model: "grok-beta", ContextWindow: 131072, SupportsTools: true},
+
+	{Provider: ProviderOllama, Model: "llama3", ContextWindow: 8192, KnowledgeCutoff: "2023-03"},
+	{Provider: ProviderOllama, Model: "llama3.1", ContextWindow: 131072, KnowledgeCutoff: "2023-12", SupportsTools: true},
+	{Provider: ProviderOllama, Model: "mistral", ContextWindow: 32768, SupportsTools: true},
+}
+
+// modelRegistry holds the live and user layers; the built-in layer is builtinModels.
+var modelRegistry = struct {
+	sync.RWMutex
+	live map[Provider]map[string]ModelInfo
+	user map[Provider]map[string]ModelInfo
+}{
+	live: make(map[Provider]map[string]ModelInfo),
+	user: make(map[Provider]map[string]ModelInfo),
+}
+
+// RegisterModelInfo adds or overrides registry data for info.Provider and info.Model,
+// for example for private fine-tunes. Non-zero fields take precedence over built-in
+// and live data. It is safe for concurrent use.
+func RegisterModelInfo(info ModelInfo) error {
+	if info.Provider == "" || info.Model == "" {
+		return NewInvalidParameterError("model_info", "provider and model are required")
+	}
+	modelRegistry.Lock()
+	defer modelRegistry.Unlock()
+	setModelInfo(modelRegistry.user, info)
+	return nil
+}
+
+// setModelInfo merges info into layer. The caller holds the registry lock.
+func setModelInfo(layer map[Provider]map[string]ModelInfo, info ModelInfo) {
+	models, ok := layer[info.Provider]
+	if !ok {
+		models = make(map[string]ModelInfo)
+		layer[info.Provider] = models
+	}
+	models[info.Model] = models[info.Model].merge(info)
+}
+
+// LookupModelInfo returns what the registry knows about model on provider. Dated or
+// versioned variants (e.g. "gpt-4o-2024-08-06") fall back to the longest registered
+// name they extend. ok is false when nothing is known about the model.
+func LookupModelInfo(provider Provider, model string) (ModelInfo, bool) {
+	modelRegistry.RLock()
+	defer modelRegistry.RUnlock()
+
+	if info, ok := lookupExact(provider, model); ok {
+		return info, true
+	}
+
+	best := ""
+	for _, name := range knownModelNames(provider) {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelInfo{}, false
+	}
+	info, _ := lookupExact(provider, best)
+	info.Model = model
+	return info, true
+}
+
+// modelInfoProviders is the order in which ModelInfoFor searches providers.
+var modelInfoProviders = []Provider{ProviderOpenAI, ProviderClaude, ProviderGemini, ProviderXAI, ProviderOllama, ProviderBedrock, ProviderVertex}
+
+// ModelInfoFor returns what the registry knows about model without naming its
+// provider. Exact names are preferred over dated variants; when several providers
+// list the same name, the first of OpenAI, Claude, Gemini, xAI, Ollama, Bedrock and
+// Vertex wins.
+func ModelInfoFor(model string) (ModelInfo, bool) {
+	if info, ok := func() (ModelInfo, bool) {
+		modelRegistry.RLock()
+		defer modelRegistry.RUnlock()
+		for _, provider := range modelInfoProviders {
+			if info, ok := lookupExact(provider, model); ok {
+				return info, true
+			}
+		}
+		return ModelInfo{}, false
+	}(); ok {
+		return info, true
+	}
+	for _, provider := range modelInfoProviders {
+		if info, ok := LookupModelInfo(provider, model); ok {
+			return info, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+// lookupExact merges every layer's entry for model. The caller holds the read lock.
+func lookupExact(provider Provider, model string) (ModelInfo, bool) {
+	info := ModelInfo{Provider: provider, Model: model}
+	found := false
+	for _, builtin := range builtinModels {
+		if builtin.Provider == provider && builtin.Model == model {
+			info, found = info.merge(builtin), true
+		}
+	}
+	for _, layer := range []map[Provider]map[string]ModelInfo{modelRegistry.live, modelRegistry.user} {
+		if entry, ok := layer[provider][model]; ok {
+			info, found = info.merge(entry), true
+		}
+	}
+	return info, found
+}
+
+// knownModelNames returns every model name registered for provider in any layer.
+// The caller holds the read lock.
+func knownModelNames(provider Provider) []string {
+	var names []string
+	for _, builtin := range builtinModels {
+		if builtin.Provider == provider {
+			names = append(names, builtin.Model)
+		}
+	}
+	for _, layer := range []map[Provider]map[string]ModelInfo{modelRegistry.live, modelRegistry.user} {
+		for name := range layer[provider] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ModelInfoProvider is implemented by clients that can describe their configured model.
+type ModelInfoProvider interface {
+	ModelInfo() (ModelInfo, bool)
+}
+
+// GetModelInfo returns registry data for client's configured model, or ok=false if
+// the client cannot describe its model or nothing is known about it.
+func GetModelInfo(client AIClient) (ModelInfo, bool) {
+	if p, ok := client.(ModelInfoProvider); ok {
+		return p.ModelInfo()
+	}
+	return ModelInfo{}, false
+}
+
+// ModelLister is implemented by clients that can list the provider's models.
+type ModelLister interface {
+	// ListModels returns the models available to the client's credentials with
+	// whatever limits the provider reports
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// RefreshModelInfo lists client's models and merges the limits the provider reports
+// into the registry's live layer. Models listed without limits are not added, so they
+// stay unknown to LookupModelInfo; built-in knowledge cutoffs are kept, since no
+// provider reports them. It returns the number of models listed.
+func RefreshModelInfo(ctx context.Context, client AIClient) (int, error) {
+	lister, ok := client.(ModelLister)
+	if !ok {
+		return 0, NewInvalidParameterError("client", fmt.Sprintf("%s cannot list models", client.Name()))
+	}
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	modelRegistry.Lock()
+	defer modelRegistry.Unlock()
+	for _, info := range models {
+		if info.Provider != "" && info.Model != "" && (info.ContextWindow != 0 || info.MaxOutputTokens != 0) {
+			setModelInfo(modelRegistry.live, info)
+		}
+	}
+	return len(models), nil
+}
@@ -0,0 +1,206 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetModelRegistry clears the live and user layers when the test ends.
+func resetModelRegistry(t *testing.T) {
+	t.Cleanup(func() {
+		modelRegistry.Lock()
+		defer modelRegistry.Unlock()
+		modelRegistry.live = make(map[Provider]map[string]ModelInfo)
+		modelRegistry.user = make(map[Provider]map[string]ModelInfo)
+	})
+}
+
+func TestLookupModelInfo_BuiltIn(t *testing.T) {
+	info, ok := LookupModelInfo(ProviderOpenAI, "gpt-4o")
+	require.True(t, ok)
+	assert.Equal(t, 128000, info.ContextWindow)
+	assert.Equal(t, 16384, info.MaxOutputTokens)
+	assert.Equal(t, "2023-10", info.KnowledgeCutoff)
+
+	_, ok = LookupModelInfo(ProviderClaude, "gpt-4o")
+	assert.False(t, ok, "entries are per provider")
+
+	_, ok = LookupModelInfo(ProviderOpenAI, "my-private-model")
+	assert.False(t, ok)
+}
+
+func TestLookupModelInfo_VersionedVariant(t *testing.T) {
+	info, ok := LookupModelInfo(ProviderOpenAI, "gpt-4o-mini-2024-07-18")
+	require.True(t, ok)
+	assert.Equal(t, "gpt-4o-mini-2024-07-18", info.Model)
+	assert.Equal(t, 16384, info.MaxOutputTokens, "longest prefix gpt-4o-mini wins over gpt-4")
+
+	info, ok = LookupModelInfo(ProviderOpenAI, "gpt-4-0613")
+	require.True(t, ok)
+	assert.Equal(t, 8192, info.ContextWindow)
+
+	_, ok = LookupModelInfo(ProviderOpenAI, "gpt-4o2")
+	assert.False(t, ok, "a prefix must end at a dash")
+}
+
+func TestRegisterModelInfo_Overrides(t *testing.T) {
+	resetModelRegistry(t)
+
+	require.NoError(t, RegisterModelInfo(ModelInfo{Provider: ProviderOpenAI, Model: "gpt-4o", MaxOutputTokens: 4096}))
+	info, ok := LookupModelInfo(ProviderOpenAI, "gpt-4o")
+	require.True(t, ok)
+	assert.Equal(t, 4096, info.MaxOutputTokens)
+	assert.Equal(t, 128000, info.ContextWindow, "unset fields keep the built-in value")
+
+	require.NoError(t, RegisterModelInfo(ModelInfo{Provider: ProviderOpenAI, Model: "ft:acme", ContextWindow: 4000}))
+	info, ok = LookupModelInfo(ProviderOpenAI, "ft:acme")
+	require.True(t, ok)
+	assert.Equal(t, 4000, info.ContextWindow)
+
+	assert.Error(t, RegisterModelInfo(ModelInfo{Model: "x"}))
+}
+
+func TestClientModelInfo(t *testing.T) {
+	claude, err := NewClaudeClient("key", "claude-3-5-haiku-20241022", nil)
+	require.NoError(t, err)
+	info, ok := GetModelInfo(claude)
+	require.True(t, ok)
+	assert.Equal(t, "2024-07", info.KnowledgeCutoff)
+
+	ollama, err := NewOllamaClient("llama3.1:70b", nil)
+	require.NoError(t, err)
+	info, ok = ollama.ModelInfo()
+	require.True(t, ok)
+	assert.Equal(t, "llama3.1:70b", info.Model)
+	assert.Equal(t, 131072, info.ContextWindow)
+
+	_, ok = GetModelInfo(NewMockClient("mock", "gpt-4o"))
+	assert.False(t, ok)
+}
+
+func TestRefreshModelInfo_GeminiLiveData(t *testing.T) {
+	resetModelRegistry(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/models", r.URL.Path)
+		assert.Equal(t, "key", r.URL.Query().Get("key"))
+		_, _ = w.Write([]byte(`{"models": [
+			{"name": "models/gemini-1.5-pro", "displayName": "Gemini 1.5 Pro", "inputTokenLimit": 2000000, "outputTokenLimit": 8192},
+			{"name": "models/gemini-exp-1206", "displayName": "Gemini Experimental", "inputTokenLimit": 32768, "outputTokenLimit": 4096}
+		]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewGeminiClient("key", "gemini-exp-1206", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	_, ok := client.ModelInfo()
+	assert.False(t, ok)
+
+	n, err := RefreshModelInfo(context.Background(), client)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	info, ok := client.ModelInfo()
+	require.True(t, ok)
+	assert.Equal(t, 32768, info.ContextWindow)
+	assert.Equal(t, "Gemini Experimental", info.DisplayName)
+
+	info, ok = LookupModelInfo(ProviderGemini, "gemini-1.5-pro")
+	require.True(t, ok)
+	assert.Equal(t, 2000000, info.ContextWindow, "live data overrides built-in limits")
+	assert.Equal(t, "2023-11", info.KnowledgeCutoff, "built-in cutoff is kept")
+}
+
+func TestListModels_OpenAIAndClaude(t *testing.T) {
+	resetModelRegistry(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/models":
+			if r.Header.Get("x-api-key") != "" {
+				assert.Equal(t, "1000", r.URL.Query().Get("limit"))
+				_, _ = w.Write([]byte(`{"data": [{"id": "claude-3-opus-20240229", "display_name": "Claude 3 Opus"}]}`))
+				return
+			}
+			assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"object": "list", "data": [{"id": "gpt-4o"}, {"id": "my-fine-tune"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	config := NewClientConfig().SetBaseURL(server.URL)
+
+	openai, err := NewOpenAIClient("key", "my-fine-tune", config)
+	require.NoError(t, err)
+	models, err := openai.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []ModelInfo{{Provider: ProviderOpenAI, Model: "gpt-4o"}, {Provider: ProviderOpenAI, Model: "my-fine-tune"}}, models)
+
+	_, err = RefreshModelInfo(context.Background(), openai)
+	require.NoError(t, err)
+	_, ok := openai.ModelInfo()
+	assert.False(t, ok, "a listed model without limits stays unknown")
+
+	claude, err := NewClaudeClient("key", "claude-3-opus-20240229", config)
+	require.NoError(t, err)
+	models, err = claude.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.Equal(t, "Claude 3 Opus", models[0].DisplayName)
+}
+
+func TestRefreshModelInfo_Unsupported(t *testing.T) {
+	_, err := RefreshModelInfo(context.Background(), NewMockClient("mock", "gpt-4o"))
+	assert.Error(t, err)
+
+	azure, err := NewAzureOpenAIClient("key", AzureConfig{Resource: "contoso", Deployment: "gpt-4o"}, nil)
+	require.NoError(t, err)
+	_, err = azure.ListModels(context.Background())
+	assert.Error(t, err)
+
+	info, ok := azure.ModelInfo()
+	require.True(t, ok, "a deployment named after its model resolves")
+	assert.Equal(t, 128000, info.ContextWindow)
+}
+
+func TestModelInfoFor(t *testing.T) {
+	resetModelRegistry(t)
+
+	info, ok := ModelInfoFor("claude-3-5-sonnet-20241022")
+	require.True(t, ok)
+	assert.Equal(t, ProviderClaude, info.Provider)
+	assert.Equal(t, 200000, info.ContextWindow)
+	assert.True(t, info.SupportsVision)
+	assert.True(t, info.SupportsTools)
+
+	info, ok = ModelInfoFor("gpt-4o-2024-08-06")
+	require.True(t, ok)
+	assert.Equal(t, ProviderOpenAI, info.Provider)
+	assert.Equal(t, 16384, info.MaxOutputTokens)
+
+	info, ok = ModelInfoFor("o1-mini")
+	require.True(t, ok)
+	assert.False(t, info.SupportsVision)
+	assert.False(t, info.SupportsTools)
+
+	_, ok = ModelInfoFor("my-private-model")
+	assert.False(t, ok)
+
+	require.NoError(t, RegisterModelInfo(ModelInfo{Provider: ProviderOllama, Model: "gpt-4o-2024-08-06", ContextWindow: 4096}))
+	info, ok = ModelInfoFor("gpt-4o-2024-08-06")
+	require.True(t, ok)
+	assert.Equal(t, ProviderOllama, info.Provider, "an exact name beats a dated variant on another provider")
+
+	require.NoError(t, RegisterModelInfo(ModelInfo{Provider: ProviderOllama, Model: "llama3", SupportsTools: true}))
+	info, _ = ModelInfoFor("llama3")
+	assert.True(t, info.SupportsTools, "overrides can add capabilities")
+	assert.Equal(t, 8192, info.ContextWindow)
+}
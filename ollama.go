@@ -0,0 +1,501 @@
+package chatdelta
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ollamaBaseURL is the default local Ollama endpoint; ClientConfig.BaseURL overrides it.
+const ollamaBaseURL = "http://localhost:11434"
+
+// OllamaClient implements the AIClient interface for a local Ollama server.
+// Ollama needs no API key.
+type OllamaClient struct {
+	model      string
+	config     *ClientConfig
+	httpClient *http.Client
+}
+
+// Ollama API request/response structures
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Images holds inline images; Ollama cannot fetch images by URL
+	Images [][]byte `json:"images,omitempty"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	TopK        *int     `json:"top_k,omitempty"`
+	NumPredict  *int     `json:"num_predict,omitempty"`
+	FreqPenalty *float64 `json:"frequency_penalty,omitempty"`
+	PresPenalty *float64 `json:"presence_penalty,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+	// Format is "json" or a JSON Schema object
+	Format json.RawMessage `json:"format,omitempty"`
+}
+
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason,omitempty"`
+	PromptEvalCount int           `json:"prompt_eval_count,omitempty"`
+	EvalCount       int           `json:"eval_count,omitempty"`
+	TotalDuration   int64         `json:"total_duration,omitempty"`
+	Error           string        `json:"error,omitempty"`
+	// info is filled in by sendRequest
+	info responseInfo
+}
+
+type ollamaErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewOllamaClient creates a new Ollama client. The server defaults to
+// http://localhost:11434; set ClientConfig.BaseURL to use another host.
+func NewOllamaClient(model string, config *ClientConfig) (*OllamaClient, error) {
+	if model == "" {
+		model = "llama3"
+	}
+
+	if config == nil {
+		config = NewClientConfig()
+	}
+
+	return &OllamaClient{
+		model:      model,
+		config:     config,
+		httpClient: newHTTPClient(config),
+	}, nil
+}
+
+// SendPrompt sends a single prompt to Ollama
+func (c *OllamaClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	return c.SendConversation(ctx, c.promptConversation(prompt))
+}
+
+// SendConversation sends a conversation to Ollama
+func (c *OllamaClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	response, err := c.SendConversationWithMetadata(ctx, conversation)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendPromptWithMetadata sends a prompt and returns the response with metadata.
+func (c *OllamaClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	return c.SendConversationWithMetadata(ctx, c.promptConversation(prompt))
+}
+
+// SendConversationWithMetadata sends a conversation and returns the response with metadata.
+func (c *OllamaClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	if c.config.keepAliveViaStreaming() {
+		return sendViaStream(ctx, c.config, conversation, c.streamRequest)
+	}
+
+	var result *AiResponse
+
+	operation := func() error {
+		start := time.Now()
+		response, err := c.sendRequest(ctx, conversation)
+		if err != nil {
+			return err
+		}
+		result = &AiResponse{
+			Content:  response.Message.Content,
+			Metadata: c.metadata(response, time.Since(start)),
+			Raw:      response.info.raw(c.config),
+		}
+		result.Content, err = postProcessContent(c.config, conversation, result.Content, &result.Metadata)
+		return err
+	}
+
+	if err := c.config.retry(ctx, operation); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// StreamPrompt streams a response for a single prompt
+func (c *OllamaClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	return c.StreamConversation(ctx, c.promptConversation(prompt))
+}
+
+// StreamConversation streams a response for a conversation
+func (c *OllamaClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
+	resultChan := make(chan StreamChunk, 10)
+
+	go func() {
+		defer close(resultChan)
+
+		ctx, cancel := c.config.withOperationTimeout(ctx)
+		defer cancel()
+
+		sink := newStreamSink(ctx, resultChan, c.config)
+		operation := func() error {
+			return c.streamRequest(ctx, conversation, sink)
+		}
+
+		if err := c.config.retry(ctx, operation); err != nil {
+			sink.fail(ctx, c.config, err)
+		}
+	}()
+
+	return resultChan, nil
+}
+
+// promptConversation wraps prompt in a conversation, adding the configured system message.
+func (c *OllamaClient) promptConversation(prompt string) *Conversation {
+	conversation := NewConversation()
+	if c.config.SystemMessage != nil {
+		conversation.AddSystemMessage(*c.config.SystemMessage)
+	}
+	conversation.AddUserMessage(prompt)
+	return conversation
+}
+
+// buildRequest converts a conversation and the client configuration into an Ollama request body
+func (c *OllamaClient) buildRequest(conversation *Conversation, stream bool) ollamaRequest {
+	messages := make([]ollamaMessage, len(conversation.Messages))
+	for i, msg := range conversation.Messages {
+		messages[i] = ollamaMessage{Role: msg.Role, Content: msg.Content}
+		for _, part := range msg.Parts {
+			switch {
+			case part.Type == ContentPartImage && part.Image != nil:
+				messages[i].Images = append(messages[i].Images, part.Image.Data)
+			case part.Type == ContentPartText:
+				messages[i].Content = strings.TrimPrefix(messages[i].Content+"\n\n"+part.Text, "\n\n")
+			}
+		}
+	}
+
+	options := &ollamaOptions{
+		Temperature: c.config.Temperature,
+		TopP:        c.config.TopP,
+		TopK:        c.config.TopK,
+		NumPredict:  c.config.MaxTokens,
+		FreqPenalty: c.config.FrequencyPenalty,
+		PresPenalty: c.config.PresencePenalty,
+		Seed:        c.config.Seed,
+		Stop:        c.config.StopSequences,
+	}
+	if reflect.ValueOf(*options).IsZero() {
+		options = nil
+	}
+
+	request := ollamaRequest{
+		Model:    c.model,
+		Messages: messages,
+		Stream:   stream,
+		Options:  options,
+	}
+	if format := c.config.ResponseFormat; format != nil {
+		request.Format = format.schema()
+		if request.Format == nil {
+			request.Format = json.RawMessage(`"json"`)
+		}
+	}
+	return request
+}
+
+// newHTTPRequest builds the HTTP request for conversation, applying any configured
+// request mutators to the body. The marshaled body is returned alongside the request.
+func (c *OllamaClient) newHTTPRequest(ctx context.Context, conversation *Conversation, stream bool) (*http.Request, []byte, error) {
+	conversation, err := prepareConversation(c.config, conversation)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateImages(ProviderOllama, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateVision(ProviderOllama, c.model, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateResponseFormat(c.config); err != nil {
+		return nil, nil, err
+	}
+	for _, msg := range conversation.Messages {
+		for _, part := range msg.Parts {
+			if part.Type == ContentPartImage && part.Image != nil && part.Image.URL != "" {
+				return nil, nil, NewInvalidParameterError("image", "Ollama only accepts inline images, not "+part.Image.URL)
+			}
+		}
+	}
+	if err := ValidateMaxTokens(ProviderOllama, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateStopSequences(ProviderOllama, c.config); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := marshalRequestBody(c.config, ProviderOllama, c.model, c.buildRequest(conversation, stream))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := c.newRawHTTPRequest(ctx, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, body, nil
+}
+
+// newRawHTTPRequest builds the /api/chat request for an already-marshaled body.
+func (c *OllamaClient) newRawHTTPRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL(c.config, ollamaBaseURL, "/api/chat"), bytes.NewReader(body))
+	if err != nil {
+		return nil, NewConnectionError(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	applyExtraHeaders(req, c.config)
+
+	return req, nil
+}
+
+// SendRaw posts body verbatim to the Ollama chat endpoint using the client's
+// credentials, extra headers, and retry policy, and returns the raw response body.
+// Request mutators are not applied.
+func (c *OllamaClient) SendRaw(ctx context.Context, body json.RawMessage) (json.RawMessage, error) {
+	if err := checkRawBody(c.config, c.model, body); err != nil {
+		return nil, err
+	}
+	return sendRaw(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		return c.newRawHTTPRequest(ctx, body)
+	}, c.parseAPIError)
+}
+
+// DryRun returns the request that would be sent for conversation without sending it.
+func (c *OllamaClient) DryRun(conversation *Conversation, stream bool) (*PreparedRequest, error) {
+	req, body, err := c.newHTTPRequest(context.Background(), conversation, stream)
+	if err != nil {
+		return nil, err
+	}
+	return newPreparedRequest(req, body), nil
+}
+
+// sendRequest sends a non-streaming request to the Ollama API
+func (c *OllamaClient) sendRequest(ctx context.Context, conversation *Conversation) (*ollamaResponse, error) {
+	req, _, err := c.newHTTPRequest(ctx, conversation, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, NewTimeoutError(c.config.Timeout)
+		}
+		return nil, NewConnectionError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, NewConnectionError(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseAPIError(resp.StatusCode, resp.Header, body)
+	}
+
+	var response ollamaResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, NewJSONParseError(err)
+	}
+	response.info = responseInfo{body: body}
+
+	return &response, nil
+}
+
+// streamRequest handles streaming requests. Ollama streams newline-delimited JSON
+// objects; the final one has done set and carries the token counts.
+func (c *OllamaClient) streamRequest(ctx context.Context, conversation *Conversation, sink *streamSink) error {
+	req, _, err := c.newHTTPRequest(ctx, conversation, true)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return NewTimeoutError(c.config.Timeout)
+		}
+		return NewConnectionError(err)
+	}
+	defer resp.Body.Close()
+	defer closeOnDone(ctx, resp.Body)()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return c.parseAPIError(resp.StatusCode, resp.Header, body)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var response ollamaResponse
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			continue // Skip malformed chunks
+		}
+		sink.event(line)
+		if response.Error != "" {
+			return NewServerError(http.StatusInternalServerError, response.Error)
+		}
+
+		if response.Message.Content != "" {
+			sink.send(StreamChunk{Content: response.Message.Content, Finished: false})
+		}
+		if response.Done {
+			metadata := c.metadata(&response, time.Since(start))
+			sink.send(StreamChunk{Content: "", Finished: true, Metadata: &metadata})
+			return nil
+		}
+	}
+
+	if err := streamReadError(ctx, scanner.Err()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// metadata converts a final Ollama response into ResponseMetadata.
+func (c *OllamaClient) metadata(response *ollamaResponse, latency time.Duration) ResponseMetadata {
+	model := response.Model
+	if model == "" {
+		model = c.model
+	}
+	return ResponseMetadata{
+		ModelUsed:              model,
+		PromptTokens:           response.PromptEvalCount,
+		CompletionTokens:       response.EvalCount,
+		TotalTokens:            response.PromptEvalCount + response.EvalCount,
+		FinishReason:           response.DoneReason,
+		NormalizedFinishReason: NormalizeFinishReason(ProviderOllama, response.DoneReason),
+		LatencyMs:              latency.Milliseconds(),
+		ServedVia:              ServedViaPrimary,
+	}
+}
+
+// parseAPIError maps Ollama's {"error": "..."} payloads onto ClientErrors
+func (c *OllamaClient) parseAPIError(statusCode int, header http.Header, body []byte) *ClientError {
+	message := string(body)
+	var errorResp ollamaErrorResponse
+	if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error != "" {
+		message = errorResp.Error
+	}
+
+	switch statusCode {
+	case http.StatusNotFound:
+		if strings.Contains(strings.ToLower(message), "model") {
+			return NewInvalidModelError(c.model)
+		}
+		return NewServerError(statusCode, message)
+	case http.StatusUnauthorized:
+		return NewInvalidAPIKeyError()
+	case http.StatusForbidden:
+		return NewPermissionDeniedError("Ollama API")
+	case http.StatusTooManyRequests:
+		return NewRateLimitError(parseRetryAfter(header, c.config.clock().Now()))
+	case http.StatusBadRequest:
+		return NewBadRequestError(message)
+	default:
+		return NewServerError(statusCode, message)
+	}
+}
+
+// SupportsStreaming returns true (Ollama supports streaming)
+func (c *OllamaClient) SupportsStreaming() bool {
+	return true
+}
+
+// SupportsSeed returns true (Ollama honours the seed option)
+func (c *OllamaClient) SupportsSeed() bool {
+	return true
+}
+
+// SupportsConversations returns true (Ollama supports conversations)
+func (c *OllamaClient) SupportsConversations() bool {
+	return true
+}
+
+// Name returns the client name
+func (c *OllamaClient) Name() string {
+	return c.config.nameOr("Ollama")
+}
+
+// Model returns the model identifier
+func (c *OllamaClient) Model() string {
+	return c.model
+}
+
+// clientConfig returns the client's configuration.
+func (c *OllamaClient) clientConfig() *ClientConfig {
+	return c.config
+}
+
+// WithOptions returns a copy of the client with opts applied over its config.
+func (c *OllamaClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	config, err := applyRequestOptions(c.config, opts)
+	if err != nil {
+		return nil, err
+	}
+	clone := *c
+	clone.config = config
+	return &clone, nil
+}
+
+// ModelInfo returns registry data for the configured model. A size or quantization
+// tag ("llama3.1:70b") falls back to the untagged model's entry.
+func (c *OllamaClient) ModelInfo() (ModelInfo, bool) {
+	if info, ok := LookupModelInfo(ProviderOllama, c.model); ok {
+		return info, true
+	}
+	name, _, tagged := strings.Cut(c.model, ":")
+	if !tagged {
+		return ModelInfo{}, false
+	}
+	info, ok := LookupModelInfo(ProviderOllama, name)
+	if !ok {
+		return ModelInfo{}, false
+	}
+	info.Model = c.model
+	return info, true
+}
+
+// EstimatePromptTokens estimates the prompt size of conversation with the token
+// estimator registered for the configured model.
+func (c *OllamaClient) EstimatePromptTokens(conversation *Conversation) int {
+	return estimateConversationTokens(conversation.Messages, TokenEstimatorForModel(c.model))
+}
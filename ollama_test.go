@@ -0,0 +1,112 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaClient_CreateWithoutAPIKey(t *testing.T) {
+	client, err := CreateClient("ollama", "", "llama3", NewClientConfig())
+	require.NoError(t, err)
+	assert.Equal(t, "Ollama", client.Name())
+	assert.Equal(t, "llama3", client.Model())
+
+	client, err = CreateClient("ollama", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "llama3", client.Model())
+}
+
+func TestOllamaClient_SendConversation(t *testing.T) {
+	var got ollamaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &got))
+		_, _ = w.Write([]byte(`{"model":"llama3","message":{"role":"assistant","content":"Paris."},"done":true,"done_reason":"stop","prompt_eval_count":20,"eval_count":3}`))
+	}))
+	defer server.Close()
+
+	client, err := NewOllamaClient("llama3", NewClientConfig().SetBaseURL(server.URL).SetTemperature(0.2))
+	require.NoError(t, err)
+
+	conv := NewConversation()
+	conv.AddSystemMessage("Be brief.")
+	conv.AddUserMessage("Capital of France?")
+	response, err := client.SendConversationWithMetadata(context.Background(), conv)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Paris.", response.Content)
+	assert.Equal(t, "llama3", response.Metadata.ModelUsed)
+	assert.Equal(t, 20, response.Metadata.PromptTokens)
+	assert.Equal(t, 3, response.Metadata.CompletionTokens)
+	assert.Equal(t, 23, response.Metadata.TotalTokens)
+	assert.Equal(t, "stop", response.Metadata.FinishReason)
+	assert.Equal(t, FinishReasonStop, response.Metadata.NormalizedFinishReason)
+	assert.Empty(t, response.Metadata.RequestID, "Ollama has no request ID")
+	assert.False(t, got.Stream)
+	require.Len(t, got.Messages, 2)
+	assert.Equal(t, "system", got.Messages[0].Role)
+	require.NotNil(t, got.Options)
+	assert.Equal(t, 0.2, *got.Options.Temperature)
+}
+
+func TestOllamaClient_StreamNDJSON(t *testing.T) {
+	server := transcriptServer(t, "ollama_stream.ndjson")
+	client, err := NewOllamaClient("llama3", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+
+	assert.Equal(t, "Hello, world!", content)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, 12, last.Metadata.PromptTokens)
+	assert.Equal(t, 4, last.Metadata.CompletionTokens)
+	assert.Equal(t, "stop", last.Metadata.FinishReason)
+}
+
+func TestOllamaClient_ErrorMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode string
+	}{
+		{"missing model", http.StatusNotFound, `{"error":"model \"nope\" not found, try pulling it first"}`, "invalid_model"},
+		{"bad request", http.StatusBadRequest, `{"error":"invalid options"}`, "bad_request"},
+		{"rate limited", http.StatusTooManyRequests, `{"error":"server busy"}`, "rate_limit"},
+		{"server error", http.StatusInternalServerError, `{"error":"out of memory"}`, "server_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewOllamaClient("nope", NewClientConfig().SetRetries(0))
+			require.NoError(t, err)
+			client.httpClient.Transport = cannedResponse(tt.status, tt.body)
+
+			_, err = client.SendPrompt(context.Background(), "hi")
+			var clientErr *ClientError
+			require.True(t, errors.As(err, &clientErr), "unexpected error: %v", err)
+			assert.Equal(t, tt.wantCode, clientErr.Code)
+		})
+	}
+}
+
+func TestOllamaClient_BuildRequestTopK(t *testing.T) {
+	client, err := NewOllamaClient("llama3", NewClientConfig().SetTopK(40))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"options":{"top_k":40}`)
+}
@@ -8,20 +8,95 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
-// OpenAIClient implements the AIClient interface for OpenAI's API
+// openAIBaseURL is the default OpenAI API endpoint; ClientConfig.BaseURL overrides it.
+const openAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIClient implements the AIClient interface for OpenAI's API. The same client
+// serves Azure OpenAI and other servers speaking the OpenAI chat protocol; they differ
+// only in the endpoint and the auth header.
 type OpenAIClient struct {
 	apiKey     string
 	model      string
 	config     *ClientConfig
 	httpClient *http.Client
+	endpoint   openAIEndpoint
+	// sendN sends ClientConfig.N as n; it is set only on the copy SendConversationN
+	// uses, so other calls never pay for choices they discard
+	sendN bool
+}
+
+// openAIEndpoint describes where an OpenAI-protocol client sends requests and how it
+// authenticates.
+type openAIEndpoint struct {
+	// name is reported by Name()
+	name string
+	// provider keys the model registry for ModelInfo and ListModels
+	provider Provider
+	// baseURL is used when ClientConfig.BaseURL is unset
+	baseURL string
+	// path is appended to the base URL, including any query string
+	path string
+	// modelsPath lists models; empty when the endpoint has no listing
+	modelsPath string
+	// authHeader carries authPrefix+apiKey; it is omitted when the key is empty
+	authHeader string
+	authPrefix string
+}
+
+// openAIDefaultEndpoint is api.openai.com with Bearer auth.
+var openAIDefaultEndpoint = openAIEndpoint{
+	name:       "OpenAI",
+	provider:   ProviderOpenAI,
+	baseURL:    openAIBaseURL,
+	path:       "/chat/completions",
+	modelsPath: "/models",
+	authHeader: "Authorization",
+	authPrefix: "Bearer ",
 }
 
 // OpenAI API request/response structures
 type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a string, or []openAIContentPart for multimodal messages
+	Content    interface{}      `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAITool struct {
+	Type     string            `json:"type"`
+	Function openAIFunctionDef `json:"function"`
+}
+
+type openAIFunctionDef struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+// openAIFunctionCall carries the arguments as a JSON-encoded string.
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 type openAIRequest struct {
@@ -33,32 +108,73 @@ type openAIRequest struct {
 	TopP        *float64        `json:"top_p,omitempty"`
 	FreqPenalty *float64        `json:"frequency_penalty,omitempty"`
 	PresPenalty *float64        `json:"presence_penalty,omitempty"`
+	Seed        *int            `json:"seed,omitempty"`
+	Stop        []string        `json:"stop,omitempty"`
+	N           *int            `json:"n,omitempty"`
+	LogitBias   map[int]int     `json:"logit_bias,omitempty"`
+	Logprobs    bool            `json:"logprobs,omitempty"`
+	TopLogprobs *int            `json:"top_logprobs,omitempty"`
+	Tools       []openAITool    `json:"tools,omitempty"`
+	// ToolChoice is a mode string or a {"type": "function"} object
+	ToolChoice interface{} `json:"tool_choice,omitempty"`
+	// StreamOptions asks for a final usage event when streaming
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type openAIChoice struct {
 	Index   int `json:"index"`
 	Message struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
+		Role      string           `json:"role"`
+		Content   string           `json:"content"`
+		ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
 	} `json:"message"`
 	Delta struct {
 		Role    string `json:"role,omitempty"`
 		Content string `json:"content,omitempty"`
 	} `json:"delta"`
 	FinishReason *string `json:"finish_reason"`
+	Logprobs     *struct {
+		Content []TokenLogprob `json:"content"`
+	} `json:"logprobs,omitempty"`
+}
+
+// logprobs returns the content token log probabilities of the choice, if any.
+func (choice *openAIChoice) logprobs() []TokenLogprob {
+	if choice.Logprobs == nil {
+		return nil
+	}
+	return choice.Logprobs.Content
 }
 
 type openAIResponse struct {
-	ID      string         `json:"id"`
-	Object  string         `json:"object"`
-	Created int64          `json:"created"`
-	Model   string         `json:"model"`
-	Choices []openAIChoice `json:"choices"`
-	Usage   struct {
+	ID                string         `json:"id"`
+	Object            string         `json:"object"`
+	Created           int64          `json:"created"`
+	Model             string         `json:"model"`
+	SystemFingerprint string         `json:"system_fingerprint,omitempty"`
+	Choices           []openAIChoice `json:"choices"`
+	Usage             struct {
 		PromptTokens     int `json:"prompt_tokens"`
 		CompletionTokens int `json:"completion_tokens"`
 		TotalTokens      int `json:"total_tokens"`
 	} `json:"usage,omitempty"`
+	// info is filled in by sendRequest
+	info responseInfo
 }
 
 type openAIErrorDetail struct {
@@ -86,12 +202,11 @@ func NewOpenAIClient(apiKey, model string, config *ClientConfig) (*OpenAIClient,
 	}
 
 	return &OpenAIClient{
-		apiKey: apiKey,
-		model:  model,
-		config: config,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		apiKey:     apiKey,
+		model:      model,
+		config:     config,
+		httpClient: newHTTPClient(config),
+		endpoint:   openAIDefaultEndpoint,
 	}, nil
 }
 
@@ -108,6 +223,17 @@ func (c *OpenAIClient) SendPrompt(ctx context.Context, prompt string) (string, e
 
 // SendConversation sends a conversation to OpenAI
 func (c *OpenAIClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	if c.config.keepAliveViaStreaming() {
+		response, err := sendViaStream(ctx, c.config, conversation, c.streamRequest)
+		if err != nil {
+			return "", err
+		}
+		return response.Content, nil
+	}
+
 	var result string
 	var lastErr error
 
@@ -123,11 +249,11 @@ func (c *OpenAIClient) SendConversation(ctx context.Context, conversation *Conve
 			return lastErr
 		}
 
-		result = response.Choices[0].Message.Content
-		return nil
+		result, err = postProcessContent(c.config, conversation, response.Choices[0].Message.Content, nil)
+		return err
 	}
 
-	err := ExecuteWithRetry(ctx, c.config.Retries, operation)
+	err := c.config.retry(ctx, operation)
 	if err != nil {
 		return "", err
 	}
@@ -153,26 +279,56 @@ func (c *OpenAIClient) StreamConversation(ctx context.Context, conversation *Con
 	go func() {
 		defer close(resultChan)
 
+		ctx, cancel := c.config.withOperationTimeout(ctx)
+		defer cancel()
+
+		sink := newStreamSink(ctx, resultChan, c.config)
 		operation := func() error {
-			return c.streamRequest(ctx, conversation, resultChan)
+			return c.streamRequest(ctx, conversation, sink)
 		}
 
-		err := ExecuteWithRetry(ctx, c.config.Retries, operation)
+		err := c.config.retry(ctx, operation)
 		if err != nil {
-			resultChan <- StreamChunk{Content: "", Finished: true}
+			sink.fail(ctx, c.config, err)
 		}
 	}()
 
 	return resultChan, nil
 }
 
-// sendRequest sends a request to the OpenAI API
-func (c *OpenAIClient) sendRequest(ctx context.Context, conversation *Conversation, stream bool) (*openAIResponse, error) {
+// openAIContentParts maps a multimodal message to content parts; inline images are
+// sent as data: URLs.
+func openAIContentParts(msg Message) []openAIContentPart {
+	var parts []openAIContentPart
+	for _, part := range messageParts(msg) {
+		switch {
+		case part.Type == ContentPartImage && part.Image != nil:
+			parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: part.Image.dataURL()}})
+		case part.Type == ContentPartText:
+			parts = append(parts, openAIContentPart{Type: "text", Text: part.Text})
+		}
+	}
+	return parts
+}
+
+// buildRequest converts a conversation and the client configuration into an OpenAI request body
+func (c *OpenAIClient) buildRequest(conversation *Conversation, stream bool) openAIRequest {
 	messages := make([]openAIMessage, len(conversation.Messages))
 	for i, msg := range conversation.Messages {
 		messages[i] = openAIMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		if len(msg.Parts) > 0 {
+			messages[i].Content = openAIContentParts(msg)
+		}
+		for _, call := range msg.ToolCalls {
+			messages[i].ToolCalls = append(messages[i].ToolCalls, openAIToolCall{
+				ID:       call.ID,
+				Type:     "function",
+				Function: openAIFunctionCall{Name: call.Name, Arguments: string(call.Arguments)},
+			})
 		}
 	}
 
@@ -185,21 +341,163 @@ func (c *OpenAIClient) sendRequest(ctx context.Context, conversation *Conversati
 		TopP:        c.config.TopP,
 		FreqPenalty: c.config.FrequencyPenalty,
 		PresPenalty: c.config.PresencePenalty,
+		Seed:        c.config.Seed,
+		Stop:        c.config.StopSequences,
+		LogitBias:   c.config.LogitBias,
+		Logprobs:    c.config.Logprobs,
+		TopLogprobs: c.config.TopLogprobs,
+	}
+	if c.sendN && !stream {
+		request.N = c.config.N
+	}
+	if len(c.config.Tools) > 0 {
+		request.Tools, request.ToolChoice = c.buildTools()
+	}
+	if stream {
+		request.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	if format := c.config.ResponseFormat; format != nil {
+		request.ResponseFormat = &openAIResponseFormat{Type: string(format.Type)}
+		if schema := format.schema(); schema != nil {
+			request.ResponseFormat.JSONSchema = &openAIJSONSchema{Name: format.Name, Schema: schema}
+		}
+	}
+	return request
+}
+
+// buildTools converts the configured tools and tool choice into OpenAI's schema.
+func (c *OpenAIClient) buildTools() ([]openAITool, interface{}) {
+	tools := make([]openAITool, len(c.config.Tools))
+	for i, tool := range c.config.Tools {
+		tools[i] = openAITool{
+			Type:     "function",
+			Function: openAIFunctionDef{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters},
+		}
+	}
+
+	var choice interface{}
+	if name, ok := c.config.ToolChoice.function(); ok {
+		choice = map[string]interface{}{"type": "function", "function": map[string]string{"name": name}}
+	} else if c.config.ToolChoice != "" {
+		choice = string(c.config.ToolChoice)
 	}
+	return tools, choice
+}
 
-	jsonData, err := json.Marshal(request)
+// toolCallsFromOpenAI converts the tool calls in a response message.
+func toolCallsFromOpenAI(calls []openAIToolCall) ([]ToolCall, error) {
+	var result []ToolCall
+	for _, call := range calls {
+		arguments, err := toolArguments([]byte(call.Function.Arguments))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: arguments})
+	}
+	return result, nil
+}
+
+// newHTTPRequest builds the HTTP request for conversation, applying any configured
+// request mutators to the body. The marshaled body is returned alongside the request.
+func (c *OpenAIClient) newHTTPRequest(ctx context.Context, conversation *Conversation, stream bool) (*http.Request, []byte, error) {
+	conversation, err := prepareConversation(c.config, conversation)
 	if err != nil {
-		return nil, NewJSONParseError(err)
+		return nil, nil, err
+	}
+	if err := ValidateImages(c.endpoint.provider, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateVision(c.endpoint.provider, c.model, conversation); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateMaxTokens(c.endpoint.provider, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateStopSequences(c.endpoint.provider, c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateTools(c.config); err != nil {
+		return nil, nil, err
+	}
+	if err := ValidateResponseFormat(c.config); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := marshalRequestBody(c.config, c.endpoint.provider, c.model, c.buildRequest(conversation, stream))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := c.newRawHTTPRequest(ctx, body, stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, body, nil
+}
+
+// newRawHTTPRequest builds the chat completions request for an already-marshaled body.
+func (c *OpenAIClient) newRawHTTPRequest(ctx context.Context, body []byte, stream bool) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL(c.config, c.endpoint.baseURL, c.endpoint.path), bytes.NewReader(body))
 	if err != nil {
 		return nil, NewConnectionError(err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	c.setAuthHeader(req)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	applyExtraHeaders(req, c.config, c.endpoint.authHeader)
 
+	return req, nil
+}
+
+// setAuthHeader adds the endpoint's auth header unless the client has no key.
+func (c *OpenAIClient) setAuthHeader(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set(c.endpoint.authHeader, c.endpoint.authPrefix+c.apiKey)
+	}
+}
+
+// SendRaw posts body verbatim to the OpenAI chat endpoint using the client's
+// credentials, extra headers, and retry policy, and returns the raw response body.
+// Request mutators are not applied.
+func (c *OpenAIClient) SendRaw(ctx context.Context, body json.RawMessage) (json.RawMessage, error) {
+	if err := checkRawBody(c.config, c.model, body); err != nil {
+		return nil, err
+	}
+	return sendRaw(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		return c.newRawHTTPRequest(ctx, body, false)
+	}, c.errorFromBody)
+}
+
+// StreamEvents streams conversation and delivers each chat completion chunk exactly
+// as OpenAI sends it.
+func (c *OpenAIClient) StreamEvents(ctx context.Context, conversation *Conversation) (<-chan StreamEvent, error) {
+	return streamEvents(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		req, _, err := c.newHTTPRequest(ctx, conversation, true)
+		return req, err
+	}, c.errorFromBody), nil
+}
+
+// DryRun returns the request that would be sent for conversation without sending it.
+// Credentials are redacted.
+func (c *OpenAIClient) DryRun(conversation *Conversation, stream bool) (*PreparedRequest, error) {
+	req, body, err := c.newHTTPRequest(context.Background(), conversation, stream)
+	if err != nil {
+		return nil, err
+	}
+	return newPreparedRequest(req, body), nil
+}
+
+// sendRequest sends a request to the OpenAI API
+func (c *OpenAIClient) sendRequest(ctx context.Context, conversation *Conversation, stream bool) (*openAIResponse, error) {
+	req, _, err := c.newHTTPRequest(ctx, conversation, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
@@ -215,56 +513,28 @@ func (c *OpenAIClient) sendRequest(ctx context.Context, conversation *Conversati
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errorResp openAIErrorResponse
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return nil, c.parseAPIError(resp.StatusCode, &errorResp.Error)
-		}
-		return nil, NewServerError(resp.StatusCode, string(body))
+		return nil, c.errorFromBody(resp.StatusCode, resp.Header, body)
 	}
 
 	var response openAIResponse
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, NewJSONParseError(err)
 	}
+	response.info = responseInfo{requestID: requestIDFromHeader(resp.Header, response.ID), latency: time.Since(start), body: body}
 
 	return &response, nil
 }
 
-// streamRequest handles streaming requests
-func (c *OpenAIClient) streamRequest(ctx context.Context, conversation *Conversation, resultChan chan<- StreamChunk) error {
-	messages := make([]openAIMessage, len(conversation.Messages))
-	for i, msg := range conversation.Messages {
-		messages[i] = openAIMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
-
-	request := openAIRequest{
-		Model:       c.model,
-		Messages:    messages,
-		Stream:      true,
-		Temperature: c.config.Temperature,
-		MaxTokens:   c.config.MaxTokens,
-		TopP:        c.config.TopP,
-		FreqPenalty: c.config.FrequencyPenalty,
-		PresPenalty: c.config.PresencePenalty,
-	}
-
-	jsonData, err := json.Marshal(request)
+// streamRequest handles streaming requests. With include_usage set, OpenAI sends the
+// finish reason and the token usage in separate events before [DONE], so metadata is
+// accumulated and attached to the Finished chunk.
+func (c *OpenAIClient) streamRequest(ctx context.Context, conversation *Conversation, sink *streamSink) error {
+	req, _, err := c.newHTTPRequest(ctx, conversation, true)
 	if err != nil {
-		return NewJSONParseError(err)
+		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return NewConnectionError(err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	req.Header.Set("Accept", "text/event-stream")
-
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		if ctx.Err() != nil {
@@ -273,23 +543,31 @@ func (c *OpenAIClient) streamRequest(ctx context.Context, conversation *Conversa
 		return NewConnectionError(err)
 	}
 	defer resp.Body.Close()
+	defer closeOnDone(ctx, resp.Body)()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		var errorResp openAIErrorResponse
-		if err := json.Unmarshal(body, &errorResp); err == nil {
-			return c.parseAPIError(resp.StatusCode, &errorResp.Error)
-		}
-		return NewServerError(resp.StatusCode, string(body))
+		return c.errorFromBody(resp.StatusCode, resp.Header, body)
 	}
 
+	metadata := &ResponseMetadata{ModelUsed: c.model, RequestID: requestIDFromHeader(resp.Header, ""), ServedVia: ServedViaPrimary}
+	finish := func() {
+		metadata.NormalizedFinishReason = NormalizeFinishReason(ProviderOpenAI, metadata.FinishReason)
+		metadata.LatencyMs = time.Since(start).Milliseconds()
+		sink.send(StreamChunk{Content: "", Finished: true, Metadata: metadata})
+	}
+
+	finished := false
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
+		if ctx.Err() != nil {
+			break
+		}
 		line := scanner.Text()
 		if strings.HasPrefix(line, "data: ") {
 			data := strings.TrimPrefix(line, "data: ")
 			if data == "[DONE]" {
-				resultChan <- StreamChunk{Content: "", Finished: true}
+				finish()
 				return nil
 			}
 
@@ -297,44 +575,78 @@ func (c *OpenAIClient) streamRequest(ctx context.Context, conversation *Conversa
 			if err := json.Unmarshal([]byte(data), &response); err != nil {
 				continue // Skip malformed chunks
 			}
+			sink.event(data)
 
-			if len(response.Choices) > 0 {
-				content := response.Choices[0].Delta.Content
-				finished := response.Choices[0].FinishReason != nil
+			if response.Model != "" {
+				metadata.ModelUsed = response.Model
+			}
+			if metadata.RequestID == "" {
+				metadata.RequestID = response.ID
+			}
+			if response.SystemFingerprint != "" {
+				metadata.SystemFingerprint = response.SystemFingerprint
+			}
+			if response.Usage.TotalTokens > 0 {
+				metadata.PromptTokens = response.Usage.PromptTokens
+				metadata.CompletionTokens = response.Usage.CompletionTokens
+				metadata.TotalTokens = response.Usage.TotalTokens
+			}
 
-				resultChan <- StreamChunk{
-					Content:  content,
-					Finished: finished,
+			if len(response.Choices) > 0 {
+				if content := response.Choices[0].Delta.Content; content != "" {
+					sink.send(StreamChunk{Content: content, Finished: false})
 				}
-
-				if finished {
-					return nil
+				metadata.Logprobs = append(metadata.Logprobs, response.Choices[0].logprobs()...)
+				if response.Choices[0].FinishReason != nil {
+					metadata.FinishReason = *response.Choices[0].FinishReason
+					finished = true
 				}
 			}
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return NewStreamReadError(err)
+	if err := streamReadError(ctx, scanner.Err()); err != nil {
+		return err
 	}
 
+	// Servers that omit [DONE] still get a terminal chunk once a finish reason arrived.
+	if finished {
+		finish()
+	}
 	return nil
 }
 
+// errorFromBody maps a non-200 response body onto a ClientError. Bodies that are not
+// OpenAI's JSON error envelope are used verbatim as the message.
+func (c *OpenAIClient) errorFromBody(statusCode int, header http.Header, body []byte) *ClientError {
+	var errorResp openAIErrorResponse
+	if err := json.Unmarshal(body, &errorResp); err == nil {
+		return c.parseAPIError(statusCode, header, &errorResp.Error)
+	}
+	return c.parseAPIError(statusCode, header, &openAIErrorDetail{Message: string(body)})
+}
+
 // parseAPIError parses OpenAI API errors
-func (c *OpenAIClient) parseAPIError(statusCode int, error *openAIErrorDetail) *ClientError {
+func (c *OpenAIClient) parseAPIError(statusCode int, header http.Header, error *openAIErrorDetail) *ClientError {
 	switch statusCode {
 	case http.StatusUnauthorized:
 		return NewInvalidAPIKeyError()
 	case http.StatusTooManyRequests:
-		return NewRateLimitError(nil)
+		// Exhausted billing also arrives as a 429 but will not clear on retry
+		if error.Code == "insufficient_quota" || error.Type == "insufficient_quota" {
+			return NewQuotaExceededError()
+		}
+		return NewRateLimitError(parseRetryAfter(header, c.config.clock().Now()))
 	case http.StatusBadRequest:
+		if error.Code == "context_length_exceeded" || isContextOverflowMessage(error.Message) {
+			return NewContextLengthError(error.Message)
+		}
 		if strings.Contains(strings.ToLower(error.Message), "model") {
 			return NewInvalidModelError(c.model)
 		}
 		return NewBadRequestError(error.Message)
 	case http.StatusForbidden:
-		return NewPermissionDeniedError("OpenAI API")
+		return NewPermissionDeniedError(c.endpoint.name + " API")
 	default:
 		return NewServerError(statusCode, error.Message)
 	}
@@ -352,6 +664,13 @@ func (c *OpenAIClient) SendPromptWithMetadata(ctx context.Context, prompt string
 
 // SendConversationWithMetadata sends a conversation and returns the response with metadata.
 func (c *OpenAIClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	if c.config.keepAliveViaStreaming() {
+		return sendViaStream(ctx, c.config, conversation, c.streamRequest)
+	}
+
 	var result *AiResponse
 	var lastErr error
 
@@ -365,36 +684,107 @@ func (c *OpenAIClient) SendConversationWithMetadata(ctx context.Context, convers
 			lastErr = NewMissingFieldError("choices")
 			return lastErr
 		}
-		finishReason := ""
-		if response.Choices[0].FinishReason != nil {
-			finishReason = *response.Choices[0].FinishReason
+		result, err = c.choiceResponse(conversation, response, 0)
+		return err
+	}
+
+	if err := c.config.retry(ctx, operation); err != nil {
+		return nil, err
+	}
+	_ = lastErr
+	return result, nil
+}
+
+// SendConversationN sends a conversation asking for ClientConfig.N completions and
+// returns every choice, in order. Token usage in each response's metadata covers
+// the whole request.
+func (c *OpenAIClient) SendConversationN(ctx context.Context, conversation *Conversation) ([]*AiResponse, error) {
+	ctx, cancel := c.config.withOperationTimeout(ctx)
+	defer cancel()
+
+	multi := *c
+	multi.sendN = true
+
+	var results []*AiResponse
+	operation := func() error {
+		response, err := multi.sendRequest(ctx, conversation, false)
+		if err != nil {
+			return err
+		}
+		if len(response.Choices) == 0 {
+			return NewMissingFieldError("choices")
 		}
-		result = &AiResponse{
-			Content: response.Choices[0].Message.Content,
-			Metadata: ResponseMetadata{
-				ModelUsed:        response.Model,
-				PromptTokens:     response.Usage.PromptTokens,
-				CompletionTokens: response.Usage.CompletionTokens,
-				TotalTokens:      response.Usage.TotalTokens,
-				FinishReason:     finishReason,
-				RequestID:        response.ID,
-			},
+		results = make([]*AiResponse, len(response.Choices))
+		for i := range response.Choices {
+			if results[i], err = c.choiceResponse(conversation, response, i); err != nil {
+				return err
+			}
 		}
 		return nil
 	}
 
-	if err := ExecuteWithRetry(ctx, c.config.Retries, operation); err != nil {
+	if err := c.config.retry(ctx, operation); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// choiceResponse converts choice i of response to an AiResponse.
+func (c *OpenAIClient) choiceResponse(conversation *Conversation, response *openAIResponse, i int) (*AiResponse, error) {
+	choice := response.Choices[i]
+	finishReason := ""
+	if choice.FinishReason != nil {
+		finishReason = *choice.FinishReason
+	}
+	toolCalls, err := toolCallsFromOpenAI(choice.Message.ToolCalls)
+	if err != nil {
+		return nil, err
+	}
+	result := &AiResponse{
+		Content:   choice.Message.Content,
+		ToolCalls: toolCalls,
+		Metadata: ResponseMetadata{
+			ModelUsed:              response.Model,
+			PromptTokens:           response.Usage.PromptTokens,
+			CompletionTokens:       response.Usage.CompletionTokens,
+			TotalTokens:            response.Usage.TotalTokens,
+			FinishReason:           finishReason,
+			NormalizedFinishReason: NormalizeFinishReason(ProviderOpenAI, finishReason),
+			RequestID:              response.info.requestID,
+			LatencyMs:              response.info.latency.Milliseconds(),
+			ServedVia:              ServedViaPrimary,
+			SystemFingerprint:      response.SystemFingerprint,
+			Logprobs:               choice.logprobs(),
+		},
+		Raw: response.info.raw(c.config),
+	}
+	result.Content, err = postProcessContent(c.config, conversation, result.Content, &result.Metadata)
+	if err != nil {
 		return nil, err
 	}
-	_ = lastErr
 	return result, nil
 }
 
+// SupportsMultipleCompletions returns true (OpenAI returns N choices per request)
+func (c *OpenAIClient) SupportsMultipleCompletions() bool {
+	return true
+}
+
+// SupportsLogprobs returns true (OpenAI returns token log probabilities)
+func (c *OpenAIClient) SupportsLogprobs() bool {
+	return true
+}
+
 // SupportsStreaming returns true (OpenAI supports streaming)
 func (c *OpenAIClient) SupportsStreaming() bool {
 	return true
 }
 
+// SupportsSeed returns true (OpenAI honours the seed parameter)
+func (c *OpenAIClient) SupportsSeed() bool {
+	return true
+}
+
 // SupportsConversations returns true (OpenAI supports conversations)
 func (c *OpenAIClient) SupportsConversations() bool {
 	return true
@@ -402,10 +792,75 @@ func (c *OpenAIClient) SupportsConversations() bool {
 
 // Name returns the client name
 func (c *OpenAIClient) Name() string {
-	return "OpenAI"
+	return c.config.nameOr(c.endpoint.name)
 }
 
 // Model returns the model identifier
 func (c *OpenAIClient) Model() string {
 	return c.model
 }
+
+// clientConfig returns the client's configuration.
+func (c *OpenAIClient) clientConfig() *ClientConfig {
+	return c.config
+}
+
+// WithOptions returns a copy of the client with opts applied over its config.
+func (c *OpenAIClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	config, err := applyRequestOptions(c.config, opts)
+	if err != nil {
+		return nil, err
+	}
+	clone := *c
+	clone.config = config
+	return &clone, nil
+}
+
+// ModelInfo returns registry data for the configured model. Azure deployments and
+// OpenAI-compatible servers are looked up under ProviderOpenAI too, so a deployment
+// named after its model resolves; register other names with RegisterModelInfo.
+func (c *OpenAIClient) ModelInfo() (ModelInfo, bool) {
+	return LookupModelInfo(c.endpoint.provider, c.model)
+}
+
+// EstimatePromptTokens estimates the prompt size of conversation with the token
+// estimator registered for the configured model.
+func (c *OpenAIClient) EstimatePromptTokens(conversation *Conversation) int {
+	return estimateConversationTokens(conversation.Messages, TokenEstimatorForModel(c.model))
+}
+
+// openAIModelList is the GET /models response. OpenAI reports no limits.
+type openAIModelList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// ListModels returns the models visible to the API key. Only IDs are reported.
+func (c *OpenAIClient) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	if c.endpoint.modelsPath == "" {
+		return nil, NewInvalidParameterError("client", c.endpoint.name+" does not support listing models")
+	}
+	body, err := sendRaw(ctx, c.httpClient, c.config, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", endpointURL(c.config, c.endpoint.baseURL, c.endpoint.modelsPath), nil)
+		if err != nil {
+			return nil, NewConnectionError(err)
+		}
+		c.setAuthHeader(req)
+		applyExtraHeaders(req, c.config, c.endpoint.authHeader)
+		return req, nil
+	}, c.errorFromBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var list openAIModelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, NewJSONParseError(err)
+	}
+	models := make([]ModelInfo, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, ModelInfo{Provider: c.endpoint.provider, Model: m.ID})
+	}
+	return models, nil
+}
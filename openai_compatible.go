@@ -0,0 +1,60 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// openai_compatible.go adds the "openai-compatible" provider for the many servers that
+// speak the OpenAI chat protocol (Groq, Together, DeepSeek, OpenRouter, LM Studio,
+// vLLM, ...). It reuses OpenAIClient with a caller-supplied endpoint.
+package chatdelta
+
+import (
+	"net/url"
+	"os"
+)
+
+// openAICompatibleBaseURLEnv supplies the endpoint when ClientConfig.BaseURL is unset.
+const openAICompatibleBaseURLEnv = "OPENAI_COMPATIBLE_BASE_URL"
+
+// NewOpenAICompatibleClient creates a client for a server speaking the OpenAI chat
+// protocol at ClientConfig.BaseURL (e.g. "https://api.groq.com/openai/v1"), to which
+// "/chat/completions" is appended. Any model name is accepted. apiKey may be empty for
+// local servers, in which case no Authorization header is sent.
+//
+// Name reports "OpenAI-compatible (host)" so several such clients can be told apart.
+func NewOpenAICompatibleClient(apiKey, model string, config *ClientConfig) (*OpenAIClient, error) {
+	if config == nil || config.BaseURL == nil || *config.BaseURL == "" {
+		return nil, NewMissingConfigError("BaseURL for provider: openai-compatible")
+	}
+	if model == "" {
+		return nil, NewMissingConfigError("model for provider: openai-compatible")
+	}
+
+	client := &OpenAIClient{
+		apiKey:     apiKey,
+		model:      model,
+		config:     config,
+		httpClient: newHTTPClient(config),
+		endpoint:   openAIDefaultEndpoint,
+	}
+	client.endpoint.name = "OpenAI-compatible (" + endpointHost(*config.BaseURL) + ")"
+	return client, nil
+}
+
+// newOpenAICompatibleClientFromConfig builds the client for CreateClient, taking the
+// base URL from the environment when the config does not set one.
+func newOpenAICompatibleClientFromConfig(apiKey, model string, config *ClientConfig) (AIClient, error) {
+	if config.BaseURL == nil || *config.BaseURL == "" {
+		if base := os.Getenv(openAICompatibleBaseURLEnv); base != "" {
+			withBase := *config
+			config = withBase.SetBaseURL(base)
+		}
+	}
+	return NewOpenAICompatibleClient(apiKey, model, config)
+}
+
+// endpointHost returns the host (with port) of rawURL, or rawURL itself if it does
+// not parse.
+func endpointHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
@@ -0,0 +1,61 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAICompatibleClient_LocalServerWithoutKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"), "no key means no auth header")
+		_, _ = w.Write([]byte(`{"id":"cmpl-1","model":"qwen2.5-7b-instruct","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	defer server.Close()
+
+	client, err := CreateClient("openai-compatible", "", "qwen2.5-7b-instruct", NewClientConfig().SetBaseURL(server.URL+"/v1"))
+	require.NoError(t, err)
+	assert.Equal(t, "OpenAI-compatible ("+server.Listener.Addr().String()+")", client.Name())
+
+	response, err := client.SendPromptWithMetadata(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hi", response.Content)
+	assert.Equal(t, 4, response.Metadata.TotalTokens)
+}
+
+func TestOpenAICompatibleClient_BearerAuth(t *testing.T) {
+	client, err := NewOpenAICompatibleClient("gsk-test", "llama-3.1-70b", NewClientConfig().SetBaseURL("https://api.groq.com/openai/v1"))
+	require.NoError(t, err)
+	assert.Equal(t, "OpenAI-compatible (api.groq.com)", client.Name())
+
+	prepared, err := client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.groq.com/openai/v1/chat/completions", prepared.URL)
+	assert.Equal(t, redactedValue, prepared.Header.Get("Authorization"))
+}
+
+func TestOpenAICompatibleClient_RequiresBaseURLAndModel(t *testing.T) {
+	t.Setenv(openAICompatibleBaseURLEnv, "")
+
+	_, err := CreateClient("openai-compatible", "", "some-model", nil)
+	assert.Error(t, err, "BaseURL is required")
+
+	_, err = CreateClient("openai-compatible", "", "", NewClientConfig().SetBaseURL("http://localhost:1234/v1"))
+	assert.Error(t, err, "a model is required")
+}
+
+func TestOpenAICompatibleClient_BaseURLFromEnv(t *testing.T) {
+	t.Setenv(openAICompatibleBaseURLEnv, "http://localhost:1234/v1")
+	config := NewClientConfig()
+
+	client, err := CreateClient("openai-compatible", "", "local-model", config)
+	require.NoError(t, err)
+	assert.Equal(t, "OpenAI-compatible (localhost:1234)", client.Name())
+	assert.Nil(t, config.BaseURL, "the caller's config is not modified")
+	assert.Contains(t, GetAvailableProviders(), "openai-compatible")
+}
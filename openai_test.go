@@ -0,0 +1,296 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripFunc adapts a function into an http.RoundTripper so tests can serve
+// canned provider responses without a network connection.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// cannedResponse returns a RoundTripper that always answers with status and body.
+func cannedResponse(status int, body string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+}
+
+// metadataServer starts a server answering every request with header and a JSON body
+// after a short delay, so measured latency is non-zero.
+func metadataServer(t *testing.T, header http.Header, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		time.Sleep(5 * time.Millisecond)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// transcriptServer starts a server that replays the recorded SSE transcript
+// testdata/name for every request.
+func transcriptServer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	transcript, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write(transcript)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// collectStream drains ch, returning the concatenated content and the final chunk.
+func collectStream(t *testing.T, ch <-chan StreamChunk) (string, StreamChunk) {
+	t.Helper()
+	var content strings.Builder
+	var last StreamChunk
+	for chunk := range ch {
+		content.WriteString(chunk.Content)
+		last = chunk
+	}
+	require.True(t, last.Finished, "stream must end with a Finished chunk")
+	return content.String(), last
+}
+
+func TestOpenAIClient_BuildRequestSeed(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetSeed(42))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"seed":42`)
+
+	client, err = NewOpenAIClient("test-key", "gpt-4o", nil)
+	require.NoError(t, err)
+	body, err = json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), `"seed"`)
+}
+
+func TestOpenAIClient_BuildRequestOmitsTopK(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetTopK(40))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "top_k")
+}
+
+func TestOpenAIClient_BuildRequestLogitBias(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetLogitBias(map[int]int{50256: -100, 1734: 100}))
+	require.NoError(t, err)
+
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	var sent struct {
+		LogitBias map[string]int `json:"logit_bias"`
+	}
+	require.NoError(t, json.Unmarshal(body, &sent))
+	assert.Equal(t, map[string]int{"50256": -100, "1734": 100}, sent.LogitBias)
+
+	client, err = NewOpenAIClient("test-key", "gpt-4o", NewClientConfig())
+	require.NoError(t, err)
+	body, err = json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "logit_bias")
+}
+
+func TestOpenAIClient_BuildRequestLogprobs(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetTopLogprobs(2))
+	require.NoError(t, err)
+	body, err := json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"logprobs":true,"top_logprobs":2`)
+
+	client, err = NewOpenAIClient("test-key", "gpt-4o", nil)
+	require.NoError(t, err)
+	body, err = json.Marshal(client.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "logprobs")
+}
+
+func TestOpenAIClient_ParsesLogprobs(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetTopLogprobs(2).SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK, `{
+		"id": "chatcmpl-1",
+		"model": "gpt-4o-2024-08-06",
+		"choices": [{
+			"index": 0,
+			"message": {"role": "assistant", "content": "Positive"},
+			"logprobs": {"content": [{
+				"token": "Positive",
+				"logprob": -0.0123,
+				"bytes": [80, 111, 115, 105, 116, 105, 118, 101],
+				"top_logprobs": [
+					{"token": "Positive", "logprob": -0.0123, "bytes": [80, 111, 115, 105, 116, 105, 118, 101]},
+					{"token": "Negative", "logprob": -4.41, "bytes": null}
+				]
+			}]},
+			"finish_reason": "stop"
+		}],
+		"usage": {"prompt_tokens": 9, "completion_tokens": 1, "total_tokens": 10}
+	}`)
+
+	resp, err := client.SendPromptWithMetadata(context.Background(), "Classify: I love it")
+	require.NoError(t, err)
+	assert.Equal(t, "Positive", resp.Content)
+	require.Len(t, resp.Metadata.Logprobs, 1)
+	token := resp.Metadata.Logprobs[0]
+	assert.Equal(t, "Positive", token.Token)
+	assert.InDelta(t, -0.0123, token.Logprob, 1e-9)
+	assert.Equal(t, []int{80, 111, 115, 105, 116, 105, 118, 101}, token.Bytes)
+	require.Len(t, token.TopLogprobs, 2)
+	assert.Equal(t, TopLogprob{Token: "Negative", Logprob: -4.41}, token.TopLogprobs[1])
+}
+
+func TestOpenAIClient_StreamCollectsLogprobs(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetLogprobs(true).SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK, strings.Join([]string{
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"Hel"},"logprobs":{"content":[{"token":"Hel","logprob":-0.5}]}}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"lo"},"logprobs":{"content":[{"token":"lo","logprob":-0.25}]},"finish_reason":"stop"}]}`,
+		`data: [DONE]`,
+	}, "\n\n"))
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "Hello", content)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, []TokenLogprob{{Token: "Hel", Logprob: -0.5}, {Token: "lo", Logprob: -0.25}}, last.Metadata.Logprobs)
+}
+
+func TestSupportsLogprobs(t *testing.T) {
+	openai, _ := NewOpenAIClient("k", "", nil)
+	claude, _ := NewClaudeClient("k", "", nil)
+	gemini, _ := NewGeminiClient("k", "", nil)
+
+	assert.True(t, SupportsLogprobs(openai))
+	assert.False(t, SupportsLogprobs(claude))
+	assert.False(t, SupportsLogprobs(gemini))
+	assert.True(t, GetClientInfo(openai).SupportsLogprobs)
+}
+
+func TestOpenAIClient_SystemFingerprint(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetSeed(7).SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK, `{
+		"id": "chatcmpl-1",
+		"model": "gpt-4o-2024-08-06",
+		"system_fingerprint": "fp_abc123",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "hello"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4}
+	}`)
+
+	resp, err := client.SendPromptWithMetadata(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", resp.Content)
+	assert.Equal(t, "fp_abc123", resp.Metadata.SystemFingerprint)
+}
+
+func TestOpenAIClient_MetadataFields(t *testing.T) {
+	server := metadataServer(t, http.Header{"X-Request-Id": {"req_abc"}}, `{
+		"id": "chatcmpl-1",
+		"model": "gpt-4o-2024-08-06",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "hello"}, "finish_reason": "length"}],
+		"usage": {"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4}
+	}`)
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetBaseURL(server.URL).SetRetries(0))
+	require.NoError(t, err)
+
+	resp, err := client.SendPromptWithMetadata(context.Background(), "hi")
+	require.NoError(t, err)
+	meta := resp.Metadata
+	assert.Equal(t, "gpt-4o-2024-08-06", meta.ModelUsed)
+	assert.Equal(t, 3, meta.PromptTokens)
+	assert.Equal(t, 1, meta.CompletionTokens)
+	assert.Equal(t, 4, meta.TotalTokens)
+	assert.Equal(t, "length", meta.FinishReason)
+	assert.Equal(t, FinishReasonLength, meta.NormalizedFinishReason)
+	assert.Equal(t, "req_abc", meta.RequestID)
+	assert.GreaterOrEqual(t, meta.LatencyMs, int64(5))
+}
+
+func TestSupportsSeed(t *testing.T) {
+	openai, _ := NewOpenAIClient("k", "", nil)
+	claude, _ := NewClaudeClient("k", "", nil)
+	gemini, _ := NewGeminiClient("k", "", nil)
+
+	assert.True(t, SupportsSeed(openai))
+	assert.False(t, SupportsSeed(claude))
+	assert.True(t, SupportsSeed(gemini))
+	assert.False(t, SupportsSeed(NewMockClient("mock", "")))
+	assert.True(t, GetClientInfo(openai).SupportsSeed)
+}
+
+func TestOpenAIClient_StreamFinalChunkMetadata(t *testing.T) {
+	server := transcriptServer(t, "openai_stream.sse")
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+
+	assert.Equal(t, "Hello there!", content)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, "gpt-4o-2024-08-06", last.Metadata.ModelUsed)
+	assert.Equal(t, "stop", last.Metadata.FinishReason)
+	assert.Equal(t, 12, last.Metadata.PromptTokens)
+	assert.Equal(t, 3, last.Metadata.CompletionTokens)
+	assert.Equal(t, 15, last.Metadata.TotalTokens)
+	assert.Equal(t, "chatcmpl-abc123", last.Metadata.RequestID)
+	assert.Equal(t, "fp_9a7b", last.Metadata.SystemFingerprint)
+	assert.GreaterOrEqual(t, last.Metadata.LatencyMs, int64(0))
+}
+
+func TestOpenAIClient_StreamRequestsUsage(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", nil)
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(promptConversation("hi"), true)
+	require.NoError(t, err)
+	assert.Contains(t, string(prepared.Body), `"stream_options":{"include_usage":true}`)
+
+	prepared, err = client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.NotContains(t, string(prepared.Body), "stream_options")
+}
+
+func TestOpenAIClient_BaseURL(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetBaseURL("http://localhost:8080/v1/"))
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/v1/chat/completions", prepared.URL)
+}
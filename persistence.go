@@ -0,0 +1,206 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// persistence.go serializes conversations for storage. Saved documents carry a schema
+// version so that files written by older releases keep loading as Message grows;
+// each version bump adds a migration that upgrades the previous document shape.
+package chatdelta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ConversationSchemaVersion is the version written by Conversation.MarshalJSON.
+//
+// Version history:
+//   - 0: unversioned documents: {"messages": [...]} or a bare message array
+//   - 1: {"version": 1, "messages": [...]}
+const ConversationSchemaVersion = 1
+
+// SessionSchemaVersion is the version written by ChatSession.Save.
+//
+// Version history:
+//   - 1: {"version": 1, "provider": ..., "model": ..., "saved_at": ..., "conversation": {...}}
+const SessionSchemaVersion = 1
+
+// savedConversation is the on-disk form of a Conversation.
+type savedConversation struct {
+	Version  int       `json:"version"`
+	Messages []Message `json:"messages"`
+}
+
+// conversationMigrations upgrades a document from version i to i+1 at index i.
+var conversationMigrations = []func(json.RawMessage) (json.RawMessage, error){
+	migrateConversationV0,
+}
+
+// migrateConversationV0 wraps a bare message array in an object; unversioned objects
+// already have the version 1 shape.
+func migrateConversationV0(doc json.RawMessage) (json.RawMessage, error) {
+	if trimmed := bytes.TrimSpace(doc); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Marshal(map[string]json.RawMessage{"messages": trimmed})
+	}
+	return doc, nil
+}
+
+// MarshalJSON encodes the conversation with the current schema version.
+func (c Conversation) MarshalJSON() ([]byte, error) {
+	messages := c.Messages
+	if messages == nil {
+		messages = []Message{}
+	}
+	return json.Marshal(savedConversation{Version: ConversationSchemaVersion, Messages: messages})
+}
+
+// UnmarshalJSON decodes a conversation saved by this or an earlier release, migrating
+// older schema versions. Documents from a newer release are rejected.
+func (c *Conversation) UnmarshalJSON(data []byte) error {
+	version := 0
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var header struct {
+			Version int `json:"version"`
+		}
+		if err := json.Unmarshal(trimmed, &header); err != nil {
+			return NewJSONParseError(err)
+		}
+		version = header.Version
+	}
+	if version < 0 || version > ConversationSchemaVersion {
+		return NewConfigError(fmt.Sprintf("conversation schema version %d is not supported (latest is %d)",
+			version, ConversationSchemaVersion))
+	}
+
+	doc := json.RawMessage(data)
+	for ; version < ConversationSchemaVersion; version++ {
+		migrated, err := conversationMigrations[version](doc)
+		if err != nil {
+			return NewJSONParseError(err)
+		}
+		doc = migrated
+	}
+
+	var saved savedConversation
+	if err := json.Unmarshal(doc, &saved); err != nil {
+		return NewJSONParseError(err)
+	}
+	c.Messages = saved.Messages
+	if c.Messages == nil {
+		c.Messages = make([]Message, 0)
+	}
+	return nil
+}
+
+// Save writes the conversation to w as versioned JSON.
+func (c *Conversation) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+// Load replaces the conversation's messages with those read from r.
+func (c *Conversation) Load(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return c.UnmarshalJSON(data)
+}
+
+// LoadConversation reads a conversation saved with Conversation.Save.
+func LoadConversation(r io.Reader) (*Conversation, error) {
+	conv := NewConversation()
+	if err := conv.Load(r); err != nil {
+		return nil, err
+	}
+	return conv, nil
+}
+
+// Export writes the session's history to w in the format of Conversation.Save.
+func (s *ChatSession) Export(w io.Writer) error {
+	return s.saveHistory(w)
+}
+
+// Import replaces the session's history with a conversation read from r and resets
+// Stats. The history is left unchanged if r cannot be decoded.
+func (s *ChatSession) Import(r io.Reader) error {
+	conv, err := LoadConversation(r)
+	if err != nil {
+		return err
+	}
+	s.replace(conv)
+	return nil
+}
+
+// SessionMetadata describes the session a saved file was written from. It is
+// informational: a saved session can be loaded into a client of any provider.
+type SessionMetadata struct {
+	// Provider is the Name of the client the session was using
+	Provider string `json:"provider,omitempty"`
+	// Model is the model of the client the session was using
+	Model string `json:"model,omitempty"`
+	// SavedAt is when the session was saved
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// savedSession is the on-disk form of a ChatSession.
+type savedSession struct {
+	Version int `json:"version"`
+	SessionMetadata
+	Conversation *Conversation `json:"conversation"`
+}
+
+// Save writes the session's history to w as versioned JSON, together with the name
+// and model of its client and the time of saving. Settings such as compaction and
+// history limits are not saved. Use LoadChatSession to resume the session.
+func (s *ChatSession) Save(w io.Writer) error {
+	s.mu.Lock()
+	saved := savedSession{
+		Version: SessionSchemaVersion,
+		SessionMetadata: SessionMetadata{
+			Provider: s.client.Name(),
+			Model:    s.client.Model(),
+			SavedAt:  time.Now().UTC(),
+		},
+		Conversation: s.conversation.Clone(),
+	}
+	s.mu.Unlock()
+	return json.NewEncoder(w).Encode(saved)
+}
+
+// LoadChatSession reads a session written by ChatSession.Save and resumes it with
+// client, which need not use the provider the session was saved from. It also
+// accepts a conversation written by Export or Conversation.Save, for which the
+// returned metadata is empty.
+func LoadChatSession(r io.Reader, client AIClient) (*ChatSession, SessionMetadata, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, SessionMetadata{}, err
+	}
+
+	var header struct {
+		Version      int             `json:"version"`
+		Conversation json.RawMessage `json:"conversation"`
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &header); err != nil {
+			return nil, SessionMetadata{}, NewJSONParseError(err)
+		}
+	}
+	if header.Conversation == nil {
+		conv := NewConversation()
+		if err := conv.UnmarshalJSON(data); err != nil {
+			return nil, SessionMetadata{}, err
+		}
+		return NewChatSessionFromConversation(client, conv), SessionMetadata{}, nil
+	}
+
+	if header.Version < 1 || header.Version > SessionSchemaVersion {
+		return nil, SessionMetadata{}, NewConfigError(fmt.Sprintf("session schema version %d is not supported (latest is %d)",
+			header.Version, SessionSchemaVersion))
+	}
+	var saved savedSession
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, SessionMetadata{}, err
+	}
+	return NewChatSessionFromConversation(client, saved.Conversation), saved.SessionMetadata, nil
+}
@@ -0,0 +1,153 @@
+package chatdelta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversation_SaveLoadRoundTrip(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystemMessage("Be brief.")
+	conv.AddUserMessage("Weather?")
+	conv.AddToolCalls("", []ToolCall{{ID: "call_1", Name: "get_weather", Arguments: json.RawMessage(`{"city":"Oslo"}`)}})
+	conv.AddToolResult("call_1", "rain")
+	conv.AddImageBytes([]byte{0x89, 'P', 'N', 'G'}, "image/png")
+	conv.AddAssistantMessage("It is raining.")
+
+	var buf bytes.Buffer
+	require.NoError(t, conv.Save(&buf))
+	assert.Contains(t, buf.String(), `"version":1`)
+
+	loaded, err := LoadConversation(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, conv.Messages, loaded.Messages)
+}
+
+func TestConversation_LoadsUnversionedJSON(t *testing.T) {
+	for name, doc := range map[string]string{
+		"object": `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}`,
+		"array":  `[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			conv, err := LoadConversation(strings.NewReader(doc))
+			require.NoError(t, err)
+			assert.Equal(t, []Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}}, conv.Messages)
+		})
+	}
+}
+
+func TestConversation_LoadRejectsNewerAndInvalid(t *testing.T) {
+	_, err := LoadConversation(strings.NewReader(`{"version":99,"messages":[]}`))
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, ErrorTypeConfig, ce.Type)
+	assert.Contains(t, err.Error(), "99")
+
+	_, err = LoadConversation(strings.NewReader(`{"version":1,"messages":"nope"}`))
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, "json_parse_error", ce.Code)
+}
+
+func TestChatSession_ExportImport(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	mock.QueueResponse("Go is a language.")
+	session := NewChatSessionWithSystemMessage(mock, "You are helpful.")
+	_, err := session.Send(context.Background(), "What is Go?")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, session.Export(&buf))
+
+	restored := NewChatSession(mock)
+	require.NoError(t, restored.Import(&buf))
+	assert.Equal(t, session.History().Messages, restored.History().Messages)
+
+	require.Error(t, restored.Import(strings.NewReader("not json")))
+	assert.Equal(t, 3, restored.Len(), "failed import keeps history")
+}
+
+func TestChatSession_SaveLoadRoundTrip(t *testing.T) {
+	openai := NewMockClient("openai", "gpt-4o")
+	openai.QueueResponse("Go is a language.")
+	openai.QueueResponse("Google.")
+	session := NewChatSessionWithSystemMessage(openai, "You are helpful.")
+	_, err := session.Send(context.Background(), "What is Go?")
+	require.NoError(t, err)
+	_, err = session.Send(context.Background(), "Who made it?")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, session.Save(&buf))
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.EqualValues(t, SessionSchemaVersion, doc["version"])
+
+	// A session saved from one provider resumes on another.
+	claude := NewMockClient("claude", "")
+	claude.QueueResponse("In 2009.")
+	restored, meta, err := LoadChatSession(&buf, claude)
+	require.NoError(t, err)
+	assert.Equal(t, "openai", meta.Provider)
+	assert.Equal(t, "gpt-4o", meta.Model)
+	assert.False(t, meta.SavedAt.IsZero())
+
+	messages := restored.History().Messages
+	assert.Equal(t, session.History().Messages, messages)
+	assert.Equal(t, Message{Role: "system", Content: "You are helpful."}, messages[0])
+	var roles []string
+	for _, msg := range messages {
+		roles = append(roles, msg.Role)
+	}
+	assert.Equal(t, []string{"system", "user", "assistant", "user", "assistant"}, roles)
+
+	_, err = restored.Send(context.Background(), "When?")
+	require.NoError(t, err)
+	sent := claude.Conversations()[0]
+	require.Len(t, sent.Messages, 6)
+	assert.Equal(t, "Who made it?", sent.Messages[3].Content)
+}
+
+func TestLoadChatSession_AcceptsExportedConversation(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystemMessage("Be brief.")
+	conv.AddUserMessage("hi")
+	var buf bytes.Buffer
+	require.NoError(t, conv.Save(&buf))
+
+	session, meta, err := LoadChatSession(&buf, NewMockClient("mock", ""))
+	require.NoError(t, err)
+	assert.Equal(t, SessionMetadata{}, meta)
+	assert.Equal(t, conv.Messages, session.History().Messages)
+}
+
+func TestLoadChatSession_RejectsNewerAndInvalid(t *testing.T) {
+	_, _, err := LoadChatSession(strings.NewReader(`{"version": 99, "conversation": {"messages": []}}`), NewMockClient("mock", ""))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "session schema version 99")
+
+	_, _, err = LoadChatSession(strings.NewReader("not json"), NewMockClient("mock", ""))
+	require.Error(t, err)
+
+	session, _, err := LoadChatSession(strings.NewReader(`{"version": 1, "conversation": null}`), NewMockClient("mock", ""))
+	require.NoError(t, err)
+	assert.True(t, session.IsEmpty())
+}
+
+func TestNewChatSessionFromConversation(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystemMessage("Be brief.")
+	conv.AddUserMessage("hi")
+	conv.AddAssistantMessage("hello")
+
+	session := NewChatSessionFromConversation(NewMockClient("mock", ""), conv)
+	conv.AddUserMessage("not in the session")
+	assert.Equal(t, 3, session.Len(), "the session keeps its own copy")
+	assert.True(t, NewChatSessionFromConversation(NewMockClient("mock", ""), nil).IsEmpty())
+}
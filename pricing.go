@@ -0,0 +1,183 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// pricing.go estimates the cost of requests from their token usage. Prices are held
+// per model in USD per 1,000 tokens; the built-in table reflects published list
+// prices when it was last updated, and RegisterModelPricing overrides or extends it
+// so current prices can be used without waiting for a release.
+package chatdelta
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ModelPricing is the price of a model in USD per 1,000 tokens.
+type ModelPricing struct {
+	// InputPer1K is the price of 1,000 prompt tokens
+	InputPer1K float64 `json:"input_per_1k"`
+	// OutputPer1K is the price of 1,000 completion tokens
+	OutputPer1K float64 `json:"output_per_1k"`
+}
+
+// builtinPricing lists list prices for well-known models.
+var builtinPricing = map[string]ModelPricing{
+	"gpt-4o":        {InputPer1K: 0.0025, OutputPer1K: 0.01},
+	"gpt-4o-mini":   {InputPer1K: 0.00015, OutputPer1K: 0.0006},
+	"gpt-4-turbo":   {InputPer1K: 0.01, OutputPer1K: 0.03},
+	"gpt-4":         {InputPer1K: 0.03, OutputPer1K: 0.06},
+	"gpt-3.5-turbo": {InputPer1K: 0.0005, OutputPer1K: 0.0015},
+	"o1":            {InputPer1K: 0.015, OutputPer1K: 0.06},
+	"o1-mini":       {InputPer1K: 0.003, OutputPer1K: 0.012},
+
+	"claude-3-5-sonnet-20241022": {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"claude-3-5-sonnet-20240620": {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"claude-3-5-haiku-20241022":  {InputPer1K: 0.0008, OutputPer1K: 0.004},
+	"claude-3-opus-20240229":     {InputPer1K: 0.015, OutputPer1K: 0.075},
+	"claude-3-sonnet-20240229":   {InputPer1K: 0.003, OutputPer1K: 0.015},
+	"claude-3-haiku-20240307":    {InputPer1K: 0.00025, OutputPer1K: 0.00125},
+
+	"gemini-2.0-flash":    {InputPer1K: 0.0001, OutputPer1K: 0.0004},
+	"gemini-1.5-pro":      {InputPer1K: 0.00125, OutputPer1K: 0.005},
+	"gemini-1.5-flash":    {InputPer1K: 0.000075, OutputPer1K: 0.0003},
+	"gemini-1.5-flash-8b": {InputPer1K: 0.0000375, OutputPer1K: 0.00015},
+
+	"grok-2":    {InputPer1K: 0.002, OutputPer1K: 0.01},
+	"grok-beta": {InputPer1K: 0.005, OutputPer1K: 0.015},
+}
+
+// modelPricing holds prices registered with RegisterModelPricing, which take
+// precedence over builtinPricing.
+var modelPricing = struct {
+	sync.RWMutex
+	user map[string]ModelPricing
+}{user: make(map[string]ModelPricing)}
+
+// RegisterModelPricing sets the price of model, replacing any built-in or previously
+// registered price. It is safe for concurrent use.
+func RegisterModelPricing(model string, pricing ModelPricing) error {
+	if model == "" {
+		return NewInvalidParameterError("model", "empty model name")
+	}
+	if pricing.InputPer1K < 0 || pricing.OutputPer1K < 0 {
+		return NewInvalidParameterError("pricing", fmt.Sprintf("negative price for %s", model))
+	}
+	modelPricing.Lock()
+	defer modelPricing.Unlock()
+	modelPricing.user[model] = pricing
+	return nil
+}
+
+// LookupModelPricing returns the price of model. Dated or versioned variants (e.g.
+// "gpt-4o-2024-08-06") fall back to the longest priced name they extend.
+func LookupModelPricing(model string) (ModelPricing, bool) {
+	modelPricing.RLock()
+	defer modelPricing.RUnlock()
+
+	if pricing, ok := lookupPricingExact(model); ok {
+		return pricing, true
+	}
+	best := ""
+	for _, names := range []map[string]ModelPricing{builtinPricing, modelPricing.user} {
+		for name := range names {
+			if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+				best = name
+			}
+		}
+	}
+	if best == "" {
+		return ModelPricing{}, false
+	}
+	return lookupPricingExact(best)
+}
+
+// lookupPricingExact returns the registered or built-in price of model. The caller
+// holds the read lock.
+func lookupPricingExact(model string) (ModelPricing, bool) {
+	if pricing, ok := modelPricing.user[model]; ok {
+		return pricing, true
+	}
+	pricing, ok := builtinPricing[model]
+	return pricing, ok
+}
+
+// EstimateCost returns the cost in USD of a request to model that used the tokens
+// reported in meta. An empty model uses meta.ModelUsed. Responses served from a cache
+// cost nothing. Models without a price are reported as a config error.
+func EstimateCost(model string, meta ResponseMetadata) (float64, error) {
+	if model == "" {
+		model = meta.ModelUsed
+	}
+	pricing, ok := LookupModelPricing(model)
+	if !ok {
+		return 0, NewInvalidParameterError("model", fmt.Sprintf("no pricing for %q", model))
+	}
+	for _, segment := range ServedViaSegments(meta.ServedVia) {
+		if segment == ServedViaCache {
+			return 0, nil
+		}
+	}
+	return float64(meta.PromptTokens)/1000*pricing.InputPer1K +
+		float64(meta.CompletionTokens)/1000*pricing.OutputPer1K, nil
+}
+
+// Cost returns the estimated cost in USD of the response, priced by the model the
+// provider reported in Metadata.ModelUsed; see EstimateCost.
+func (r *AiResponse) Cost() (float64, error) {
+	return EstimateCost(r.Metadata.ModelUsed, r.Metadata)
+}
+
+// Cost returns the estimated cost in USD of the result, priced by the model the
+// provider reported or, if that has no price, the client's model; see EstimateCost.
+// Failed results cost nothing.
+func (r ParallelResult) Cost() (float64, error) {
+	if r.Error != nil || r.Metadata == nil {
+		return 0, nil
+	}
+	return estimateCostWithFallback(r.Model, *r.Metadata)
+}
+
+// estimateCostWithFallback prices meta by meta.ModelUsed or, if that has no price,
+// by the client's model.
+func estimateCostWithFallback(clientModel string, meta ResponseMetadata) (float64, error) {
+	model := meta.ModelUsed
+	if _, ok := LookupModelPricing(model); !ok {
+		model = clientModel
+	}
+	return EstimateCost(model, meta)
+}
+
+// TotalResponseCost sums the estimated cost of responses, leaving out those whose
+// model has no price and returning the first such error with the total.
+func TotalResponseCost(responses []*AiResponse) (float64, error) {
+	total := 0.0
+	var firstErr error
+	for _, response := range responses {
+		cost, err := response.Cost()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		total += cost
+	}
+	return total, firstErr
+}
+
+// TotalCost sums the estimated cost of results. Results whose model has no price are
+// left out of the total, and the first such error is returned with it.
+func TotalCost(results []ParallelResult) (float64, error) {
+	total := 0.0
+	var firstErr error
+	for _, result := range results {
+		cost, err := result.Cost()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		total += cost
+	}
+	return total, firstErr
+}
@@ -0,0 +1,112 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetModelPricing drops prices registered by a test when it ends.
+func resetModelPricing(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		modelPricing.Lock()
+		defer modelPricing.Unlock()
+		modelPricing.user = make(map[string]ModelPricing)
+	})
+}
+
+func TestEstimateCost(t *testing.T) {
+	meta := ResponseMetadata{ModelUsed: "gpt-4o-2024-08-06", PromptTokens: 1000, CompletionTokens: 500}
+
+	cost, err := EstimateCost("gpt-4o", meta)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0025+0.005, cost, 1e-12)
+
+	cost, err = EstimateCost("", meta)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0075, cost, 1e-12, "an empty model uses the dated ModelUsed")
+
+	cost, err = EstimateCost("gpt-4o-mini-2024-07-18", meta)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00015+0.0003, cost, 1e-12, "the longest priced prefix wins")
+
+	meta.ServedVia = ServedViaPrimary + ">" + ServedViaCache
+	cost, err = EstimateCost("gpt-4o", meta)
+	require.NoError(t, err)
+	assert.Zero(t, cost, "cache hits are free")
+
+	_, err = EstimateCost("my-fine-tune", meta)
+	require.Error(t, err)
+	assert.True(t, isConfigError(err))
+}
+
+func TestRegisterModelPricing(t *testing.T) {
+	resetModelPricing(t)
+
+	require.NoError(t, RegisterModelPricing("gpt-4o", ModelPricing{InputPer1K: 0.001, OutputPer1K: 0.002}))
+	require.NoError(t, RegisterModelPricing("my-fine-tune", ModelPricing{InputPer1K: 0.01, OutputPer1K: 0.01}))
+
+	cost, err := EstimateCost("gpt-4o-2024-08-06", ResponseMetadata{PromptTokens: 2000, CompletionTokens: 1000})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.004, cost, 1e-12, "registered prices override built-ins")
+
+	pricing, ok := LookupModelPricing("my-fine-tune")
+	require.True(t, ok)
+	assert.Equal(t, 0.01, pricing.InputPer1K)
+
+	assert.Error(t, RegisterModelPricing("", ModelPricing{}))
+	assert.Error(t, RegisterModelPricing("cheap", ModelPricing{InputPer1K: -1}))
+}
+
+func TestTotalResponseCost(t *testing.T) {
+	responses := []*AiResponse{
+		{Metadata: ResponseMetadata{ModelUsed: "claude-3-haiku-20240307", PromptTokens: 4000, CompletionTokens: 800}},
+		{Metadata: ResponseMetadata{ModelUsed: "gemini-1.5-pro", PromptTokens: 1000, CompletionTokens: 1000}},
+		{Metadata: ResponseMetadata{ModelUsed: "unpriced", PromptTokens: 1000}},
+	}
+
+	cost, err := responses[0].Cost()
+	require.NoError(t, err)
+	assert.InDelta(t, 0.001+0.001, cost, 1e-12)
+
+	total, err := TotalResponseCost(responses)
+	assert.Error(t, err, "the unpriced response is reported")
+	assert.InDelta(t, 0.002+0.00625, total, 1e-12)
+}
+
+func TestExecuteParallel_TotalCost(t *testing.T) {
+	newClient := func(model, body string) AIClient {
+		client, err := NewOpenAIClient("key", model, NewClientConfig().SetRetries(0))
+		require.NoError(t, err)
+		client.httpClient.Transport = cannedResponse(http.StatusOK, body)
+		return client
+	}
+	large := newClient("gpt-4o", `{"model": "gpt-4o-2024-08-06",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "large"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 1000, "completion_tokens": 1000, "total_tokens": 2000}}`)
+	small := newClient("gpt-4o-mini", `{
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "small"}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 1000, "completion_tokens": 1000, "total_tokens": 2000}}`)
+	failing := NewMockClient("failing", "")
+	failing.QueueError(NewServerError(500, "down"))
+
+	results := ExecuteParallel(context.Background(), []AIClient{large, small, failing}, "hi")
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "gpt-4o", results[0].Model)
+	require.NotNil(t, results[0].Metadata)
+	assert.Equal(t, 2000, results[0].Metadata.TotalTokens)
+	assert.Nil(t, results[2].Metadata)
+
+	cost, err := results[1].Cost()
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00015+0.0006, cost, 1e-12, "priced by the client's model when the provider reports none")
+
+	total, err := TotalCost(results)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0125+0.00075, total, 1e-12)
+}
@@ -0,0 +1,295 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// rate_limiter.go implements RateLimiter, a token-bucket budget of requests and
+// tokens per minute, and RateLimitedClient, which waits for that budget before each
+// call instead of letting the provider answer with 429s. One RateLimiter can be
+// shared by any number of clients to enforce an application-wide budget.
+package chatdelta
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiterOptions configures a RateLimiter. A zero limit is not enforced.
+type RateLimiterOptions struct {
+	// RequestsPerMinute caps the number of calls started per minute
+	RequestsPerMinute int
+	// TokensPerMinute caps prompt plus completion tokens per minute
+	TokensPerMinute int
+	// Clock supplies time and timers; nil uses the system clock
+	Clock Clock
+}
+
+// RateLimitCapacity is a snapshot of a RateLimiter's budget. Tokens can be negative
+// when calls used more tokens than were reserved for them.
+type RateLimitCapacity struct {
+	// Requests is the number of calls that may start now
+	Requests int
+	// Tokens is the number of tokens available now
+	Tokens int
+}
+
+// RateLimiter holds request and token buckets that refill continuously up to one
+// minute's allowance. It is safe for concurrent use.
+type RateLimiter struct {
+	opts     RateLimiterOptions
+	clock    Clock
+	mu       sync.Mutex
+	requests float64
+	tokens   float64
+	updated  time.Time
+}
+
+// NewRateLimiter creates a RateLimiter whose buckets start full.
+func NewRateLimiter(opts RateLimiterOptions) *RateLimiter {
+	clock := opts.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &RateLimiter{
+		opts:     opts,
+		clock:    clock,
+		requests: float64(opts.RequestsPerMinute),
+		tokens:   float64(opts.TokensPerMinute),
+		updated:  clock.Now(),
+	}
+}
+
+// Wait blocks until a request slot and tokens tokens are available, then takes them.
+// A reservation larger than the whole per-minute allowance waits for a full bucket.
+// It returns ctx.Err() if ctx ends first.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	for {
+		delay := l.reserve(tokens)
+		if delay == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.clock.After(delay):
+		}
+	}
+}
+
+// reserve takes a request slot and tokens if both are available and returns 0, or
+// otherwise returns how long to wait before trying again.
+func (l *RateLimiter) reserve(tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+
+	var delay time.Duration
+	if rpm := l.opts.RequestsPerMinute; rpm > 0 && l.requests < 1 {
+		delay = max(delay, refillDelay(1-l.requests, rpm))
+	}
+	if tpm := l.opts.TokensPerMinute; tpm > 0 {
+		need := math.Min(float64(tokens), float64(tpm))
+		if l.tokens < need {
+			delay = max(delay, refillDelay(need-l.tokens, tpm))
+		}
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	if l.opts.RequestsPerMinute > 0 {
+		l.requests--
+	}
+	if l.opts.TokensPerMinute > 0 {
+		l.tokens -= float64(tokens)
+	}
+	return 0
+}
+
+// Charge adjusts the token bucket after the fact: a positive n takes tokens a call
+// used beyond its reservation, a negative n returns unused ones.
+func (l *RateLimiter) Charge(n int) {
+	if l.opts.TokensPerMinute <= 0 || n == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens = math.Min(l.tokens-float64(n), float64(l.opts.TokensPerMinute))
+}
+
+// Remaining returns the budget available now.
+func (l *RateLimiter) Remaining() RateLimitCapacity {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	return RateLimitCapacity{Requests: int(l.requests), Tokens: int(math.Floor(l.tokens))}
+}
+
+// refill credits the buckets for the time since the last update. The caller holds mu.
+func (l *RateLimiter) refill() {
+	now := l.clock.Now()
+	minutes := now.Sub(l.updated).Minutes()
+	l.updated = now
+	if minutes <= 0 {
+		return
+	}
+	if rpm := float64(l.opts.RequestsPerMinute); rpm > 0 {
+		l.requests = math.Min(l.requests+minutes*rpm, rpm)
+	}
+	if tpm := float64(l.opts.TokensPerMinute); tpm > 0 {
+		l.tokens = math.Min(l.tokens+minutes*tpm, tpm)
+	}
+}
+
+// refillDelay returns how long a bucket refilling at perMinute takes to gain amount.
+func refillDelay(amount float64, perMinute int) time.Duration {
+	return time.Duration(math.Ceil(amount / float64(perMinute) * float64(time.Minute)))
+}
+
+// RateLimitedClient wraps an AIClient and waits on a RateLimiter before every call.
+// Each call reserves its estimated prompt tokens (see EstimatePromptTokens) and is
+// charged the difference once the provider reports actual usage. SendPrompt and
+// SendConversation are served through their WithMetadata variants for that reason.
+type RateLimitedClient struct {
+	inner   AIClient
+	limiter *RateLimiter
+}
+
+// NewRateLimitedClient creates a RateLimitedClient drawing on limiter, which may be
+// shared with other clients.
+func NewRateLimitedClient(inner AIClient, limiter *RateLimiter) *RateLimitedClient {
+	return &RateLimitedClient{inner: inner, limiter: limiter}
+}
+
+// Limiter returns the RateLimiter the client draws on.
+func (r *RateLimitedClient) Limiter() *RateLimiter {
+	return r.limiter
+}
+
+// acquire waits for capacity to send conversation and returns the tokens reserved.
+func (r *RateLimitedClient) acquire(ctx context.Context, conversation *Conversation) (int, error) {
+	reserved := 0
+	if r.limiter.opts.TokensPerMinute > 0 {
+		reserved = EstimatePromptTokens(r.inner, conversation)
+	}
+	return reserved, r.limiter.Wait(ctx, reserved)
+}
+
+// settle charges the difference between the reservation and the reported usage.
+func (r *RateLimitedClient) settle(reserved int, metadata *ResponseMetadata) {
+	if metadata == nil {
+		return
+	}
+	used := metadata.TotalTokens
+	if used == 0 {
+		used = metadata.PromptTokens + metadata.CompletionTokens
+	}
+	if used > 0 {
+		r.limiter.Charge(used - reserved)
+	}
+}
+
+// SendPrompt waits for capacity and forwards to the inner client.
+func (r *RateLimitedClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	response, err := r.SendPromptWithMetadata(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendPromptWithMetadata waits for capacity and forwards to the inner client.
+func (r *RateLimitedClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	reserved, err := r.acquire(ctx, promptConversation(prompt))
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.inner.SendPromptWithMetadata(ctx, prompt)
+	if response != nil {
+		r.settle(reserved, &response.Metadata)
+	}
+	return response, err
+}
+
+// SendConversation waits for capacity and forwards to the inner client.
+func (r *RateLimitedClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	response, err := r.SendConversationWithMetadata(ctx, conversation)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendConversationWithMetadata waits for capacity and forwards to the inner client.
+func (r *RateLimitedClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	reserved, err := r.acquire(ctx, conversation)
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.inner.SendConversationWithMetadata(ctx, conversation)
+	if response != nil {
+		r.settle(reserved, &response.Metadata)
+	}
+	return response, err
+}
+
+// StreamPrompt waits for capacity and forwards to the inner client.
+func (r *RateLimitedClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	reserved, err := r.acquire(ctx, promptConversation(prompt))
+	if err != nil {
+		return nil, err
+	}
+	ch, err := r.inner.StreamPrompt(ctx, prompt)
+	return r.settleStream(reserved, ch, err)
+}
+
+// StreamConversation waits for capacity and forwards to the inner client.
+func (r *RateLimitedClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
+	reserved, err := r.acquire(ctx, conversation)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := r.inner.StreamConversation(ctx, conversation)
+	return r.settleStream(reserved, ch, err)
+}
+
+// settleStream forwards src unchanged and settles the reservation from the final
+// chunk's metadata.
+func (r *RateLimitedClient) settleStream(reserved int, src <-chan StreamChunk, err error) (<-chan StreamChunk, error) {
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan StreamChunk, 10)
+	go func() {
+		defer close(out)
+		for chunk := range src {
+			if chunk.Finished {
+				r.settle(reserved, chunk.Metadata)
+			}
+			out <- chunk
+		}
+	}()
+	return out, nil
+}
+
+// SupportsStreaming delegates to the inner client.
+func (r *RateLimitedClient) SupportsStreaming() bool { return r.inner.SupportsStreaming() }
+
+// SupportsConversations delegates to the inner client.
+func (r *RateLimitedClient) SupportsConversations() bool { return r.inner.SupportsConversations() }
+
+// Name delegates to the inner client.
+func (r *RateLimitedClient) Name() string { return r.inner.Name() }
+
+// Model delegates to the inner client.
+func (r *RateLimitedClient) Model() string { return r.inner.Model() }
+
+// WithOptions applies opts to the inner client and wraps the result the same way.
+func (r *RateLimitedClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	inner, err := ClientWithOptions(r.inner, opts...)
+	if err != nil {
+		return nil, err
+	}
+	clone := *r
+	clone.inner = inner
+	return &clone, nil
+}
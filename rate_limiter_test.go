@@ -0,0 +1,164 @@
+package chatdelta
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usageClient is a MockClient that reports a fixed token usage on every response.
+type usageClient struct {
+	*MockClient
+	total int
+}
+
+func (u *usageClient) SendConversationWithMetadata(ctx context.Context, conv *Conversation) (*AiResponse, error) {
+	response, err := u.MockClient.SendConversationWithMetadata(ctx, conv)
+	if response != nil {
+		response.Metadata.TotalTokens = u.total
+	}
+	return response, err
+}
+
+func (u *usageClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	return u.SendConversationWithMetadata(ctx, promptConversation(prompt))
+}
+
+func TestRateLimiter_StartsFullAndRefills(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(RateLimiterOptions{RequestsPerMinute: 60, TokensPerMinute: 600, Clock: clock})
+	assert.Equal(t, RateLimitCapacity{Requests: 60, Tokens: 600}, limiter.Remaining())
+
+	require.NoError(t, limiter.Wait(context.Background(), 100))
+	assert.Equal(t, RateLimitCapacity{Requests: 59, Tokens: 500}, limiter.Remaining())
+
+	clock.now = clock.now.Add(10 * time.Second)
+	assert.Equal(t, RateLimitCapacity{Requests: 60, Tokens: 600}, limiter.Remaining())
+	assert.Empty(t, clock.waits)
+}
+
+func TestRateLimiter_WaitsForRequestSlot(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(RateLimiterOptions{RequestsPerMinute: 2, Clock: clock})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, limiter.Wait(context.Background(), 0))
+	}
+	assert.Equal(t, []time.Duration{30 * time.Second}, clock.waits)
+}
+
+func TestRateLimiter_WaitsForTokens(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(RateLimiterOptions{TokensPerMinute: 600, Clock: clock})
+
+	require.NoError(t, limiter.Wait(context.Background(), 500))
+	require.NoError(t, limiter.Wait(context.Background(), 200))
+	assert.Equal(t, []time.Duration{10 * time.Second}, clock.waits)
+	assert.Equal(t, 0, limiter.Remaining().Tokens)
+}
+
+func TestRateLimiter_OversizedReservationWaitsForFullBucket(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(RateLimiterOptions{TokensPerMinute: 100, Clock: clock})
+
+	require.NoError(t, limiter.Wait(context.Background(), 250))
+	assert.Empty(t, clock.waits)
+	assert.Equal(t, -150, limiter.Remaining().Tokens)
+}
+
+func TestRateLimiter_WaitRespectsContext(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterOptions{RequestsPerMinute: 1})
+	require.NoError(t, limiter.Wait(context.Background(), 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := limiter.Wait(ctx, 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRateLimiter_ChargeCapsRefundAtCapacity(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(RateLimiterOptions{TokensPerMinute: 100, Clock: clock})
+
+	limiter.Charge(30)
+	assert.Equal(t, 70, limiter.Remaining().Tokens)
+	limiter.Charge(-500)
+	assert.Equal(t, 100, limiter.Remaining().Tokens)
+}
+
+func TestRateLimitedClient_SharedLimiterAcrossClients(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(RateLimiterOptions{RequestsPerMinute: 2, Clock: clock})
+
+	first := NewMockClient("first", "")
+	first.SetDefaultResponse("one", nil)
+	second := NewMockClient("second", "")
+	second.SetDefaultResponse("two", nil)
+	a := Chain(first, WithRateLimit(limiter))
+	b := NewRateLimitedClient(second, limiter)
+	assert.Same(t, limiter, b.Limiter())
+
+	ctx := context.Background()
+	_, err := a.SendPrompt(ctx, "hi")
+	require.NoError(t, err)
+	_, err = b.SendPrompt(ctx, "hi")
+	require.NoError(t, err)
+	assert.Empty(t, clock.waits)
+	assert.Equal(t, 0, limiter.Remaining().Requests)
+
+	_, err = a.SendPrompt(ctx, "hi")
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{30 * time.Second}, clock.waits)
+}
+
+func TestRateLimitedClient_ChargesReportedUsage(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(RateLimiterOptions{TokensPerMinute: 1000, Clock: clock})
+	inner := &usageClient{MockClient: NewMockClient("mock", ""), total: 300}
+	inner.SetDefaultResponse("ok", nil)
+	client := NewRateLimitedClient(inner, limiter)
+
+	response, err := client.SendPromptWithMetadata(context.Background(), "hello there")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response.Content)
+	assert.Equal(t, 700, limiter.Remaining().Tokens)
+}
+
+func TestRateLimitedClient_StreamSettlesOnFinalChunk(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(RateLimiterOptions{RequestsPerMinute: 5, TokensPerMinute: 1000, Clock: clock})
+	inner := NewMockClient("mock", "")
+	inner.SetDefaultResponse("streamed", nil)
+	client := NewRateLimitedClient(inner, limiter)
+
+	conv := promptConversation("hello there")
+	reserved := EstimatePromptTokens(inner, conv)
+	ch, err := client.StreamConversation(context.Background(), conv)
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "streamed", content)
+	assert.True(t, last.Finished)
+	// The mock reports no usage, so the reservation stands.
+	assert.Equal(t, RateLimitCapacity{Requests: 4, Tokens: 1000 - reserved}, limiter.Remaining())
+}
+
+func TestRateLimitedClient_CancelledBeforeCall(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterOptions{RequestsPerMinute: 1})
+	inner := NewMockClient("mock", "")
+	inner.SetDefaultResponse("ok", nil)
+	client := NewRateLimitedClient(inner, limiter)
+
+	_, err := client.SendPrompt(context.Background(), "first")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.SendPrompt(ctx, "second")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, inner.CallCount())
+}
@@ -0,0 +1,95 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// raw.go lets callers send an exact provider payload (for example one copied from a
+// bug report) through a client's auth, extra headers, retry, and error mapping
+// without the Conversation abstraction reshaping it.
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// RawSender is implemented by clients that can post a pre-marshaled provider payload
+// to their chat endpoint.
+type RawSender interface {
+	// SendRaw posts body verbatim and returns the provider's response body
+	SendRaw(ctx context.Context, body json.RawMessage) (json.RawMessage, error)
+}
+
+// SendRaw posts body through client if it implements RawSender, and returns a config
+// error otherwise.
+func SendRaw(ctx context.Context, client AIClient, body json.RawMessage) (json.RawMessage, error) {
+	raw, ok := client.(RawSender)
+	if !ok {
+		return nil, NewInvalidParameterError("client", fmt.Sprintf("%s does not support raw payloads", client.Name()))
+	}
+	return raw.SendRaw(ctx, body)
+}
+
+// checkRawBody rejects payloads SendRaw cannot handle: bodies that are not a JSON
+// object, bodies requesting a stream, and (unless config allows it) bodies naming a
+// model other than the client's.
+func checkRawBody(config *ClientConfig, model string, body json.RawMessage) error {
+	var fields struct {
+		Model  *string `json:"model"`
+		Stream bool    `json:"stream"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return NewInvalidParameterError("body", err.Error())
+	}
+	if fields.Stream {
+		return NewInvalidParameterError("body", "streaming payloads are not supported by SendRaw")
+	}
+	if fields.Model != nil && *fields.Model != model && !config.AllowModelOverride {
+		return NewInvalidParameterError("model", fmt.Sprintf("body sets %q but the client uses %q; enable AllowModelOverride to send it", *fields.Model, model))
+	}
+	return nil
+}
+
+// sendRaw performs a raw request with the config's retry policy. build creates a fresh
+// request per attempt and apiError maps non-200 responses.
+func sendRaw(ctx context.Context, httpClient *http.Client, config *ClientConfig, build func(context.Context) (*http.Request, error), apiError func(statusCode int, header http.Header, body []byte) *ClientError) (json.RawMessage, error) {
+	ctx, cancel := config.withOperationTimeout(ctx)
+	defer cancel()
+
+	var result json.RawMessage
+
+	operation := func() error {
+		req, err := build(ctx)
+		if err != nil {
+			return err
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return NewTimeoutError(config.Timeout)
+			}
+			return NewConnectionError(err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return NewConnectionError(err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return apiError(resp.StatusCode, resp.Header, body)
+		}
+		if !json.Valid(body) {
+			return NewJSONParseError(fmt.Errorf("response is not valid JSON"))
+		}
+
+		result = json.RawMessage(body)
+		return nil
+	}
+
+	if err := config.retry(ctx, operation); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
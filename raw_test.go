@@ -0,0 +1,139 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendRaw_PostsBodyVerbatim(t *testing.T) {
+	payload := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"logit_bias":{"50256":-100}}`
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, payload, string(body))
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "debug", r.Header.Get("X-Trace"))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"message":"try again"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[]}`))
+	}))
+	defer server.Close()
+
+	config := NewClientConfig().SetBaseURL(server.URL).SetHeader("X-Trace", "debug").SetRetries(1).SetClock(&fakeClock{})
+	client, err := NewOpenAIClient("test-key", "gpt-4o", config)
+	require.NoError(t, err)
+
+	response, err := SendRaw(context.Background(), client, json.RawMessage(payload))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"chatcmpl-1","choices":[]}`, string(response))
+	assert.Equal(t, int32(2), calls.Load(), "server errors are retried")
+}
+
+func TestSendRaw_ModelOverride(t *testing.T) {
+	client, err := NewClaudeClient("test-key", "claude-3-haiku-20240307", NewClientConfig())
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK, `{"id":"msg_1"}`)
+
+	body := json.RawMessage(`{"model":"claude-3-opus-20240229","max_tokens":10,"messages":[]}`)
+	_, err = client.SendRaw(context.Background(), body)
+	var clientErr *ClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, "invalid_parameter", clientErr.Code)
+
+	client.config.SetAllowModelOverride(true)
+	response, err := client.SendRaw(context.Background(), body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"msg_1"}`, string(response))
+}
+
+func TestSendRaw_Rejections(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusUnauthorized, `{"error":{"message":"bad key","type":"invalid_request_error"}}`)
+
+	_, err = client.SendRaw(context.Background(), json.RawMessage(`{"model":"gpt-4o","stream":true}`))
+	assert.Error(t, err, "streaming payloads are refused")
+
+	_, err = client.SendRaw(context.Background(), json.RawMessage(`[1,2]`))
+	assert.Error(t, err, "non-object payloads are refused")
+
+	_, err = client.SendRaw(context.Background(), json.RawMessage(`{"messages":[]}`))
+	assert.True(t, IsAuthenticationError(err), "provider errors are mapped: %v", err)
+
+	_, err = SendRaw(context.Background(), NewMockClient("mock", ""), json.RawMessage(`{}`))
+	assert.Error(t, err, "clients without SendRaw are reported")
+}
+
+func TestIncludeRawResponse_RoundTripsBody(t *testing.T) {
+	bodies := map[string]string{
+		"openai": `{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop","logprobs":null}],
+  "usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4},"service_tier":"default"}`,
+		"claude": `{"id":"msg_01","type":"message","role":"assistant","model":"claude-3-5-haiku-20241022",
+  "content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1,"cache_creation_input_tokens":0}}`,
+		"gemini": `{"candidates":[{"content":{"role":"model","parts":[{"text":"Hi"}]},"finishReason":"STOP","safetyRatings":[]}],
+  "usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1,"totalTokenCount":4}}`,
+		"ollama": `{"model":"llama3","message":{"role":"assistant","content":"Hi"},"done":true,"done_reason":"stop","eval_count":1}`,
+	}
+	newClient := map[string]func(config *ClientConfig) (AIClient, error){
+		"openai": func(config *ClientConfig) (AIClient, error) { return NewOpenAIClient("test-key", "gpt-4o", config) },
+		"claude": func(config *ClientConfig) (AIClient, error) {
+			return NewClaudeClient("test-key", "claude-3-5-haiku-latest", config)
+		},
+		"gemini": func(config *ClientConfig) (AIClient, error) {
+			return NewGeminiClient("test-key", "gemini-1.5-flash", config)
+		},
+		"ollama": func(config *ClientConfig) (AIClient, error) { return NewOllamaClient("llama3", config) },
+	}
+
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			server := metadataServer(t, nil, body)
+
+			client, err := newClient[name](NewClientConfig().SetBaseURL(server.URL).SetRetries(0).SetIncludeRawResponse(true))
+			require.NoError(t, err)
+			resp, err := client.SendPromptWithMetadata(context.Background(), "hi")
+			require.NoError(t, err)
+			assert.Equal(t, "Hi", resp.Content)
+			assert.Equal(t, body, string(resp.Raw), "raw body is kept byte for byte")
+
+			client, err = newClient[name](NewClientConfig().SetBaseURL(server.URL).SetRetries(0))
+			require.NoError(t, err)
+			resp, err = client.SendPromptWithMetadata(context.Background(), "hi")
+			require.NoError(t, err)
+			assert.Nil(t, resp.Raw, "raw body is off by default")
+		})
+	}
+}
+
+func TestIncludeRawResponse_StreamKeepsFinalEvent(t *testing.T) {
+	server := transcriptServer(t, "openai_stream.sse")
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetBaseURL(server.URL).SetIncludeRawResponse(true))
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	_, last := collectStream(t, ch)
+	assert.Equal(t, `{"id":"chatcmpl-abc123","object":"chat.completion.chunk","created":1718000000,"model":"gpt-4o-2024-08-06","system_fingerprint":"fp_9a7b","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`,
+		string(last.Raw))
+
+	server = transcriptServer(t, "ollama_stream.ndjson")
+	ollama, err := NewOllamaClient("llama3", NewClientConfig().SetBaseURL(server.URL).SetIncludeRawResponse(true))
+	require.NoError(t, err)
+	ch, err = ollama.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	_, last = collectStream(t, ch)
+	assert.JSONEq(t, `{"model":"llama3","created_at":"2024-06-01T12:00:00.150Z","message":{"role":"assistant","content":""},"done_reason":"stop","done":true,"total_duration":150000000,"prompt_eval_count":12,"eval_count":4}`,
+		string(last.Raw))
+}
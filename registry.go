@@ -0,0 +1,120 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// registry.go lets third-party AIClient implementations plug into CreateClient and
+// GetAvailableProviders without modifying this package.
+//
+// Usage:
+//
+//	err := chatdelta.RegisterProvider("acme", newAcmeClient,
+//	    chatdelta.WithEnvKeys("ACME_API_KEY"),
+//	    chatdelta.WithDefaultModel("acme-large"))
+//	client, err := chatdelta.CreateClient("acme", "", "", nil)
+package chatdelta
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ProviderFactory constructs a client for a registered provider.
+type ProviderFactory func(apiKey, model string, config *ClientConfig) (AIClient, error)
+
+// ProviderOption configures a provider registration.
+type ProviderOption func(*registeredProvider)
+
+// WithEnvKeys sets the environment variables, in priority order, consulted for an
+// API key when CreateClient is called without one.
+func WithEnvKeys(keys ...string) ProviderOption {
+	return func(p *registeredProvider) {
+		p.envKeys = append([]string(nil), keys...)
+	}
+}
+
+// WithDefaultModel sets the model used when CreateClient is called without one.
+func WithDefaultModel(model string) ProviderOption {
+	return func(p *registeredProvider) {
+		p.defaultModel = model
+	}
+}
+
+// WithoutAPIKey lets CreateClient construct the provider without an API key, for
+// local or otherwise unauthenticated backends. The factory receives "" in that case.
+func WithoutAPIKey() ProviderOption {
+	return func(p *registeredProvider) {
+		p.keyless = true
+	}
+}
+
+type registeredProvider struct {
+	factory      ProviderFactory
+	envKeys      []string
+	defaultModel string
+	keyless      bool
+}
+
+// providerRegistry holds third-party providers in registration order.
+var providerRegistry = struct {
+	sync.RWMutex
+	providers map[string]*registeredProvider
+	order     []string
+}{providers: make(map[string]*registeredProvider)}
+
+// RegisterProvider makes a third-party provider available to CreateClient under
+// name (case-insensitive). It returns an error if name is empty, factory is nil, or
+// the name is already used by a built-in or previously registered provider.
+// It is safe for concurrent use.
+func RegisterProvider(name string, factory ProviderFactory, opts ...ProviderOption) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return NewInvalidParameterError("provider", "empty name")
+	}
+	if factory == nil {
+		return NewInvalidParameterError("provider", fmt.Sprintf("%s has a nil factory", name))
+	}
+	for _, builtin := range SupportedProviders {
+		if builtin == name {
+			return NewInvalidParameterError("provider", fmt.Sprintf("%s is a built-in provider", name))
+		}
+	}
+
+	p := &registeredProvider{factory: factory}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	providerRegistry.Lock()
+	defer providerRegistry.Unlock()
+	if _, exists := providerRegistry.providers[name]; exists {
+		return NewInvalidParameterError("provider", fmt.Sprintf("%s is already registered", name))
+	}
+	providerRegistry.providers[name] = p
+	providerRegistry.order = append(providerRegistry.order, name)
+	return nil
+}
+
+// lookupProvider returns the registration for name, if any.
+func lookupProvider(name string) (*registeredProvider, bool) {
+	providerRegistry.RLock()
+	defer providerRegistry.RUnlock()
+	p, ok := providerRegistry.providers[name]
+	return p, ok
+}
+
+// registeredProviderNames returns registered provider names in registration order.
+func registeredProviderNames() []string {
+	providerRegistry.RLock()
+	defer providerRegistry.RUnlock()
+	return append([]string(nil), providerRegistry.order...)
+}
+
+// apiKeyFromEnv returns the first non-empty environment variable among the
+// provider's registered keys.
+func (p *registeredProvider) apiKeyFromEnv() string {
+	for _, key := range p.envKeys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package chatdelta
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unregisterProvider removes name from the registry so tests stay independent.
+func unregisterProvider(name string) {
+	providerRegistry.Lock()
+	defer providerRegistry.Unlock()
+	delete(providerRegistry.providers, name)
+	for i, n := range providerRegistry.order {
+		if n == name {
+			providerRegistry.order = append(providerRegistry.order[:i], providerRegistry.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func TestRegisterProvider_CreateClient(t *testing.T) {
+	defer unregisterProvider("acme")
+	t.Setenv("ACME_API_KEY", "env-key")
+
+	var gotKey, gotModel string
+	err := RegisterProvider("Acme", func(apiKey, model string, config *ClientConfig) (AIClient, error) {
+		gotKey, gotModel = apiKey, model
+		return NewMockClient("acme", model), nil
+	}, WithEnvKeys("ACME_API_KEY"), WithDefaultModel("acme-large"))
+	require.NoError(t, err)
+
+	client, err := CreateClient("acme", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", client.Name())
+	assert.Equal(t, "env-key", gotKey)
+	assert.Equal(t, "acme-large", gotModel)
+
+	_, err = CreateClient("ACME", "explicit", "acme-small", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "explicit", gotKey)
+	assert.Equal(t, "acme-small", gotModel)
+
+	assert.Contains(t, GetAvailableProviders(), "acme")
+}
+
+func TestRegisterProvider_MissingKey(t *testing.T) {
+	defer unregisterProvider("keyless")
+	require.NoError(t, RegisterProvider("keyless", func(string, string, *ClientConfig) (AIClient, error) {
+		return NewMockClient("keyless", ""), nil
+	}, WithEnvKeys("CHATDELTA_TEST_UNSET_KEY")))
+
+	_, err := CreateClient("keyless", "", "", nil)
+	assert.Error(t, err)
+	assert.NotContains(t, GetAvailableProviders(), "keyless")
+}
+
+func TestRegisterProvider_Errors(t *testing.T) {
+	defer unregisterProvider("dup")
+	factory := func(string, string, *ClientConfig) (AIClient, error) { return NewMockClient("dup", ""), nil }
+
+	require.NoError(t, RegisterProvider("dup", factory))
+	assert.Error(t, RegisterProvider("DUP", factory), "duplicate names must be rejected")
+	assert.Error(t, RegisterProvider("openai", factory), "built-in names must be rejected")
+	assert.Error(t, RegisterProvider("", factory))
+	assert.Error(t, RegisterProvider("nilfactory", nil))
+}
+
+func TestRegisterProvider_Concurrent(t *testing.T) {
+	factory := func(string, string, *ClientConfig) (AIClient, error) { return NewMockClient("c", ""), nil }
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("concurrent-%d", i%5)
+			if RegisterProvider(name, factory) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+			_, _ = CreateClient(name, "key", "", nil)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 5; i++ {
+		unregisterProvider(fmt.Sprintf("concurrent-%d", i))
+	}
+	assert.Equal(t, 5, succeeded)
+}
+
+func TestRegisterProvider_WithoutAPIKey(t *testing.T) {
+	defer unregisterProvider("localbox")
+	require.NoError(t, RegisterProvider("localbox", func(apiKey, model string, _ *ClientConfig) (AIClient, error) {
+		assert.Empty(t, apiKey)
+		return NewMockClient("localbox", model), nil
+	}, WithoutAPIKey(), WithDefaultModel("tiny")))
+
+	client, err := CreateClient("localbox", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "tiny", client.Model())
+}
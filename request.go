@@ -0,0 +1,168 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// request.go holds the request-preparation plumbing shared by the provider clients:
+// body marshaling with RequestMutator support and the PreparedRequest dry-run view.
+package chatdelta
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// redactedValue replaces credentials in PreparedRequest output.
+const redactedValue = "REDACTED"
+
+// sensitiveHeaders lists headers that carry credentials and are redacted in dry-run
+// output and HTTP hook events.
+var sensitiveHeaders = []string{"Authorization", "x-api-key", "api-key"}
+
+// PreparedRequest is the fully-shaped HTTP request a client would send, including
+// the effect of any RequestMutators. It is returned by DryRun for debugging and
+// never carries credentials: auth headers and the "key" query parameter are redacted.
+type PreparedRequest struct {
+	// Method is the HTTP method, e.g. "POST".
+	Method string `json:"method"`
+	// URL is the target endpoint.
+	URL string `json:"url"`
+	// Header holds the request headers.
+	Header http.Header `json:"header"`
+	// Body is the exact JSON payload.
+	Body json.RawMessage `json:"body"`
+}
+
+// DryRunner is implemented by clients that can report the request they would send
+// for a conversation without performing any network I/O.
+type DryRunner interface {
+	DryRun(conversation *Conversation, stream bool) (*PreparedRequest, error)
+}
+
+// marshalRequestBody marshals request and, when the config has RequestMutators,
+// round-trips it through a generic map so each mutator can edit the payload in order.
+// A mutator error aborts the request with a config error.
+func marshalRequestBody(config *ClientConfig, provider Provider, model string, request interface{}) ([]byte, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, NewJSONParseError(err)
+	}
+	if len(config.RequestMutators) == 0 {
+		return data, nil
+	}
+
+	var body map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as json.Number so integers such as seeds survive the round trip.
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, NewJSONParseError(err)
+	}
+
+	for i, mutate := range config.RequestMutators {
+		if err := mutate(provider, model, body); err != nil {
+			return nil, NewRequestMutatorError(i, err)
+		}
+	}
+
+	data, err = json.Marshal(body)
+	if err != nil {
+		return nil, NewJSONParseError(err)
+	}
+	return data, nil
+}
+
+// newPreparedRequest captures req and body as a PreparedRequest with credentials redacted.
+func newPreparedRequest(req *http.Request, body []byte) *PreparedRequest {
+	return &PreparedRequest{
+		Method: req.Method,
+		URL:    redactURL(req),
+		Header: redactHeader(req.Header),
+		Body:   json.RawMessage(body),
+	}
+}
+
+// redactHeader returns a copy of header with credential headers redacted.
+func redactHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range sensitiveHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, redactedValue)
+		}
+	}
+	return header
+}
+
+// redactURL returns req's URL with the "key" query parameter redacted.
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	if q := u.Query(); q.Has("key") {
+		q.Set("key", redactedValue)
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// requestIDHeaders are the response headers providers use for their request ID:
+// OpenAI and xAI, Anthropic, and AWS respectively.
+var requestIDHeaders = []string{"x-request-id", "request-id", "x-amzn-requestid"}
+
+// requestIDFromHeader returns the provider request ID carried by header, or fallback
+// (typically the response body's ID) when there is none.
+func requestIDFromHeader(header http.Header, fallback string) string {
+	for _, name := range requestIDHeaders {
+		if id := header.Get(name); id != "" {
+			return id
+		}
+	}
+	return fallback
+}
+
+// responseInfo is the transport-level data captured alongside a parsed response
+// body: the provider request ID header, the measured round-trip latency, and the
+// body itself.
+type responseInfo struct {
+	requestID string
+	latency   time.Duration
+	body      []byte
+}
+
+// raw returns the response body when config asks for raw responses.
+func (info responseInfo) raw(config *ClientConfig) json.RawMessage {
+	if !config.IncludeRawResponse {
+		return nil
+	}
+	return info.body
+}
+
+// endpointURL joins path onto the configured BaseURL, falling back to defaultBase
+// when the config does not override it.
+func endpointURL(config *ClientConfig, defaultBase, path string) string {
+	base := defaultBase
+	if config.BaseURL != nil && *config.BaseURL != "" {
+		base = *config.BaseURL
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+// applyExtraHeaders copies the configured ExtraHeaders onto req. It runs after the
+// provider has set its own headers; Content-Type and the reserved headers (the
+// provider's auth header) are never overwritten.
+func applyExtraHeaders(req *http.Request, config *ClientConfig, reserved ...string) {
+	for key, value := range config.ExtraHeaders {
+		canonical := http.CanonicalHeaderKey(key)
+		if canonical == "Content-Type" || containsHeader(reserved, canonical) {
+			continue
+		}
+		req.Header.Set(canonical, value)
+	}
+}
+
+// containsHeader reports whether names contains name, ignoring case.
+func containsHeader(names []string, name string) bool {
+	for _, n := range names {
+		if http.CanonicalHeaderKey(n) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// request_options.go lets a single call override settings baked into a client's
+// ClientConfig, without building a second client or mutating the first.
+//
+// Usage:
+//
+//	response, err := chatdelta.SendPromptWithOptions(ctx, client, "Name a color",
+//	    chatdelta.WithTemperature(0), chatdelta.WithMaxTokens(50))
+package chatdelta
+
+import (
+	"context"
+	"fmt"
+)
+
+// RequestOption overrides one ClientConfig setting for a single call.
+type RequestOption func(*ClientConfig)
+
+// WithTemperature overrides the sampling temperature.
+func WithTemperature(temperature float64) RequestOption {
+	return func(c *ClientConfig) { c.SetTemperature(temperature) }
+}
+
+// WithMaxTokens overrides the response length limit.
+func WithMaxTokens(maxTokens int) RequestOption {
+	return func(c *ClientConfig) { c.SetMaxTokens(maxTokens) }
+}
+
+// WithTopP overrides the nucleus sampling parameter.
+func WithTopP(topP float64) RequestOption {
+	return func(c *ClientConfig) { c.SetTopP(topP) }
+}
+
+// WithTopK overrides the top-k sampling parameter.
+func WithTopK(topK int) RequestOption {
+	return func(c *ClientConfig) { c.SetTopK(topK) }
+}
+
+// WithStopSequences overrides the strings that end generation.
+func WithStopSequences(sequences ...string) RequestOption {
+	return func(c *ClientConfig) { c.SetStopSequences(append([]string(nil), sequences...)...) }
+}
+
+// WithSystemMessage overrides the system message.
+func WithSystemMessage(message string) RequestOption {
+	return func(c *ClientConfig) { c.SetSystemMessage(message) }
+}
+
+// WithLogitBias overrides the per-token bias map.
+func WithLogitBias(bias map[int]int) RequestOption {
+	return func(c *ClientConfig) { c.SetLogitBias(bias) }
+}
+
+// WithLogprobs overrides whether token log probabilities are requested.
+func WithLogprobs(enabled bool) RequestOption {
+	return func(c *ClientConfig) { c.SetLogprobs(enabled) }
+}
+
+// WithTopLogprobs requests token log probabilities with the n most likely
+// alternatives at each position.
+func WithTopLogprobs(n int) RequestOption {
+	return func(c *ClientConfig) { c.SetTopLogprobs(n) }
+}
+
+// WithN overrides the number of completions returned by SendConversationN.
+func WithN(n int) RequestOption {
+	return func(c *ClientConfig) { c.SetN(n) }
+}
+
+// WithSeed overrides the sampling seed.
+func WithSeed(seed int) RequestOption {
+	return func(c *ClientConfig) { c.SetSeed(seed) }
+}
+
+// OptionsClient is implemented by clients that accept per-call overrides. The
+// provider clients implement it, as do the CachingClient, TracingClient,
+// AuditingClient, and RateLimitedClient wrappers when their inner client does.
+type OptionsClient interface {
+	// WithOptions returns a client that sends with opts applied over the receiver's
+	// config. Neither the receiver nor its ClientConfig is modified.
+	WithOptions(opts ...RequestOption) (AIClient, error)
+}
+
+// ClientWithOptions returns a copy of client with opts applied, or client itself
+// when there are no opts. It returns a config error if client does not implement
+// OptionsClient or the resulting config is invalid.
+func ClientWithOptions(client AIClient, opts ...RequestOption) (AIClient, error) {
+	if len(opts) == 0 {
+		return client, nil
+	}
+	configurable, ok := client.(OptionsClient)
+	if !ok {
+		return nil, NewInvalidParameterError("client", fmt.Sprintf("%s does not support per-request options", client.Name()))
+	}
+	return configurable.WithOptions(opts...)
+}
+
+// SendPromptWithOptions sends prompt through client with opts applied to this call only.
+func SendPromptWithOptions(ctx context.Context, client AIClient, prompt string, opts ...RequestOption) (*AiResponse, error) {
+	configured, err := ClientWithOptions(client, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return configured.SendPromptWithMetadata(ctx, prompt)
+}
+
+// SendConversationWithOptions sends conversation through client with opts applied to
+// this call only.
+func SendConversationWithOptions(ctx context.Context, client AIClient, conversation *Conversation, opts ...RequestOption) (*AiResponse, error) {
+	configured, err := ClientWithOptions(client, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return configured.SendConversationWithMetadata(ctx, conversation)
+}
+
+// StreamPromptWithOptions streams prompt through client with opts applied to this
+// call only.
+func StreamPromptWithOptions(ctx context.Context, client AIClient, prompt string, opts ...RequestOption) (<-chan StreamChunk, error) {
+	configured, err := ClientWithOptions(client, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return configured.StreamPrompt(ctx, prompt)
+}
+
+// StreamConversationWithOptions streams conversation through client with opts
+// applied to this call only.
+func StreamConversationWithOptions(ctx context.Context, client AIClient, conversation *Conversation, opts ...RequestOption) (<-chan StreamChunk, error) {
+	configured, err := ClientWithOptions(client, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return configured.StreamConversation(ctx, conversation)
+}
+
+// applyRequestOptions returns a validated copy of config with opts applied.
+func applyRequestOptions(config *ClientConfig, opts []RequestOption) (*ClientConfig, error) {
+	merged := *config
+	for _, opt := range opts {
+		opt(&merged)
+	}
+	if err := ValidateConfig(&merged); err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
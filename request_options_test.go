@@ -0,0 +1,119 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturingOpenAIClient returns an OpenAI client that records each request body.
+func capturingOpenAIClient(t *testing.T, config *ClientConfig) (*OpenAIClient, *[]map[string]any) {
+	t.Helper()
+	client, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+	var bodies []map[string]any
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		data, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(data, &body))
+		bodies = append(bodies, body)
+		return cannedResponse(http.StatusOK, `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)(req)
+	})
+	return client, &bodies
+}
+
+func TestSendPromptWithOptions_OverridesSingleCall(t *testing.T) {
+	config := NewClientConfig().SetTemperature(0.9).SetMaxTokens(500).SetSystemMessage("base")
+	client, bodies := capturingOpenAIClient(t, config)
+	ctx := context.Background()
+
+	_, err := SendPromptWithOptions(ctx, client, "hi",
+		WithTemperature(0), WithMaxTokens(50), WithSystemMessage("override"), WithStopSequences("END"))
+	require.NoError(t, err)
+	_, err = client.SendPrompt(ctx, "hi")
+	require.NoError(t, err)
+
+	require.Len(t, *bodies, 2)
+	overridden, base := (*bodies)[0], (*bodies)[1]
+	assert.Equal(t, 0.0, overridden["temperature"])
+	assert.Equal(t, 50.0, overridden["max_tokens"])
+	assert.Equal(t, []any{"END"}, overridden["stop"])
+	assert.Equal(t, "override", overridden["messages"].([]any)[0].(map[string]any)["content"])
+
+	assert.Equal(t, 0.9, base["temperature"])
+	assert.Equal(t, 500.0, base["max_tokens"])
+	assert.NotContains(t, base, "stop")
+	assert.Equal(t, "base", base["messages"].([]any)[0].(map[string]any)["content"])
+}
+
+func TestSendPromptWithOptions_BaseConfigUntouched(t *testing.T) {
+	config := NewClientConfig().SetTemperature(0.9).SetStopSequences("STOP")
+	client, _ := capturingOpenAIClient(t, config)
+
+	_, err := SendPromptWithOptions(context.Background(), client, "hi",
+		WithTemperature(0.1), WithTopK(5), WithSeed(7), WithStopSequences("END"))
+	require.NoError(t, err)
+
+	assert.Same(t, config, client.clientConfig())
+	assert.Equal(t, 0.9, *config.Temperature)
+	assert.Nil(t, config.TopK)
+	assert.Nil(t, config.Seed)
+	assert.Equal(t, []string{"STOP"}, config.StopSequences)
+}
+
+func TestClientWithOptions_NoOptionsReturnsClient(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	client, err := ClientWithOptions(mock)
+	require.NoError(t, err)
+	assert.Same(t, AIClient(mock), client)
+}
+
+func TestClientWithOptions_UnsupportedClient(t *testing.T) {
+	_, err := ClientWithOptions(NewMockClient("mock", ""), WithTemperature(0))
+	var ce *ClientError
+	require.ErrorAs(t, err, &ce)
+	assert.Equal(t, ErrorTypeConfig, ce.Type)
+	assert.Contains(t, ce.Message, "per-request options")
+}
+
+func TestClientWithOptions_RejectsInvalidOverride(t *testing.T) {
+	client, err := NewClaudeClient("key", "", NewClientConfig())
+	require.NoError(t, err)
+	_, err = ClientWithOptions(client, WithTemperature(5))
+	require.Error(t, err)
+}
+
+func TestClientWithOptions_BedrockFormatsWithOverrides(t *testing.T) {
+	client, err := NewBedrockClient("", NewClientConfig().SetMaxTokens(100).SetBedrock("us-east-1",
+		&AWSCredentials{AccessKeyID: "id", SecretAccessKey: "secret"}))
+	require.NoError(t, err)
+
+	configured, err := ClientWithOptions(client, WithMaxTokens(10))
+	require.NoError(t, err)
+	bedrock := configured.(*BedrockClient)
+	assert.Equal(t, 10, *bedrock.format.config.MaxTokens)
+	assert.Equal(t, 100, *client.format.config.MaxTokens)
+}
+
+func TestClientWithOptions_ThroughCache(t *testing.T) {
+	client, bodies := capturingOpenAIClient(t, NewClientConfig())
+	cached := NewCachingClient(client, NewMemoryCache(MemoryCacheOptions{}))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := SendPromptWithOptions(ctx, cached, "hi", WithTemperature(0))
+		require.NoError(t, err)
+	}
+	_, err := SendPromptWithOptions(ctx, cached, "hi", WithTemperature(1))
+	require.NoError(t, err)
+
+	// The override is part of the cache key: the repeat is a hit, the new value a miss.
+	require.Len(t, *bodies, 2)
+	assert.Equal(t, 1.0, (*bodies)[1]["temperature"])
+}
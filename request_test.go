@@ -0,0 +1,168 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestMutators_AppliedInOrder(t *testing.T) {
+	var seen []string
+	config := NewClientConfig().
+		SetTopP(0.9).
+		SetSeed(123456789).
+		AddRequestMutator(func(p Provider, model string, body map[string]any) error {
+			seen = append(seen, string(p)+":"+model)
+			delete(body, "top_p")
+			body["gateway_tag"] = "first"
+			return nil
+		}).
+		AddRequestMutator(func(_ Provider, _ string, body map[string]any) error {
+			body["gateway_tag"] = body["gateway_tag"].(string) + "+second"
+			return nil
+		})
+
+	client, err := NewOpenAIClient("secret-key", "gpt-4o", config)
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openai:gpt-4o"}, seen)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(prepared.Body, &body))
+	assert.NotContains(t, body, "top_p")
+	assert.Equal(t, "first+second", body["gateway_tag"])
+	// Integers survive the generic round trip unchanged.
+	assert.Contains(t, string(prepared.Body), `"seed":123456789`)
+}
+
+func TestRequestMutators_ErrorAbortsRequest(t *testing.T) {
+	config := NewClientConfig().SetRetries(0).
+		AddRequestMutator(func(Provider, string, map[string]any) error {
+			return errors.New("refusing to send")
+		})
+
+	client, err := NewClaudeClient("secret-key", "", config)
+	require.NoError(t, err)
+	client.httpClient.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Fatal("request must not be sent when a mutator fails")
+		return nil, nil
+	})
+
+	_, err = client.SendPrompt(context.Background(), "hi")
+	require.Error(t, err)
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, ErrorTypeConfig, ce.Type)
+	assert.Equal(t, "request_mutator_failed", ce.Code)
+	assert.EqualError(t, ce.Unwrap(), "refusing to send")
+}
+
+func TestRequestMutators_SentBodyMatchesDryRun(t *testing.T) {
+	config := NewClientConfig().SetRetries(0).
+		AddRequestMutator(func(_ Provider, _ string, body map[string]any) error {
+			body["extra"] = true
+			return nil
+		})
+	client, err := NewGeminiClient("secret-key", "gemini-1.5-flash", config)
+	require.NoError(t, err)
+
+	var sent []byte
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent, _ = io.ReadAll(req.Body)
+		return cannedResponse(http.StatusOK, `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`)(req)
+	})
+
+	_, err = client.SendPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(sent), string(prepared.Body))
+	assert.Contains(t, string(prepared.Body), `"extra":true`)
+}
+
+func TestDryRun_RedactsCredentials(t *testing.T) {
+	openai, _ := NewOpenAIClient("sk-secret", "", nil)
+	claude, _ := NewClaudeClient("sk-ant-secret", "", nil)
+	gemini, _ := NewGeminiClient("AIza-secret", "", nil)
+
+	for _, client := range []DryRunner{openai, claude, gemini} {
+		prepared, err := client.DryRun(promptConversation("hi"), true)
+		require.NoError(t, err)
+
+		dump, err := json.Marshal(prepared)
+		require.NoError(t, err)
+		assert.NotContains(t, string(dump), "secret")
+		assert.Equal(t, "POST", prepared.Method)
+		assert.Equal(t, "application/json", prepared.Header.Get("Content-Type"))
+	}
+
+	prepared, err := claude.DryRun(promptConversation("hi"), true)
+	require.NoError(t, err)
+	assert.Equal(t, redactedValue, prepared.Header.Get("x-api-key"))
+	assert.Equal(t, "text/event-stream", prepared.Header.Get("Accept"))
+}
+
+func TestExtraHeaders_AppliedToAllClients(t *testing.T) {
+	newConfig := func() *ClientConfig {
+		return NewClientConfig().
+			SetRetries(0).
+			SetHeader("Helicone-Auth", "Bearer gateway").
+			SetHeader("http-referer", "https://example.com").
+			SetHeader("Content-Type", "text/plain").
+			SetHeader("Authorization", "Bearer hijack").
+			SetHeader("X-Api-Key", "hijack")
+	}
+	openai, err := NewOpenAIClient("test-key", "", newConfig())
+	require.NoError(t, err)
+	claude, err := NewClaudeClient("test-key", "", newConfig())
+	require.NoError(t, err)
+	gemini, err := NewGeminiClient("test-key", "", newConfig())
+	require.NoError(t, err)
+
+	clients := []struct {
+		client     AIClient
+		httpClient *http.Client
+		authHeader string
+		authValue  string
+	}{
+		{openai, openai.httpClient, "Authorization", "Bearer test-key"},
+		{claude, claude.httpClient, "X-Api-Key", "test-key"},
+		{gemini, gemini.httpClient, "", ""},
+	}
+
+	for _, tc := range clients {
+		for _, stream := range []bool{false, true} {
+			var captured http.Header
+			tc.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				captured = req.Header.Clone()
+				return cannedResponse(http.StatusInternalServerError, `{}`)(req)
+			})
+
+			if stream {
+				ch, err := tc.client.StreamPrompt(context.Background(), "hi")
+				require.NoError(t, err)
+				for range ch {
+				}
+			} else {
+				_, _ = tc.client.SendPrompt(context.Background(), "hi")
+			}
+
+			require.NotNil(t, captured, "%s stream=%v", tc.client.Name(), stream)
+			assert.Equal(t, "Bearer gateway", captured.Get("Helicone-Auth"))
+			assert.Equal(t, "https://example.com", captured.Get("Http-Referer"))
+			assert.Equal(t, "application/json", captured.Get("Content-Type"))
+			if tc.authHeader != "" {
+				assert.Equal(t, tc.authValue, captured.Get(tc.authHeader))
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// response_format.go asks models for machine-readable JSON. A ResponseFormat set on
+// ClientConfig is translated by each client: OpenAI response_format, Gemini
+// responseMimeType and responseSchema, and Ollama format. Claude has no JSON mode,
+// so the instruction and schema are appended to its system prompt instead.
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ResponseFormatType selects how strictly the response is constrained.
+type ResponseFormatType string
+
+const (
+	// ResponseFormatJSONObject asks for any valid JSON object
+	ResponseFormatJSONObject ResponseFormatType = "json_object"
+	// ResponseFormatJSONSchema asks for JSON conforming to ResponseFormat.Schema
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+)
+
+// ResponseFormat requests JSON output from the model.
+type ResponseFormat struct {
+	// Type is ResponseFormatJSONObject or ResponseFormatJSONSchema
+	Type ResponseFormatType `json:"type"`
+	// Name identifies the schema; letters, digits, '_' and '-', at most 64 characters
+	Name string `json:"name,omitempty"`
+	// Schema is the JSON Schema object the response must satisfy
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+// ValidateResponseFormat checks config.ResponseFormat: a schema format needs a valid
+// name and a schema that is a JSON object.
+func ValidateResponseFormat(config *ClientConfig) error {
+	format := config.ResponseFormat
+	if format == nil {
+		return nil
+	}
+	switch format.Type {
+	case ResponseFormatJSONObject:
+		return nil
+	case ResponseFormatJSONSchema:
+	default:
+		return NewInvalidParameterError("response_format", string(format.Type))
+	}
+	if !toolNamePattern.MatchString(format.Name) {
+		return NewInvalidParameterError("response_format", fmt.Sprintf("invalid schema name %q", format.Name))
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(format.Schema, &schema); err != nil {
+		return NewInvalidParameterError("response_format", fmt.Sprintf("schema %q is not a JSON object", format.Name))
+	}
+	return nil
+}
+
+// schema returns the format's schema, or nil for a plain JSON object format.
+func (f *ResponseFormat) schema() json.RawMessage {
+	if f == nil || f.Type != ResponseFormatJSONSchema {
+		return nil
+	}
+	return f.Schema
+}
+
+// instruction returns the system prompt text that stands in for a JSON mode on
+// providers without one.
+func (f *ResponseFormat) instruction() string {
+	if schema := f.schema(); schema != nil {
+		return "Respond only with a JSON object that conforms to this JSON Schema, with no other text:\n" + string(schema)
+	}
+	return "Respond only with a single valid JSON object, with no other text."
+}
+
+// SendPromptJSON sends prompt and decodes the response into out, which must be a
+// pointer. Markdown code fences around the JSON are ignored. If the response does
+// not decode, the returned error is a parse ClientError whose cause is a *ParseError
+// holding the response text.
+func SendPromptJSON(ctx context.Context, client AIClient, prompt string, out any) error {
+	text, err := client.SendPrompt(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(trimJSONFence(text)), out); err != nil {
+		return NewResponseDecodeError(text, err)
+	}
+	return nil
+}
+
+// ParseError carries the response text that could not be decoded.
+type ParseError struct {
+	// Text is the response exactly as the model returned it
+	Text string
+	// Err is the decoding error
+	Err error
+}
+
+// Error implements the error interface
+func (e *ParseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the decoding error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// trimJSONFence strips surrounding whitespace and a ```json or ``` fence, which some
+// models add even when asked for bare JSON.
+func trimJSONFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "```"), "```")
+	text = strings.TrimPrefix(text, "json")
+	return strings.TrimSpace(text)
+}
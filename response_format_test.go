@@ -0,0 +1,79 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var citySchema = json.RawMessage(`{"type":"object","properties":{"name":{"type":"string"}}}`)
+
+func TestValidateResponseFormat(t *testing.T) {
+	assert.NoError(t, ValidateResponseFormat(NewClientConfig()))
+	assert.NoError(t, ValidateResponseFormat(NewClientConfig().SetResponseFormatJSON()))
+	assert.NoError(t, ValidateResponseFormat(NewClientConfig().SetResponseJSONSchema("city", citySchema)))
+
+	assert.Error(t, ValidateResponseFormat(NewClientConfig().SetResponseJSONSchema("bad name", citySchema)))
+	assert.Error(t, ValidateResponseFormat(NewClientConfig().SetResponseJSONSchema("city", json.RawMessage(`[1]`))))
+	assert.Error(t, ValidateResponseFormat(&ClientConfig{ResponseFormat: &ResponseFormat{Type: "xml"}}))
+}
+
+func TestResponseFormatJSON_PerProvider(t *testing.T) {
+	config := NewClientConfig().SetResponseFormatJSON()
+
+	openai, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+	body, err := json.Marshal(openai.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"response_format":{"type":"json_object"}`)
+
+	gemini, err := NewGeminiClient("key", "", config)
+	require.NoError(t, err)
+	body, err = json.Marshal(gemini.buildRequest(promptConversation("hi")))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"generationConfig":{"responseMimeType":"application/json"}`)
+
+	ollama, err := NewOllamaClient("llama3", config)
+	require.NoError(t, err)
+	body, err = json.Marshal(ollama.buildRequest(promptConversation("hi"), false))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"format":"json"`)
+
+	claude, err := NewClaudeClient("key", "", NewClientConfig().SetSystemMessage("Be terse.").SetResponseFormatJSON())
+	require.NoError(t, err)
+	assert.Equal(t, "Be terse.\n\nRespond only with a single valid JSON object, with no other text.",
+		claude.buildRequest(promptConversation("hi"), false).System)
+}
+
+func TestSendPromptJSON_Decodes(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	mock.QueueResponse(`{"name":"Oslo"}`)
+	mock.QueueResponse("```json\n{\"name\":\"Bergen\"}\n```")
+
+	var city struct{ Name string }
+	require.NoError(t, SendPromptJSON(context.Background(), mock, "a city", &city))
+	assert.Equal(t, "Oslo", city.Name)
+
+	require.NoError(t, SendPromptJSON(context.Background(), mock, "another", &city))
+	assert.Equal(t, "Bergen", city.Name)
+}
+
+func TestSendPromptJSON_ParseErrorCarriesText(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	mock.QueueResponse("Sure! Oslo is a city.")
+
+	var city struct{ Name string }
+	err := SendPromptJSON(context.Background(), mock, "a city", &city)
+
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, ErrorTypeParse, ce.Type)
+	assert.Equal(t, "response_decode_error", ce.Code)
+	var pe *ParseError
+	require.True(t, errors.As(err, &pe))
+	assert.Equal(t, "Sure! Oslo is a city.", pe.Text)
+}
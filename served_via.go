@@ -0,0 +1,189 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// served_via.go records how a response was produced. Provider clients mark responses
+// as ServedViaPrimary; wrappers that change how an answer is obtained (fallback,
+// caching, hedging, resuming) append a segment, so composed wrappers yield a path
+// such as "fallback:gemini>cache".
+package chatdelta
+
+import (
+	"context"
+	"strings"
+)
+
+// ServedVia segments recorded in ResponseMetadata.ServedVia.
+const (
+	// ServedViaPrimary means the response came straight from the requested provider
+	ServedViaPrimary = "primary"
+	// ServedViaCache means the response was replayed from an exact-match cache
+	ServedViaCache = "cache"
+	// ServedViaSemanticCache means the response was replayed for a similar prompt
+	ServedViaSemanticCache = "semantic_cache"
+	// ServedViaHedgeWinner means the response won a hedged or raced request
+	ServedViaHedgeWinner = "hedge_winner"
+	// ServedViaResumed means the response was stitched together after an interruption
+	ServedViaResumed = "resumed"
+)
+
+// servedViaSeparator joins segments, innermost first.
+const servedViaSeparator = ">"
+
+// ServedViaFallback returns the segment recorded when provider answered in place of
+// the primary client.
+func ServedViaFallback(provider string) string {
+	return "fallback:" + strings.ToLower(provider)
+}
+
+// AppendServedVia adds segment to the provenance path in metadata. A bare
+// ServedViaPrimary is replaced rather than extended, since the new segment explains
+// how the response deviated from the primary path.
+func AppendServedVia(metadata *ResponseMetadata, segment string) {
+	if metadata.ServedVia == "" || metadata.ServedVia == ServedViaPrimary {
+		metadata.ServedVia = segment
+		return
+	}
+	metadata.ServedVia += servedViaSeparator + segment
+}
+
+// ServedViaSegments splits a provenance path into its segments, innermost first.
+func ServedViaSegments(servedVia string) []string {
+	if servedVia == "" {
+		return nil
+	}
+	return strings.Split(servedVia, servedViaSeparator)
+}
+
+// FallbackClient sends each request to a primary client and, when it fails with a
+// retryable error, to each backup in turn. Responses served by a backup carry a
+// ServedViaFallback segment naming it.
+type FallbackClient struct {
+	primary AIClient
+	backups []AIClient
+}
+
+// NewFallbackClient creates a FallbackClient trying primary, then backups in order.
+func NewFallbackClient(primary AIClient, backups ...AIClient) *FallbackClient {
+	return &FallbackClient{primary: primary, backups: append([]AIClient(nil), backups...)}
+}
+
+// SendPrompt sends prompt, falling back on retryable errors.
+func (f *FallbackClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	response, err := f.SendPromptWithMetadata(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendPromptWithMetadata sends prompt, falling back on retryable errors.
+func (f *FallbackClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	return f.sendWithFallback(func(client AIClient) (*AiResponse, error) {
+		return client.SendPromptWithMetadata(ctx, prompt)
+	})
+}
+
+// SendConversation sends conversation, falling back on retryable errors.
+func (f *FallbackClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	response, err := f.SendConversationWithMetadata(ctx, conversation)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendConversationWithMetadata sends conversation, falling back on retryable errors.
+func (f *FallbackClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	return f.sendWithFallback(func(client AIClient) (*AiResponse, error) {
+		return client.SendConversationWithMetadata(ctx, conversation)
+	})
+}
+
+// StreamPrompt streams prompt. Fallback only happens when a client fails to start
+// the stream; errors after the first chunk are delivered as-is.
+func (f *FallbackClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	return f.streamWithFallback(ctx, func(client AIClient) (<-chan StreamChunk, error) {
+		return client.StreamPrompt(ctx, prompt)
+	})
+}
+
+// StreamConversation streams conversation, with the same fallback rules as StreamPrompt.
+func (f *FallbackClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
+	return f.streamWithFallback(ctx, func(client AIClient) (<-chan StreamChunk, error) {
+		return client.StreamConversation(ctx, conversation)
+	})
+}
+
+// sendWithFallback runs send against the primary and then each backup until one
+// succeeds or a non-retryable error occurs.
+func (f *FallbackClient) sendWithFallback(send func(AIClient) (*AiResponse, error)) (*AiResponse, error) {
+	response, err := send(f.primary)
+	if err == nil || !IsRetryableError(err) {
+		return response, err
+	}
+	for _, backup := range f.backups {
+		response, err = send(backup)
+		if err == nil {
+			AppendServedVia(&response.Metadata, ServedViaFallback(backup.Name()))
+			return response, nil
+		}
+		if !IsRetryableError(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// streamWithFallback is sendWithFallback for streams. The terminal chunk of a
+// backup's stream gets the fallback segment; ctx is the request's context.
+func (f *FallbackClient) streamWithFallback(ctx context.Context, stream func(AIClient) (<-chan StreamChunk, error)) (<-chan StreamChunk, error) {
+	ch, err := stream(f.primary)
+	if err == nil || !IsRetryableError(err) {
+		return ch, err
+	}
+	for _, backup := range f.backups {
+		ch, err = stream(backup)
+		if err == nil {
+			return tagStream(ctx, ch, ServedViaFallback(backup.Name())), nil
+		}
+		if !IsRetryableError(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// tagStream forwards src, appending segment to the terminal chunk's metadata. If ctx
+// ends while a chunk goes unread, the rest of src is drained unforwarded.
+func tagStream(ctx context.Context, src <-chan StreamChunk, segment string) <-chan StreamChunk {
+	out := make(chan StreamChunk, 10)
+	go func() {
+		defer close(out)
+		for chunk := range src {
+			if chunk.Finished {
+				var metadata ResponseMetadata
+				if chunk.Metadata != nil {
+					metadata = *chunk.Metadata
+				}
+				AppendServedVia(&metadata, segment)
+				chunk.Metadata = &metadata
+			}
+			if !deliverChunk(ctx, out, chunk) {
+				for range src {
+				}
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// SupportsStreaming reports whether the primary client supports streaming.
+func (f *FallbackClient) SupportsStreaming() bool { return f.primary.SupportsStreaming() }
+
+// SupportsConversations reports whether the primary client supports conversations.
+func (f *FallbackClient) SupportsConversations() bool { return f.primary.SupportsConversations() }
+
+// Name returns the primary client's name.
+func (f *FallbackClient) Name() string { return f.primary.Name() }
+
+// Model returns the primary client's model.
+func (f *FallbackClient) Model() string { return f.primary.Model() }
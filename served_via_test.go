@@ -0,0 +1,88 @@
+package chatdelta
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendServedVia(t *testing.T) {
+	meta := ResponseMetadata{ServedVia: ServedViaPrimary}
+	AppendServedVia(&meta, ServedViaFallback("Gemini"))
+	assert.Equal(t, "fallback:gemini", meta.ServedVia)
+
+	AppendServedVia(&meta, ServedViaCache)
+	assert.Equal(t, "fallback:gemini>cache", meta.ServedVia)
+	assert.Equal(t, []string{"fallback:gemini", "cache"}, ServedViaSegments(meta.ServedVia))
+}
+
+func TestFallbackClient_PrimaryServes(t *testing.T) {
+	primary := NewMockClient("openai", "gpt-4o")
+	primary.QueueResponse("hello")
+
+	response, err := NewFallbackClient(primary, NewMockClient("claude", "")).SendPromptWithMetadata(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, ServedViaPrimary, response.Metadata.ServedVia)
+}
+
+func TestFallbackClient_NonRetryableErrorDoesNotFallBack(t *testing.T) {
+	primary := NewMockClient("openai", "")
+	primary.QueueError(NewInvalidAPIKeyError())
+	backup := NewMockClient("claude", "")
+
+	_, err := NewFallbackClient(primary, backup).SendPrompt(context.Background(), "hi")
+	assert.True(t, IsAuthenticationError(err))
+	assert.Equal(t, 0, backup.CallCount())
+}
+
+func TestFallbackClient_AbandonedStreamStopsForwarding(t *testing.T) {
+	primary := NewMockClient("openai", "")
+	primary.QueueError(NewServerError(503, "unavailable"))
+	backup := NewMockClient("claude", "")
+	backup.SetChunkSize(1)
+	backup.QueueResponse(strings.Repeat("x", 100))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := NewFallbackClient(primary, backup).StreamPrompt(ctx, "hi")
+	require.NoError(t, err)
+	cancel()
+
+	// Once the grace period passes, the forwarding goroutine gives up and closes the
+	// channel; one still blocked on a send would hand over every chunk instead.
+	time.Sleep(3 * streamAbandonGrace)
+	received := 0
+	for range ch {
+		received++
+	}
+	assert.Less(t, received, 101, "chunks after the abandonment are not forwarded")
+}
+
+func TestServedVia_ComposedWrappers(t *testing.T) {
+	down := func(name string) *MockClient {
+		client := NewMockClient(name, "")
+		client.SetDefaultResponse("", NewServerError(503, "unavailable"))
+		return client
+	}
+	gemini := NewMockClient("gemini", "gemini-1.5-flash")
+	gemini.SetDefaultResponse("answer", nil)
+
+	// fallback(openai → middleware(fallback(claude → gemini)))
+	inner := NewFallbackClient(down("claude"), gemini)
+	logged := NewMiddlewareClient(inner, TimeoutMiddleware(time.Second))
+	outer := NewFallbackClient(down("openai"), logged)
+
+	response, err := outer.SendConversationWithMetadata(context.Background(), promptConversation("q"))
+	require.NoError(t, err)
+	assert.Equal(t, "answer", response.Content)
+	assert.Equal(t, "fallback:gemini>fallback:claude", response.Metadata.ServedVia)
+
+	ch, err := outer.StreamPrompt(context.Background(), "q")
+	require.NoError(t, err)
+	_, last := collectStream(t, ch)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, "fallback:gemini>fallback:claude", last.Metadata.ServedVia)
+}
@@ -2,19 +2,72 @@ package chatdelta
 
 import (
 	"context"
+	"io"
+	"strings"
+	"sync"
 )
 
 // ChatSession manages multi-turn conversations with an AI client.
 // It automatically maintains conversation history and handles context.
 //
+// A ChatSession is safe for concurrent use once configured: Send, Stream, History,
+// Len, Clear, and the other history methods may be called from any goroutine. The
+// history is only updated when a turn completes, so History does not show a
+// message whose request is still in flight, and concurrent turns are recorded in
+// the order they finish. A turn that completes after Clear, ResetWithSystem, Undo,
+// or Import is not recorded. Configure compaction and trimming before sharing the
+// session.
+//
 // Example:
 //
 //	session := NewChatSessionWithSystemMessage(client, "You are a helpful assistant.")
 //	response1, err := session.Send(ctx, "What is Go?")
 //	response2, err := session.Send(ctx, "What are its benefits?") // Remembers context
 type ChatSession struct {
-	client       AIClient
+	client AIClient
+	// mu guards conversation, version, and resets
+	mu           sync.Mutex
 	conversation *Conversation
+	// version counts changes to conversation; resets counts the changes that
+	// replace it outright (Clear, ResetWithSystem, Import)
+	version    uint64
+	resets     uint64
+	compaction *CompactionOptions
+	// maxContextTokens, when positive, caps the estimated history size per request
+	maxContextTokens int
+	estimator        TokenEstimator
+	// maxHistoryMessages and maxHistoryTokens, when positive, cap the history by
+	// dropping whole exchanges before each request
+	maxHistoryMessages int
+	maxHistoryTokens   int
+	historyEstimator   TokenEstimator
+	// contextCounts and historyCounts remember estimator and historyEstimator's
+	// per-message counts across requests
+	contextCounts *tokenCountCache
+	historyCounts *tokenCountCache
+	// checkContext runs CheckContextWindow before each request
+	checkContext bool
+	// stats accumulates the usage of recorded turns; guarded by mu
+	stats SessionStats
+}
+
+// SessionStats summarises the usage of the turns a ChatSession has recorded.
+type SessionStats struct {
+	// Requests is the number of recorded turns
+	Requests int `json:"requests"`
+	// PromptTokens is the total number of prompt tokens the provider reported
+	PromptTokens int `json:"prompt_tokens"`
+	// CompletionTokens is the total number of completion tokens the provider reported
+	CompletionTokens int `json:"completion_tokens"`
+	// TotalTokens is the total number of tokens the provider reported
+	TotalTokens int `json:"total_tokens"`
+	// LatencyMs is the cumulative latency the provider reported
+	LatencyMs int64 `json:"latency_ms"`
+	// EstimatedCost is the estimated cost in USD of the priced turns; see EstimateCost
+	EstimatedCost float64 `json:"estimated_cost"`
+	// UnpricedRequests counts the turns whose model has no price, which are left
+	// out of EstimatedCost
+	UnpricedRequests int `json:"unpriced_requests"`
 }
 
 // NewChatSession creates a new chat session with the given client.
@@ -37,108 +90,366 @@ func NewChatSessionWithSystemMessage(client AIClient, message string) *ChatSessi
 	return session
 }
 
+// NewChatSessionFromConversation creates a chat session that continues conversation.
+// The session works on a copy, so later changes to conversation do not affect it. A
+// nil conversation starts the session empty.
+func NewChatSessionFromConversation(client AIClient, conversation *Conversation) *ChatSession {
+	session := NewChatSession(client)
+	if conversation != nil {
+		session.conversation = conversation.Clone()
+	}
+	return session
+}
+
+// sessionTurn is a request in flight: the new messages it adds to the history and
+// the conversation actually sent, as of the history version it was built from.
+type sessionTurn struct {
+	pending      []Message
+	conversation *Conversation
+	version      uint64
+	resets       uint64
+}
+
+// beginTurn copies the history, appends pending, and compacts and trims the copy
+// for sending. The session's history is not modified.
+func (s *ChatSession) beginTurn(ctx context.Context, pending ...Message) (*sessionTurn, error) {
+	s.mu.Lock()
+	turn := &sessionTurn{
+		pending:      pending,
+		conversation: s.conversation.Clone(),
+		version:      s.version,
+		resets:       s.resets,
+	}
+	s.mu.Unlock()
+
+	turn.conversation.Messages = append(turn.conversation.Messages, pending...)
+	if err := s.fitContext(ctx, turn.conversation); err != nil {
+		return nil, err
+	}
+	if s.checkContext {
+		if err := CheckContextWindow(s.client, turn.conversation); err != nil {
+			return nil, err
+		}
+	}
+	return turn, nil
+}
+
+// finishTurn records a completed turn, its reply, and the usage in meta, which may
+// be nil. If the history is unchanged since the turn began it becomes the
+// conversation that was sent, keeping any compaction or trimming; if other turns
+// finished meanwhile, the turn's messages are appended after theirs; and if the
+// history was replaced, the turn is dropped.
+func (s *ChatSession) finishTurn(turn *sessionTurn, reply Message, meta *ResponseMetadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case s.resets != turn.resets:
+		return
+	case s.version == turn.version:
+		s.conversation = turn.conversation
+	default:
+		s.conversation.Messages = append(s.conversation.Messages, turn.pending...)
+	}
+	s.conversation.Messages = append(s.conversation.Messages, reply)
+	s.version++
+	s.recordUsage(meta)
+}
+
+// recordUsage adds a turn with the usage in meta to the statistics. The caller holds
+// mu.
+func (s *ChatSession) recordUsage(meta *ResponseMetadata) {
+	s.stats.Requests++
+	if meta == nil {
+		return
+	}
+	s.stats.PromptTokens += meta.PromptTokens
+	s.stats.CompletionTokens += meta.CompletionTokens
+	s.stats.TotalTokens += meta.TotalTokens
+	s.stats.LatencyMs += meta.LatencyMs
+	if cost, err := estimateCostWithFallback(s.client.Model(), *meta); err == nil {
+		s.stats.EstimatedCost += cost
+	} else {
+		s.stats.UnpricedRequests++
+	}
+}
+
+// Stats returns the usage of the turns recorded since the session was created or its
+// history was last replaced by Clear, ResetWithSystem, or Import. Failed turns, turns
+// discarded by Clear or Undo, and requests made to compact the history are not
+// counted. Token counts and latency are those the provider reported, so they stay
+// zero for clients that report none.
+func (s *ChatSession) Stats() SessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
 // Send sends a message and gets a response.
 // The message is added to the conversation history as a user message,
 // and the response is added as an assistant message.
-// If an error occurs, the user message is removed from history.
+// If an error occurs, the history is left unchanged.
 func (s *ChatSession) Send(ctx context.Context, message string) (string, error) {
-	s.conversation.AddUserMessage(message)
-	
-	response, err := s.client.SendConversation(ctx, s.conversation)
+	response, err := s.sendPending(ctx, Message{Role: "user", Content: message})
 	if err != nil {
-		// Remove the user message if the request failed
-		if len(s.conversation.Messages) > 0 {
-			s.conversation.Messages = s.conversation.Messages[:len(s.conversation.Messages)-1]
-		}
 		return "", err
 	}
-	
-	s.conversation.AddAssistantMessage(response)
-	return response, nil
+	return response.Content, nil
 }
 
 // SendWithMetadata sends a message and gets a response with metadata.
 // This includes token counts, latency, and other provider-specific information.
-// The conversation history is updated the same as Send.
+// The conversation history is updated the same as Send; tool calls in the response
+// are recorded with it, so the results can be returned with SendToolResults.
 func (s *ChatSession) SendWithMetadata(ctx context.Context, message string) (*AiResponse, error) {
-	s.conversation.AddUserMessage(message)
-	
-	response, err := s.client.SendConversationWithMetadata(ctx, s.conversation)
+	return s.sendPending(ctx, Message{Role: "user", Content: message})
+}
+
+// SendToolResults adds the results of the tool calls from the previous response to
+// the history and sends it, returning the model's next response, which may request
+// further tool calls. If an error occurs, the history is left unchanged.
+func (s *ChatSession) SendToolResults(ctx context.Context, results ...ToolResult) (*AiResponse, error) {
+	pending := make([]Message, len(results))
+	for i, result := range results {
+		pending[i] = Message{Role: "tool", Content: result.Content, ToolCallID: result.ToolCallID}
+	}
+	return s.sendPending(ctx, pending...)
+}
+
+// sendPending sends the history followed by pending and records the response. The
+// history is left unchanged if the request fails.
+func (s *ChatSession) sendPending(ctx context.Context, pending ...Message) (*AiResponse, error) {
+	turn, err := s.beginTurn(ctx, pending...)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.client.SendConversationWithMetadata(ctx, turn.conversation)
 	if err != nil {
-		// Remove the user message if the request failed
-		if len(s.conversation.Messages) > 0 {
-			s.conversation.Messages = s.conversation.Messages[:len(s.conversation.Messages)-1]
-		}
 		return nil, err
 	}
-	
-	s.conversation.AddAssistantMessage(response.Content)
+
+	s.finishTurn(turn, response.ToMessage(), &response.Metadata)
 	return response, nil
 }
 
 // Stream sends a message and returns a channel for streaming chunks.
-// The complete response is assembled and added to history when streaming completes.
-// The returned channel is buffered and will be closed when streaming ends.
+// The channel is unbuffered, and the turn is recorded only once the consumer has
+// received the final chunk: the complete (or Truncated partial) response is added to
+// history before the channel is closed, so ranging to the end and then reading
+// History sees it. A stream that ends with an error leaves the history unchanged.
+//
+// To stop reading early, cancel ctx rather than just breaking out of the loop. Once
+// ctx has ended, a consumer that does not take the next chunk within
+// streamAbandonGrace is treated as gone: the stream's goroutines exit and the turn
+// is discarded as if it had failed, so the history never holds a partial exchange.
+// The stream then ends with a chunk whose Err wraps ctx's error, for a consumer that
+// comes back to read it. A consumer still ranging, however slowly, gets every chunk;
+// while ctx is live no chunk is ever dropped. Breaking out without cancelling ctx
+// leaves the stream blocked until ctx ends.
 func (s *ChatSession) Stream(ctx context.Context, message string) (<-chan StreamChunk, error) {
-	s.conversation.AddUserMessage(message)
-	
-	chunks, err := s.client.StreamConversation(ctx, s.conversation)
+	turn, err := s.beginTurn(ctx, Message{Role: "user", Content: message})
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := s.client.StreamConversation(ctx, turn.conversation)
 	if err != nil {
-		// Remove the user message if the request failed
-		if len(s.conversation.Messages) > 0 {
-			s.conversation.Messages = s.conversation.Messages[:len(s.conversation.Messages)-1]
-		}
 		return nil, err
 	}
-	
-	// Create a wrapper channel to collect the full response
-	wrapped := make(chan StreamChunk, 100)
+
+	wrapped := make(chan StreamChunk)
 	go func() {
 		defer close(wrapped)
-		var fullContent string
+		var fullContent strings.Builder
 		for chunk := range chunks {
-			fullContent += chunk.Content
-			wrapped <- chunk
-			if chunk.Finished {
-				// Add the complete response to conversation
-				s.conversation.AddAssistantMessage(fullContent)
+			fullContent.WriteString(chunk.Content)
+			if !deliverChunk(ctx, wrapped, chunk) {
+				// Abandoned: let the client's stream finish without a reader, then
+				// offer a consumer that comes back the reason the stream ended
+				for range chunks {
+				}
+				deliverChunk(ctx, wrapped, StreamChunk{Finished: true, Err: NewStreamReadError(ctx.Err())})
+				return
+			}
+			if chunk.Finished && chunk.Err == nil {
+				s.finishTurn(turn, Message{Role: "assistant", Content: fullContent.String()}, chunk.Metadata)
 			}
 		}
 	}()
-	
+
 	return wrapped, nil
 }
 
+// SetMaxContextTokens makes the session trim its history with TrimToTokenLimit before
+// each request, so the estimated size stays within maxTokens. System messages are
+// always kept; the oldest other messages are truncated or dropped first. Sizes are
+// estimates from estimator (nil uses DefaultTokenEstimator), not exact tokenizer
+// counts, so leave headroom below the model's context window. Compaction, when
+// enabled, runs first. A non-positive maxTokens disables trimming.
+func (s *ChatSession) SetMaxContextTokens(maxTokens int, estimator TokenEstimator) *ChatSession {
+	s.maxContextTokens = maxTokens
+	s.estimator = estimator
+	s.contextCounts = newTokenCountCache(estimator)
+	return s
+}
+
+// SetMaxHistoryMessages makes the session drop its oldest exchanges before each
+// request until at most n non-system messages remain. An exchange is a user message
+// with the replies and tool results that follow it, so user and assistant messages
+// are removed together; system messages and the exchange being sent are always kept.
+// A non-positive n disables the limit.
+func (s *ChatSession) SetMaxHistoryMessages(n int) *ChatSession {
+	s.maxHistoryMessages = n
+	return s
+}
+
+// SetMaxHistoryTokens makes the session drop its oldest exchanges before each
+// request, with Conversation.TruncateToTokens, until the history's size as estimated
+// by estimator (nil uses DefaultTokenEstimator) fits n. Messages are never shortened;
+// combine with SetMaxContextTokens to also cap an oversized latest exchange. A
+// non-positive n disables the limit.
+func (s *ChatSession) SetMaxHistoryTokens(n int, estimator TokenEstimator) *ChatSession {
+	s.maxHistoryTokens = n
+	s.historyEstimator = estimator
+	s.historyCounts = newTokenCountCache(estimator)
+	return s
+}
+
+// fitContext applies the history limits, then compacts and trims conversation
+// before a request. Token counts are cached per message, so only messages new since
+// the last request are estimated.
+func (s *ChatSession) fitContext(ctx context.Context, conversation *Conversation) error {
+	if s.maxHistoryMessages > 0 {
+		conversation.truncateToMessages(s.maxHistoryMessages)
+	}
+	if s.maxHistoryTokens > 0 {
+		conversation.TruncateToTokens(s.maxHistoryTokens, s.historyCounts)
+		s.historyCounts.retain(conversation.Messages)
+	}
+	if err := s.compactIfNeeded(ctx, conversation); err != nil {
+		return err
+	}
+	if s.maxContextTokens > 0 {
+		conversation.TrimToTokenLimit(s.maxContextTokens, s.contextCounts)
+		s.contextCounts.retain(conversation.Messages)
+	}
+	return nil
+}
+
+// SetContextCheck makes the session run CheckContextWindow on each request after
+// compaction and trimming, failing with an error matching ErrContextTooLarge instead
+// of sending a conversation estimated not to fit the model's context window. The
+// history is left unchanged, so the caller can trim it and try again.
+func (s *ChatSession) SetContextCheck(enabled bool) *ChatSession {
+	s.checkContext = enabled
+	return s
+}
+
 // AddMessage adds a message to the conversation without sending it.
 // Use this to manually construct conversation history.
 func (s *ChatSession) AddMessage(message Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.conversation.Messages = append(s.conversation.Messages, message)
+	s.version++
+}
+
+// Undo removes the last exchange from the history: the last user message and
+// everything after it, normally the assistant's reply. It returns the removed
+// messages, oldest first, and false if the history holds no user message, in which
+// case nothing changes. Like Clear, it discards turns still in flight.
+func (s *ChatSession) Undo() ([]Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := s.conversation.Messages
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		removed := append([]Message(nil), messages[i:]...)
+		s.conversation = &Conversation{Messages: append([]Message(nil), messages[:i]...)}
+		s.version++
+		s.resets++
+		return removed, true
+	}
+	return nil, false
+}
+
+// Fork returns an independent copy of the session bound to the same client: its
+// history is a deep copy of the current one, and its compaction and trimming
+// settings match. Turns sent on either session do not affect the other.
+func (s *ChatSession) Fork() *ChatSession {
+	return s.ForkWithClient(s.client)
 }
 
-// History returns the conversation history.
-// The returned conversation can be modified directly if needed.
+// ForkWithClient is like Fork but binds the copy to client, for example to continue
+// the conversation with another provider.
+func (s *ChatSession) ForkWithClient(client AIClient) *ChatSession {
+	s.mu.Lock()
+	conversation := s.conversation.Clone()
+	s.mu.Unlock()
+	return &ChatSession{
+		client:             client,
+		conversation:       conversation,
+		compaction:         s.compaction,
+		maxContextTokens:   s.maxContextTokens,
+		estimator:          s.estimator,
+		maxHistoryMessages: s.maxHistoryMessages,
+		maxHistoryTokens:   s.maxHistoryTokens,
+		historyEstimator:   s.historyEstimator,
+		contextCounts:      newTokenCountCache(s.estimator),
+		historyCounts:      newTokenCountCache(s.historyEstimator),
+		checkContext:       s.checkContext,
+	}
+}
+
+// History returns a copy of the conversation history. Changes to the copy do not
+// affect the session; use AddMessage, Clear, or Import to change the history.
 func (s *ChatSession) History() *Conversation {
-	return s.conversation
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conversation.Clone()
 }
 
-// Clear removes all messages from the conversation history.
+// Clear removes all messages from the conversation history and resets Stats.
 func (s *ChatSession) Clear() {
-	s.conversation.Messages = make([]Message, 0)
+	s.replace(NewConversation())
 }
 
-// ResetWithSystem clears the conversation and sets a new system message.
+// ResetWithSystem clears the conversation and Stats and sets a new system message.
 // This is useful for changing the AI's behavior mid-session.
 func (s *ChatSession) ResetWithSystem(message string) {
-	s.conversation = NewConversation()
-	s.conversation.AddSystemMessage(message)
+	conversation := NewConversation()
+	conversation.AddSystemMessage(message)
+	s.replace(conversation)
+}
+
+// replace swaps in a new history and resets the statistics. Turns in flight are not
+// recorded in either.
+func (s *ChatSession) replace(conversation *Conversation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conversation = conversation
+	s.stats = SessionStats{}
+	s.version++
+	s.resets++
 }
 
 // Len returns the number of messages in the conversation.
 func (s *ChatSession) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return len(s.conversation.Messages)
 }
 
 // IsEmpty returns true if the conversation has no messages.
 func (s *ChatSession) IsEmpty() bool {
-	return len(s.conversation.Messages) == 0
-}
\ No newline at end of file
+	return s.Len() == 0
+}
+
+// saveHistory writes the history to w while holding the lock.
+func (s *ChatSession) saveHistory(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conversation.Save(w)
+}
@@ -0,0 +1,618 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatSession_SendKeepsHistory(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.ScriptReply("What is Go?", "A programming language.")
+	m.ScriptReply("Who made it?", "Google.")
+
+	s := NewChatSessionWithSystemMessage(m, "You are terse.")
+	r1, err := s.Send(context.Background(), "What is Go?")
+	require.NoError(t, err)
+	assert.Equal(t, "A programming language.", r1)
+
+	r2, err := s.Send(context.Background(), "Who made it?")
+	require.NoError(t, err)
+	assert.Equal(t, "Google.", r2)
+
+	assert.Equal(t, 5, s.Len())
+	// The second request carried the whole history.
+	recorded := m.Conversations()
+	require.Len(t, recorded, 2)
+	assert.Len(t, recorded[1].Messages, 4)
+	assert.Equal(t, "assistant", recorded[1].Messages[2].Role)
+}
+
+func TestChatSession_SendErrorRollsBack(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.QueueError(errors.New("boom"))
+
+	s := NewChatSession(m)
+	_, err := s.Send(context.Background(), "hello")
+	assert.EqualError(t, err, "boom")
+	assert.True(t, s.IsEmpty())
+}
+
+func TestChatSession_SendWithMetadata(t *testing.T) {
+	m := NewMockClient("mock", "mock-1")
+	m.QueueResponse("hi")
+
+	s := NewChatSession(m)
+	resp, err := s.SendWithMetadata(context.Background(), "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hi", resp.Content)
+	assert.Equal(t, "mock-1", resp.Metadata.ModelUsed)
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestChatSession_Stream(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.SetChunkSize(4)
+	m.QueueResponse("streamed reply")
+
+	s := NewChatSession(m)
+	chunks, err := s.Stream(context.Background(), "hello")
+	require.NoError(t, err)
+
+	var content string
+	for chunk := range chunks {
+		content += chunk.Content
+	}
+	assert.Equal(t, "streamed reply", content)
+	require.Equal(t, 2, s.Len())
+	assert.Equal(t, "streamed reply", s.History().Messages[1].Content)
+}
+
+func TestChatSession_ClearAndReset(t *testing.T) {
+	s := NewChatSession(NewMockClient("mock", ""))
+	_, err := s.Send(context.Background(), "hello")
+	require.NoError(t, err)
+
+	s.Clear()
+	assert.True(t, s.IsEmpty())
+
+	s.ResetWithSystem("new system")
+	require.Equal(t, 1, s.Len())
+	assert.Equal(t, "system", s.History().Messages[0].Role)
+}
+
+func TestChatSession_StreamPassesMetadata(t *testing.T) {
+	server := transcriptServer(t, "claude_stream.sse")
+	client, err := NewClaudeClient("test-key", "", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	session := NewChatSession(client)
+	ch, err := session.Stream(context.Background(), "hi")
+	require.NoError(t, err)
+	_, last := collectStream(t, ch)
+
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, 7, last.Metadata.CompletionTokens)
+	assert.Equal(t, "Hello there!", session.History().Messages[1].Content)
+}
+
+func TestChatSession_CompactionKeepsSystemAndRecentMessages(t *testing.T) {
+	client := NewMockClient("mock", "")
+	session := NewChatSessionWithSystemMessage(client, "You are a travel agent.")
+	for i := 0; i < 6; i++ {
+		session.AddMessage(Message{Role: "user", Content: strings.Repeat("I would like to visit Lisbon in spring. ", 3)})
+		session.AddMessage(Message{Role: "assistant", Content: strings.Repeat("Lisbon in April is lovely and mild. ", 3)})
+	}
+	session.SetCompaction(CompactionOptions{Threshold: 200, KeepLast: 3})
+
+	client.QueueResponse("The user plans a spring trip to Lisbon.")
+	client.QueueResponse("Booked.")
+	_, err := session.Send(context.Background(), "Book the flight.")
+	require.NoError(t, err)
+
+	messages := session.History().Messages
+	require.Len(t, messages, 6, "system + recap + 3 kept + new reply")
+	assert.Equal(t, Message{Role: "system", Content: "You are a travel agent."}, messages[0])
+	assert.Equal(t, "assistant", messages[1].Role)
+	assert.Equal(t, compactionRecapPrefix+"The user plans a spring trip to Lisbon.", messages[1].Content)
+	assert.Equal(t, "Book the flight.", messages[4].Content)
+	assert.Equal(t, "Booked.", messages[5].Content)
+
+	sent := client.Conversations()
+	require.Len(t, sent, 2)
+	assert.Contains(t, lastUserMessage(sent[0]), "user: I would like to visit Lisbon", "the recap request carries the transcript")
+	assert.LessOrEqual(t, estimateConversationTokens(sent[1].Messages, DefaultTokenEstimator), 200)
+}
+
+func TestChatSession_CompactionShortensOversizedRecap(t *testing.T) {
+	client := NewMockClient("mock", "")
+	session := NewChatSession(client).SetCompaction(CompactionOptions{Threshold: 60, KeepLast: 1})
+	for i := 0; i < 4; i++ {
+		session.AddMessage(Message{Role: "user", Content: strings.Repeat("filler ", 20)})
+	}
+
+	client.QueueResponse(strings.Repeat("A very long recap sentence. ", 40))
+	client.QueueResponse("ok")
+	_, err := session.Send(context.Background(), "next")
+	require.NoError(t, err)
+
+	sent := client.Conversations()
+	assert.LessOrEqual(t, estimateConversationTokens(sent[1].Messages, DefaultTokenEstimator), 60)
+}
+
+func TestChatSession_CompactionErrorRollsBack(t *testing.T) {
+	client := NewMockClient("mock", "")
+	session := NewChatSession(client).SetCompaction(CompactionOptions{Threshold: 10, KeepLast: 1})
+	session.AddMessage(Message{Role: "user", Content: strings.Repeat("long ", 40)})
+	session.AddMessage(Message{Role: "assistant", Content: strings.Repeat("long ", 40)})
+
+	client.QueueError(NewServerError(500, "down"))
+	_, err := session.Send(context.Background(), "next")
+	require.Error(t, err)
+	assert.Equal(t, 2, session.Len(), "history is unchanged")
+}
+
+// wordEstimator counts one token per word.
+type wordEstimator struct{}
+
+func (wordEstimator) EstimateTokens(text string) int { return len(strings.Fields(text)) }
+
+func TestChatSession_MaxContextTokensTrimsBeforeSend(t *testing.T) {
+	client := NewMockClient("mock", "")
+	session := NewChatSessionWithSystemMessage(client, "You are terse.").SetMaxContextTokens(40, wordEstimator{})
+	for i := 0; i < 5; i++ {
+		session.AddMessage(Message{Role: "user", Content: "tell me one more fact please"})
+		session.AddMessage(Message{Role: "assistant", Content: "here is yet another fact"})
+	}
+
+	client.QueueResponse("ok")
+	_, err := session.Send(context.Background(), "last question")
+	require.NoError(t, err)
+
+	sent := client.Conversations()[0]
+	assert.LessOrEqual(t, estimateConversationTokens(sent.Messages, wordEstimator{}), 40)
+	assert.Equal(t, Message{Role: "system", Content: "You are terse."}, sent.Messages[0])
+	assert.Equal(t, "last question", sent.Messages[len(sent.Messages)-1].Content)
+	assert.Less(t, len(sent.Messages), 12, "oldest messages were dropped")
+
+	session.SetMaxContextTokens(0, nil)
+	for i := 0; i < 5; i++ {
+		session.AddMessage(Message{Role: "user", Content: "tell me one more fact please"})
+	}
+	before := session.Len()
+	client.QueueResponse("ok")
+	_, err = session.Send(context.Background(), "untrimmed")
+	require.NoError(t, err)
+	assert.Equal(t, before+2, session.Len(), "trimming is disabled")
+}
+
+func TestChatSession_TokenCountsCachedAcrossTurns(t *testing.T) {
+	client := NewMockClient("mock", "")
+	estimator := &countingEstimator{inner: wordEstimator{}}
+	session := NewChatSessionWithSystemMessage(client, "You are terse.").
+		SetMaxContextTokens(1000, estimator).SetMaxHistoryTokens(1000, estimator)
+
+	for i := 0; i < 5; i++ {
+		client.QueueResponse(fmt.Sprintf("answer %d", i))
+		_, err := session.Send(context.Background(), fmt.Sprintf("question %d", i))
+		require.NoError(t, err)
+	}
+	// Each text is estimated once per limit, however many requests it is sent in
+	assert.Equal(t, 2, estimator.texts["You are terse."])
+	assert.Equal(t, 2, estimator.texts["question 0"])
+	assert.Equal(t, 2, estimator.texts["answer 3"])
+
+	// An edited history is estimated afresh
+	_, ok := session.Undo()
+	require.True(t, ok)
+	session.AddMessage(Message{Role: "user", Content: "question 4, edited"})
+	session.AddMessage(Message{Role: "assistant", Content: "answer 4"})
+	client.QueueResponse("answer 5")
+	_, err := session.Send(context.Background(), "question 5")
+	require.NoError(t, err)
+	assert.Equal(t, 2, estimator.texts["question 4, edited"])
+	assert.Equal(t, 2, estimator.texts["question 0"])
+}
+
+func TestChatSession_MaxHistoryMessagesDropsExchanges(t *testing.T) {
+	client := NewMockClient("mock", "")
+	session := NewChatSessionWithSystemMessage(client, "You are terse.").SetMaxHistoryMessages(4)
+	for i := 0; i < 3; i++ {
+		_, err := session.Send(context.Background(), fmt.Sprintf("question %d", i))
+		require.NoError(t, err)
+	}
+
+	sent := client.Conversations()[2]
+	require.Len(t, sent.Messages, 4, "system + one earlier exchange + the new question")
+	assert.Equal(t, Message{Role: "system", Content: "You are terse."}, sent.Messages[0])
+	assert.Equal(t, Message{Role: "user", Content: "question 1"}, sent.Messages[1])
+	assert.Equal(t, "assistant", sent.Messages[2].Role)
+	assert.Equal(t, "question 2", sent.Messages[3].Content)
+	assert.Equal(t, 5, session.Len(), "the stored history is trimmed too")
+
+	ch, err := session.Stream(context.Background(), "question 3")
+	require.NoError(t, err)
+	collectStream(t, ch)
+	streamed := client.Conversations()[3]
+	assert.Equal(t, "system", streamed.Messages[0].Role)
+	assert.Equal(t, "question 2", streamed.Messages[1].Content)
+}
+
+func TestChatSession_MaxHistoryTokensUsesEstimator(t *testing.T) {
+	client := NewMockClient("mock", "")
+	estimator := &countingEstimator{inner: wordEstimator{}}
+	session := NewChatSessionWithSystemMessage(client, "You are terse.").SetMaxHistoryTokens(30, estimator)
+	for i := 0; i < 5; i++ {
+		session.AddMessage(Message{Role: "user", Content: "tell me one more fact please"})
+		session.AddMessage(Message{Role: "assistant", Content: "here is yet another fact"})
+	}
+
+	client.QueueResponse("ok")
+	_, err := session.Send(context.Background(), "last question")
+	require.NoError(t, err)
+
+	assert.Positive(t, estimator.calls)
+	sent := client.Conversations()[0]
+	assert.LessOrEqual(t, estimateConversationTokens(sent.Messages, wordEstimator{}), 30)
+	assert.Equal(t, Message{Role: "system", Content: "You are terse."}, sent.Messages[0])
+	assert.Equal(t, 1, (len(sent.Messages)-1)%2, "exchanges are dropped in user/assistant pairs")
+	for i, msg := range sent.Messages[1:] {
+		if i%2 == 0 {
+			assert.Equal(t, "user", msg.Role)
+		} else {
+			assert.Equal(t, "assistant", msg.Role)
+		}
+	}
+	assert.Equal(t, "last question", sent.Messages[len(sent.Messages)-1].Content)
+}
+
+func TestChatSession_Undo(t *testing.T) {
+	client := NewMockClient("mock", "")
+	client.QueueResponse("Paris.")
+	client.QueueResponse("Wrong answer.")
+	session := NewChatSessionWithSystemMessage(client, "Be brief.")
+	_, err := session.Send(context.Background(), "Capital of France?")
+	require.NoError(t, err)
+	_, err = session.Send(context.Background(), "Capital of Spain?")
+	require.NoError(t, err)
+
+	removed, ok := session.Undo()
+	require.True(t, ok)
+	assert.Equal(t, []Message{
+		{Role: "user", Content: "Capital of Spain?"},
+		{Role: "assistant", Content: "Wrong answer."},
+	}, removed)
+	assert.Equal(t, 3, session.Len())
+	assert.Equal(t, "Paris.", session.History().Messages[2].Content)
+
+	// A user message without a reply is removed on its own.
+	session.AddMessage(Message{Role: "user", Content: "unanswered"})
+	removed, ok = session.Undo()
+	require.True(t, ok)
+	assert.Equal(t, []Message{{Role: "user", Content: "unanswered"}}, removed)
+
+	_, ok = session.Undo()
+	require.True(t, ok)
+	removed, ok = session.Undo()
+	assert.False(t, ok, "only the system message is left")
+	assert.Nil(t, removed)
+	assert.Equal(t, 1, session.Len())
+
+	removed, ok = NewChatSession(client).Undo()
+	assert.False(t, ok)
+	assert.Nil(t, removed)
+}
+
+func TestChatSession_Fork(t *testing.T) {
+	client := NewMockClient("mock", "")
+	client.QueueResponse("Go is a language.")
+	session := NewChatSessionWithSystemMessage(client, "Be brief.").SetMaxHistoryMessages(10)
+	_, err := session.Send(context.Background(), "What is Go?")
+	require.NoError(t, err)
+
+	fork := session.Fork()
+	assert.Equal(t, session.History().Messages, fork.History().Messages)
+	assert.Equal(t, 10, fork.maxHistoryMessages, "settings are copied")
+
+	client.QueueResponse("Rob Pike and others.")
+	_, err = fork.Send(context.Background(), "Who made it?")
+	require.NoError(t, err)
+	assert.Equal(t, 3, session.Len(), "the original is unaffected")
+	assert.Equal(t, 5, fork.Len())
+
+	session.AddMessage(Message{Role: "user", Content: "original branch"})
+	assert.Equal(t, "Who made it?", fork.History().Messages[3].Content, "no shared backing array")
+
+	other := NewMockClient("other", "")
+	other.QueueResponse("Hola.")
+	switched := session.ForkWithClient(other)
+	_, err = switched.Send(context.Background(), "Say hi in Spanish.")
+	require.NoError(t, err)
+	require.Len(t, other.Conversations(), 1)
+	assert.Equal(t, "original branch", other.Conversations()[0].Messages[3].Content)
+	assert.Equal(t, 4, session.Len())
+}
+
+func TestChatSession_HistoryIsACopy(t *testing.T) {
+	s := NewChatSessionWithSystemMessage(NewMockClient("mock", ""), "be terse")
+	history := s.History()
+	history.AddUserMessage("not sent")
+	history.Messages[0].Content = "changed"
+
+	assert.Equal(t, 1, s.Len())
+	assert.Equal(t, "be terse", s.History().Messages[0].Content)
+}
+
+func TestChatSession_ClearDuringTurnDropsIt(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.SetLatency(50 * time.Millisecond)
+	m.QueueResponse("late reply")
+	s := NewChatSession(m)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := s.Send(context.Background(), "hello")
+		assert.NoError(t, err)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Clear()
+	<-done
+
+	assert.True(t, s.IsEmpty())
+}
+
+// Run with -race: Send, Stream, History, Len, and Clear share the session.
+func TestChatSession_ConcurrentUse(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.SetChunkSize(3)
+	m.SetDefaultResponse("concurrent reply", nil)
+	s := NewChatSessionWithSystemMessage(m, "be terse")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			_, err := s.Send(context.Background(), "question")
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			ch, err := s.Stream(context.Background(), "stream me")
+			if assert.NoError(t, err) {
+				for range ch {
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for _, msg := range s.History().Messages {
+				_ = msg.Content
+			}
+			_ = s.Len()
+		}()
+		go func() {
+			defer wg.Done()
+			if i%4 == 0 {
+				s.Clear()
+			}
+			s.AddMessage(Message{Role: "user", Content: "aside"})
+		}()
+	}
+	wg.Wait()
+
+	// Every recorded reply directly follows the user turn that asked for it.
+	messages := s.History().Messages
+	for i, msg := range messages {
+		if msg.Role == "assistant" {
+			require.Greater(t, i, 0)
+			assert.Contains(t, []string{"question", "stream me"}, messages[i-1].Content)
+		}
+	}
+}
+
+func TestChatSession_StreamAbandonedAfterTwoChunks(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.SetChunkSize(1)
+	m.QueueResponse(strings.Repeat("x", 500))
+	s := NewChatSessionWithSystemMessage(m, "be terse")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := s.Stream(ctx, "long answer please")
+	require.NoError(t, err)
+	received := 0
+	for range ch {
+		received++
+		if received == 2 {
+			cancel()
+			break
+		}
+	}
+
+	// Once the grace periods pass unread, the goroutine exits and closes the channel;
+	// a goroutine still waiting to deliver would hand over a content chunk instead.
+	time.Sleep(3 * streamAbandonGrace)
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok, "the stream goroutine has exited")
+	case <-time.After(time.Second):
+		t.Fatal("stream goroutine is still blocked")
+	}
+	assert.Equal(t, []Message{{Role: "system", Content: "be terse"}}, s.History().Messages,
+		"the abandoned turn is not recorded")
+
+	m.QueueResponse("short")
+	reply, err := s.Send(context.Background(), "again")
+	require.NoError(t, err)
+	assert.Equal(t, "short", reply)
+	sent := m.Conversations()
+	assert.Len(t, sent[len(sent)-1].Messages, 2, "no dangling user message from the abandoned stream")
+}
+
+func TestChatSession_StreamAbandonedStopsProviderStream(t *testing.T) {
+	transport, closed := endlessStream(`data: {"choices":[{"index":0,"delta":{"content":"more"},"finish_reason":null}]}`)
+	client, err := NewOpenAIClient("key", "", NewClientConfig().SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = transport
+	s := NewChatSession(client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := s.Stream(ctx, "tell me a story")
+	require.NoError(t, err)
+	for range ch {
+		cancel()
+		break
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("provider stream still open after the consumer left")
+	}
+	assert.Zero(t, s.Len(), "the abandoned turn is not recorded")
+}
+
+func TestChatSession_StreamAbandonedEndsWithError(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.SetChunkSize(1)
+	m.QueueResponse(strings.Repeat("x", 500))
+	s := NewChatSession(m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := s.Stream(ctx, "long answer please")
+	require.NoError(t, err)
+	<-ch
+	cancel()
+
+	// A consumer that comes back after the grace period learns why the stream ended
+	time.Sleep(streamAbandonGrace + streamAbandonGrace/2)
+	var last StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	assert.True(t, last.Finished)
+	assert.ErrorIs(t, last.Err, context.Canceled)
+	assert.Zero(t, s.Len(), "the abandoned turn is not recorded")
+}
+
+func TestChatSession_StreamSlowConsumer(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.SetChunkSize(1)
+	m.QueueResponse("abcdef")
+	s := NewChatSession(m)
+
+	// A live reader slower than the grace period still gets every chunk
+	ch, err := s.Stream(context.Background(), "hi")
+	require.NoError(t, err)
+	var content strings.Builder
+	var last StreamChunk
+	for chunk := range ch {
+		time.Sleep(250 * time.Millisecond)
+		require.NoError(t, chunk.Err)
+		content.WriteString(chunk.Content)
+		last = chunk
+	}
+	assert.Equal(t, "abcdef", content.String())
+	assert.True(t, last.Finished)
+	require.Equal(t, 2, s.Len())
+	assert.Equal(t, "abcdef", s.History().Messages[1].Content)
+}
+
+func TestChatSession_StreamRecordsTurnBeforeClose(t *testing.T) {
+	m := NewMockClient("mock", "")
+	m.SetChunkSize(2)
+	m.QueueResponse("complete reply")
+	s := NewChatSession(m)
+
+	ch, err := s.Stream(context.Background(), "hi")
+	require.NoError(t, err)
+	var content strings.Builder
+	for chunk := range ch {
+		if !chunk.Finished {
+			assert.Equal(t, 0, s.Len(), "nothing is recorded until the final chunk is taken")
+		}
+		content.WriteString(chunk.Content)
+	}
+	assert.Equal(t, "complete reply", content.String())
+	require.Equal(t, 2, s.Len())
+	assert.Equal(t, "complete reply", s.History().Messages[1].Content)
+}
+
+func TestChatSession_Stats(t *testing.T) {
+	resetModelPricing(t)
+	require.NoError(t, RegisterModelPricing("session-model", ModelPricing{InputPer1K: 1, OutputPer1K: 2}))
+	m := NewMockClient("mock", "session-model")
+	m.SetUsage(100, 20)
+	m.SetLatency(2 * time.Millisecond)
+	s := NewChatSession(m)
+	ctx := context.Background()
+
+	_, err := s.Send(ctx, "one")
+	require.NoError(t, err)
+	_, err = s.SendWithMetadata(ctx, "two")
+	require.NoError(t, err)
+	ch, err := s.Stream(ctx, "three")
+	require.NoError(t, err)
+	collectStream(t, ch)
+	m.QueueError(NewServerError(500, "down"))
+	_, err = s.Send(ctx, "four")
+	require.Error(t, err)
+
+	stats := s.Stats()
+	assert.Equal(t, 3, stats.Requests, "failed turns are not counted")
+	assert.Equal(t, 300, stats.PromptTokens)
+	assert.Equal(t, 60, stats.CompletionTokens)
+	assert.Equal(t, 360, stats.TotalTokens)
+	assert.Equal(t, int64(6), stats.LatencyMs)
+	assert.InDelta(t, 3*(0.1+0.04), stats.EstimatedCost, 1e-12)
+	assert.Zero(t, stats.UnpricedRequests)
+
+	s.Clear()
+	assert.Equal(t, SessionStats{}, s.Stats())
+
+	_, err = s.Send(ctx, "again")
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Stats().Requests)
+}
+
+func TestChatSession_StatsUnpricedModel(t *testing.T) {
+	m := NewMockClient("mock", "unpriced-model")
+	m.SetUsage(10, 5)
+	s := NewChatSession(m)
+
+	_, err := s.Send(context.Background(), "hi")
+	require.NoError(t, err)
+
+	stats := s.Stats()
+	assert.Equal(t, 15, stats.TotalTokens)
+	assert.Zero(t, stats.EstimatedCost)
+	assert.Equal(t, 1, stats.UnpricedRequests)
+}
+
+func TestChatSession_SetContextCheck(t *testing.T) {
+	m := NewMockClient("mock", "gpt-4")
+	m.QueueResponse("short answer")
+	s := NewChatSession(m).SetContextCheck(true)
+
+	_, err := s.Send(context.Background(), "short question")
+	require.NoError(t, err)
+
+	_, err = s.Send(context.Background(), strings.Repeat("abcd", 10000))
+	require.ErrorIs(t, err, ErrContextTooLarge)
+	assert.Equal(t, 1, m.CallCount(), "the oversized request is not sent")
+	assert.Equal(t, 2, s.Len(), "the history is left unchanged")
+
+	s.SetMaxContextTokens(4000, nil)
+	_, err = s.Send(context.Background(), strings.Repeat("abcd", 10000))
+	assert.NoError(t, err, "trimming runs before the check")
+}
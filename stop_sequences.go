@@ -0,0 +1,35 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// stop_sequences.go enforces provider limits on ClientConfig.StopSequences so that
+// an oversized list fails fast with a descriptive error instead of a provider 400.
+package chatdelta
+
+import "fmt"
+
+// providerMaxStopSequences holds the most stop sequences each provider accepts per
+// request. Claude and Ollama publish no limit and have no entry.
+var providerMaxStopSequences = map[Provider]int{
+	ProviderOpenAI: 4,
+	ProviderGemini: 5,
+	ProviderXAI:    4,
+}
+
+// MaxStopSequencesFor returns the most stop sequences provider accepts, if it has a limit.
+func MaxStopSequencesFor(provider Provider) (int, bool) {
+	limit, ok := providerMaxStopSequences[provider]
+	return limit, ok
+}
+
+// ValidateStopSequences checks config.StopSequences: every sequence must be non-empty
+// and the list must fit the limit for provider.
+func ValidateStopSequences(provider Provider, config *ClientConfig) error {
+	for i, sequence := range config.StopSequences {
+		if sequence == "" {
+			return NewInvalidParameterError("stop_sequences", fmt.Sprintf("sequence %d is empty", i))
+		}
+	}
+	if limit, ok := MaxStopSequencesFor(provider); ok && len(config.StopSequences) > limit {
+		return NewInvalidParameterError("stop_sequences",
+			fmt.Sprintf("%d sequences exceed the %s limit of %d", len(config.StopSequences), provider, limit))
+	}
+	return nil
+}
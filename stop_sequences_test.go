@@ -0,0 +1,45 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStopSequences(t *testing.T) {
+	four := NewClientConfig().SetStopSequences("a", "b", "c", "d")
+	five := NewClientConfig().SetStopSequences("a", "b", "c", "d", "e")
+
+	assert.NoError(t, ValidateStopSequences(ProviderOpenAI, four))
+	assert.Error(t, ValidateStopSequences(ProviderOpenAI, five))
+	assert.NoError(t, ValidateStopSequences(ProviderGemini, five))
+	assert.NoError(t, ValidateStopSequences(ProviderClaude, NewClientConfig().SetStopSequences(make([]string, 0, 10)...)))
+
+	err := ValidateStopSequences(ProviderClaude, NewClientConfig().SetStopSequences("ok", ""))
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, "invalid_parameter", ce.Code)
+}
+
+func TestStopSequences_RejectedBeforeSending(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetStopSequences("a", "b", "c", "d", "e"))
+	require.NoError(t, err)
+
+	_, err = client.DryRun(promptConversation("hi"), false)
+	assert.ErrorContains(t, err, "exceed the openai limit of 4")
+}
+
+func TestClaudeClient_ReportsStopSequence(t *testing.T) {
+	server := metadataServer(t, nil, `{"id":"msg_1","model":"claude-3-5-haiku-latest","content":[{"type":"text","text":"one two"}],"stop_reason":"stop_sequence","stop_sequence":"three","usage":{"input_tokens":3,"output_tokens":2}}`)
+	client, err := NewClaudeClient("key", "", NewClientConfig().SetBaseURL(server.URL).SetStopSequences("three"))
+	require.NoError(t, err)
+
+	response, err := client.SendPromptWithMetadata(context.Background(), "count")
+	require.NoError(t, err)
+	assert.Equal(t, "stop_sequence", response.Metadata.FinishReason)
+	assert.Equal(t, FinishReasonStop, response.Metadata.NormalizedFinishReason)
+	assert.Equal(t, "three", response.Metadata.StopSequence)
+}
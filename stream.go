@@ -0,0 +1,193 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// stream.go holds the plumbing shared by the provider streaming implementations:
+// tracking what has been delivered to the caller, building the terminal chunk
+// when a stream fails part-way through, and ending streams promptly when their
+// context is cancelled.
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// streamSink forwards chunks to the caller's channel and remembers whether any
+// content has been delivered, which decides how a later failure is reported.
+type streamSink struct {
+	ch chan<- StreamChunk
+	// ctx, when set, bounds how long a send waits for a caller that stopped reading
+	ctx context.Context
+	// abandoned is set once the caller stopped reading after ctx ended; later
+	// chunks are dropped
+	abandoned bool
+	delivered bool
+	// responseFilters, when set, rewrite content before the filter screens it
+	responseFilters *responseFilterStream
+	// rejected is the error from a response filter that rejected content; the rest
+	// of the stream is dropped and the final chunk reports it
+	rejected error
+	// filter, when set, screens content before it is delivered
+	filter *streamFilter
+	// keepRaw records provider events so the final chunk can carry the last one
+	keepRaw bool
+	lastRaw json.RawMessage
+}
+
+// newStreamSink returns a sink for ch that applies the config's ResponseFilters,
+// ContentFilter and IncludeRawResponse settings and gives up on a caller that stops
+// reading after ctx ends.
+func newStreamSink(ctx context.Context, ch chan<- StreamChunk, config *ClientConfig) *streamSink {
+	return &streamSink{
+		ch:              ch,
+		ctx:             ctx,
+		responseFilters: newResponseFilterStream(config),
+		filter:          newStreamFilter(config),
+		keepRaw:         config.IncludeRawResponse,
+	}
+}
+
+// event records data, one provider stream event, as the latest raw event.
+func (s *streamSink) event(data string) {
+	if s.keepRaw {
+		s.lastRaw = json.RawMessage(data)
+	}
+}
+
+// send forwards chunk to the caller.
+func (s *streamSink) send(chunk StreamChunk) {
+	if s.rejected != nil {
+		if chunk.Finished {
+			s.deliver(StreamChunk{Finished: true, Err: s.rejected})
+		}
+		return
+	}
+	if s.responseFilters != nil {
+		content, err := s.responseFilters.push(chunk.Content, chunk.Finished)
+		if err != nil {
+			s.rejected = err
+			s.send(chunk)
+			return
+		}
+		if chunk.Content = content; chunk.Content == "" && !chunk.Finished {
+			return
+		}
+	}
+	if s.filter != nil {
+		if chunk.Finished {
+			chunk.Content = s.filter.push(chunk.Content, true)
+			s.filter.finish(chunk.Metadata)
+		} else if chunk.Content = s.filter.push(chunk.Content, false); chunk.Content == "" {
+			return
+		}
+	}
+	if chunk.Content != "" {
+		s.delivered = true
+	}
+	if chunk.Finished && s.keepRaw {
+		chunk.Raw = s.lastRaw
+	}
+	s.deliver(chunk)
+}
+
+// deliver forwards chunk to the caller's channel. Once ctx has ended, a caller that
+// does not take the chunk within streamAbandonGrace is treated as gone and this and
+// later chunks are dropped, so the stream's goroutine can exit.
+func (s *streamSink) deliver(chunk StreamChunk) {
+	if s.abandoned {
+		return
+	}
+	if s.ctx == nil {
+		s.ch <- chunk
+		return
+	}
+	s.abandoned = !deliverChunk(s.ctx, s.ch, chunk)
+}
+
+// fail emits the terminal chunk for a stream that ended with err. When the config
+// opts into PartialOnTimeout and content was already delivered before a timeout, the
+// stream is closed as Truncated rather than failed so callers keep the partial text.
+// Text held back by the response and content filters is released first.
+func (s *streamSink) fail(ctx context.Context, config *ClientConfig, err error) {
+	if s.rejected != nil {
+		s.deliver(StreamChunk{Content: "", Finished: true, Err: s.rejected})
+		return
+	}
+	rest := ""
+	if s.responseFilters != nil {
+		var rejected error
+		if rest, rejected = s.responseFilters.push("", true); rejected != nil {
+			s.deliver(StreamChunk{Content: "", Finished: true, Err: rejected})
+			return
+		}
+	}
+	if s.filter != nil {
+		rest = s.filter.push(rest, true)
+	}
+	if rest != "" {
+		s.delivered = true
+		s.deliver(StreamChunk{Content: rest})
+	}
+	if config.PartialOnTimeout && s.delivered && (isTimeoutError(err) || errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+		s.deliver(StreamChunk{Content: "", Finished: true, Truncated: true})
+		return
+	}
+	s.deliver(StreamChunk{Content: "", Finished: true, Err: err})
+}
+
+// isTimeoutError reports whether err was caused by a deadline or network timeout.
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ce *ClientError
+	if errors.As(err, &ce) && ce.Code == "timeout" {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// streamAbandonGrace is how long a stream waits, after its context ends, for the
+// consumer to take a chunk before treating the stream as abandoned.
+const streamAbandonGrace = 100 * time.Millisecond
+
+// deliverChunk sends chunk to out. It reports false if ctx has ended and out is not
+// read within streamAbandonGrace.
+func deliverChunk[T any](ctx context.Context, out chan<- T, chunk T) bool {
+	select {
+	case out <- chunk:
+		return true
+	case <-ctx.Done():
+	}
+	timer := time.NewTimer(streamAbandonGrace)
+	defer timer.Stop()
+	select {
+	case out <- chunk:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// closeOnDone closes body as soon as ctx ends, unblocking a read in progress even
+// when the transport does not watch the request context. The returned func stops
+// watching and must be called once the body is no longer read.
+func closeOnDone(ctx context.Context, body io.Closer) func() bool {
+	return context.AfterFunc(ctx, func() { _ = body.Close() })
+}
+
+// streamReadError returns the error for a stream read that failed with err, or nil
+// if err is nil and ctx is live. Once ctx has ended its error is reported instead,
+// since the read failed because the body was closed on cancellation.
+func streamReadError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return NewStreamReadError(ctxErr)
+	}
+	if err != nil {
+		return NewStreamReadError(err)
+	}
+	return nil
+}
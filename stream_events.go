@@ -0,0 +1,112 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// stream_events.go exposes a provider's stream as the events it sends, for consumers
+// that need event types or fields the StreamChunk abstraction drops.
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StreamEvent is one server-sent event from a provider stream, exactly as sent.
+type StreamEvent struct {
+	// Event is the SSE event name, or "message" when the provider names none
+	Event string
+	// Data is the event's JSON payload
+	Data json.RawMessage
+	// Err is set on the last event when the stream failed; Event and Data are empty
+	Err error
+}
+
+// EventStreamer is implemented by clients that can stream a conversation as raw
+// provider events.
+type EventStreamer interface {
+	// StreamEvents streams conversation and delivers every event the provider sends
+	StreamEvents(ctx context.Context, conversation *Conversation) (<-chan StreamEvent, error)
+}
+
+// StreamEvents streams conversation through client if it implements EventStreamer,
+// and returns a config error otherwise. The channel is closed when the stream ends;
+// a failure is reported by a final event with Err set.
+func StreamEvents(ctx context.Context, client AIClient, conversation *Conversation) (<-chan StreamEvent, error) {
+	streamer, ok := client.(EventStreamer)
+	if !ok {
+		return nil, NewInvalidParameterError("client", fmt.Sprintf("%s does not support raw stream events", client.Name()))
+	}
+	return streamer.StreamEvents(ctx, conversation)
+}
+
+// streamEvents opens a streaming request with the config's retry policy and forwards
+// each SSE event on the returned channel. build creates a fresh request per attempt
+// and apiError maps non-200 responses. Once the stream is open it is not retried.
+// OpenAI's "[DONE]" sentinel ends the stream and other non-JSON payloads are skipped.
+func streamEvents(ctx context.Context, httpClient *http.Client, config *ClientConfig, build func(context.Context) (*http.Request, error), apiError func(statusCode int, header http.Header, body []byte) *ClientError) <-chan StreamEvent {
+	out := make(chan StreamEvent, 10)
+
+	go func() {
+		defer close(out)
+
+		ctx, cancel := config.withOperationTimeout(ctx)
+		defer cancel()
+
+		var resp *http.Response
+		operation := func() error {
+			req, err := build(ctx)
+			if err != nil {
+				return err
+			}
+
+			r, err := httpClient.Do(req)
+			if err != nil {
+				if ctx.Err() != nil {
+					return NewTimeoutError(config.Timeout)
+				}
+				return NewConnectionError(err)
+			}
+			if r.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(r.Body)
+				r.Body.Close()
+				return apiError(r.StatusCode, r.Header, body)
+			}
+			resp = r
+			return nil
+		}
+
+		if err := config.retry(ctx, operation); err != nil {
+			deliverChunk(ctx, out, StreamEvent{Err: err})
+			return
+		}
+		defer resp.Body.Close()
+		defer closeOnDone(ctx, resp.Body)()
+
+		reader := NewSseReader(resp.Body)
+		for {
+			event, err := reader.Next()
+			if event == nil || err != nil {
+				if err := streamReadError(ctx, err); err != nil {
+					deliverChunk(ctx, out, StreamEvent{Err: err})
+				}
+				return
+			}
+			if event.Data == "[DONE]" {
+				return
+			}
+			if !json.Valid([]byte(event.Data)) {
+				continue
+			}
+
+			name := event.Event
+			if name == "" {
+				name = "message"
+			}
+			if !deliverChunk(ctx, out, StreamEvent{Event: name, Data: json.RawMessage(event.Data)}) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,80 @@
+package chatdelta
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectEvents drains ch.
+func collectEvents(ch <-chan StreamEvent) []StreamEvent {
+	var events []StreamEvent
+	for event := range ch {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestClaudeClient_StreamEvents(t *testing.T) {
+	server := transcriptServer(t, "claude_stream.sse")
+	client, err := NewClaudeClient("test-key", "", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	ch, err := StreamEvents(context.Background(), client, promptConversation("hi"))
+	require.NoError(t, err)
+	events := collectEvents(ch)
+
+	var names []string
+	for _, event := range events {
+		require.NoError(t, event.Err)
+		names = append(names, event.Event)
+	}
+	assert.Equal(t, []string{
+		"message_start", "content_block_start", "ping", "content_block_delta",
+		"content_block_delta", "content_block_stop", "message_delta", "message_stop",
+	}, names)
+	assert.JSONEq(t, `{"type": "ping"}`, string(events[2].Data))
+	assert.JSONEq(t, `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`, string(events[3].Data))
+}
+
+func TestOpenAIClient_StreamEventsEndsAtDone(t *testing.T) {
+	server := transcriptServer(t, "openai_stream.sse")
+	client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+
+	ch, err := client.StreamEvents(context.Background(), promptConversation("hi"))
+	require.NoError(t, err)
+	events := collectEvents(ch)
+
+	require.NotEmpty(t, events)
+	for _, event := range events {
+		require.NoError(t, event.Err)
+		assert.Equal(t, "message", event.Event)
+		assert.NotEqual(t, "[DONE]", string(event.Data))
+	}
+}
+
+func TestStreamEvents_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"bad key"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClaudeClient("test-key", "", NewClientConfig().SetBaseURL(server.URL).SetRetries(0))
+	require.NoError(t, err)
+	ch, err := client.StreamEvents(context.Background(), promptConversation("hi"))
+	require.NoError(t, err)
+	events := collectEvents(ch)
+	require.Len(t, events, 1)
+	assert.True(t, IsAuthenticationError(events[0].Err))
+
+	_, err = StreamEvents(context.Background(), NewMockClient("mock", ""), promptConversation("hi"))
+	var clientErr *ClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, "invalid_parameter", clientErr.Code)
+}
@@ -0,0 +1,61 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// stream_metadata.go provides streaming entry points that guarantee a final chunk
+// with metadata. Provider clients already attach metadata to their Finished chunk;
+// these helpers extend the guarantee to any AIClient, including wrappers.
+package chatdelta
+
+import (
+	"context"
+	"time"
+)
+
+// StreamPromptWithMetadata streams a response to prompt. The stream always ends with a
+// Finished chunk; when it succeeds, that chunk's Metadata is non-nil. Clients that do
+// not report metadata get the configured model and the measured latency.
+func StreamPromptWithMetadata(ctx context.Context, client AIClient, prompt string) (<-chan StreamChunk, error) {
+	start := time.Now()
+	ch, err := client.StreamPrompt(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return withStreamMetadata(client, start, ch), nil
+}
+
+// StreamConversationWithMetadata streams a response to conversation with the same
+// guarantees as StreamPromptWithMetadata.
+func StreamConversationWithMetadata(ctx context.Context, client AIClient, conversation *Conversation) (<-chan StreamChunk, error) {
+	start := time.Now()
+	ch, err := client.StreamConversation(ctx, conversation)
+	if err != nil {
+		return nil, err
+	}
+	return withStreamMetadata(client, start, ch), nil
+}
+
+// withStreamMetadata forwards src, filling in metadata on a successful final chunk
+// that lacks it and adding a final chunk if src closes without one.
+func withStreamMetadata(client AIClient, start time.Time, src <-chan StreamChunk) <-chan StreamChunk {
+	out := make(chan StreamChunk, 10)
+	go func() {
+		defer close(out)
+		finished := false
+		for chunk := range src {
+			if chunk.Finished {
+				finished = true
+				if chunk.Err == nil && chunk.Metadata == nil {
+					chunk.Metadata = fallbackStreamMetadata(client, start)
+				}
+			}
+			out <- chunk
+		}
+		if !finished {
+			out <- StreamChunk{Finished: true, Metadata: fallbackStreamMetadata(client, start)}
+		}
+	}()
+	return out
+}
+
+// fallbackStreamMetadata describes a stream whose client reported no metadata.
+func fallbackStreamMetadata(client AIClient, start time.Time) *ResponseMetadata {
+	return &ResponseMetadata{ModelUsed: client.Model(), LatencyMs: time.Since(start).Milliseconds()}
+}
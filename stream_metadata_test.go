@@ -0,0 +1,82 @@
+package chatdelta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamConversationWithMetadata_Providers(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    func(t *testing.T) AIClient
+		content   string
+		model     string
+		tokens    int
+		finishing string
+	}{
+		{"openai", func(t *testing.T) AIClient {
+			client, err := NewOpenAIClient("test-key", "gpt-4o", NewClientConfig().SetBaseURL(transcriptServer(t, "openai_stream.sse").URL))
+			require.NoError(t, err)
+			return client
+		}, "Hello there!", "gpt-4o-2024-08-06", 15, "stop"},
+		{"claude", func(t *testing.T) AIClient {
+			client, err := NewClaudeClient("test-key", "", NewClientConfig().SetBaseURL(transcriptServer(t, "claude_stream.sse").URL))
+			require.NoError(t, err)
+			return client
+		}, "Hello there!", "claude-3-5-sonnet-20241022", 32, "end_turn"},
+		{"gemini", func(t *testing.T) AIClient {
+			client, err := NewGeminiClient("test-key", "gemini-1.5-flash", NewClientConfig().SetBaseURL(transcriptServer(t, "gemini_stream.sse").URL))
+			require.NoError(t, err)
+			return client
+		}, "Hello there!", "gemini-1.5-flash-002", 7, "STOP"},
+		{"ollama", func(t *testing.T) AIClient {
+			client, err := NewOllamaClient("llama3", NewClientConfig().SetBaseURL(transcriptServer(t, "ollama_stream.ndjson").URL))
+			require.NoError(t, err)
+			return client
+		}, "Hello, world!", "llama3", 16, "stop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, err := StreamConversationWithMetadata(context.Background(), tt.client(t), promptConversation("hi"))
+			require.NoError(t, err)
+			content, last := collectStream(t, ch)
+
+			assert.Equal(t, tt.content, content)
+			require.NoError(t, last.Err)
+			require.NotNil(t, last.Metadata)
+			assert.Equal(t, tt.model, last.Metadata.ModelUsed)
+			assert.Equal(t, tt.tokens, last.Metadata.TotalTokens)
+			assert.Equal(t, tt.finishing, last.Metadata.FinishReason)
+		})
+	}
+}
+
+func TestStreamPromptWithMetadata_FillsMissingMetadata(t *testing.T) {
+	client := &chunkStreamClient{MockClient: NewMockClient("bare", "bare-model"), chunks: []StreamChunk{
+		{Content: "Hel"},
+		{Content: "lo", Finished: true},
+	}}
+	ch, err := StreamPromptWithMetadata(context.Background(), client, "hi")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "Hello", content)
+	require.NotNil(t, last.Metadata)
+	assert.Equal(t, "bare-model", last.Metadata.ModelUsed)
+
+	client.chunks = []StreamChunk{{Content: "cut"}}
+	ch, err = StreamPromptWithMetadata(context.Background(), client, "hi")
+	require.NoError(t, err)
+	content, last = collectStream(t, ch)
+	assert.Equal(t, "cut", content)
+	assert.NotNil(t, last.Metadata, "a stream closed without a final chunk gets one")
+
+	client.chunks = []StreamChunk{{Finished: true, Err: NewServerError(500, "down")}}
+	ch, err = StreamPromptWithMetadata(context.Background(), client, "hi")
+	require.NoError(t, err)
+	_, last = collectStream(t, ch)
+	assert.Error(t, last.Err)
+	assert.Nil(t, last.Metadata, "failed streams are passed through")
+}
@@ -0,0 +1,281 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stallingServer streams two OpenAI content events and then stalls until the
+// client gives up.
+func stallingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":"Once upon"},"finish_reason":null}]}` + "\n\n"))
+		_, _ = w.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":" a time"},"finish_reason":null}]}` + "\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStream_TimeoutReturnsPartialWhenEnabled(t *testing.T) {
+	server := stallingServer(t)
+	config := NewClientConfig().SetBaseURL(server.URL).SetRetries(0).SetPartialOnTimeout(true)
+	client, err := NewOpenAIClient("test-key", "", config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	content, err := StreamToString(ctx, client, "tell me a story")
+	assert.Equal(t, "Once upon a time", content)
+	var ce *ClientError
+	require.True(t, errors.As(err, &ce))
+	assert.Equal(t, "stream_truncated", ce.Code)
+}
+
+func TestStream_TimeoutFailsByDefault(t *testing.T) {
+	server := stallingServer(t)
+	config := NewClientConfig().SetBaseURL(server.URL).SetRetries(0)
+	client, err := NewOpenAIClient("test-key", "", config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	ch, err := client.StreamPrompt(ctx, "tell me a story")
+	require.NoError(t, err)
+	var last StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	assert.True(t, last.Finished)
+	assert.False(t, last.Truncated)
+	assert.Error(t, last.Err)
+}
+
+func TestChatSession_StreamTruncatedKeepsPartial(t *testing.T) {
+	server := stallingServer(t)
+	config := NewClientConfig().SetBaseURL(server.URL).SetRetries(0).SetPartialOnTimeout(true)
+	client, err := NewOpenAIClient("test-key", "", config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	session := NewChatSession(client)
+	ch, err := session.Stream(ctx, "tell me a story")
+	require.NoError(t, err)
+	var last StreamChunk
+	for chunk := range ch {
+		last = chunk
+	}
+	assert.True(t, last.Truncated)
+	require.Len(t, session.History().Messages, 2)
+	assert.Equal(t, "Once upon a time", session.History().Messages[1].Content)
+}
+
+// endlessStream returns a transport whose response body writes event every 10ms until
+// the body is closed, ignoring the request context the way some custom transports
+// do. closed is closed once the body has been.
+func endlessStream(event string) (roundTripFunc, <-chan struct{}) {
+	closed := make(chan struct{})
+	return func(req *http.Request) (*http.Response, error) {
+		reader, writer := io.Pipe()
+		go func() {
+			defer close(closed)
+			for {
+				if _, err := writer.Write([]byte(event + "\n\n")); err != nil {
+					return
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			Body:       reader,
+			Request:    req,
+		}, nil
+	}, closed
+}
+
+func TestStream_CancelEndsSlowStream(t *testing.T) {
+	config := func() *ClientConfig { return NewClientConfig().SetRetries(0) }
+	providers := map[string]func(transport http.RoundTripper) AIClient{
+		"openai": func(transport http.RoundTripper) AIClient {
+			client, err := NewOpenAIClient("key", "", config())
+			require.NoError(t, err)
+			client.httpClient.Transport = transport
+			return client
+		},
+		"claude": func(transport http.RoundTripper) AIClient {
+			client, err := NewClaudeClient("key", "", config())
+			require.NoError(t, err)
+			client.httpClient.Transport = transport
+			return client
+		},
+		"gemini": func(transport http.RoundTripper) AIClient {
+			client, err := NewGeminiClient("key", "", config())
+			require.NoError(t, err)
+			client.httpClient.Transport = transport
+			return client
+		},
+		"ollama": func(transport http.RoundTripper) AIClient {
+			client, err := NewOllamaClient("", config())
+			require.NoError(t, err)
+			client.httpClient.Transport = transport
+			return client
+		},
+	}
+	events := map[string]string{
+		"openai": `data: {"choices":[{"index":0,"delta":{"content":"more"},"finish_reason":null}]}`,
+		"claude": `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"more"}}`,
+		"gemini": `data: {"candidates":[{"content":{"parts":[{"text":"more"}],"role":"model"},"index":0}]}`,
+		"ollama": `{"model":"llama3.2","message":{"role":"assistant","content":"more"},"done":false}`,
+	}
+
+	for name, newClient := range providers {
+		t.Run(name, func(t *testing.T) {
+			transport, closed := endlessStream(events[name])
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			ch, err := newClient(transport).StreamPrompt(ctx, "tell me a story")
+			require.NoError(t, err)
+			first := <-ch
+			require.Equal(t, "more", first.Content)
+			cancel()
+
+			var last StreamChunk
+			deadline := time.After(time.Second)
+		drain:
+			for {
+				select {
+				case chunk, ok := <-ch:
+					if !ok {
+						break drain
+					}
+					last = chunk
+				case <-deadline:
+					t.Fatal("stream still open a second after cancel")
+				}
+			}
+			require.True(t, last.Finished)
+			assert.ErrorIs(t, last.Err, context.Canceled)
+
+			select {
+			case <-closed:
+			case <-time.After(time.Second):
+				t.Fatal("response body not closed after cancel")
+			}
+		})
+	}
+}
+
+func TestStream_CancelWithoutReaderReleasesStream(t *testing.T) {
+	transport, closed := endlessStream(`data: {"choices":[{"index":0,"delta":{"content":"more"},"finish_reason":null}]}`)
+	client, err := NewOpenAIClient("key", "", NewClientConfig().SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = transport
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch, err := client.StreamPrompt(ctx, "tell me a story")
+	require.NoError(t, err)
+	<-ch
+	// Let the channel's buffer fill, then cancel and walk away
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("response body not closed after cancel")
+	}
+	// A reader that left is given up on, so the terminal chunk is dropped rather than
+	// blocking the stream's goroutine until someone reads it
+	time.Sleep(3 * streamAbandonGrace)
+	for chunk := range ch {
+		assert.False(t, chunk.Finished, "the terminal chunk was not dropped")
+	}
+}
+
+func TestMergeStreamChunks_Error(t *testing.T) {
+	ch := make(chan StreamChunk, 2)
+	ch <- StreamChunk{Content: "partial"}
+	ch <- StreamChunk{Finished: true, Err: NewServerError(500, "boom")}
+	close(ch)
+
+	content, err := MergeStreamChunks(ch)
+	assert.Empty(t, content)
+	assert.Error(t, err)
+}
+
+func TestStreamToWriter(t *testing.T) {
+	client := NewMockClient("mock", "")
+	client.SetChunkSize(4)
+	client.QueueResponse("Hello, world!")
+
+	rec := httptest.NewRecorder()
+	n, err := StreamToWriter(context.Background(), client, "hi", rec)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("Hello, world!")), n)
+	assert.Equal(t, "Hello, world!", rec.Body.String())
+	assert.True(t, rec.Flushed, "http.Flushers are flushed as content arrives")
+}
+
+func TestStreamToWriter_StreamError(t *testing.T) {
+	client, err := NewOpenAIClient("test-key", "", NewClientConfig().SetBaseURL(stallingServer(t).URL).SetRetries(0))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var out strings.Builder
+	n, err := StreamToWriter(ctx, client, "tell me a story", &out)
+	require.Error(t, err)
+	assert.Equal(t, "Once upon a time", out.String(), "content before the failure is written")
+	assert.Equal(t, int64(out.Len()), n)
+}
+
+// failingWriter accepts limit writes and then fails.
+type failingWriter struct {
+	limit  int
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes == w.limit {
+		return 0, errors.New("client went away")
+	}
+	w.writes++
+	return len(p), nil
+}
+
+func TestStreamToWriter_WriteErrorStopsStream(t *testing.T) {
+	transport, closed := endlessStream(`data: {"choices":[{"index":0,"delta":{"content":"more"},"finish_reason":null}]}`)
+	client, err := NewOpenAIClient("key", "", NewClientConfig().SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = transport
+
+	n, err := StreamToWriter(context.Background(), client, "tell me a story", &failingWriter{limit: 2})
+	assert.EqualError(t, err, "client went away")
+	assert.Equal(t, int64(len("moremore")), n)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("provider stream not closed after the write failed")
+	}
+}
@@ -0,0 +1,110 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// summarize.go implements map-reduce summarization of documents too large for a single
+// request: the text is split with SplitIntoChunks, each chunk is summarized, and the
+// partial summaries are combined into one.
+package chatdelta
+
+import (
+	"context"
+	"strings"
+)
+
+// Default prompts used by SummarizeDocument. The {text} placeholder is replaced with
+// the chunk or the joined partial summaries.
+const (
+	DefaultSummarizeChunkPrompt   = "Summarize the following section of a longer document. Keep every key fact, name, and number.\n\n{text}"
+	DefaultSummarizeCombinePrompt = "The following are summaries of consecutive sections of one document. Combine them into a single coherent summary.\n\n{text}"
+)
+
+// summaryPlaceholder marks where text is inserted into summarization prompts.
+const summaryPlaceholder = "{text}"
+
+// summarySeparator joins partial summaries before they are combined.
+const summarySeparator = "\n\n"
+
+// SummarizeOptions configures SummarizeDocument. The zero value is usable.
+type SummarizeOptions struct {
+	// ChunkTokens is the maximum size of each chunk sent to the model. Defaults to 2000.
+	ChunkTokens int
+	// Concurrency bounds the number of chunk summaries in flight. Defaults to 1
+	// (sequential); a negative value means no limit.
+	Concurrency int
+	// ChunkPrompt summarizes one chunk; {text} is replaced with the chunk.
+	// Defaults to DefaultSummarizeChunkPrompt.
+	ChunkPrompt string
+	// CombinePrompt merges partial summaries; {text} is replaced with the summaries
+	// separated by blank lines. Defaults to DefaultSummarizeCombinePrompt.
+	CombinePrompt string
+}
+
+// withDefaults returns a copy of o with unset fields filled in.
+func (o SummarizeOptions) withDefaults() SummarizeOptions {
+	if o.ChunkTokens <= 0 {
+		o.ChunkTokens = 2000
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = 1
+	}
+	if o.ChunkPrompt == "" {
+		o.ChunkPrompt = DefaultSummarizeChunkPrompt
+	}
+	if o.CombinePrompt == "" {
+		o.CombinePrompt = DefaultSummarizeCombinePrompt
+	}
+	return o
+}
+
+// SummarizeDocument summarizes text with client using map-reduce. The text is split
+// into chunks of at most opts.ChunkTokens, each chunk is summarized with
+// opts.ChunkPrompt, and the partial summaries are merged with opts.CombinePrompt. If
+// the partial summaries are themselves too large to combine in one request, they are
+// combined in groups and the results combined again until one summary remains.
+// A document that fits in a single chunk is summarized with one request.
+//
+// The first failing request aborts the pipeline; its error is returned as a *MapError
+// for chunk summaries and as-is for combine steps.
+func SummarizeDocument(ctx context.Context, client AIClient, text string, opts SummarizeOptions) (string, error) {
+	opts = opts.withDefaults()
+
+	chunks := SplitIntoChunks(text, opts.ChunkTokens, client.Model())
+	if len(chunks) == 0 {
+		return "", nil
+	}
+
+	summaries, err := summarizeAll(ctx, client, chunks, opts.ChunkPrompt, opts.Concurrency)
+	if err != nil {
+		return "", err
+	}
+
+	for len(summaries) > 1 {
+		joined := strings.Join(summaries, summarySeparator)
+		groups := SplitIntoChunks(joined, opts.ChunkTokens, client.Model())
+		// When every summary fills a chunk on its own, grouping cannot shrink the
+		// input any further, so everything is combined in one request regardless.
+		if len(groups) <= 1 || len(groups) >= len(summaries) {
+			final, err := client.SendPrompt(ctx, renderSummaryPrompt(opts.CombinePrompt, joined))
+			return strings.TrimSpace(final), err
+		}
+		summaries, err = summarizeAll(ctx, client, groups, opts.CombinePrompt, opts.Concurrency)
+		if err != nil {
+			return "", err
+		}
+	}
+	return summaries[0], nil
+}
+
+// summarizeAll sends prompt for each piece of text with bounded concurrency.
+func summarizeAll(ctx context.Context, client AIClient, texts []string, prompt string, concurrency int) ([]string, error) {
+	render := func(text string) string { return renderSummaryPrompt(prompt, text) }
+	parse := func(response string) (string, error) { return strings.TrimSpace(response), nil }
+	return MapPrompts(ctx, client, texts, render, parse, concurrency)
+}
+
+// renderSummaryPrompt substitutes text into prompt. A prompt without the placeholder
+// gets the text appended after a blank line.
+func renderSummaryPrompt(prompt, text string) string {
+	if !strings.Contains(prompt, summaryPlaceholder) {
+		return prompt + "\n\n" + text
+	}
+	return strings.ReplaceAll(prompt, summaryPlaceholder, text)
+}
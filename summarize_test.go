@@ -0,0 +1,66 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeDocument_MapThenCombine(t *testing.T) {
+	sections := []string{
+		strings.Repeat("Alpha facts about the first topic. ", 4),
+		strings.Repeat("Beta facts about the second topic. ", 4),
+		strings.Repeat("Gamma facts about the third topic. ", 4),
+	}
+	document := strings.Join(sections, "\n\n")
+	opts := SummarizeOptions{
+		ChunkTokens:   40,
+		Concurrency:   3,
+		ChunkPrompt:   "Summarize: {text}",
+		CombinePrompt: "Combine:\n{text}",
+	}
+
+	client := NewMockClient("mock", "")
+	for i, section := range sections {
+		client.ScriptReply("Summarize: "+strings.TrimSpace(section), []string{"alpha", "beta", "gamma"}[i])
+	}
+	client.ScriptReply("Combine:\nalpha\n\nbeta\n\ngamma", "final summary")
+
+	summary, err := SummarizeDocument(context.Background(), client, document, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "final summary", summary)
+	assert.Equal(t, 4, client.CallCount())
+
+	calls := client.Conversations()
+	assert.Equal(t, "Combine:\nalpha\n\nbeta\n\ngamma", lastUserMessage(calls[len(calls)-1]), "combine runs last over ordered partials")
+}
+
+func TestSummarizeDocument_SingleChunk(t *testing.T) {
+	client := NewMockClient("mock", "")
+	client.QueueResponse("  short  ")
+
+	summary, err := SummarizeDocument(context.Background(), client, "A short note.", SummarizeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "short", summary)
+	assert.Equal(t, 1, client.CallCount())
+	assert.True(t, strings.HasPrefix(lastUserMessage(client.Conversations()[0]), "Summarize the following section"))
+}
+
+func TestSummarizeDocument_ChunkError(t *testing.T) {
+	client := NewMockClient("mock", "")
+	boom := NewServerError(500, "boom")
+	client.SetDefaultResponse("", boom)
+
+	_, err := SummarizeDocument(context.Background(), client, "One. \n\nTwo.", SummarizeOptions{ChunkTokens: 1})
+	var mapErr *MapError
+	require.True(t, errors.As(err, &mapErr))
+	assert.True(t, errors.Is(err, boom))
+}
+
+func TestRenderSummaryPrompt_AppendsWithoutPlaceholder(t *testing.T) {
+	assert.Equal(t, "Summarize this.\n\nbody", renderSummaryPrompt("Summarize this.", "body"))
+}
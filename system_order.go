@@ -0,0 +1,60 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// system_order.go checks where system messages sit in a conversation. Several providers
+// only accept system content ahead of the other turns, so a system message added
+// mid-conversation can either be rejected or moved to the front before sending.
+package chatdelta
+
+import "fmt"
+
+// SystemOrder controls how clients treat system messages that follow user, assistant,
+// or tool messages.
+type SystemOrder int
+
+const (
+	// SystemOrderAllow sends the conversation as is; providers with a separate system
+	// field merge every system message into it. This is the default.
+	SystemOrderAllow SystemOrder = iota
+	// SystemOrderStrict rejects conversations with a misplaced system message
+	SystemOrderStrict
+	// SystemOrderReorder moves system messages ahead of the other turns, keeping the
+	// relative order within each group
+	SystemOrderReorder
+)
+
+// ValidateSystemOrder returns an error naming the first system message that follows
+// a non-system message.
+func ValidateSystemOrder(conversation *Conversation) error {
+	for i, msg := range conversation.Messages {
+		if msg.Role == "system" && i > 0 && conversation.Messages[i-1].Role != "system" {
+			return NewInvalidParameterError("messages",
+				fmt.Sprintf("system message at index %d follows a %q message; system messages must come first", i, conversation.Messages[i-1].Role))
+		}
+	}
+	return nil
+}
+
+// applySystemOrder enforces config.SystemOrder on conversation. A reordered
+// conversation is a copy; the caller's history is left unchanged.
+func applySystemOrder(config *ClientConfig, conversation *Conversation) (*Conversation, error) {
+	switch config.SystemOrder {
+	case SystemOrderStrict:
+		return conversation, ValidateSystemOrder(conversation)
+	case SystemOrderReorder:
+		if ValidateSystemOrder(conversation) == nil {
+			return conversation, nil
+		}
+		ordered := make([]Message, 0, len(conversation.Messages))
+		for _, msg := range conversation.Messages {
+			if msg.Role == "system" {
+				ordered = append(ordered, msg)
+			}
+		}
+		for _, msg := range conversation.Messages {
+			if msg.Role != "system" {
+				ordered = append(ordered, msg)
+			}
+		}
+		return &Conversation{Messages: ordered}, nil
+	}
+	return conversation, nil
+}
@@ -0,0 +1,70 @@
+package chatdelta
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// misplacedSystemConversation has a system message after the first exchange.
+func misplacedSystemConversation() *Conversation {
+	conv := NewConversation()
+	conv.AddSystemMessage("Be brief.")
+	conv.AddUserMessage("Hi")
+	conv.AddAssistantMessage("Hello")
+	conv.AddSystemMessage("Answer in French.")
+	conv.AddUserMessage("How are you?")
+	return conv
+}
+
+func TestValidateSystemOrder(t *testing.T) {
+	assert.NoError(t, ValidateSystemOrder(promptConversation("hi")))
+
+	err := ValidateSystemOrder(misplacedSystemConversation())
+	var clientErr *ClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, "invalid_parameter", clientErr.Code)
+	assert.Contains(t, err.Error(), `system message at index 3 follows a "assistant" message`)
+}
+
+func TestSystemOrder_Strict(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetSystemOrder(SystemOrderStrict))
+	require.NoError(t, err)
+
+	_, err = client.DryRun(misplacedSystemConversation(), false)
+	assert.ErrorContains(t, err, "system messages must come first")
+
+	_, err = client.DryRun(promptConversation("hi"), false)
+	assert.NoError(t, err)
+}
+
+func TestSystemOrder_Reorder(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetSystemOrder(SystemOrderReorder))
+	require.NoError(t, err)
+	conv := misplacedSystemConversation()
+
+	prepared, err := client.DryRun(conv, false)
+	require.NoError(t, err)
+	var body openAIRequest
+	require.NoError(t, json.Unmarshal(prepared.Body, &body))
+	var roles []string
+	for _, msg := range body.Messages {
+		roles = append(roles, msg.Role)
+	}
+	assert.Equal(t, []string{"system", "system", "user", "assistant", "user"}, roles)
+	assert.Equal(t, "Answer in French.", body.Messages[1].Content)
+	assert.Equal(t, "system", conv.Messages[3].Role, "the caller's conversation is not modified")
+}
+
+func TestSystemOrder_AllowByDefault(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", nil)
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(misplacedSystemConversation(), false)
+	require.NoError(t, err)
+	var body openAIRequest
+	require.NoError(t, json.Unmarshal(prepared.Body, &body))
+	assert.Equal(t, "system", body.Messages[3].Role)
+}
@@ -0,0 +1,114 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// text_filter.go applies the client-side prompt and response filters configured with
+// AddPromptFilter and AddResponseFilter, for example to redact personal data before it
+// leaves the process. Unlike ContentFilter, these filters can reject text outright,
+// which fails the request with a content_rejected error.
+package chatdelta
+
+import "unicode/utf8"
+
+// TextFilter rewrites text, returning it unchanged if nothing needs to change, or
+// returns an error to reject it.
+type TextFilter func(text string) (string, error)
+
+// runTextFilters applies filters in order. stage names the filter kind in errors.
+func runTextFilters(stage string, filters []TextFilter, text string) (string, error) {
+	for i, filter := range filters {
+		var err error
+		if text, err = filter(text); err != nil {
+			return "", NewContentRejectedError(stage, i, err)
+		}
+	}
+	return text, nil
+}
+
+// applyPromptFilters runs config.PromptFilters over the text of every user and tool
+// message. Filtered conversations are copies; the caller's history keeps the original
+// text.
+func applyPromptFilters(config *ClientConfig, conversation *Conversation) (*Conversation, error) {
+	if len(config.PromptFilters) == 0 {
+		return conversation, nil
+	}
+	filtered := &Conversation{Messages: make([]Message, len(conversation.Messages))}
+	for i, msg := range conversation.Messages {
+		if msg.Role == "user" || msg.Role == "tool" {
+			var err error
+			if msg.Content, err = runTextFilters("prompt", config.PromptFilters, msg.Content); err != nil {
+				return nil, err
+			}
+			if len(msg.Parts) > 0 {
+				parts := make([]ContentPart, len(msg.Parts))
+				copy(parts, msg.Parts)
+				for j := range parts {
+					if parts[j].Type != ContentPartText {
+						continue
+					}
+					if parts[j].Text, err = runTextFilters("prompt", config.PromptFilters, parts[j].Text); err != nil {
+						return nil, err
+					}
+				}
+				msg.Parts = parts
+			}
+		}
+		filtered.Messages[i] = msg
+	}
+	return filtered, nil
+}
+
+// prepareConversation validates conversation and applies the config's system message
+// ordering and prompt filters to it before a request body is built.
+func prepareConversation(config *ClientConfig, conversation *Conversation) (*Conversation, error) {
+	if err := conversation.Validate(); err != nil {
+		return nil, err
+	}
+	conversation, err := applySystemOrder(config, conversation)
+	if err != nil {
+		return nil, err
+	}
+	return applyPromptFilters(config, conversation)
+}
+
+// responseFilterStream is the per-stream state of the response filters. Like
+// streamFilter, it holds back the last window bytes of filtered text so that a match
+// split across chunks is seen whole; held-back text is filtered again with the next
+// chunk, so filters should leave their own output unchanged.
+type responseFilterStream struct {
+	filters []TextFilter
+	window  int
+	pending string
+}
+
+func newResponseFilterStream(config *ClientConfig) *responseFilterStream {
+	if len(config.ResponseFilters) == 0 {
+		return nil
+	}
+	window := config.ContentFilterWindow
+	if window <= 0 {
+		window = DefaultContentFilterWindow
+	}
+	return &responseFilterStream{filters: config.ResponseFilters, window: window}
+}
+
+// push filters content together with the held-back text and returns the text that can
+// be released now. Everything is released when flush is set.
+func (f *responseFilterStream) push(content string, flush bool) (string, error) {
+	out, err := runTextFilters("response", f.filters, f.pending+content)
+	if err != nil {
+		f.pending = ""
+		return "", err
+	}
+
+	cut := len(out)
+	if !flush {
+		cut -= f.window
+		for cut > 0 && !utf8.RuneStart(out[cut]) {
+			cut--
+		}
+		if cut <= 0 {
+			f.pending = out
+			return "", nil
+		}
+	}
+	f.pending = out[cut:]
+	return out[:cut], nil
+}
@@ -0,0 +1,122 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var emailPattern = regexp.MustCompile(`[\w.]+@[\w.]+\.\w+`)
+
+// redactEmails replaces email addresses with a placeholder.
+func redactEmails(text string) (string, error) {
+	return emailPattern.ReplaceAllString(text, "[email]"), nil
+}
+
+// rejectContaining returns a filter that rejects text containing word.
+func rejectContaining(word string) TextFilter {
+	return func(text string) (string, error) {
+		if strings.Contains(text, word) {
+			return "", fmt.Errorf("contains %q", word)
+		}
+		return text, nil
+	}
+}
+
+// sseServer streams pieces as OpenAI content deltas.
+func sseServer(t *testing.T, pieces ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, piece := range pieces {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", piece)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPromptFilter_Redacts(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().AddPromptFilter(redactEmails))
+	require.NoError(t, err)
+	conv := NewConversation()
+	conv.AddSystemMessage("Support for jane@example.com's team.")
+	conv.AddUserMessage("Email me at bob@example.com")
+
+	prepared, err := client.DryRun(conv, false)
+	require.NoError(t, err)
+	var body openAIRequest
+	require.NoError(t, json.Unmarshal(prepared.Body, &body))
+	assert.Equal(t, "Email me at [email]", body.Messages[1].Content)
+	assert.Equal(t, "Support for jane@example.com's team.", body.Messages[0].Content, "system messages are not filtered")
+	assert.Equal(t, "Email me at bob@example.com", conv.Messages[1].Content, "the caller's conversation is not modified")
+}
+
+func TestPromptFilter_RejectsBeforeSending(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetRetries(0).AddPromptFilter(redactEmails).AddPromptFilter(rejectContaining("password")))
+	require.NoError(t, err)
+	client.httpClient.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Fatal("rejected prompts must not be sent")
+		return nil, nil
+	})
+
+	_, err = client.SendPrompt(context.Background(), "my password is hunter2")
+	var clientErr *ClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, "content_rejected", clientErr.Code)
+	assert.Contains(t, err.Error(), "prompt filter 1")
+	assert.ErrorContains(t, errors.Unwrap(err), `contains "password"`)
+}
+
+func TestResponseFilter_NonStreaming(t *testing.T) {
+	config := NewClientConfig().SetRetries(0).AddResponseFilter(redactEmails)
+	client, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK,
+		`{"choices":[{"message":{"role":"assistant","content":"Write to ops@example.com."},"finish_reason":"stop"}]}`)
+
+	response, err := client.SendPromptWithMetadata(context.Background(), "Who do I contact?")
+	require.NoError(t, err)
+	assert.Equal(t, "Write to [email].", response.Content)
+
+	config.AddResponseFilter(rejectContaining("[email]"))
+	_, err = client.SendPrompt(context.Background(), "Who do I contact?")
+	var clientErr *ClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, "content_rejected", clientErr.Code)
+	assert.Contains(t, err.Error(), "response filter 1")
+}
+
+func TestResponseFilter_StreamRedactsAcrossChunks(t *testing.T) {
+	server := sseServer(t, "Write to ops@exa", "mple.com or call", " us.")
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetBaseURL(server.URL).AddResponseFilter(redactEmails))
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "Who do I contact?")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "Write to [email] or call us.", content)
+	assert.NoError(t, last.Err)
+}
+
+func TestResponseFilter_StreamRejects(t *testing.T) {
+	server := sseServer(t, "Sure. The pass", "word is hunter2.", " Keep it safe.")
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetBaseURL(server.URL).AddResponseFilter(rejectContaining("hunter2")))
+	require.NoError(t, err)
+
+	ch, err := client.StreamPrompt(context.Background(), "What is the password?")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.NotContains(t, content, "hunter2")
+	var clientErr *ClientError
+	require.ErrorAs(t, last.Err, &clientErr)
+	assert.Equal(t, "content_rejected", clientErr.Code)
+}
@@ -0,0 +1,457 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// tokens.go provides client-side token estimation and the helpers that use it to keep
+// conversations within a token budget. Estimates are heuristics, not exact tokenizer
+// counts; leave headroom when comparing them against a model's context window.
+package chatdelta
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenEstimator estimates how many tokens a piece of text will consume.
+type TokenEstimator interface {
+	EstimateTokens(text string) int
+}
+
+// HeuristicEstimator estimates tokens as one token per four characters, which is
+// close to the average for English text across the supported providers.
+type HeuristicEstimator struct{}
+
+// EstimateTokens returns ceil(runes/4).
+func (HeuristicEstimator) EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// DefaultTokenEstimator is used by helpers that do not take an explicit estimator.
+var DefaultTokenEstimator TokenEstimator = HeuristicEstimator{}
+
+// tokenEstimators holds estimators registered with RegisterTokenEstimator, keyed by
+// model name prefix.
+var tokenEstimators = struct {
+	sync.RWMutex
+	byPrefix map[string]TokenEstimator
+}{byPrefix: make(map[string]TokenEstimator)}
+
+// RegisterTokenEstimator makes estimator the token estimator for every model whose name
+// starts with modelPrefix, for example a tiktoken-backed counter for "gpt-4o". The
+// longest matching prefix wins; a nil estimator removes the registration. It is safe
+// for concurrent use.
+func RegisterTokenEstimator(modelPrefix string, estimator TokenEstimator) {
+	tokenEstimators.Lock()
+	defer tokenEstimators.Unlock()
+	if estimator == nil {
+		delete(tokenEstimators.byPrefix, modelPrefix)
+		return
+	}
+	tokenEstimators.byPrefix[modelPrefix] = estimator
+}
+
+// TokenEstimatorForModel returns the estimator registered for the longest prefix of
+// model, or DefaultTokenEstimator if none matches.
+func TokenEstimatorForModel(model string) TokenEstimator {
+	tokenEstimators.RLock()
+	defer tokenEstimators.RUnlock()
+	best, estimator := "", DefaultTokenEstimator
+	for prefix, registered := range tokenEstimators.byPrefix {
+		if strings.HasPrefix(model, prefix) && len(prefix) >= len(best) {
+			best, estimator = prefix, registered
+		}
+	}
+	return estimator
+}
+
+// messageTokenOverhead approximates the per-message framing (role, separators)
+// that providers add on top of the content.
+const messageTokenOverhead = 4
+
+// TruncationStrategy selects which part of an oversized message is kept.
+type TruncationStrategy int
+
+const (
+	// TruncateHeadTail keeps the beginning and the end, eliding the middle
+	TruncateHeadTail TruncationStrategy = iota
+	// TruncateHead keeps the beginning, eliding the end
+	TruncateHead
+	// TruncateTail keeps the end, eliding the beginning
+	TruncateTail
+)
+
+// elisionMarker is inserted where content was removed.
+func elisionMarker(omitted int) string {
+	return fmt.Sprintf("[... %d tokens omitted ...]", omitted)
+}
+
+// TruncateMessageToTokens shortens m so its content fits in maxTokens according to
+// DefaultTokenEstimator. Whole lines and sentences are kept where possible and an
+// elision marker recording the number of omitted tokens replaces the removed text.
+// It returns the truncated message and the number of tokens omitted; a message that
+// already fits is returned unchanged with 0.
+func TruncateMessageToTokens(m Message, maxTokens int, strategy TruncationStrategy) (Message, int) {
+	return truncateMessage(m, maxTokens, strategy, DefaultTokenEstimator)
+}
+
+func truncateMessage(m Message, maxTokens int, strategy TruncationStrategy, estimator TokenEstimator) (Message, int) {
+	total := estimator.EstimateTokens(m.Content)
+	if total <= maxTokens {
+		return m, 0
+	}
+
+	// Reserve room for the marker, sized for the worst-case omitted count.
+	budget := maxTokens - estimator.EstimateTokens("\n"+elisionMarker(total)+"\n")
+	if budget < 0 {
+		budget = 0
+	}
+
+	units := splitSentences(m.Content)
+	var head, tail string
+	switch strategy {
+	case TruncateHead:
+		head = takeHead(units, budget, estimator)
+	case TruncateTail:
+		tail = takeTail(units, budget, estimator)
+	default:
+		head = takeHead(units, budget/2, estimator)
+		tail = takeTail(units, budget-estimator.EstimateTokens(head), estimator)
+	}
+
+	omitted := total - estimator.EstimateTokens(head) - estimator.EstimateTokens(tail)
+	if omitted < 0 {
+		omitted = 0
+	}
+
+	parts := make([]string, 0, 3)
+	if head != "" {
+		parts = append(parts, strings.TrimRight(head, "\n"))
+	}
+	parts = append(parts, elisionMarker(omitted))
+	if tail != "" {
+		parts = append(parts, strings.TrimLeft(tail, " \n"))
+	}
+
+	m.Content = strings.Join(parts, "\n")
+	return m, omitted
+}
+
+// splitSentences splits text into lines and, within lines, sentences. Each unit
+// keeps its trailing delimiter so concatenating the units yields text again.
+func splitSentences(text string) []string {
+	var units []string
+	start := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		boundary := r == '\n'
+		if !boundary && (r == '.' || r == '!' || r == '?') && i+1 < len(runes) && unicode.IsSpace(runes[i+1]) {
+			boundary = true
+		}
+		if boundary {
+			units = append(units, string(runes[start:i+1]))
+			start = i + 1
+		}
+	}
+	if start < len(runes) {
+		units = append(units, string(runes[start:]))
+	}
+	return units
+}
+
+// takeHead returns the longest prefix of whole units that fits budget. If even the
+// first unit is too large, it is cut at a rune boundary instead.
+func takeHead(units []string, budget int, estimator TokenEstimator) string {
+	var b strings.Builder
+	for _, unit := range units {
+		if estimator.EstimateTokens(b.String()+unit) > budget {
+			if b.Len() == 0 {
+				return cutRunes(unit, budget, estimator, false)
+			}
+			break
+		}
+		b.WriteString(unit)
+	}
+	return b.String()
+}
+
+// takeTail returns the longest suffix of whole units that fits budget. If even the
+// last unit is too large, it is cut at a rune boundary instead.
+func takeTail(units []string, budget int, estimator TokenEstimator) string {
+	kept := ""
+	for i := len(units) - 1; i >= 0; i-- {
+		if estimator.EstimateTokens(units[i]+kept) > budget {
+			if kept == "" {
+				return cutRunes(units[i], budget, estimator, true)
+			}
+			break
+		}
+		kept = units[i] + kept
+	}
+	return kept
+}
+
+// cutRunes returns the longest prefix (or suffix, when fromEnd is set) of s that fits budget.
+func cutRunes(s string, budget int, estimator TokenEstimator, fromEnd bool) string {
+	runes := []rune(s)
+	slice := func(n int) string {
+		if fromEnd {
+			return string(runes[len(runes)-n:])
+		}
+		return string(runes[:n])
+	}
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if estimator.EstimateTokens(slice(mid)) <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return slice(lo)
+}
+
+// estimateConversationTokens sums the estimated size of every message, including
+// per-message framing overhead.
+func estimateConversationTokens(messages []Message, estimator TokenEstimator) int {
+	total := 0
+	for _, msg := range messages {
+		total += estimator.EstimateTokens(msg.Content) + messageTokenOverhead
+	}
+	return total
+}
+
+// tokenCountCache memoizes an estimator's counts by text, so a session that trims its
+// history before every request only estimates messages it has not seen before. An
+// edited message has new content and is estimated afresh. It is safe for concurrent
+// use.
+type tokenCountCache struct {
+	estimator TokenEstimator
+	mu        sync.Mutex
+	counts    map[string]int
+}
+
+// newTokenCountCache returns an empty cache for estimator; nil uses
+// DefaultTokenEstimator.
+func newTokenCountCache(estimator TokenEstimator) *tokenCountCache {
+	if estimator == nil {
+		estimator = DefaultTokenEstimator
+	}
+	return &tokenCountCache{estimator: estimator, counts: make(map[string]int)}
+}
+
+// EstimateTokens returns the cached count for text, estimating it on first use.
+func (c *tokenCountCache) EstimateTokens(text string) int {
+	c.mu.Lock()
+	n, ok := c.counts[text]
+	c.mu.Unlock()
+	if ok {
+		return n
+	}
+	n = c.estimator.EstimateTokens(text)
+	c.mu.Lock()
+	c.counts[text] = n
+	c.mu.Unlock()
+	return n
+}
+
+// retain drops the counts of every text that is not the content of one of messages,
+// such as removed messages and the pieces tried while truncating one.
+func (c *tokenCountCache) retain(messages []Message) {
+	keep := make(map[string]struct{}, len(messages))
+	for _, msg := range messages {
+		keep[msg.Content] = struct{}{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for text := range c.counts {
+		if _, ok := keep[text]; !ok {
+			delete(c.counts, text)
+		}
+	}
+}
+
+// EstimateTokens returns the estimated prompt size of conversation, including
+// per-message framing, using DefaultTokenEstimator. Use EstimatePromptTokens to
+// apply the estimator registered for a client's model.
+func EstimateTokens(conversation *Conversation) int {
+	return estimateConversationTokens(conversation.Messages, DefaultTokenEstimator)
+}
+
+// EstimateTokens returns the estimated prompt size of the conversation; see the
+// package-level EstimateTokens.
+func (c *Conversation) EstimateTokens() int {
+	return EstimateTokens(c)
+}
+
+// CheckContextWindow estimates conversation's prompt size for client, as
+// EstimatePromptTokens does, and returns an error matching ErrContextTooLarge if it
+// exceeds the model's context window less the client's MaxTokens, which is kept for
+// the response. Models whose context window is unknown are not checked. Estimates
+// are heuristics, so a conversation that passes can still be rejected by the
+// provider.
+func CheckContextWindow(client AIClient, conversation *Conversation) error {
+	info, ok := GetModelInfo(client)
+	if !ok {
+		info, ok = ModelInfoFor(client.Model())
+	}
+	if !ok || info.ContextWindow <= 0 {
+		return nil
+	}
+	limit := info.ContextWindow
+	if configured, ok := client.(configuredClient); ok {
+		if maxTokens := configured.clientConfig().MaxTokens; maxTokens != nil {
+			limit -= *maxTokens
+		}
+	}
+	if estimated := EstimatePromptTokens(client, conversation); estimated > limit {
+		return NewContextTooLargeError(estimated, limit)
+	}
+	return nil
+}
+
+// PromptTokenEstimator is implemented by clients that can estimate the prompt size of
+// a conversation for their configured model.
+type PromptTokenEstimator interface {
+	EstimatePromptTokens(conversation *Conversation) int
+}
+
+// EstimatePromptTokens estimates the prompt size of conversation when sent through
+// client. Clients that do not implement PromptTokenEstimator, such as wrappers, are
+// estimated with the estimator registered for client.Model(). Compare the result with
+// GetModelInfo's ContextWindow to check a request before sending it.
+func EstimatePromptTokens(client AIClient, conversation *Conversation) int {
+	if e, ok := client.(PromptTokenEstimator); ok {
+		return e.EstimatePromptTokens(conversation)
+	}
+	return estimateConversationTokens(conversation.Messages, TokenEstimatorForModel(client.Model()))
+}
+
+// TrimToTokenLimit shrinks the conversation until its estimated size fits maxTokens,
+// always preserving system messages. A nil estimator uses DefaultTokenEstimator.
+//
+// Non-system messages larger than half of the non-system budget are first truncated
+// (head and tail kept) so that a single pasted document does not evict the rest of
+// the history. If the conversation is still too large, the oldest non-system messages
+// are dropped; the most recent message is never dropped. It returns the number of
+// messages removed.
+func (c *Conversation) TrimToTokenLimit(maxTokens int, estimator TokenEstimator) int {
+	if estimator == nil {
+		estimator = DefaultTokenEstimator
+	}
+	if estimateConversationTokens(c.Messages, estimator) <= maxTokens {
+		return 0
+	}
+
+	budget := maxTokens
+	for _, msg := range c.Messages {
+		if msg.Role == "system" {
+			budget -= estimator.EstimateTokens(msg.Content) + messageTokenOverhead
+		}
+	}
+
+	perMessage := budget/2 - messageTokenOverhead
+	if perMessage > 0 {
+		for i, msg := range c.Messages {
+			if estimateConversationTokens(c.Messages, estimator) <= maxTokens {
+				return 0
+			}
+			if msg.Role != "system" && estimator.EstimateTokens(msg.Content) > perMessage {
+				c.Messages[i], _ = truncateMessage(msg, perMessage, TruncateHeadTail, estimator)
+			}
+		}
+	}
+
+	removed := 0
+	for estimateConversationTokens(c.Messages, estimator) > maxTokens {
+		idx := -1
+		for i, msg := range c.Messages[:len(c.Messages)-1] {
+			if msg.Role != "system" {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			break
+		}
+		c.Messages = append(c.Messages[:idx], c.Messages[idx+1:]...)
+		removed++
+	}
+	return removed
+}
+
+// TruncateToTokens drops the oldest exchanges until the conversation's estimated size
+// fits maxTokens, always preserving system messages. A nil estimator uses
+// DefaultTokenEstimator.
+//
+// An exchange is a user message together with the replies and tool results that
+// follow it, so the history keeps starting with a user turn after trimming. Unlike
+// TrimToTokenLimit, no message is shortened and the latest exchange is never dropped,
+// so the result can still exceed maxTokens. It returns the number of messages removed.
+func (c *Conversation) TruncateToTokens(maxTokens int, estimator TokenEstimator) int {
+	if estimator == nil {
+		estimator = DefaultTokenEstimator
+	}
+	removed := 0
+	for estimateConversationTokens(c.Messages, estimator) > maxTokens {
+		n := c.dropOldestExchange()
+		if n == 0 {
+			break
+		}
+		removed += n
+	}
+	return removed
+}
+
+// truncateToMessages drops the oldest exchanges until at most maxMessages
+// non-system messages remain, never dropping the latest exchange. It returns the
+// number of messages removed.
+func (c *Conversation) truncateToMessages(maxMessages int) int {
+	removed := 0
+	for c.countNonSystem() > maxMessages {
+		n := c.dropOldestExchange()
+		if n == 0 {
+			break
+		}
+		removed += n
+	}
+	return removed
+}
+
+// countNonSystem returns the number of messages that are not system messages.
+func (c *Conversation) countNonSystem() int {
+	count := 0
+	for _, msg := range c.Messages {
+		if msg.Role != "system" {
+			count++
+		}
+	}
+	return count
+}
+
+// dropOldestExchange removes the non-system messages before the second user
+// message, keeping any system messages among them. It returns the number of
+// messages removed, or 0 if the conversation holds a single exchange.
+func (c *Conversation) dropOldestExchange() int {
+	start := -1
+	for i, msg := range c.Messages {
+		switch {
+		case msg.Role == "system":
+		case start < 0:
+			start = i
+		case msg.Role == "user":
+			kept := append([]Message(nil), c.Messages[:start]...)
+			removed := 0
+			for _, old := range c.Messages[start:i] {
+				if old.Role == "system" {
+					kept = append(kept, old)
+				} else {
+					removed++
+				}
+			}
+			c.Messages = append(kept, c.Messages[i:]...)
+			return removed
+		}
+	}
+	return 0
+}
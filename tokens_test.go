@@ -0,0 +1,249 @@
+package chatdelta
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// longDocument builds a multi-line document of numbered sentences.
+func longDocument(lines int) string {
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "Line %d has a first sentence. It also has a second one.\n", i)
+	}
+	return b.String()
+}
+
+func TestHeuristicEstimator(t *testing.T) {
+	est := HeuristicEstimator{}
+	assert.Equal(t, 0, est.EstimateTokens(""))
+	assert.Equal(t, 1, est.EstimateTokens("abcd"))
+	assert.Equal(t, 2, est.EstimateTokens("abcde"))
+	assert.Equal(t, 1, est.EstimateTokens("日本語"))
+}
+
+func TestTruncateMessageToTokens_FitsUnchanged(t *testing.T) {
+	m := Message{Role: "user", Content: "short"}
+	out, omitted := TruncateMessageToTokens(m, 100, TruncateHeadTail)
+	assert.Equal(t, m, out)
+	assert.Zero(t, omitted)
+}
+
+func TestTruncateMessageToTokens_WithinTolerance(t *testing.T) {
+	doc := longDocument(200)
+	original := DefaultTokenEstimator.EstimateTokens(doc)
+
+	for _, tc := range []struct {
+		name     string
+		strategy TruncationStrategy
+	}{
+		{"head_tail", TruncateHeadTail},
+		{"head", TruncateHead},
+		{"tail", TruncateTail},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			target := 300
+			out, omitted := TruncateMessageToTokens(Message{Role: "user", Content: doc}, target, tc.strategy)
+
+			size := DefaultTokenEstimator.EstimateTokens(out.Content)
+			assert.LessOrEqual(t, size, target)
+			assert.GreaterOrEqual(t, size, target*85/100, "truncated size should be close to the target")
+			assert.Contains(t, out.Content, elisionMarker(omitted))
+			assert.InDelta(t, original-target, omitted, float64(target)*0.15)
+			assert.Equal(t, "user", out.Role)
+		})
+	}
+}
+
+func TestTruncateMessageToTokens_PreservesLines(t *testing.T) {
+	doc := longDocument(100)
+	out, _ := TruncateMessageToTokens(Message{Content: doc}, 200, TruncateHeadTail)
+
+	assert.True(t, strings.HasPrefix(out.Content, "Line 0 has a first sentence."))
+	assert.True(t, strings.HasSuffix(out.Content, "Line 99 has a first sentence. It also has a second one.\n"))
+	for _, line := range strings.Split(strings.TrimRight(out.Content, "\n"), "\n") {
+		if strings.HasPrefix(line, "[...") {
+			continue
+		}
+		assert.True(t, strings.HasPrefix(line, "Line ") || strings.HasPrefix(line, "It also"), "partial line %q", line)
+	}
+
+	head, _ := TruncateMessageToTokens(Message{Content: doc}, 200, TruncateHead)
+	assert.True(t, strings.HasSuffix(head.Content, "tokens omitted ...]"))
+
+	tail, _ := TruncateMessageToTokens(Message{Content: doc}, 200, TruncateTail)
+	assert.True(t, strings.HasPrefix(tail.Content, "[... "))
+}
+
+func TestTruncateMessageToTokens_UnbrokenText(t *testing.T) {
+	doc := strings.Repeat("x", 4000)
+	out, omitted := TruncateMessageToTokens(Message{Content: doc}, 100, TruncateHeadTail)
+
+	assert.LessOrEqual(t, DefaultTokenEstimator.EstimateTokens(out.Content), 100)
+	assert.Greater(t, omitted, 850)
+	assert.True(t, strings.HasPrefix(out.Content, "xxx"))
+	assert.True(t, strings.HasSuffix(out.Content, "xxx"))
+}
+
+func TestConversation_TrimToTokenLimit_TruncatesBeforeDropping(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystemMessage("You are helpful.")
+	conv.AddUserMessage("hello")
+	conv.AddAssistantMessage("hi, how can I help?")
+	conv.AddUserMessage(longDocument(200))
+
+	removed := conv.TrimToTokenLimit(500, nil)
+
+	assert.Zero(t, removed)
+	require.Len(t, conv.Messages, 4)
+	assert.Equal(t, "hello", conv.Messages[1].Content)
+	assert.Contains(t, conv.Messages[3].Content, "tokens omitted")
+	assert.LessOrEqual(t, estimateConversationTokens(conv.Messages, DefaultTokenEstimator), 500)
+}
+
+func TestConversation_TrimToTokenLimit_DropsOldest(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystemMessage("You are helpful.")
+	for i := 0; i < 20; i++ {
+		conv.AddUserMessage(fmt.Sprintf("question number %d with some padding text", i))
+		conv.AddAssistantMessage(fmt.Sprintf("answer number %d with some padding text", i))
+	}
+
+	removed := conv.TrimToTokenLimit(100, nil)
+
+	assert.Greater(t, removed, 0)
+	assert.Equal(t, "system", conv.Messages[0].Role)
+	assert.Equal(t, "answer number 19 with some padding text", conv.Messages[len(conv.Messages)-1].Content)
+	assert.LessOrEqual(t, estimateConversationTokens(conv.Messages, DefaultTokenEstimator), 100)
+}
+
+func TestConversation_TruncateToTokens_DropsWholeExchanges(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystemMessage("You are helpful.")
+	for i := 0; i < 5; i++ {
+		conv.AddUserMessage(fmt.Sprintf("question number %d with some padding text", i))
+		conv.AddAssistantMessage(fmt.Sprintf("answer number %d with some padding text", i))
+	}
+	conv.AddUserMessage("latest question")
+
+	removed := conv.TruncateToTokens(60, nil)
+
+	assert.Equal(t, 0, removed%2, "user and assistant messages are removed in pairs")
+	assert.Equal(t, "system", conv.Messages[0].Role)
+	assert.Equal(t, "user", conv.Messages[1].Role)
+	assert.Equal(t, "latest question", conv.Messages[len(conv.Messages)-1].Content)
+	assert.LessOrEqual(t, estimateConversationTokens(conv.Messages, DefaultTokenEstimator), 60)
+	require.NoError(t, conv.ValidateAlternation())
+}
+
+func TestConversation_TruncateToTokens_KeepsLatestExchange(t *testing.T) {
+	conv := NewConversation()
+	conv.AddUserMessage("old question")
+	conv.AddAssistantMessage("old answer")
+	conv.AddSystemMessage("Late system note.")
+	conv.AddUserMessage(longDocument(50))
+
+	removed := conv.TruncateToTokens(10, nil)
+
+	assert.Equal(t, 2, removed)
+	require.Len(t, conv.Messages, 2)
+	assert.Equal(t, "Late system note.", conv.Messages[0].Content, "system messages inside a dropped exchange are kept")
+	assert.Equal(t, longDocument(50), conv.Messages[1].Content, "messages are never shortened")
+}
+
+func TestConversation_TruncateToTokens_UsesEstimator(t *testing.T) {
+	conv := NewConversation()
+	conv.AddUserMessage("one two three")
+	conv.AddAssistantMessage("four five")
+	conv.AddUserMessage("six")
+
+	estimator := &countingEstimator{inner: wordEstimator{}}
+	removed := conv.TruncateToTokens(1+messageTokenOverhead, estimator)
+
+	assert.Equal(t, 2, removed)
+	assert.Positive(t, estimator.calls)
+	assert.Equal(t, "six", conv.Messages[0].Content)
+}
+
+// countingEstimator counts calls to the estimator it wraps, in total and per text.
+type countingEstimator struct {
+	inner TokenEstimator
+	calls int
+	texts map[string]int
+}
+
+func (e *countingEstimator) EstimateTokens(text string) int {
+	e.calls++
+	if e.texts == nil {
+		e.texts = make(map[string]int)
+	}
+	e.texts[text]++
+	return e.inner.EstimateTokens(text)
+}
+
+func TestEstimateTokens(t *testing.T) {
+	conv := NewConversation()
+	conv.AddSystemMessage("abcd")
+	conv.AddUserMessage("abcdefgh")
+	assert.Equal(t, 1+2+2*messageTokenOverhead, EstimateTokens(conv))
+	assert.Equal(t, 0, EstimateTokens(NewConversation()))
+	assert.Equal(t, EstimateTokens(conv), conv.EstimateTokens())
+}
+
+func TestCheckContextWindow(t *testing.T) {
+	// gpt-4 has an 8,192-token context window
+	conv := NewConversation()
+	conv.AddUserMessage(strings.Repeat("abcd", 8000))
+
+	client, err := NewOpenAIClient("test-key", "gpt-4", nil)
+	require.NoError(t, err)
+	assert.NoError(t, CheckContextWindow(client, conv))
+
+	client, err = NewOpenAIClient("test-key", "gpt-4", NewClientConfig().SetMaxTokens(1000))
+	require.NoError(t, err)
+	err = CheckContextWindow(client, conv)
+	require.Error(t, err, "MaxTokens is kept for the response")
+	assert.ErrorIs(t, err, ErrContextTooLarge)
+	assert.True(t, IsContextLengthError(err))
+	assert.Contains(t, err.Error(), "estimated at 8004 tokens, more than the 7192 available")
+
+	oversized := NewConversation()
+	oversized.AddUserMessage(strings.Repeat("abcd", 9000))
+	assert.ErrorIs(t, CheckContextWindow(NewDrainingClient(client), oversized), ErrContextTooLarge,
+		"wrappers are checked against the registry entry for their model")
+
+	unknown, err := NewOpenAIClient("test-key", "my-fine-tune", nil)
+	require.NoError(t, err)
+	conv.AddUserMessage(strings.Repeat("abcd", 1_000_000))
+	assert.NoError(t, CheckContextWindow(unknown, conv), "unknown context windows are not checked")
+}
+
+func TestRegisterTokenEstimator(t *testing.T) {
+	RegisterTokenEstimator("gpt-", wordEstimator{})
+	RegisterTokenEstimator("gpt-4o", HeuristicEstimator{})
+	t.Cleanup(func() {
+		RegisterTokenEstimator("gpt-", nil)
+		RegisterTokenEstimator("gpt-4o", nil)
+	})
+
+	assert.Equal(t, wordEstimator{}, TokenEstimatorForModel("gpt-4-turbo"))
+	assert.Equal(t, HeuristicEstimator{}, TokenEstimatorForModel("gpt-4o-mini"), "longest prefix wins")
+	assert.Equal(t, DefaultTokenEstimator, TokenEstimatorForModel("claude-3-5-haiku-latest"))
+
+	conv := NewConversation()
+	conv.AddUserMessage("three short words")
+
+	openai, err := NewOpenAIClient("test-key", "gpt-4-turbo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3+messageTokenOverhead, openai.EstimatePromptTokens(conv))
+	assert.Equal(t, 3+messageTokenOverhead, EstimatePromptTokens(NewDrainingClient(openai), conv),
+		"wrappers fall back to the estimator for the model")
+
+	claude, err := NewClaudeClient("test-key", "claude-3-5-haiku-latest", nil)
+	require.NoError(t, err)
+	assert.Equal(t, EstimateTokens(conv), EstimatePromptTokens(claude, conv))
+}
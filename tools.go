@@ -0,0 +1,164 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// tools.go defines provider-independent tool (function) calling. Tools are declared
+// once on ClientConfig and translated by each client into the provider's schema:
+// OpenAI "tools", Claude "tools" with input_schema, and Gemini functionDeclarations.
+//
+// A tool-calling loop looks like:
+//
+//	response, _ := client.SendConversationWithMetadata(ctx, conversation)
+//	for len(response.ToolCalls) > 0 {
+//		conversation.AddToolCalls(response.Content, response.ToolCalls)
+//		for _, call := range response.ToolCalls {
+//			conversation.AddToolResult(call.ID, run(call.Name, call.Arguments))
+//		}
+//		response, _ = client.SendConversationWithMetadata(ctx, conversation)
+//	}
+//
+// Tool calls are reported by SendConversationWithMetadata; streaming and the plain
+// string methods return text only. Ollama ignores tools.
+package chatdelta
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Tool describes a function the model may ask the caller to run.
+type Tool struct {
+	// Name identifies the tool; letters, digits, '_' and '-', at most 64 characters
+	Name string `json:"name"`
+	// Description tells the model what the tool does and when to use it
+	Description string `json:"description,omitempty"`
+	// Parameters is a JSON Schema object describing the arguments. Nil means the
+	// tool takes no arguments.
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a model's request to run a tool.
+type ToolCall struct {
+	// ID identifies the call; pass it to Conversation.AddToolResult. Providers that
+	// do not assign IDs (Gemini) get generated ones.
+	ID string `json:"id"`
+	// Name of the tool to run
+	Name string `json:"name"`
+	// Arguments is the JSON object of arguments chosen by the model
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ToolChoice controls whether and which tools the model calls. The zero value leaves
+// the choice to the provider's default, which is ToolChoiceAuto.
+type ToolChoice string
+
+const (
+	// ToolChoiceAuto lets the model decide whether to call a tool
+	ToolChoiceAuto ToolChoice = "auto"
+	// ToolChoiceNone prevents tool calls
+	ToolChoiceNone ToolChoice = "none"
+	// ToolChoiceRequired makes the model call at least one tool
+	ToolChoiceRequired ToolChoice = "required"
+)
+
+// toolChoiceFunctionPrefix marks a ToolChoice that forces a specific tool.
+const toolChoiceFunctionPrefix = "function:"
+
+// ToolChoiceFunction forces the model to call the named tool.
+func ToolChoiceFunction(name string) ToolChoice {
+	return ToolChoice(toolChoiceFunctionPrefix + name)
+}
+
+// function returns the tool forced by c, if any.
+func (c ToolChoice) function() (string, bool) {
+	return strings.CutPrefix(string(c), toolChoiceFunctionPrefix)
+}
+
+// toolNamePattern matches the tool names accepted by every supported provider.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ValidateTools checks config.Tools and config.ToolChoice: names must be valid and
+// unique, parameters must be JSON objects, and a forced tool must be declared.
+func ValidateTools(config *ClientConfig) error {
+	names := make(map[string]bool, len(config.Tools))
+	for _, tool := range config.Tools {
+		if !toolNamePattern.MatchString(tool.Name) {
+			return NewInvalidParameterError("tools", fmt.Sprintf("invalid tool name %q", tool.Name))
+		}
+		if names[tool.Name] {
+			return NewInvalidParameterError("tools", fmt.Sprintf("duplicate tool name %q", tool.Name))
+		}
+		names[tool.Name] = true
+
+		if len(tool.Parameters) > 0 {
+			var schema map[string]any
+			if err := json.Unmarshal(tool.Parameters, &schema); err != nil {
+				return NewInvalidParameterError("tools", fmt.Sprintf("parameters of %q are not a JSON object", tool.Name))
+			}
+		}
+	}
+
+	switch config.ToolChoice {
+	case "", ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+	default:
+		name, ok := config.ToolChoice.function()
+		if !ok {
+			return NewInvalidParameterError("tool_choice", string(config.ToolChoice))
+		}
+		if !names[name] {
+			return NewInvalidParameterError("tool_choice", fmt.Sprintf("tool %q is not declared", name))
+		}
+	}
+	return nil
+}
+
+// ToolResult is the output of running one tool call, for ChatSession.SendToolResults.
+type ToolResult struct {
+	// ToolCallID is the ID of the ToolCall that was run
+	ToolCallID string `json:"tool_call_id"`
+	// Content is the result, usually JSON
+	Content string `json:"content"`
+}
+
+// AddToolCalls adds an assistant message recording the tool calls from a response,
+// along with any text the model produced alongside them.
+func (c *Conversation) AddToolCalls(content string, calls []ToolCall) {
+	c.Messages = append(c.Messages, Message{
+		Role:      "assistant",
+		Content:   content,
+		ToolCalls: calls,
+	})
+}
+
+// AddToolResult adds a "tool" message carrying the result of the call with the given
+// ID. The result is usually JSON, but any text is accepted.
+func (c *Conversation) AddToolResult(toolCallID, content string) {
+	c.Messages = append(c.Messages, Message{
+		Role:       "tool",
+		Content:    content,
+		ToolCallID: toolCallID,
+	})
+}
+
+// toolCallName returns the name of the tool called with id by the latest assistant
+// message before messages[index], for providers whose results are keyed by name.
+func toolCallName(messages []Message, index int, id string) string {
+	for i := index - 1; i >= 0; i-- {
+		for _, call := range messages[i].ToolCalls {
+			if call.ID == id {
+				return call.Name
+			}
+		}
+	}
+	return ""
+}
+
+// toolArguments returns raw as a JSON object, treating empty arguments as {}.
+func toolArguments(raw []byte) (json.RawMessage, error) {
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return json.RawMessage("{}"), nil
+	}
+	if !json.Valid(raw) {
+		return nil, NewJSONParseError(fmt.Errorf("tool call arguments are not valid JSON: %s", raw))
+	}
+	return json.RawMessage(raw), nil
+}
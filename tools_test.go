@@ -0,0 +1,287 @@
+package chatdelta
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var weatherTool = Tool{
+	Name:        "get_weather",
+	Description: "Get the current weather for a city.",
+	Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+}
+
+// scriptedResponses answers successive requests with replies in order, recording each
+// request body.
+func scriptedResponses(t *testing.T, replies ...string) (roundTripFunc, *[]json.RawMessage) {
+	var requests []json.RawMessage
+	return func(req *http.Request) (*http.Response, error) {
+		var body json.RawMessage
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		requests = append(requests, body)
+		require.LessOrEqual(t, len(requests), len(replies), "unexpected request")
+		return cannedResponse(http.StatusOK, replies[len(requests)-1])(req)
+	}, &requests
+}
+
+func TestValidateTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *ClientConfig
+		wantErr bool
+	}{
+		{"no tools", NewClientConfig(), false},
+		{"valid", NewClientConfig().SetTools(weatherTool, Tool{Name: "now"}), false},
+		{"forced tool", NewClientConfig().SetTools(weatherTool).SetToolChoice(ToolChoiceFunction("get_weather")), false},
+		{"bad name", NewClientConfig().SetTools(Tool{Name: "get weather"}), true},
+		{"duplicate", NewClientConfig().SetTools(weatherTool, weatherTool), true},
+		{"schema not an object", NewClientConfig().SetTools(Tool{Name: "x", Parameters: json.RawMessage(`[]`)}), true},
+		{"unknown forced tool", NewClientConfig().SetTools(weatherTool).SetToolChoice(ToolChoiceFunction("other")), true},
+		{"unknown choice", NewClientConfig().SetTools(weatherTool).SetToolChoice("sometimes"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(tt.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestToolChoice_ForcedFunctionMapping(t *testing.T) {
+	config := NewClientConfig().SetTools(weatherTool).SetToolChoice(ToolChoiceFunction("get_weather"))
+
+	openai, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+	_, choice := openai.buildTools()
+	assert.Equal(t, map[string]interface{}{"type": "function", "function": map[string]string{"name": "get_weather"}}, choice)
+
+	claude, err := NewClaudeClient("key", "", config)
+	require.NoError(t, err)
+	_, claudeChoice := claude.buildTools()
+	assert.Equal(t, &claudeToolChoice{Type: "tool", Name: "get_weather"}, claudeChoice)
+
+	gemini, err := NewGeminiClient("key", "", config)
+	require.NoError(t, err)
+	_, toolConfig := gemini.buildTools()
+	require.NotNil(t, toolConfig)
+	assert.Equal(t, "ANY", toolConfig.FunctionCallingConfig.Mode)
+	assert.Equal(t, []string{"get_weather"}, toolConfig.FunctionCallingConfig.AllowedFunctionNames)
+}
+
+func TestClaudeBuildRequest_ToolWithoutParameters(t *testing.T) {
+	claude, err := NewClaudeClient("key", "", NewClientConfig().SetTools(Tool{Name: "now"}))
+	require.NoError(t, err)
+	request := claude.buildRequest(promptConversation("What time is it?"), false)
+	require.Len(t, request.Tools, 1)
+	assert.JSONEq(t, `{"type":"object","properties":{}}`, string(request.Tools[0].InputSchema))
+	assert.Nil(t, request.ToolChoice)
+}
+
+func TestSendConversationWithMetadata_ToolCalls(t *testing.T) {
+	config := NewClientConfig().SetTools(weatherTool).SetRetries(0)
+	want := []ToolCall{{Name: "get_weather", Arguments: json.RawMessage(`{"city":"Paris"}`)}}
+
+	tests := []struct {
+		name   string
+		client func() AIClient
+		wantID string
+	}{
+		{"openai", func() AIClient {
+			client, err := NewOpenAIClient("key", "gpt-4o", config)
+			require.NoError(t, err)
+			client.httpClient.Transport = cannedResponse(http.StatusOK, `{"id":"r1","model":"gpt-4o","choices":[{"index":0,
+				"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_abc","type":"function",
+				"function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`)
+			return client
+		}, "call_abc"},
+		{"claude", func() AIClient {
+			client, err := NewClaudeClient("key", "", config)
+			require.NoError(t, err)
+			client.httpClient.Transport = cannedResponse(http.StatusOK, `{"id":"msg_1","type":"message","role":"assistant",
+				"content":[{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}],
+				"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`)
+			return client
+		}, "toolu_1"},
+		{"gemini", func() AIClient {
+			client, err := NewGeminiClient("key", "", config)
+			require.NoError(t, err)
+			client.httpClient.Transport = cannedResponse(http.StatusOK, `{"candidates":[{"content":{"role":"model",
+				"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP","index":0}]}`)
+			return client
+		}, "call_0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := tt.client().SendConversationWithMetadata(context.Background(), promptConversation("Weather in Paris?"))
+			require.NoError(t, err)
+			assert.Empty(t, response.Content)
+			require.Len(t, response.ToolCalls, 1)
+			assert.Equal(t, tt.wantID, response.ToolCalls[0].ID)
+			assert.Equal(t, want[0].Name, response.ToolCalls[0].Name)
+			assert.JSONEq(t, string(want[0].Arguments), string(response.ToolCalls[0].Arguments))
+			assert.Equal(t, FinishReasonToolCalls, response.Metadata.NormalizedFinishReason)
+		})
+	}
+}
+
+func TestOpenAIToolCalls_InvalidArguments(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetTools(weatherTool).SetRetries(0))
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusOK, `{"choices":[{"message":{"role":"assistant",
+		"tool_calls":[{"id":"c","type":"function","function":{"name":"get_weather","arguments":"{\"city\":"}}]},
+		"finish_reason":"tool_calls"}]}`)
+
+	_, err = client.SendConversationWithMetadata(context.Background(), promptConversation("Weather?"))
+	var clientErr *ClientError
+	require.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, ErrorTypeParse, clientErr.Type)
+}
+
+func TestConversation_ToolMessages(t *testing.T) {
+	conv := NewConversation()
+	conv.AddUserMessage("Weather in Paris?")
+	conv.AddToolCalls("", []ToolCall{{ID: "call_0", Name: "get_weather", Arguments: json.RawMessage(`{}`)}})
+	conv.AddToolResult("call_0", "Sunny")
+
+	require.Len(t, conv.Messages, 3)
+	assert.Equal(t, "assistant", conv.Messages[1].Role)
+	assert.Equal(t, "tool", conv.Messages[2].Role)
+	assert.Equal(t, "call_0", conv.Messages[2].ToolCallID)
+	assert.Equal(t, "get_weather", toolCallName(conv.Messages, 2, "call_0"))
+	assert.Empty(t, toolCallName(conv.Messages, 2, "missing"))
+}
+
+func TestChatSession_ToolLoop(t *testing.T) {
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetTools(weatherTool).SetRetries(0))
+	require.NoError(t, err)
+	transport, requests := scriptedResponses(t,
+		`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_abc","type":"function",
+			"function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[{"message":{"role":"assistant","content":"It is sunny in Paris."},"finish_reason":"stop"}]}`,
+	)
+	client.httpClient.Transport = transport
+	session := NewChatSession(client)
+
+	response, err := session.SendWithMetadata(context.Background(), "Weather in Paris?")
+	require.NoError(t, err)
+	require.Len(t, response.ToolCalls, 1)
+	assert.Equal(t, response.ToolCalls, session.History().Messages[1].ToolCalls)
+
+	response, err = session.SendToolResults(context.Background(), ToolResult{ToolCallID: "call_abc", Content: `{"sky":"sunny"}`})
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny in Paris.", response.Content)
+
+	require.Len(t, *requests, 2)
+	var second openAIRequest
+	require.NoError(t, json.Unmarshal((*requests)[1], &second))
+	sent := second.Messages
+	require.Len(t, sent, 3)
+	assert.Equal(t, "call_abc", sent[1].ToolCalls[0].ID)
+	assert.Equal(t, "tool", sent[2].Role)
+	assert.Equal(t, "call_abc", sent[2].ToolCallID)
+	assert.Equal(t, 4, session.Len())
+}
+
+func TestChatSession_SendToolResultsFailureRollsBack(t *testing.T) {
+	mock := NewMockClient("mock", "")
+	mock.QueueError(NewServerError(500, "down"))
+	session := NewChatSession(mock)
+	session.AddMessage(Message{Role: "user", Content: "Weather?"})
+	session.AddMessage(Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "get_weather"}}})
+
+	_, err := session.SendToolResults(context.Background(), ToolResult{ToolCallID: "c1", Content: "sunny"})
+	require.Error(t, err)
+	assert.Equal(t, 2, session.Len())
+}
+
+func TestClaudeToolRoundTrip(t *testing.T) {
+	client, err := NewClaudeClient("key", "claude-3-5-sonnet-20241022", NewClientConfig().SetTools(weatherTool).SetRetries(0))
+	require.NoError(t, err)
+	transport, requests := scriptedResponses(t,
+		`{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022",
+			"content":[{"type":"text","text":"Let me check."},{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}],
+			"stop_reason":"tool_use","usage":{"input_tokens":20,"output_tokens":10}}`,
+		`{"id":"msg_2","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022",
+			"content":[{"type":"text","text":"It is sunny in Paris."}],
+			"stop_reason":"end_turn","usage":{"input_tokens":40,"output_tokens":8}}`,
+	)
+	client.httpClient.Transport = transport
+	session := NewChatSession(client)
+
+	response, err := session.SendWithMetadata(context.Background(), "Weather in Paris?")
+	require.NoError(t, err)
+	assert.Equal(t, "Let me check.", response.Content)
+	require.Len(t, response.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", response.ToolCalls[0].ID)
+	assert.Equal(t, FinishReasonToolCalls, response.Metadata.NormalizedFinishReason)
+
+	response, err = session.SendToolResults(context.Background(), ToolResult{ToolCallID: "toolu_1", Content: `{"sky":"sunny"}`})
+	require.NoError(t, err)
+	assert.Equal(t, "It is sunny in Paris.", response.Content)
+	assert.Empty(t, response.ToolCalls)
+
+	require.Len(t, *requests, 2)
+	var sent struct {
+		Tools    json.RawMessage `json:"tools"`
+		Messages json.RawMessage `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal((*requests)[1], &sent))
+	assert.JSONEq(t, `[{"name":"get_weather","description":"Get the current weather for a city.",
+		"input_schema":{"type":"object","properties":{"city":{"type":"string"}}}}]`, string(sent.Tools))
+	assert.JSONEq(t, `[
+		{"role":"user","content":"Weather in Paris?"},
+		{"role":"assistant","content":[{"type":"text","text":"Let me check."},
+			{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"{\"sky\":\"sunny\"}"}]}
+	]`, string(sent.Messages))
+}
+
+func TestGeminiToolRoundTrip(t *testing.T) {
+	client, err := NewGeminiClient("key", "gemini-1.5-flash", NewClientConfig().SetTools(weatherTool).SetRetries(0))
+	require.NoError(t, err)
+	transport, requests := scriptedResponses(t,
+		`{"candidates":[{"content":{"role":"model","parts":[
+			{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}},
+			{"functionCall":{"name":"get_weather","args":{"city":"Oslo"}}}]},"finishReason":"STOP","index":0}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Sunny in Paris, rain in Oslo."}]},"finishReason":"STOP","index":0}]}`,
+	)
+	client.httpClient.Transport = transport
+	session := NewChatSession(client)
+
+	response, err := session.SendWithMetadata(context.Background(), "Weather in Paris and Oslo?")
+	require.NoError(t, err)
+	require.Len(t, response.ToolCalls, 2)
+	assert.Equal(t, []string{"call_0", "call_1"}, []string{response.ToolCalls[0].ID, response.ToolCalls[1].ID})
+	assert.Equal(t, FinishReasonToolCalls, response.Metadata.NormalizedFinishReason)
+
+	response, err = session.SendToolResults(context.Background(),
+		ToolResult{ToolCallID: "call_0", Content: `{"sky":"sunny"}`},
+		ToolResult{ToolCallID: "call_1", Content: "rain"})
+	require.NoError(t, err)
+	assert.Equal(t, "Sunny in Paris, rain in Oslo.", response.Content)
+
+	require.Len(t, *requests, 2)
+	var sent struct {
+		Tools    json.RawMessage `json:"tools"`
+		Contents json.RawMessage `json:"contents"`
+	}
+	require.NoError(t, json.Unmarshal((*requests)[1], &sent))
+	assert.JSONEq(t, `[{"functionDeclarations":[{"name":"get_weather","description":"Get the current weather for a city.",
+		"parameters":{"type":"object","properties":{"city":{"type":"string"}}}}]}]`, string(sent.Tools))
+	assert.JSONEq(t, `[
+		{"role":"user","parts":[{"text":"Weather in Paris and Oslo?"}]},
+		{"role":"model","parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}},
+			{"functionCall":{"name":"get_weather","args":{"city":"Oslo"}}}]},
+		{"role":"user","parts":[{"functionResponse":{"name":"get_weather","response":{"sky":"sunny"}}},
+			{"functionResponse":{"name":"get_weather","response":{"content":"rain"}}}]}
+	]`, string(sent.Contents))
+}
@@ -0,0 +1,114 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// trace.go carries a caller-chosen trace ID through a request so that the library's
+// log lines, metrics records, and retry callbacks can be correlated with the
+// application's own traces. Attach one with WithTrace; calls without one get a
+// generated ID.
+package chatdelta
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// traceKey is the context key for the trace ID.
+type traceKey struct{}
+
+// WithTrace returns a copy of ctx carrying traceID. Every client call made with the
+// returned context reports traceID to ClientConfig.Logger, ClientConfig.Metrics,
+// ClientConfig.OnRetry, and LoggingMiddleware.
+func WithTrace(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceID)
+}
+
+// TraceID returns the trace ID attached to ctx with WithTrace, or "" if none.
+func TraceID(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceKey{}).(string)
+	return traceID
+}
+
+// ensureTrace returns ctx and its trace ID, attaching a newly generated ID when ctx
+// does not carry one.
+func ensureTrace(ctx context.Context) (context.Context, string) {
+	if traceID := TraceID(ctx); traceID != "" {
+		return ctx, traceID
+	}
+	traceID := newTraceID()
+	return WithTrace(ctx, traceID), traceID
+}
+
+// newTraceID returns a random 16-byte hex ID, the format of a W3C trace-id.
+func newTraceID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// RetryEvent describes a failed attempt that is about to be retried.
+type RetryEvent struct {
+	// TraceID identifies the call; see WithTrace
+	TraceID string
+	// Attempt is the 1-based number of the attempt that failed
+	Attempt int
+	// Err is the error that attempt returned
+	Err error
+	// Delay is how long the client waits before the next attempt
+	Delay time.Duration
+}
+
+// RequestRecord summarizes one client call after all of its attempts.
+type RequestRecord struct {
+	// TraceID identifies the call; see WithTrace
+	TraceID string
+	// Attempts is the number of attempts made, including the first
+	Attempts int
+	// LatencyMs is the wall-clock time of the call, including retry waits
+	LatencyMs int64
+	// Err is the final error, or nil on success
+	Err error
+}
+
+// MetricsCollector receives a RequestRecord for every client call. It is called
+// synchronously on the calling goroutine and must be safe for concurrent use.
+type MetricsCollector interface {
+	RecordCall(record RequestRecord)
+}
+
+// retry runs operation with the config's retry count and clock, reporting the call
+// under its trace ID to the configured logger, metrics collector, and OnRetry hook.
+func (c *ClientConfig) retry(ctx context.Context, operation func() error) error {
+	ctx, traceID := ensureTrace(ctx)
+	clock := c.clock()
+	start := clock.Now()
+
+	attempts := 0
+	counted := func() error {
+		attempts++
+		return operation()
+	}
+	onRetry := func(err error, delay time.Duration) {
+		if c.Logger != nil {
+			c.Logger.Printf("[chatdelta] trace_id=%s attempt=%d error=%v retry_in=%s", traceID, attempts, err, delay)
+		}
+		if c.OnRetry != nil {
+			c.OnRetry(RetryEvent{TraceID: traceID, Attempt: attempts, Err: err, Delay: delay})
+		}
+	}
+
+	backoff := retryBackoff{strategy: c.RetryStrategy, base: c.BaseRetryDelay, max: c.MaxRetryDelay, rng: c.RetryRand}
+	err := executeWithRetryHook(ctx, c.Retries, clock, backoff, counted, onRetry)
+
+	latencyMs := clock.Now().Sub(start).Milliseconds()
+	if c.Logger != nil {
+		if err != nil {
+			c.Logger.Printf("[chatdelta] trace_id=%s attempts=%d latency_ms=%d error=%v", traceID, attempts, latencyMs, err)
+		} else {
+			c.Logger.Printf("[chatdelta] trace_id=%s attempts=%d latency_ms=%d ok", traceID, attempts, latencyMs)
+		}
+	}
+	if c.Metrics != nil {
+		c.Metrics.RecordCall(RequestRecord{TraceID: traceID, Attempts: attempts, LatencyMs: latencyMs, Err: err})
+	}
+	return err
+}
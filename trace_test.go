@@ -0,0 +1,106 @@
+package chatdelta
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingCollector is a MetricsCollector that keeps every record.
+type recordingCollector struct {
+	mu      sync.Mutex
+	records []RequestRecord
+}
+
+func (c *recordingCollector) RecordCall(record RequestRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, record)
+}
+
+func TestWithTrace_FlowsToLoggerMetricsAndOnRetry(t *testing.T) {
+	var logs bytes.Buffer
+	collector := &recordingCollector{}
+	var events []RetryEvent
+	config := NewClientConfig().
+		SetRetries(1).
+		SetClock(&fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}).
+		SetLogger(log.New(&logs, "", 0)).
+		SetMetrics(collector).
+		SetOnRetry(func(event RetryEvent) { events = append(events, event) })
+
+	client, err := NewOpenAIClient("key", "gpt-4o", config)
+	require.NoError(t, err)
+	calls := 0
+	client.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		status, body := http.StatusOK, `{"choices":[{"message":{"role":"assistant","content":"Hi"},"finish_reason":"stop"}]}`
+		if calls == 1 {
+			status, body = http.StatusInternalServerError, `{"error":{"message":"boom"}}`
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+
+	ctx := WithTrace(context.Background(), "trace-123")
+	_, err = client.SendPrompt(ctx, "Hello")
+	require.NoError(t, err)
+
+	assert.Contains(t, logs.String(), "trace_id=trace-123 attempt=1")
+	assert.Contains(t, logs.String(), "trace_id=trace-123 attempts=2 latency_ms=1000 ok")
+
+	require.Len(t, collector.records, 1)
+	assert.Equal(t, RequestRecord{TraceID: "trace-123", Attempts: 2, LatencyMs: 1000}, collector.records[0])
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "trace-123", events[0].TraceID)
+	assert.Equal(t, 1, events[0].Attempt)
+	assert.Equal(t, time.Second, events[0].Delay)
+	assert.Error(t, events[0].Err)
+}
+
+func TestWithTrace_GeneratedWhenMissing(t *testing.T) {
+	collector := &recordingCollector{}
+	client, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetRetries(0).SetMetrics(collector))
+	require.NoError(t, err)
+	client.httpClient.Transport = cannedResponse(http.StatusBadRequest, `{"error":{"message":"bad"}}`)
+
+	_, err = client.SendPrompt(context.Background(), "Hello")
+	require.Error(t, err)
+	_, _ = client.SendPrompt(context.Background(), "Hello")
+
+	require.Len(t, collector.records, 2)
+	assert.Len(t, collector.records[0].TraceID, 32)
+	assert.NotEqual(t, collector.records[0].TraceID, collector.records[1].TraceID)
+	assert.Equal(t, 1, collector.records[0].Attempts)
+	assert.Error(t, collector.records[0].Err)
+}
+
+func TestLoggingMiddleware_PassesTraceToClient(t *testing.T) {
+	var logs bytes.Buffer
+	mock := NewMockClient("mock", "model")
+	mock.QueueResponse("ok")
+
+	var seen string
+	inner := func(ctx context.Context, prompt string, next func(context.Context, string) (string, error)) (string, error) {
+		seen = TraceID(ctx)
+		return next(ctx, prompt)
+	}
+	client := NewMiddlewareClient(mock, LoggingMiddleware(log.New(&logs, "", 0)), inner)
+
+	_, err := client.SendPrompt(WithTrace(context.Background(), "abc"), "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", seen)
+	assert.Contains(t, logs.String(), "trace_id=abc request")
+
+	_, _ = client.SendPrompt(context.Background(), "Hello")
+	assert.Len(t, seen, 32)
+}
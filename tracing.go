@@ -0,0 +1,205 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// tracing.go defines TracingClient, an AIClient wrapper that opens a span per call for
+// distributed tracing. Spans are created through the small Tracer and Span interfaces
+// so the library does not depend on OpenTelemetry; the README shows the adapter that
+// connects them to an OpenTelemetry tracer.
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Span attribute keys set by TracingClient. The gen_ai keys follow the OpenTelemetry
+// semantic conventions for generative AI.
+const (
+	SpanAttrSystem        = "gen_ai.system"
+	SpanAttrRequestModel  = "gen_ai.request.model"
+	SpanAttrResponseModel = "gen_ai.response.model"
+	SpanAttrInputTokens   = "gen_ai.usage.input_tokens"
+	SpanAttrOutputTokens  = "gen_ai.usage.output_tokens"
+	SpanAttrErrorType     = "error.type"
+	SpanAttrErrorCode     = "chatdelta.error.code"
+	SpanAttrLatencyMs     = "chatdelta.latency_ms"
+	SpanAttrStreamed      = "chatdelta.streamed"
+)
+
+// Tracer starts spans. Start returns a context carrying the new span, which is
+// passed to the wrapped client so spans it starts are children of this one.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an in-progress operation. Attribute values are strings, ints, int64s, or
+// bools.
+type Span interface {
+	SetAttribute(key string, value any)
+	RecordError(err error)
+	End()
+}
+
+// TracingClient wraps an AIClient and records every call as a span named after the
+// method, e.g. "chatdelta.SendPrompt". SendPrompt and SendConversation are served
+// through their WithMetadata variants so that token counts can be recorded.
+type TracingClient struct {
+	inner  AIClient
+	tracer Tracer
+}
+
+// NewTracingClient creates a TracingClient recording inner's calls with tracer.
+func NewTracingClient(inner AIClient, tracer Tracer) *TracingClient {
+	return &TracingClient{inner: inner, tracer: tracer}
+}
+
+// tracedCall is one call's span and start time.
+type tracedCall struct {
+	span  Span
+	start time.Time
+}
+
+// begin starts the span for method, returning the context to call the inner client with.
+func (t *TracingClient) begin(ctx context.Context, method string, streamed bool) (context.Context, tracedCall) {
+	ctx, span := t.tracer.Start(ctx, "chatdelta."+method)
+	span.SetAttribute(SpanAttrSystem, t.inner.Name())
+	span.SetAttribute(SpanAttrRequestModel, t.inner.Model())
+	span.SetAttribute(SpanAttrStreamed, streamed)
+	return ctx, tracedCall{span: span, start: time.Now()}
+}
+
+// finish records the outcome of the call and ends its span.
+func (c tracedCall) finish(metadata *ResponseMetadata, err error) {
+	c.span.SetAttribute(SpanAttrLatencyMs, time.Since(c.start).Milliseconds())
+	if metadata != nil {
+		if metadata.ModelUsed != "" {
+			c.span.SetAttribute(SpanAttrResponseModel, metadata.ModelUsed)
+		}
+		if metadata.PromptTokens > 0 {
+			c.span.SetAttribute(SpanAttrInputTokens, metadata.PromptTokens)
+		}
+		if metadata.CompletionTokens > 0 {
+			c.span.SetAttribute(SpanAttrOutputTokens, metadata.CompletionTokens)
+		}
+	}
+	if err != nil {
+		c.span.RecordError(err)
+		var ce *ClientError
+		if errors.As(err, &ce) {
+			c.span.SetAttribute(SpanAttrErrorType, string(ce.Type))
+			if ce.Code != "" {
+				c.span.SetAttribute(SpanAttrErrorCode, ce.Code)
+			}
+		} else {
+			c.span.SetAttribute(SpanAttrErrorType, "_OTHER")
+		}
+	}
+	c.span.End()
+}
+
+// finishResponse is finish for calls returning an AiResponse.
+func (c tracedCall) finishResponse(response *AiResponse, err error) {
+	if response == nil {
+		c.finish(nil, err)
+		return
+	}
+	c.finish(&response.Metadata, err)
+}
+
+// SendPrompt forwards to the inner client within a span.
+func (t *TracingClient) SendPrompt(ctx context.Context, prompt string) (string, error) {
+	ctx, call := t.begin(ctx, "SendPrompt", false)
+	response, err := t.inner.SendPromptWithMetadata(ctx, prompt)
+	call.finishResponse(response, err)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendPromptWithMetadata forwards to the inner client within a span.
+func (t *TracingClient) SendPromptWithMetadata(ctx context.Context, prompt string) (*AiResponse, error) {
+	ctx, call := t.begin(ctx, "SendPromptWithMetadata", false)
+	response, err := t.inner.SendPromptWithMetadata(ctx, prompt)
+	call.finishResponse(response, err)
+	return response, err
+}
+
+// SendConversation forwards to the inner client within a span.
+func (t *TracingClient) SendConversation(ctx context.Context, conversation *Conversation) (string, error) {
+	ctx, call := t.begin(ctx, "SendConversation", false)
+	response, err := t.inner.SendConversationWithMetadata(ctx, conversation)
+	call.finishResponse(response, err)
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// SendConversationWithMetadata forwards to the inner client within a span.
+func (t *TracingClient) SendConversationWithMetadata(ctx context.Context, conversation *Conversation) (*AiResponse, error) {
+	ctx, call := t.begin(ctx, "SendConversationWithMetadata", false)
+	response, err := t.inner.SendConversationWithMetadata(ctx, conversation)
+	call.finishResponse(response, err)
+	return response, err
+}
+
+// StreamPrompt forwards to the inner client; the span ends when the stream does.
+func (t *TracingClient) StreamPrompt(ctx context.Context, prompt string) (<-chan StreamChunk, error) {
+	ctx, call := t.begin(ctx, "StreamPrompt", true)
+	ch, err := t.inner.StreamPrompt(ctx, prompt)
+	return t.traceStream(call, ch, err)
+}
+
+// StreamConversation forwards to the inner client; the span ends when the stream does.
+func (t *TracingClient) StreamConversation(ctx context.Context, conversation *Conversation) (<-chan StreamChunk, error) {
+	ctx, call := t.begin(ctx, "StreamConversation", true)
+	ch, err := t.inner.StreamConversation(ctx, conversation)
+	return t.traceStream(call, ch, err)
+}
+
+// traceStream forwards src unchanged and finishes call with the final chunk's
+// metadata and error once the stream ends.
+func (t *TracingClient) traceStream(call tracedCall, src <-chan StreamChunk, err error) (<-chan StreamChunk, error) {
+	if err != nil {
+		call.finish(nil, err)
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 10)
+	go func() {
+		defer close(out)
+		var metadata *ResponseMetadata
+		var streamErr error
+		for chunk := range src {
+			if chunk.Finished {
+				metadata, streamErr = chunk.Metadata, chunk.Err
+			}
+			out <- chunk
+		}
+		call.finish(metadata, streamErr)
+	}()
+	return out, nil
+}
+
+// SupportsStreaming delegates to the inner client.
+func (t *TracingClient) SupportsStreaming() bool { return t.inner.SupportsStreaming() }
+
+// SupportsConversations delegates to the inner client.
+func (t *TracingClient) SupportsConversations() bool { return t.inner.SupportsConversations() }
+
+// Name delegates to the inner client.
+func (t *TracingClient) Name() string { return t.inner.Name() }
+
+// Model delegates to the inner client.
+func (t *TracingClient) Model() string { return t.inner.Model() }
+
+// WithOptions applies opts to the inner client and wraps the result the same way.
+func (t *TracingClient) WithOptions(opts ...RequestOption) (AIClient, error) {
+	inner, err := ClientWithOptions(t.inner, opts...)
+	if err != nil {
+		return nil, err
+	}
+	clone := *t
+	clone.inner = inner
+	return &clone, nil
+}
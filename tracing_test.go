@@ -0,0 +1,131 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedSpan is a finished span captured by memoryTracer.
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]any
+	errs   []error
+	ended  bool
+}
+
+type spanKey struct{}
+
+// memoryTracer records spans and links each to the span found in its start context.
+type memoryTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (tr *memoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(string)
+	span := &recordedSpan{name: name, parent: parent, attrs: map[string]any{}}
+	tr.mu.Lock()
+	tr.spans = append(tr.spans, span)
+	tr.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, name), &memorySpan{tracer: tr, span: span}
+}
+
+type memorySpan struct {
+	tracer *memoryTracer
+	span   *recordedSpan
+}
+
+func (s *memorySpan) SetAttribute(key string, value any) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.attrs[key] = value
+}
+
+func (s *memorySpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.errs = append(s.span.errs, err)
+}
+
+func (s *memorySpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.span.ended = true
+}
+
+func TestTracingClient_RecordsUsage(t *testing.T) {
+	server := metadataServer(t, nil, `{"model":"gpt-4o-2024-08-06","choices":[{"message":{"content":"Hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":2,"total_tokens":11}}`)
+	inner, err := NewOpenAIClient("key", "gpt-4o", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+	tracer := &memoryTracer{}
+	client := NewTracingClient(inner, tracer)
+
+	ctx := context.WithValue(context.Background(), spanKey{}, "incoming")
+	content, err := client.SendPrompt(ctx, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", content)
+
+	require.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "chatdelta.SendPrompt", span.name)
+	assert.Equal(t, "incoming", span.parent)
+	assert.True(t, span.ended)
+	assert.Equal(t, "OpenAI", span.attrs[SpanAttrSystem])
+	assert.Equal(t, "gpt-4o", span.attrs[SpanAttrRequestModel])
+	assert.Equal(t, "gpt-4o-2024-08-06", span.attrs[SpanAttrResponseModel])
+	assert.Equal(t, 9, span.attrs[SpanAttrInputTokens])
+	assert.Equal(t, 2, span.attrs[SpanAttrOutputTokens])
+	assert.Contains(t, span.attrs, SpanAttrLatencyMs)
+	assert.Empty(t, span.errs)
+}
+
+func TestTracingClient_RecordsClientError(t *testing.T) {
+	mock := NewMockClient("mock", "mock-model")
+	mock.QueueError(NewRateLimitError(nil))
+	tracer := &memoryTracer{}
+	client := NewTracingClient(mock, tracer)
+
+	_, err := client.SendConversation(context.Background(), promptConversation("hi"))
+	require.Error(t, err)
+
+	span := tracer.spans[0]
+	assert.Equal(t, "chatdelta.SendConversation", span.name)
+	require.Len(t, span.errs, 1)
+	assert.Equal(t, string(ErrorTypeAPI), span.attrs[SpanAttrErrorType])
+	assert.Equal(t, "rate_limit", span.attrs[SpanAttrErrorCode])
+
+	mock.QueueError(errors.New("plain failure"))
+	_, err = client.SendPrompt(context.Background(), "hi")
+	require.Error(t, err)
+	assert.Equal(t, "_OTHER", tracer.spans[1].attrs[SpanAttrErrorType])
+}
+
+func TestTracingClient_StreamSpanEndsWithStream(t *testing.T) {
+	mock := NewMockClient("mock", "mock-model")
+	mock.QueueResponse("streamed reply")
+	tracer := &memoryTracer{}
+	client := NewTracingClient(mock, tracer)
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, _ := collectStream(t, ch)
+	assert.Equal(t, "streamed reply", content)
+
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	span := tracer.spans[0]
+	assert.Equal(t, "chatdelta.StreamPrompt", span.name)
+	assert.Equal(t, true, span.attrs[SpanAttrStreamed])
+	assert.Equal(t, "mock-model", span.attrs[SpanAttrResponseModel])
+	assert.True(t, span.ended)
+}
+
+func TestTracingClient_ImplementsAIClient(t *testing.T) {
+	var _ AIClient = (*TracingClient)(nil)
+}
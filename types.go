@@ -2,16 +2,57 @@ package chatdelta
 
 import (
 	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
 	"time"
 )
 
 // Message represents a single message in a conversation.
-// Role should be one of "system", "user", or "assistant".
+// Role should be one of "system", "user", "assistant", or "tool" (a tool result).
 type Message struct {
 	// Role of the message sender ("system", "user", or "assistant")
-	Role    string `json:"role"`
+	Role string `json:"role"`
 	// Content of the message
 	Content string `json:"content"`
+	// Parts holds optional multimodal content such as images.
+	// Plain text messages only need Content; when both are set, Content is sent
+	// as a text part before Parts.
+	Parts []ContentPart `json:"parts,omitempty"`
+	// ToolCalls lists the tools an assistant message asked to call
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID links a "tool" message to the call whose result it carries
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// ContentPartType identifies the kind of data carried by a ContentPart.
+type ContentPartType string
+
+const (
+	// ContentPartText is a plain text part
+	ContentPartText ContentPartType = "text"
+	// ContentPartImage is an image part
+	ContentPartImage ContentPartType = "image"
+)
+
+// ContentPart is one piece of a multimodal message.
+type ContentPart struct {
+	// Type selects which of the fields below is populated
+	Type ContentPartType `json:"type"`
+	// Text is set for text parts
+	Text string `json:"text,omitempty"`
+	// Image is set for image parts
+	Image *ImagePart `json:"image,omitempty"`
+}
+
+// ImagePart references an image either by URL or by inline bytes.
+type ImagePart struct {
+	// URL of a remotely hosted image
+	URL string `json:"url,omitempty"`
+	// Data holds raw image bytes; it is base64-encoded on the wire
+	Data []byte `json:"data,omitempty"`
+	// MIMEType of Data, e.g. "image/png"
+	MIMEType string `json:"mime_type,omitempty"`
 }
 
 // Conversation represents a collection of messages forming a dialogue.
@@ -51,45 +92,154 @@ func (c *Conversation) AddAssistantMessage(content string) {
 	c.AddMessage("assistant", content)
 }
 
+// AddImageMessage adds a user message containing the image at url
+func (c *Conversation) AddImageMessage(url string) {
+	c.addImage(&ImagePart{URL: url})
+}
+
+// AddImageBytes adds a user message containing an inline image of the given MIME type
+func (c *Conversation) AddImageBytes(data []byte, mimeType string) {
+	c.addImage(&ImagePart{Data: data, MIMEType: mimeType})
+}
+
+// AddUserImageMessage adds a user message holding caption followed by image
+func (c *Conversation) AddUserImageMessage(caption string, image ImagePart) {
+	c.Messages = append(c.Messages, Message{
+		Role:    "user",
+		Content: caption,
+		Parts:   []ContentPart{{Type: ContentPartImage, Image: &image}},
+	})
+}
+
+// Clone returns a deep copy of the conversation; changes to either do not affect
+// the other.
+func (c *Conversation) Clone() *Conversation {
+	messages := make([]Message, len(c.Messages))
+	for i, msg := range c.Messages {
+		if msg.Parts != nil {
+			msg.Parts = append([]ContentPart(nil), msg.Parts...)
+			for j, part := range msg.Parts {
+				if part.Image != nil {
+					image := *part.Image
+					image.Data = append([]byte(nil), image.Data...)
+					msg.Parts[j].Image = &image
+				}
+			}
+		}
+		if msg.ToolCalls != nil {
+			msg.ToolCalls = append([]ToolCall(nil), msg.ToolCalls...)
+			for j, call := range msg.ToolCalls {
+				msg.ToolCalls[j].Arguments = append(json.RawMessage(nil), call.Arguments...)
+			}
+		}
+		messages[i] = msg
+	}
+	return &Conversation{Messages: messages}
+}
+
+func (c *Conversation) addImage(image *ImagePart) {
+	c.Messages = append(c.Messages, Message{
+		Role:  "user",
+		Parts: []ContentPart{{Type: ContentPartImage, Image: image}},
+	})
+}
+
 // ResponseMetadata contains additional information from the AI provider.
 // Not all fields are populated by all providers.
 type ResponseMetadata struct {
 	// ModelUsed is the actual model version used (may differ from requested)
-	ModelUsed        string      `json:"model_used,omitempty"`
+	ModelUsed string `json:"model_used,omitempty"`
 	// PromptTokens is the number of tokens in the prompt
-	PromptTokens     int         `json:"prompt_tokens,omitempty"`
+	PromptTokens int `json:"prompt_tokens,omitempty"`
 	// CompletionTokens is the number of tokens in the completion
-	CompletionTokens int         `json:"completion_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
 	// TotalTokens is the sum of prompt and completion tokens
-	TotalTokens      int         `json:"total_tokens,omitempty"`
+	TotalTokens int `json:"total_tokens,omitempty"`
 	// FinishReason indicates why generation ended (e.g., "stop", "length", "content_filter")
-	FinishReason     string      `json:"finish_reason,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	// NormalizedFinishReason is FinishReason mapped onto the provider-independent set
+	NormalizedFinishReason FinishReason `json:"normalized_finish_reason,omitempty"`
+	// StopSequence is the configured stop sequence that ended generation. Only Claude
+	// and Bedrock report it; other providers give FinishReason "stop" for both natural
+	// stops and stop sequences.
+	StopSequence string `json:"stop_sequence,omitempty"`
 	// SafetyRatings contains provider-specific safety or content filter results
-	SafetyRatings    interface{} `json:"safety_ratings,omitempty"`
+	SafetyRatings interface{} `json:"safety_ratings,omitempty"`
 	// RequestID for debugging and tracking
-	RequestID        string      `json:"request_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 	// LatencyMs is the time taken to generate response in milliseconds
-	LatencyMs        int64       `json:"latency_ms,omitempty"`
+	LatencyMs int64 `json:"latency_ms,omitempty"`
+	// SystemFingerprint identifies the backend configuration that served the request.
+	// A change between runs with the same seed means outputs may no longer be reproducible.
+	SystemFingerprint string `json:"system_fingerprint,omitempty"`
+	// Notices lists post-processing steps applied to the response content,
+	// e.g. "prompt_echo_stripped"
+	Notices []string `json:"notices,omitempty"`
+	// ServedVia records how the response was produced: ServedViaPrimary for a direct
+	// provider call, or a path of wrapper segments such as "fallback:gemini>cache"
+	ServedVia string `json:"served_via,omitempty"`
+	// Logprobs holds the log probability of each content token, in order, when
+	// ClientConfig.Logprobs is set and the provider supports it
+	Logprobs []TokenLogprob `json:"logprobs,omitempty"`
+}
+
+// TokenLogprob is the log probability of one generated token.
+type TokenLogprob struct {
+	// Token is the token text
+	Token string `json:"token"`
+	// Logprob is the natural log of the token's probability
+	Logprob float64 `json:"logprob"`
+	// Bytes is the UTF-8 encoding of Token, for tokens that split a character
+	Bytes []int `json:"bytes,omitempty"`
+	// TopLogprobs lists the most likely tokens at this position, most likely first,
+	// when ClientConfig.TopLogprobs is set
+	TopLogprobs []TopLogprob `json:"top_logprobs,omitempty"`
+}
+
+// TopLogprob is one of the most likely tokens at a position.
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
 }
 
 // AiResponse combines the text content with response metadata.
 // Use this when you need detailed information about token usage and performance.
 type AiResponse struct {
 	// Content is the actual text response from the AI
-	Content  string           `json:"content"`
+	Content string `json:"content"`
 	// Metadata contains additional information about the response
 	Metadata ResponseMetadata `json:"metadata"`
+	// ToolCalls lists the tools the model asked to call, if any
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// Raw is the provider's response body exactly as received, for fields this
+	// library does not model. Only set with ClientConfig.IncludeRawResponse.
+	Raw json.RawMessage `json:"raw,omitempty"`
+}
+
+// ToMessage returns the response as an assistant message, including any tool calls,
+// ready to append to the conversation that produced it.
+func (r *AiResponse) ToMessage() Message {
+	return Message{Role: "assistant", Content: r.Content, ToolCalls: r.ToolCalls}
 }
 
 // StreamChunk represents a chunk of streaming response.
 // When Finished is true, this is the final chunk and Metadata may be populated.
 type StreamChunk struct {
 	// Content of this chunk
-	Content  string            `json:"content"`
+	Content string `json:"content"`
 	// Finished indicates if this is the final chunk
-	Finished bool              `json:"finished"`
+	Finished bool `json:"finished"`
 	// Metadata is only populated on the final chunk
 	Metadata *ResponseMetadata `json:"metadata,omitempty"`
+	// Truncated is set on the final chunk when the stream timed out part-way and
+	// ClientConfig.PartialOnTimeout kept the content received so far
+	Truncated bool `json:"truncated,omitempty"`
+	// Err is set on the final chunk when the stream failed
+	Err error `json:"-"`
+	// Raw is set on the final chunk to the last provider event received, exactly as
+	// sent, when ClientConfig.IncludeRawResponse is enabled
+	Raw json.RawMessage `json:"raw,omitempty"`
 }
 
 // RetryStrategy defines the retry behavior for failed requests.
@@ -97,39 +247,187 @@ type RetryStrategy string
 
 const (
 	// RetryStrategyFixed uses a fixed delay between retries
-	RetryStrategyFixed               RetryStrategy = "fixed"
+	RetryStrategyFixed RetryStrategy = "fixed"
 	// RetryStrategyLinear increases delay linearly with each attempt
-	RetryStrategyLinear              RetryStrategy = "linear"
+	RetryStrategyLinear RetryStrategy = "linear"
 	// RetryStrategyExponentialBackoff doubles the delay with each attempt
-	RetryStrategyExponentialBackoff  RetryStrategy = "exponential"
+	RetryStrategyExponentialBackoff RetryStrategy = "exponential"
 	// RetryStrategyExponentialWithJitter adds random jitter to prevent thundering herd
 	RetryStrategyExponentialWithJitter RetryStrategy = "exponential_with_jitter"
 )
 
+// Provider identifies the backend API a client talks to.
+type Provider string
+
+const (
+	// ProviderOpenAI is OpenAI's chat completions API
+	ProviderOpenAI Provider = "openai"
+	// ProviderClaude is Anthropic's messages API
+	ProviderClaude Provider = "claude"
+	// ProviderGemini is Google's generative language API
+	ProviderGemini Provider = "gemini"
+	// ProviderOllama is a local Ollama server's chat API
+	ProviderOllama Provider = "ollama"
+	// ProviderXAI is xAI's OpenAI-compatible chat completions API for Grok
+	ProviderXAI Provider = "xai"
+	// ProviderBedrock is AWS Bedrock's invoke API, carrying Claude Messages payloads
+	ProviderBedrock Provider = "bedrock"
+	// ProviderVertex is Google Cloud Vertex AI's generateContent API for Gemini
+	ProviderVertex Provider = "vertex"
+)
+
+// RequestMutator edits a prepared request body just before it is marshaled and sent.
+// Mutators run in the order they were added and can only see the JSON body, never
+// the URL or auth headers. Returning an error aborts the request with a config error.
+//
+// Mutators are intended for hot-fixing provider quirks without forking the library,
+// e.g. adding a field a gateway requires or dropping a parameter a model rejects:
+//
+//	config.AddRequestMutator(func(p chatdelta.Provider, model string, body map[string]any) error {
+//		if p == chatdelta.ProviderOpenAI && strings.HasPrefix(model, "o1") {
+//			delete(body, "top_p")
+//		}
+//		return nil
+//	})
+type RequestMutator func(provider Provider, model string, body map[string]any) error
+
 // ClientConfig holds configuration options for AI clients.
 // Use NewClientConfig to create a config with sensible defaults,
 // then use the Set* methods to customize.
 type ClientConfig struct {
+	// Name, when set, is reported by the client's Name() in place of the provider's
+	// name, to tell apart clients of the same provider, e.g. "openai-primary"
+	Name string
 	// Timeout for HTTP requests
-	Timeout          time.Duration
+	Timeout time.Duration
+	// OperationTimeout bounds a whole call, every retry and backoff wait included,
+	// through a context deadline; for streams it bounds the whole stream. Zero, the
+	// default, leaves the call bounded only by Timeout per attempt and the caller's
+	// context.
+	OperationTimeout time.Duration
 	// Retries is the number of retry attempts for failed requests
-	Retries          int
+	Retries int
+	// BaseRetryDelay is the wait before the first retry; RetryStrategy decides how
+	// later waits grow from it. Zero means one second.
+	BaseRetryDelay time.Duration
+	// MaxRetryDelay caps the wait between retries. Zero leaves fixed and linear waits
+	// uncapped and caps exponential ones at 30 seconds. A longer Retry-After from the
+	// provider is still honoured.
+	MaxRetryDelay time.Duration
 	// Temperature controls randomness (0.0-2.0), higher = more random
-	Temperature      *float64
+	Temperature *float64
 	// MaxTokens limits the response length
-	MaxTokens        *int
+	MaxTokens *int
 	// TopP is nucleus sampling parameter (0.0-1.0)
-	TopP             *float64
+	TopP *float64
+	// TopK limits sampling to the K most likely tokens. It is sent to Claude, Gemini,
+	// and Ollama; OpenAI-style APIs have no equivalent and ignore it.
+	TopK *int
+	// StopSequences ends generation when the model produces any of these strings.
+	// OpenAI and xAI accept at most 4 and Gemini at most 5; see ValidateStopSequences.
+	StopSequences []string
 	// FrequencyPenalty reduces repetition of token sequences (-2.0 to 2.0)
 	FrequencyPenalty *float64
 	// PresencePenalty reduces repetition of any tokens that have appeared (-2.0 to 2.0)
-	PresencePenalty  *float64
+	PresencePenalty *float64
+	// LogitBias adjusts the likelihood of specific token IDs, from -100 (ban) to 100
+	// (force). Token IDs are model-specific. Only OpenAI-style APIs support it; other
+	// providers ignore it.
+	LogitBias map[int]int
+	// Logprobs asks for the log probability of each generated token, returned in
+	// ResponseMetadata.Logprobs. Only OpenAI-style APIs support it; see
+	// SupportsLogprobs.
+	Logprobs bool
+	// TopLogprobs additionally asks for the 0-20 most likely alternatives at each
+	// position. It requires Logprobs.
+	TopLogprobs *int
 	// SystemMessage sets context for the AI assistant
-	SystemMessage    *string
+	SystemMessage *string
 	// BaseURL allows custom endpoints (e.g., Azure OpenAI, local models)
-	BaseURL          *string
-	// RetryStrategy determines how delays are calculated between retries
-	RetryStrategy    RetryStrategy
+	BaseURL *string
+	// RetryStrategy determines how delays are calculated between retries, starting
+	// from BaseRetryDelay and capped at MaxRetryDelay; see BackoffDelay. Empty, like
+	// the NewClientConfig default, means RetryStrategyLinear
+	RetryStrategy RetryStrategy
+	// RetryRand, when set, supplies the randomness for RetryStrategyExponentialWithJitter
+	// instead of the shared math/rand source; a seeded source makes the waits
+	// repeatable. Calls sharing it take turns drawing from it. Intended for tests.
+	RetryRand *rand.Rand
+	// N is the number of completions SendConversationN asks for; nil means 1. Other
+	// calls always return a single completion.
+	N *int
+	// Seed requests deterministic sampling from providers that support it.
+	// Providers without seeding support silently ignore it; see SupportsSeed.
+	Seed *int
+	// RequestMutators are applied in order to every outgoing request body
+	RequestMutators []RequestMutator
+	// ExtraHeaders are added to every outgoing request, e.g. for API gateways.
+	// Content-Type and the provider's auth header cannot be overridden.
+	ExtraHeaders map[string]string
+	// PartialOnTimeout ends a stream that times out after producing content with a
+	// Truncated final chunk instead of an error. Defaults to false.
+	PartialOnTimeout bool
+	// EchoGuard, when set, strips a repeated copy of the prompt from the start of
+	// non-streaming responses
+	EchoGuard *EchoGuard
+	// Clock supplies time for retry waits and Retry-After handling; nil uses the
+	// system clock. Intended for tests.
+	Clock Clock
+	// AllowModelOverride lets SendRaw bodies name a model other than the client's
+	AllowModelOverride bool
+	// IncludeRawResponse keeps the provider's response body on AiResponse.Raw, and
+	// the last stream event on the final StreamChunk. Defaults to false.
+	IncludeRawResponse bool
+	// SystemOrder controls how system messages after other turns are handled;
+	// defaults to SystemOrderAllow
+	SystemOrder SystemOrder
+	// Azure identifies the deployment used by the "azure" provider
+	Azure *AzureConfig
+	// Bedrock selects the AWS region and credentials used by the "bedrock" provider
+	Bedrock *BedrockConfig
+	// Vertex selects the Google Cloud project, location, and credentials used by the
+	// "vertex" provider
+	Vertex *VertexConfig
+	// Tools are the functions the model may call; see Tool
+	Tools []Tool
+	// ToolChoice controls whether and which tools are called; only sent with Tools
+	ToolChoice ToolChoice
+	// ResponseFormat, when set, asks the model for JSON output; see ResponseFormat
+	ResponseFormat *ResponseFormat
+	// KeepAliveViaStreaming serves non-streaming calls through the provider's
+	// streaming API so idle-connection timeouts in proxies are never hit. Ignored by
+	// providers without streaming and when Tools are set.
+	KeepAliveViaStreaming bool
+	// Logger, when set, receives a line for every retried attempt and completed call,
+	// tagged with the call's trace ID; see WithTrace
+	Logger *log.Logger
+	// Metrics, when set, receives a RequestRecord for every completed call
+	Metrics MetricsCollector
+	// OnRetry, when set, is called before the wait preceding each retry
+	OnRetry func(event RetryEvent)
+	// OnRequest, when set, is called before every HTTP request to the provider;
+	// it must be set before the client is created
+	OnRequest func(event HTTPRequestEvent)
+	// OnResponse, when set, is called after every HTTP response from the provider;
+	// it must be set before the client is created
+	OnResponse func(event HTTPResponseEvent)
+	// HTTPBodyRedactor, when set, rewrites the request and response bodies passed
+	// to OnRequest and OnResponse; the bodies actually sent are unaffected
+	HTTPBodyRedactor func(body []byte) []byte
+	// ContentFilter, when set, screens every response before it is returned or
+	// streamed; see ContentFilter
+	ContentFilter ContentFilter
+	// ContentFilterWindow is how many bytes of a stream are held back so phrases
+	// spanning chunks are screened whole; 0 means DefaultContentFilterWindow
+	ContentFilterWindow int
+	// PromptFilters rewrite or reject the text of user and tool messages before
+	// every request; see TextFilter
+	PromptFilters []TextFilter
+	// ResponseFilters rewrite or reject response text before the ContentFilter runs
+	ResponseFilters []TextFilter
+	// Cache, when set, makes CreateClient wrap the client in a CachingClient so
+	// identical requests are answered without a network call; see NewMemoryCache
+	Cache Cache
 }
 
 // NewClientConfig creates a new ClientConfig with default values
@@ -137,22 +435,54 @@ func NewClientConfig() *ClientConfig {
 	return &ClientConfig{
 		Timeout:       30 * time.Second,
 		Retries:       3,
-		RetryStrategy: RetryStrategyExponentialBackoff,
+		RetryStrategy: RetryStrategyLinear,
 	}
 }
 
+// SetName sets the name the client reports, e.g. in ParallelResult.ClientName
+func (c *ClientConfig) SetName(name string) *ClientConfig {
+	c.Name = name
+	return c
+}
+
+// nameOr returns the configured Name, or providerName if none is set.
+func (c *ClientConfig) nameOr(providerName string) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return providerName
+}
+
 // SetTimeout sets the timeout duration
 func (c *ClientConfig) SetTimeout(timeout time.Duration) *ClientConfig {
 	c.Timeout = timeout
 	return c
 }
 
+// SetOperationTimeout sets the deadline for a whole call, retries included
+func (c *ClientConfig) SetOperationTimeout(timeout time.Duration) *ClientConfig {
+	c.OperationTimeout = timeout
+	return c
+}
+
 // SetRetries sets the number of retries
 func (c *ClientConfig) SetRetries(retries int) *ClientConfig {
 	c.Retries = retries
 	return c
 }
 
+// SetBaseRetryDelay sets the wait before the first retry
+func (c *ClientConfig) SetBaseRetryDelay(delay time.Duration) *ClientConfig {
+	c.BaseRetryDelay = delay
+	return c
+}
+
+// SetMaxRetryDelay sets the cap on the wait between retries
+func (c *ClientConfig) SetMaxRetryDelay(delay time.Duration) *ClientConfig {
+	c.MaxRetryDelay = delay
+	return c
+}
+
 // SetTemperature sets the temperature parameter
 func (c *ClientConfig) SetTemperature(temperature float64) *ClientConfig {
 	c.Temperature = &temperature
@@ -171,6 +501,18 @@ func (c *ClientConfig) SetTopP(topP float64) *ClientConfig {
 	return c
 }
 
+// SetTopK sets the top-k parameter
+func (c *ClientConfig) SetTopK(topK int) *ClientConfig {
+	c.TopK = &topK
+	return c
+}
+
+// SetStopSequences sets the strings that end generation
+func (c *ClientConfig) SetStopSequences(sequences ...string) *ClientConfig {
+	c.StopSequences = sequences
+	return c
+}
+
 // SetFrequencyPenalty sets the frequency penalty parameter
 func (c *ClientConfig) SetFrequencyPenalty(penalty float64) *ClientConfig {
 	c.FrequencyPenalty = &penalty
@@ -183,6 +525,26 @@ func (c *ClientConfig) SetPresencePenalty(penalty float64) *ClientConfig {
 	return c
 }
 
+// SetLogitBias sets the per-token bias map sent as logit_bias
+func (c *ClientConfig) SetLogitBias(bias map[int]int) *ClientConfig {
+	c.LogitBias = bias
+	return c
+}
+
+// SetLogprobs controls whether token log probabilities are requested
+func (c *ClientConfig) SetLogprobs(enabled bool) *ClientConfig {
+	c.Logprobs = enabled
+	return c
+}
+
+// SetTopLogprobs requests token log probabilities with the n most likely
+// alternatives at each position
+func (c *ClientConfig) SetTopLogprobs(n int) *ClientConfig {
+	c.Logprobs = true
+	c.TopLogprobs = &n
+	return c
+}
+
 // SetSystemMessage sets the system message
 func (c *ClientConfig) SetSystemMessage(message string) *ClientConfig {
 	c.SystemMessage = &message
@@ -195,12 +557,207 @@ func (c *ClientConfig) SetBaseURL(url string) *ClientConfig {
 	return c
 }
 
+// SetCache sets the cache CreateClient answers repeated requests from
+func (c *ClientConfig) SetCache(cache Cache) *ClientConfig {
+	c.Cache = cache
+	return c
+}
+
+// SetHeader adds an extra header sent with every request. Content-Type and the
+// provider's auth header are reserved and ignored if set here.
+func (c *ClientConfig) SetHeader(key, value string) *ClientConfig {
+	if c.ExtraHeaders == nil {
+		c.ExtraHeaders = make(map[string]string)
+	}
+	c.ExtraHeaders[key] = value
+	return c
+}
+
+// SetPartialOnTimeout controls whether content streamed before a timeout is kept
+// (marked Truncated) rather than reported as a failed stream.
+func (c *ClientConfig) SetPartialOnTimeout(enabled bool) *ClientConfig {
+	c.PartialOnTimeout = enabled
+	return c
+}
+
+// SetEchoGuard enables stripping of prompt echoes from non-streaming responses.
+// A non-positive threshold or minLength uses DefaultEchoThreshold/DefaultEchoMinLength.
+func (c *ClientConfig) SetEchoGuard(threshold float64, minLength int) *ClientConfig {
+	if threshold <= 0 {
+		threshold = DefaultEchoThreshold
+	}
+	if minLength <= 0 {
+		minLength = DefaultEchoMinLength
+	}
+	c.EchoGuard = &EchoGuard{Threshold: threshold, MinLength: minLength}
+	return c
+}
+
+// SetClock sets the clock used for retry waits
+func (c *ClientConfig) SetClock(clock Clock) *ClientConfig {
+	c.Clock = clock
+	return c
+}
+
+// SetAzure sets the Azure OpenAI resource, deployment, and API version used by the
+// "azure" provider. An empty deployment falls back to the model passed to
+// CreateClient; an empty apiVersion uses DefaultAzureAPIVersion.
+func (c *ClientConfig) SetAzure(resource, deployment, apiVersion string) *ClientConfig {
+	c.Azure = &AzureConfig{Resource: resource, Deployment: deployment, APIVersion: apiVersion}
+	return c
+}
+
+// SetBedrock sets the AWS region and credentials used by the "bedrock" provider. An
+// empty region or nil credentials fall back to the environment; see BedrockConfig.
+func (c *ClientConfig) SetBedrock(region string, credentials *AWSCredentials) *ClientConfig {
+	c.Bedrock = &BedrockConfig{Region: region, Credentials: credentials}
+	return c
+}
+
+// SetVertex sets the Google Cloud project, location, and token source used by the
+// "vertex" provider. Empty values and a nil token source fall back to the
+// environment; see VertexConfig.
+func (c *ClientConfig) SetVertex(project, location string, tokens TokenSource) *ClientConfig {
+	c.Vertex = &VertexConfig{Project: project, Location: location, TokenSource: tokens}
+	return c
+}
+
+// SetAllowModelOverride controls whether SendRaw accepts bodies whose "model"
+// differs from the client's model
+func (c *ClientConfig) SetAllowModelOverride(allow bool) *ClientConfig {
+	c.AllowModelOverride = allow
+	return c
+}
+
+// SetIncludeRawResponse controls whether responses carry the untouched provider body
+// in AiResponse.Raw
+func (c *ClientConfig) SetIncludeRawResponse(include bool) *ClientConfig {
+	c.IncludeRawResponse = include
+	return c
+}
+
+// SetSystemOrder sets how system messages that follow other turns are handled
+func (c *ClientConfig) SetSystemOrder(order SystemOrder) *ClientConfig {
+	c.SystemOrder = order
+	return c
+}
+
+// SetLogger sets the logger that receives per-call and per-retry lines
+func (c *ClientConfig) SetLogger(logger *log.Logger) *ClientConfig {
+	c.Logger = logger
+	return c
+}
+
+// SetMetrics sets the collector that receives a RequestRecord for every call
+func (c *ClientConfig) SetMetrics(metrics MetricsCollector) *ClientConfig {
+	c.Metrics = metrics
+	return c
+}
+
+// SetOnRetry sets the callback invoked before each retry
+func (c *ClientConfig) SetOnRetry(onRetry func(event RetryEvent)) *ClientConfig {
+	c.OnRetry = onRetry
+	return c
+}
+
+// SetOnRequest sets the callback invoked before each HTTP request
+func (c *ClientConfig) SetOnRequest(onRequest func(event HTTPRequestEvent)) *ClientConfig {
+	c.OnRequest = onRequest
+	return c
+}
+
+// SetOnResponse sets the callback invoked after each HTTP response
+func (c *ClientConfig) SetOnResponse(onResponse func(event HTTPResponseEvent)) *ClientConfig {
+	c.OnResponse = onResponse
+	return c
+}
+
+// SetHTTPBodyRedactor sets the function that redacts bodies reported to the HTTP hooks
+func (c *ClientConfig) SetHTTPBodyRedactor(redact func(body []byte) []byte) *ClientConfig {
+	c.HTTPBodyRedactor = redact
+	return c
+}
+
+// SetContentFilter sets the filter applied to every response and the number of bytes
+// held back from streams for it (0 uses DefaultContentFilterWindow)
+func (c *ClientConfig) SetContentFilter(filter ContentFilter, window int) *ClientConfig {
+	c.ContentFilter = filter
+	c.ContentFilterWindow = window
+	return c
+}
+
+// SetKeepAliveViaStreaming enables or disables serving non-streaming calls by streaming
+func (c *ClientConfig) SetKeepAliveViaStreaming(enabled bool) *ClientConfig {
+	c.KeepAliveViaStreaming = enabled
+	return c
+}
+
+// SetTools sets the tools the model may call
+func (c *ClientConfig) SetTools(tools ...Tool) *ClientConfig {
+	c.Tools = tools
+	return c
+}
+
+// SetToolChoice sets whether and which tools the model calls
+func (c *ClientConfig) SetToolChoice(choice ToolChoice) *ClientConfig {
+	c.ToolChoice = choice
+	return c
+}
+
+// SetResponseFormatJSON asks the model to respond with a JSON object
+func (c *ClientConfig) SetResponseFormatJSON() *ClientConfig {
+	c.ResponseFormat = &ResponseFormat{Type: ResponseFormatJSONObject}
+	return c
+}
+
+// SetResponseJSONSchema asks the model to respond with JSON conforming to schema
+func (c *ClientConfig) SetResponseJSONSchema(name string, schema json.RawMessage) *ClientConfig {
+	c.ResponseFormat = &ResponseFormat{Type: ResponseFormatJSONSchema, Name: name, Schema: schema}
+	return c
+}
+
+// SetN sets the number of completions returned by SendPromptN and SendConversationN
+func (c *ClientConfig) SetN(n int) *ClientConfig {
+	c.N = &n
+	return c
+}
+
+// SetSeed sets the sampling seed for reproducible outputs
+func (c *ClientConfig) SetSeed(seed int) *ClientConfig {
+	c.Seed = &seed
+	return c
+}
+
+// AddPromptFilter appends a filter applied to outgoing user and tool message text
+func (c *ClientConfig) AddPromptFilter(filter TextFilter) *ClientConfig {
+	c.PromptFilters = append(c.PromptFilters, filter)
+	return c
+}
+
+// AddResponseFilter appends a filter applied to response text
+func (c *ClientConfig) AddResponseFilter(filter TextFilter) *ClientConfig {
+	c.ResponseFilters = append(c.ResponseFilters, filter)
+	return c
+}
+
+// AddRequestMutator appends a mutator to the request mutation chain
+func (c *ClientConfig) AddRequestMutator(mutator RequestMutator) *ClientConfig {
+	c.RequestMutators = append(c.RequestMutators, mutator)
+	return c
+}
+
 // SetRetryStrategy sets the retry strategy
 func (c *ClientConfig) SetRetryStrategy(strategy RetryStrategy) *ClientConfig {
 	c.RetryStrategy = strategy
 	return c
 }
 
+// SetRetryRand sets the random source for jittered retry waits
+func (c *ClientConfig) SetRetryRand(rng *rand.Rand) *ClientConfig {
+	c.RetryRand = rng
+	return c
+}
+
 // AIClient defines the interface for all AI clients
 type AIClient interface {
 	// SendPrompt sends a single prompt and returns the response
@@ -239,8 +796,31 @@ type AIClient interface {
 type ParallelResult struct {
 	// ClientName identifies which client produced this result
 	ClientName string
+	// Model is the client's configured model
+	Model string
 	// Result contains the successful response text
-	Result     string
+	Result string
+	// Metadata holds the token usage and other details of a successful response
+	Metadata *ResponseMetadata
+	// Latency is how long the client took, including retries. For a client cut off
+	// by the context ending, it is how long it ran before that.
+	Latency time.Duration
+	// Error contains any error that occurred
+	Error error
+}
+
+// ParallelMetadataResult is the result of ExecuteParallelWithMetadata for one client.
+// Either Response or Error will be populated, not both.
+type ParallelMetadataResult struct {
+	// ClientName identifies which client produced this result
+	ClientName string
+	// Model is the client's configured model
+	Model string
+	// Response holds the content and metadata of a successful response
+	Response *AiResponse
+	// LatencyMs is the wall-clock time the client took to answer or fail,
+	// including any retries
+	LatencyMs int64
 	// Error contains any error that occurred
-	Error      error
+	Error error
 }
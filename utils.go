@@ -2,13 +2,76 @@ package chatdelta
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 // ExecuteWithRetry executes a function with retry logic and exponential backoff
 func ExecuteWithRetry(ctx context.Context, retries int, operation func() error) error {
+	return executeWithRetry(ctx, retries, systemClock{}, operation)
+}
+
+// executeWithRetry is ExecuteWithRetry with the waits taken from clock.
+func executeWithRetry(ctx context.Context, retries int, clock Clock, operation func() error) error {
+	return executeWithRetryHook(ctx, retries, clock, retryBackoff{strategy: RetryStrategyLinear}, operation, nil)
+}
+
+// Defaults for the delays between retries when ClientConfig leaves them unset.
+const (
+	defaultBaseRetryDelay = time.Second
+	defaultMaxRetryDelay  = 30 * time.Second
+)
+
+// retryBackoff computes the delays between retries with BackoffDelay. An empty
+// strategy means RetryStrategyLinear and a zero base defaultBaseRetryDelay. A zero max
+// leaves fixed and linear waits uncapped and caps exponential ones at
+// defaultMaxRetryDelay. Jitter is drawn from rng when set, otherwise from the shared
+// math/rand source.
+type retryBackoff struct {
+	strategy RetryStrategy
+	base     time.Duration
+	max      time.Duration
+	rng      *rand.Rand
+}
+
+// retryRandMu serializes draws from a ClientConfig.RetryRand, which may be shared by
+// concurrent calls although a *rand.Rand is not safe for concurrent use.
+var retryRandMu sync.Mutex
+
+// delay returns the wait after the given zero-based failed attempt.
+func (b retryBackoff) delay(attempt int) time.Duration {
+	base := b.base
+	if base <= 0 {
+		base = defaultBaseRetryDelay
+	}
+	strategy := b.strategy
+	if strategy == "" {
+		strategy = RetryStrategyLinear
+	}
+	maxDelay := b.max
+	if maxDelay <= 0 && (strategy == RetryStrategyFixed || strategy == RetryStrategyLinear) {
+		maxDelay = math.MaxInt64
+	}
+	if b.rng != nil {
+		retryRandMu.Lock()
+		defer retryRandMu.Unlock()
+	}
+	return BackoffDelay(strategy, attempt, base, maxDelay, b.rng)
+}
+
+// executeWithRetryHook is executeWithRetry with the delays computed by backoff that
+// calls onRetry, when non-nil, with each retryable error and the delay before the
+// next attempt.
+func executeWithRetryHook(ctx context.Context, retries int, clock Clock, backoff retryBackoff, operation func() error, onRetry func(err error, delay time.Duration)) error {
 	var lastErr error
 
 	for attempt := 0; attempt <= retries; attempt++ {
@@ -30,14 +93,17 @@ func ExecuteWithRetry(ctx context.Context, retries int, operation func() error)
 			break
 		}
 
-		// Calculate backoff delay: 1s, 2s, 3s, etc.
-		delay := time.Duration(attempt+1) * time.Second
+		// Calculate backoff delay
+		delay := honorRetryAfter(err, backoff.delay(attempt))
+		if onRetry != nil {
+			onRetry(err, delay)
+		}
 
 		// Check if context is cancelled
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(delay):
+		case <-clock.After(delay):
 			// Continue to next attempt
 		}
 	}
@@ -45,8 +111,64 @@ func ExecuteWithRetry(ctx context.Context, retries int, operation func() error)
 	return lastErr
 }
 
-// ExecuteWithExponentialBackoff executes a function with exponential backoff
+// ExecuteWithExponentialBackoff executes a function with exponential backoff, waiting
+// baseDelay, 2*baseDelay, 4*baseDelay and so on, up to 30 seconds. Clients apply
+// their own ClientConfig.BaseRetryDelay and MaxRetryDelay instead.
 func ExecuteWithExponentialBackoff(ctx context.Context, retries int, baseDelay time.Duration, operation func() error) error {
+	return executeWithBackoff(ctx, retries, RetryStrategyExponentialBackoff, baseDelay, nil, operation)
+}
+
+// ExecuteWithJitteredBackoff is ExecuteWithExponentialBackoff with full jitter: each
+// wait is a random duration below the exponential delay, so clients retrying after a
+// shared outage spread out instead of hitting the API in lockstep. rng supplies the
+// randomness; nil uses the shared math/rand source, and a seeded source makes the
+// waits repeatable in tests. A *rand.Rand is not safe for concurrent use, so do not
+// share one between concurrent calls.
+func ExecuteWithJitteredBackoff(ctx context.Context, retries int, baseDelay time.Duration, rng *rand.Rand, operation func() error) error {
+	return executeWithBackoff(ctx, retries, RetryStrategyExponentialWithJitter, baseDelay, rng, operation)
+}
+
+// BackoffDelay returns the wait after the given zero-based failed attempt under
+// strategy, capped at maxDelay, or at 30 seconds if maxDelay is zero:
+//   - RetryStrategyFixed waits baseDelay every time
+//   - RetryStrategyLinear waits (attempt+1) * baseDelay
+//   - RetryStrategyExponentialBackoff waits 2^attempt * baseDelay
+//   - RetryStrategyExponentialWithJitter waits a random duration in
+//     [0, 2^attempt * baseDelay), drawn from rng or, if nil, the shared math/rand source
+//
+// Any other strategy is treated as RetryStrategyExponentialBackoff.
+func BackoffDelay(strategy RetryStrategy, attempt int, baseDelay, maxDelay time.Duration, rng *rand.Rand) time.Duration {
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxRetryDelay
+	}
+	var delay time.Duration
+	switch strategy {
+	case RetryStrategyFixed:
+		delay = baseDelay
+	case RetryStrategyLinear:
+		delay = time.Duration(attempt+1) * baseDelay
+	default:
+		delay = maxDelay
+		if factor := math.Pow(2, float64(attempt)); factor*float64(baseDelay) < float64(maxDelay) {
+			delay = time.Duration(factor * float64(baseDelay))
+		}
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if strategy == RetryStrategyExponentialWithJitter && delay > 0 {
+		if rng != nil {
+			delay = time.Duration(rng.Int63n(int64(delay)))
+		} else {
+			delay = time.Duration(rand.Int63n(int64(delay)))
+		}
+	}
+	return delay
+}
+
+// executeWithBackoff retries operation while it fails with a retryable error, waiting
+// BackoffDelay between attempts, or longer if the error carries a Retry-After hint.
+func executeWithBackoff(ctx context.Context, retries int, strategy RetryStrategy, baseDelay time.Duration, rng *rand.Rand, operation func() error) error {
 	var lastErr error
 
 	for attempt := 0; attempt <= retries; attempt++ {
@@ -68,13 +190,7 @@ func ExecuteWithExponentialBackoff(ctx context.Context, retries int, baseDelay t
 			break
 		}
 
-		// Calculate exponential backoff delay
-		delay := time.Duration(math.Pow(2, float64(attempt))) * baseDelay
-
-		// Cap the maximum delay at 30 seconds
-		if delay > 30*time.Second {
-			delay = 30 * time.Second
-		}
+		delay := honorRetryAfter(err, BackoffDelay(strategy, attempt, baseDelay, 0, rng))
 
 		// Check if context is cancelled
 		select {
@@ -88,67 +204,274 @@ func ExecuteWithExponentialBackoff(ctx context.Context, retries int, baseDelay t
 	return lastErr
 }
 
-// ExecuteParallel executes multiple AI clients in parallel with the same prompt
+// withOperationTimeout derives the context a whole call runs under: ctx with the
+// config's OperationTimeout as a deadline, or ctx itself when none is set.
+func (c *ClientConfig) withOperationTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.OperationTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.OperationTimeout)
+}
+
+// honorRetryAfter returns the larger of delay and the Retry-After hint carried by a
+// rate-limit error, so retries never come back sooner than the server asked.
+func honorRetryAfter(err error, delay time.Duration) time.Duration {
+	var ce *ClientError
+	if errors.As(err, &ce) && ce.Code == "rate_limit" && ce.RetryAfter > delay {
+		return ce.RetryAfter
+	}
+	return delay
+}
+
+// ExecuteParallel executes multiple AI clients in parallel with the same prompt.
+// Results are in the order of clients. If ctx ends first, clients still running are
+// reported with ctx's error.
 func ExecuteParallel(ctx context.Context, clients []AIClient, prompt string) []ParallelResult {
-	results := make([]ParallelResult, len(clients))
-	var wg sync.WaitGroup
+	return collectParallel(clients, runParallel(ctx, clients, func(ctx context.Context, c AIClient) (*AiResponse, error) {
+		return c.SendPromptWithMetadata(ctx, prompt)
+	}))
+}
 
-	for i, client := range clients {
-		wg.Add(1)
-		go func(index int, c AIClient) {
-			defer wg.Done()
+// ExecuteParallelWithMetadata executes multiple AI clients in parallel with the same
+// prompt, like ExecuteParallel, but returns each client's full response and how long
+// it took, so token usage and speed can be compared across providers. Results are in
+// the order of clients. If ctx ends first, clients still running are reported with
+// ctx's error.
+func ExecuteParallelWithMetadata(ctx context.Context, clients []AIClient, prompt string) []ParallelMetadataResult {
+	results := make([]ParallelMetadataResult, len(clients))
+	for r := range runParallel(ctx, clients, func(ctx context.Context, c AIClient) (*AiResponse, error) {
+		return c.SendPromptWithMetadata(ctx, prompt)
+	}) {
+		result := ParallelMetadataResult{
+			ClientName: r.result.ClientName,
+			Model:      r.result.Model,
+			LatencyMs:  r.result.Latency.Milliseconds(),
+			Error:      r.result.Error,
+		}
+		if r.result.Error == nil {
+			result.Response = r.response
+		}
+		results[r.index] = result
+	}
+	return results
+}
 
-			result, err := c.SendPrompt(ctx, prompt)
-			results[index] = ParallelResult{
-				ClientName: c.Name(),
-				Result:     result,
-				Error:      err,
-			}
-		}(i, client)
+// newParallelResult describes the outcome of sending to c, which took latency.
+// Metadata is left nil when the client returned no response.
+func newParallelResult(c AIClient, response *AiResponse, latency time.Duration, err error) ParallelResult {
+	result := ParallelResult{ClientName: c.Name(), Model: c.Model(), Latency: latency, Error: err}
+	if err == nil && response != nil {
+		result.Result = response.Content
+		result.Metadata = &response.Metadata
 	}
+	return result
+}
 
-	wg.Wait()
-	return results
+// ConversationFallback selects how ExecuteParallelConversationWithOptions sends a
+// conversation to a client whose SupportsConversations reports false.
+type ConversationFallback int
+
+const (
+	// ConversationFallbackLastMessage sends only the last message, which must be from
+	// the user, as a prompt. Earlier context is lost.
+	ConversationFallbackLastMessage ConversationFallback = iota
+	// ConversationFallbackFlatten sends the whole conversation as a single prompt
+	// (see FlattenConversation)
+	ConversationFallbackFlatten
+	// ConversationFallbackError fails the client with a config error
+	ConversationFallbackError
+)
+
+// ParallelConversationOptions configures ExecuteParallelConversationWithOptions.
+type ParallelConversationOptions struct {
+	// Fallback is how conversations are sent to clients that do not support them;
+	// the zero value sends the last message
+	Fallback ConversationFallback
 }
 
-// ExecuteParallelConversation executes multiple AI clients in parallel with the same conversation
+// ExecuteParallelConversation executes multiple AI clients in parallel with the same conversation.
+// Clients that do not support conversations are sent the last user message as a prompt.
 func ExecuteParallelConversation(ctx context.Context, clients []AIClient, conversation *Conversation) []ParallelResult {
-	results := make([]ParallelResult, len(clients))
-	var wg sync.WaitGroup
+	return ExecuteParallelConversationWithOptions(ctx, clients, conversation, ParallelConversationOptions{})
+}
+
+// ExecuteParallelConversationWithOptions executes multiple AI clients in parallel with
+// the same conversation, sending it to clients that do not support conversations as
+// opts.Fallback selects.
+func ExecuteParallelConversationWithOptions(ctx context.Context, clients []AIClient, conversation *Conversation, opts ParallelConversationOptions) []ParallelResult {
+	return collectParallel(clients, runParallel(ctx, clients, func(ctx context.Context, c AIClient) (*AiResponse, error) {
+		if c.SupportsConversations() {
+			return c.SendConversationWithMetadata(ctx, conversation)
+		}
+		prompt, err := fallbackPrompt(conversation, opts.Fallback)
+		if err != nil {
+			return nil, err
+		}
+		return c.SendPromptWithMetadata(ctx, prompt)
+	}))
+}
 
+// fallbackPrompt returns the prompt sent in place of conversation to a client that
+// does not support conversations.
+func fallbackPrompt(conversation *Conversation, fallback ConversationFallback) (string, error) {
+	if len(conversation.Messages) == 0 {
+		return "", NewConfigError("empty conversation")
+	}
+	switch fallback {
+	case ConversationFallbackFlatten:
+		return FlattenConversation(conversation), nil
+	case ConversationFallbackError:
+		return "", NewConfigError("client does not support conversations")
+	default:
+		lastMessage := conversation.Messages[len(conversation.Messages)-1]
+		if lastMessage.Role != "user" {
+			return "", NewConfigError("no user message found in conversation")
+		}
+		return lastMessage.Content, nil
+	}
+}
+
+// FlattenConversation renders conversation as a single prompt for clients that take
+// one, with each message in its own paragraph prefixed by its role, e.g.
+// "User: What is Go?". Only the text of each message is included.
+func FlattenConversation(conversation *Conversation) string {
+	var b strings.Builder
+	for i, message := range conversation.Messages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(roleLabel(message.Role))
+		b.WriteString(": ")
+		b.WriteString(message.Content)
+	}
+	return b.String()
+}
+
+// roleLabel returns the prefix FlattenConversation writes for role.
+func roleLabel(role string) string {
+	if role == "" {
+		return "User"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// ExecuteParallelStream sends prompt to every client in parallel, like ExecuteParallel,
+// but delivers each ParallelResult on the returned channel as soon as its client
+// finishes, so callers can render results incrementally. Results arrive in
+// completion order. If ctx ends first, each client still running is delivered at
+// once with ctx's error. The channel is closed once every client has a result.
+func ExecuteParallelStream(ctx context.Context, clients []AIClient, prompt string) <-chan ParallelResult {
+	finished := runParallel(ctx, clients, func(ctx context.Context, c AIClient) (*AiResponse, error) {
+		return c.SendPromptWithMetadata(ctx, prompt)
+	})
+	out := make(chan ParallelResult, len(clients))
+	go func() {
+		defer close(out)
+		for r := range finished {
+			out <- r.result
+		}
+	}()
+	return out
+}
+
+// FastestError is returned by ExecuteFastest when no client succeeded. It lists
+// every client's failure in the order of clients.
+type FastestError struct {
+	Failures []ParallelResult
+}
+
+func (e *FastestError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		parts[i] = fmt.Sprintf("%s: %v", f.ClientName, f.Error)
+	}
+	return fmt.Sprintf("all %d clients failed: %s", len(e.Failures), strings.Join(parts, "; "))
+}
+
+// Unwrap returns each client's error so errors.Is and errors.As can reach them.
+func (e *FastestError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Error
+	}
+	return errs
+}
+
+// ExecuteFastest sends prompt to every client in parallel and returns the first
+// successful result. The remaining requests are cancelled through a derived context,
+// which aborts their in-flight HTTP requests, so their goroutines finish promptly.
+// If every client fails, the error is a *FastestError listing each failure.
+func ExecuteFastest(ctx context.Context, clients []AIClient, prompt string) (ParallelResult, error) {
+	if len(clients) == 0 {
+		return ParallelResult{}, NewConfigError("no clients to execute")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	failures := make([]ParallelResult, len(clients))
+	for r := range runParallel(ctx, clients, func(ctx context.Context, c AIClient) (*AiResponse, error) {
+		return c.SendPromptWithMetadata(ctx, prompt)
+	}) {
+		if r.result.Error == nil {
+			return r.result, nil
+		}
+		failures[r.index] = r.result
+	}
+	return ParallelResult{}, &FastestError{Failures: failures}
+}
+
+// indexedResult is a ParallelResult tagged with its client's position, together
+// with the full response it was built from, if any.
+type indexedResult struct {
+	index    int
+	result   ParallelResult
+	response *AiResponse
+}
+
+// runParallel calls send for every client in parallel and delivers each result on the
+// returned channel as soon as its client finishes. If ctx ends first, the clients
+// still running are delivered with ctx's error and their goroutines finish in the
+// background. The channel is closed once every client has a result.
+func runParallel(ctx context.Context, clients []AIClient, send func(context.Context, AIClient) (*AiResponse, error)) <-chan indexedResult {
+	// Both channels are buffered so nothing blocks on a reader that has gone away
+	finished := make(chan indexedResult, len(clients))
+	start := time.Now()
 	for i, client := range clients {
-		wg.Add(1)
 		go func(index int, c AIClient) {
-			defer wg.Done()
-
-			var result string
-			var err error
-
-			if c.SupportsConversations() {
-				result, err = c.SendConversation(ctx, conversation)
-			} else {
-				// Fallback to sending the last user message as a prompt
-				if len(conversation.Messages) > 0 {
-					lastMessage := conversation.Messages[len(conversation.Messages)-1]
-					if lastMessage.Role == "user" {
-						result, err = c.SendPrompt(ctx, lastMessage.Content)
-					} else {
-						err = NewConfigError("no user message found in conversation")
+			response, err := send(ctx, c)
+			finished <- indexedResult{index: index, result: newParallelResult(c, response, time.Since(start), err), response: response}
+		}(i, client)
+	}
+
+	out := make(chan indexedResult, len(clients))
+	go func() {
+		defer close(out)
+		done := make([]bool, len(clients))
+		for range clients {
+			select {
+			case r := <-finished:
+				done[r.index] = true
+				out <- r
+			case <-ctx.Done():
+				for i, c := range clients {
+					if !done[i] {
+						out <- indexedResult{index: i, result: newParallelResult(c, nil, time.Since(start), ctx.Err())}
 					}
-				} else {
-					err = NewConfigError("empty conversation")
 				}
+				return
 			}
+		}
+	}()
+	return out
+}
 
-			results[index] = ParallelResult{
-				ClientName: c.Name(),
-				Result:     result,
-				Error:      err,
-			}
-		}(i, client)
+// collectParallel gathers the results from runParallel in the order of clients.
+func collectParallel(clients []AIClient, finished <-chan indexedResult) []ParallelResult {
+	results := make([]ParallelResult, len(clients))
+	for r := range finished {
+		results[r.index] = r.result
 	}
-
-	wg.Wait()
 	return results
 }
 
@@ -161,13 +484,22 @@ func NewConfigError(message string) *ClientError {
 	}
 }
 
-// MergeStreamChunks combines multiple stream chunks into a single string
+// MergeStreamChunks combines multiple stream chunks into a single string.
+// If the stream failed, the error from the final chunk is returned. If it was
+// truncated by a timeout (see ClientConfig.PartialOnTimeout), the partial content
+// is returned together with a stream_truncated error.
 func MergeStreamChunks(chunks <-chan StreamChunk) (string, error) {
 	var result string
 
 	for chunk := range chunks {
 		result += chunk.Content
 		if chunk.Finished {
+			if chunk.Err != nil {
+				return "", chunk.Err
+			}
+			if chunk.Truncated {
+				return result, NewStreamTruncatedError()
+			}
 			break
 		}
 	}
@@ -205,35 +537,166 @@ func StreamConversationToString(ctx context.Context, client AIClient, conversati
 	return result, err
 }
 
+// StreamToWriter streams the response to prompt into w, writing each chunk's content
+// as it arrives and flushing after every write if w is an http.Flusher, so CLIs and
+// HTTP handlers can forward a response as it is generated. It returns the number of
+// bytes written. A failed write stops the stream and its error is returned; a failed
+// stream returns the chunk's error, and a truncated one a stream_truncated error.
+// Clients without streaming have their whole response written at once.
+func StreamToWriter(ctx context.Context, client AIClient, prompt string, w io.Writer) (int64, error) {
+	flusher, _ := w.(http.Flusher)
+	write := func(content string) (int64, error) {
+		n, err := io.WriteString(w, content)
+		if err == nil && flusher != nil {
+			flusher.Flush()
+		}
+		return int64(n), err
+	}
+
+	if !client.SupportsStreaming() {
+		response, err := client.SendPrompt(ctx, prompt)
+		if err != nil {
+			return 0, err
+		}
+		return write(response)
+	}
+
+	// Cancelling stops the provider stream if a write fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks, err := client.StreamPrompt(ctx, prompt)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for chunk := range chunks {
+		if chunk.Content != "" {
+			n, err := write(chunk.Content)
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+		if chunk.Finished {
+			if chunk.Err != nil {
+				return total, chunk.Err
+			}
+			if chunk.Truncated {
+				return total, NewStreamTruncatedError()
+			}
+			break
+		}
+	}
+	return total, nil
+}
+
 // ValidateConfig validates a ClientConfig
 func ValidateConfig(config *ClientConfig) error {
 	if config.Timeout <= 0 {
 		return NewInvalidParameterError("timeout", config.Timeout.String())
 	}
 
+	if config.OperationTimeout < 0 {
+		return NewInvalidParameterError("operation_timeout", config.OperationTimeout.String())
+	}
+
 	if config.Retries < 0 {
-		return NewInvalidParameterError("retries", string(rune(config.Retries)))
+		return NewInvalidParameterError("retries", strconv.Itoa(config.Retries))
+	}
+
+	if config.BaseRetryDelay < 0 {
+		return NewInvalidParameterError("base_retry_delay", config.BaseRetryDelay.String())
+	}
+
+	if config.MaxRetryDelay < 0 {
+		return NewInvalidParameterError("max_retry_delay", config.MaxRetryDelay.String())
+	}
+
+	if config.MaxRetryDelay > 0 && config.MaxRetryDelay < config.BaseRetryDelay {
+		return NewInvalidParameterError("max_retry_delay",
+			fmt.Sprintf("%s is less than base_retry_delay %s", config.MaxRetryDelay, config.BaseRetryDelay))
 	}
 
 	if config.Temperature != nil && (*config.Temperature < 0 || *config.Temperature > 2) {
-		return NewInvalidParameterError("temperature", string(rune(int(*config.Temperature))))
+		return NewInvalidParameterError("temperature", formatFloat(*config.Temperature))
 	}
 
 	if config.MaxTokens != nil && *config.MaxTokens <= 0 {
-		return NewInvalidParameterError("max_tokens", string(rune(*config.MaxTokens)))
+		return NewInvalidParameterError("max_tokens", strconv.Itoa(*config.MaxTokens))
 	}
 
 	if config.TopP != nil && (*config.TopP < 0 || *config.TopP > 1) {
-		return NewInvalidParameterError("top_p", string(rune(int(*config.TopP))))
+		return NewInvalidParameterError("top_p", formatFloat(*config.TopP))
+	}
+
+	if config.TopK != nil && *config.TopK < 0 {
+		return NewInvalidParameterError("top_k", strconv.Itoa(*config.TopK))
 	}
 
 	if config.FrequencyPenalty != nil && (*config.FrequencyPenalty < -2 || *config.FrequencyPenalty > 2) {
-		return NewInvalidParameterError("frequency_penalty", string(rune(int(*config.FrequencyPenalty))))
+		return NewInvalidParameterError("frequency_penalty", formatFloat(*config.FrequencyPenalty))
 	}
 
 	if config.PresencePenalty != nil && (*config.PresencePenalty < -2 || *config.PresencePenalty > 2) {
-		return NewInvalidParameterError("presence_penalty", string(rune(int(*config.PresencePenalty))))
+		return NewInvalidParameterError("presence_penalty", formatFloat(*config.PresencePenalty))
+	}
+
+	if config.N != nil && *config.N < 1 {
+		return NewInvalidParameterError("n", strconv.Itoa(*config.N))
+	}
+
+	if err := validateLogitBias(config.LogitBias); err != nil {
+		return err
 	}
 
+	if config.TopLogprobs != nil {
+		if *config.TopLogprobs < 0 || *config.TopLogprobs > 20 {
+			return NewInvalidParameterError("top_logprobs", strconv.Itoa(*config.TopLogprobs))
+		}
+		if !config.Logprobs {
+			return NewInvalidParameterError("top_logprobs", "requires logprobs")
+		}
+	}
+
+	switch config.RetryStrategy {
+	case "", RetryStrategyFixed, RetryStrategyLinear, RetryStrategyExponentialBackoff, RetryStrategyExponentialWithJitter:
+	default:
+		return NewInvalidParameterError("retry_strategy", string(config.RetryStrategy))
+	}
+
+	if err := ValidateTools(config); err != nil {
+		return err
+	}
+
+	if err := ValidateResponseFormat(config); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// formatFloat renders a parameter value in its shortest exact form, e.g. "2.5"
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// validateLogitBias rejects negative token IDs and biases outside -100..100,
+// reporting the lowest offending token ID.
+func validateLogitBias(bias map[int]int) error {
+	tokens := make([]int, 0, len(bias))
+	for token := range bias {
+		tokens = append(tokens, token)
+	}
+	sort.Ints(tokens)
+	for _, token := range tokens {
+		if token < 0 {
+			return NewInvalidParameterError("logit_bias", fmt.Sprintf("token ID %d is negative", token))
+		}
+		if value := bias[token]; value < -100 || value > 100 {
+			return NewInvalidParameterError("logit_bias", fmt.Sprintf("bias %d for token %d is outside -100..100", value, token))
+		}
+	}
 	return nil
 }
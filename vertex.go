@@ -0,0 +1,114 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// vertex.go adds Gemini through Google Cloud Vertex AI. Vertex accepts the same
+// generateContent body as the Gemini API but is addressed by project and location and
+// authenticated with OAuth access tokens instead of an API key.
+package chatdelta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// DefaultVertexLocation is used when neither VertexConfig.Location nor
+// GOOGLE_CLOUD_LOCATION is set.
+const DefaultVertexLocation = "us-central1"
+
+// VertexConfig selects the Google Cloud project, location, and credentials used by the
+// "vertex" provider.
+type VertexConfig struct {
+	// Project is the Google Cloud project ID. When empty, GOOGLE_CLOUD_PROJECT and then
+	// the project named in the credentials file are used.
+	Project string
+	// Location is the Vertex AI region, e.g. "us-central1", or "global". When empty,
+	// GOOGLE_CLOUD_LOCATION and then DefaultVertexLocation are used.
+	Location string
+	// TokenSource supplies access tokens. When nil, Application Default Credentials
+	// are loaded from GOOGLE_APPLICATION_CREDENTIALS or the gcloud default file.
+	TokenSource TokenSource
+}
+
+// vertexTarget is the resolved project, location, and token source of a Vertex client.
+type vertexTarget struct {
+	project  string
+	location string
+	tokens   TokenSource
+}
+
+// NewVertexClient creates a Gemini client that calls Vertex AI. It uses the Gemini
+// request and response code, so metadata, tools, and error mapping behave as for
+// NewGeminiClient. ClientConfig.BaseURL, when set, replaces the regional host.
+func NewVertexClient(model string, config *ClientConfig) (*GeminiClient, error) {
+	if model == "" {
+		model = "gemini-1.5-flash"
+	}
+
+	if config == nil {
+		config = NewClientConfig()
+	}
+
+	var settings VertexConfig
+	if config.Vertex != nil {
+		settings = *config.Vertex
+	}
+	if settings.Project == "" {
+		settings.Project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if settings.Location == "" {
+		settings.Location = os.Getenv("GOOGLE_CLOUD_LOCATION")
+	}
+	if settings.Location == "" {
+		settings.Location = DefaultVertexLocation
+	}
+
+	if settings.TokenSource == nil {
+		// The token exchange uses a plain client so access tokens never reach the HTTP hooks.
+		tokens, project, err := defaultGoogleTokenSource(&http.Client{Timeout: config.Timeout}, config.clock())
+		if err != nil {
+			return nil, err
+		}
+		settings.TokenSource = tokens
+		if settings.Project == "" {
+			settings.Project = project
+		}
+	}
+	if settings.Project == "" {
+		return nil, NewMissingConfigError("Google Cloud project (set GOOGLE_CLOUD_PROJECT or use ClientConfig.SetVertex)")
+	}
+
+	return &GeminiClient{
+		model:      model,
+		config:     config,
+		httpClient: newHTTPClient(config),
+		vertex: &vertexTarget{
+			project:  settings.Project,
+			location: settings.Location,
+			tokens:   settings.TokenSource,
+		},
+	}, nil
+}
+
+// baseURL returns the regional Vertex AI endpoint.
+func (v *vertexTarget) baseURL() string {
+	if v.location == "global" {
+		return "https://aiplatform.googleapis.com/v1"
+	}
+	return "https://" + v.location + "-aiplatform.googleapis.com/v1"
+}
+
+// modelPath returns the path of model's generateContent method, or of
+// streamGenerateContent with server-sent events when stream is set.
+func (v *vertexTarget) modelPath(model string, stream bool) string {
+	return fmt.Sprintf("/projects/%s/locations/%s/publishers/google/models/%s:%s", v.project, v.location, model, geminiMethod(stream))
+}
+
+// authorize adds a Bearer access token to req.
+func (v *vertexTarget) authorize(ctx context.Context, req *http.Request) error {
+	token, err := v.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
@@ -0,0 +1,185 @@
+package chatdelta
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVertexClient_SendConversationWithMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/projects/my-project/locations/europe-west4/publishers/google/models/gemini-1.5-pro:generateContent", r.URL.Path)
+		assert.Empty(t, r.URL.Query().Get("key"))
+		assert.Equal(t, "Bearer ya29.token", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hallo"}]},"finishReason":"STOP","index":0}],
+			"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":1,"totalTokenCount":4}}`))
+	}))
+	defer server.Close()
+
+	config := NewClientConfig().
+		SetBaseURL(server.URL).
+		SetVertex("my-project", "europe-west4", StaticTokenSource("ya29.token")).
+		SetRetries(0)
+	client, err := NewVertexClient("gemini-1.5-pro", config)
+	require.NoError(t, err)
+	assert.Equal(t, "Vertex AI", client.Name())
+
+	response, err := client.SendPromptWithMetadata(context.Background(), "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "Hallo", response.Content)
+	assert.Equal(t, 4, response.Metadata.TotalTokens)
+}
+
+func TestVertexClient_TokenErrorIsReturned(t *testing.T) {
+	config := NewClientConfig().SetVertex("p", "", failingTokenSource{}).SetRetries(0)
+	client, err := NewVertexClient("", config)
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://us-central1-aiplatform.googleapis.com/v1/projects/p/locations/us-central1/publishers/google/models/gemini-1.5-flash:generateContent", prepared.URL)
+
+	_, err = client.SendPrompt(context.Background(), "hi")
+	assert.True(t, IsAuthenticationError(err))
+}
+
+type failingTokenSource struct{}
+
+func (failingTokenSource) Token(context.Context) (string, error) { return "", NewInvalidAPIKeyError() }
+
+// serviceAccountJSON writes a service account key whose token_uri is tokenURL.
+func serviceAccountJSON(t *testing.T, key *rsa.PrivateKey, tokenURL string) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "sa-project",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "bot@sa-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURL,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestGoogleTokenSource_ServiceAccount(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+		claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		assert.Contains(t, string(claims), `"iss":"bot@sa-project.iam.gserviceaccount.com"`)
+		assert.Contains(t, string(claims), `"scope":"https://www.googleapis.com/auth/cloud-platform"`)
+
+		_, _ = w.Write([]byte(`{"access_token":"ya29.sa","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	source, project, err := googleTokenSourceFromJSON(serviceAccountJSON(t, key, server.URL), server.Client(), clock)
+	require.NoError(t, err)
+	assert.Equal(t, "sa-project", project)
+
+	for i := 0; i < 2; i++ {
+		token, err := source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "ya29.sa", token)
+	}
+	assert.Equal(t, int32(1), requests.Load(), "token is cached")
+
+	clock.After(59*time.Minute + time.Second)
+	_, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load(), "token is refreshed before it expires")
+}
+
+func TestGoogleTokenSource_AuthorizedUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "1//refresh", r.PostForm.Get("refresh_token"))
+		if r.PostForm.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"ya29.user","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	credentials := `{"type":"authorized_user","client_id":"id","client_secret":"%s","refresh_token":"1//refresh","quota_project_id":"quota"}`
+	source, project, err := googleTokenSourceFromJSON([]byte(strings.Replace(credentials, "%s", "secret", 1)), server.Client(), systemClock{})
+	require.NoError(t, err)
+	assert.Equal(t, "quota", project)
+	source.(*googleTokenSource).tokenURL = server.URL
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ya29.user", token)
+
+	source, _, err = googleTokenSourceFromJSON([]byte(strings.Replace(credentials, "%s", "wrong", 1)), server.Client(), systemClock{})
+	require.NoError(t, err)
+	source.(*googleTokenSource).tokenURL = server.URL
+	_, err = source.Token(context.Background())
+	var clientErr *ClientError
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, "invalid_api_key", clientErr.Code)
+}
+
+func TestCreateClient_VertexFromADC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "sa.json")
+	require.NoError(t, os.WriteFile(path, serviceAccountJSON(t, key, "http://127.0.0.1:0/token"), 0o600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+	t.Setenv("GOOGLE_CLOUD_LOCATION", "global")
+
+	client, err := CreateClient("vertex", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-1.5-flash", client.Model())
+	assert.Contains(t, GetAvailableProviders(), "vertex")
+
+	prepared, err := client.(DryRunner).DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://aiplatform.googleapis.com/v1/projects/sa-project/locations/global/publishers/google/models/gemini-1.5-flash:generateContent", prepared.URL)
+
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+	_, err = CreateClient("vertex", "", "", nil)
+	var clientErr *ClientError
+	require.True(t, errors.As(err, &clientErr))
+	assert.Equal(t, "missing_config", clientErr.Code)
+}
@@ -0,0 +1,32 @@
+// Package chatdelta provides a unified interface for interacting with multiple AI APIs.
+// xai.go adds xAI's Grok models, served over the OpenAI chat protocol at api.x.ai.
+package chatdelta
+
+// xaiBaseURL is the default xAI API endpoint; ClientConfig.BaseURL overrides it.
+const xaiBaseURL = "https://api.x.ai/v1"
+
+// xaiEndpoint is api.x.ai with Bearer auth.
+var xaiEndpoint = openAIEndpoint{
+	name:       "xAI",
+	provider:   ProviderXAI,
+	baseURL:    xaiBaseURL,
+	path:       "/chat/completions",
+	modelsPath: "/models",
+	authHeader: "Authorization",
+	authPrefix: "Bearer ",
+}
+
+// NewXAIClient creates a client for xAI's Grok models. It uses the OpenAI request
+// and streaming code, so metadata, streaming, and error mapping behave exactly as for
+// NewOpenAIClient.
+func NewXAIClient(apiKey, model string, config *ClientConfig) (*OpenAIClient, error) {
+	if model == "" {
+		model = "grok-2-latest"
+	}
+	client, err := NewOpenAIClient(apiKey, model, config)
+	if err != nil {
+		return nil, err
+	}
+	client.endpoint = xaiEndpoint
+	return client, nil
+}
@@ -0,0 +1,114 @@
+package chatdelta
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXAIClient_Stream(t *testing.T) {
+	transcript, err := os.ReadFile(filepath.Join("testdata", "openai_stream.sse"))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer xai-key", r.Header.Get("Authorization"))
+		_, _ = w.Write(transcript)
+	}))
+	defer server.Close()
+
+	client, err := CreateClient("grok", "xai-key", "", NewClientConfig().SetBaseURL(server.URL))
+	require.NoError(t, err)
+	assert.Equal(t, "xAI", client.Name())
+	assert.Equal(t, "grok-2-latest", client.Model())
+	assert.True(t, client.SupportsStreaming())
+
+	ch, err := client.StreamPrompt(context.Background(), "hi")
+	require.NoError(t, err)
+	content, last := collectStream(t, ch)
+	assert.Equal(t, "Hello there!", content)
+	require.NotNil(t, last.Metadata)
+}
+
+func TestXAIClient_DefaultURLAndModelInfo(t *testing.T) {
+	client, err := NewXAIClient("xai-key", "", nil)
+	require.NoError(t, err)
+
+	prepared, err := client.DryRun(promptConversation("hi"), false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.x.ai/v1/chat/completions", prepared.URL)
+
+	info, ok := client.ModelInfo()
+	require.True(t, ok)
+	assert.Equal(t, ProviderXAI, info.Provider)
+	assert.Equal(t, 131072, info.ContextWindow)
+}
+
+func TestXAIClient_ErrorMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantCode string
+	}{
+		{"bad key", http.StatusUnauthorized, `{"error":{"message":"Incorrect API key provided"}}`, "invalid_api_key"},
+		{"rate limited", http.StatusTooManyRequests, `{"error":{"message":"Too many requests"}}`, "rate_limit"},
+		{"bad request", http.StatusBadRequest, `{"error":{"message":"messages must not be empty"}}`, "bad_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewXAIClient("xai-key", "", NewClientConfig().SetRetries(0))
+			require.NoError(t, err)
+			client.httpClient.Transport = cannedResponse(tt.status, tt.body)
+
+			_, err = client.SendPrompt(context.Background(), "hi")
+			var clientErr *ClientError
+			require.True(t, errors.As(err, &clientErr), "unexpected error: %v", err)
+			assert.Equal(t, tt.wantCode, clientErr.Code)
+		})
+	}
+}
+
+func TestXAIClient_ValidatesAgainstXAILimits(t *testing.T) {
+	var mutated Provider
+	config := NewClientConfig().
+		SetStopSequences("a", "b", "c", "d").
+		AddRequestMutator(func(provider Provider, model string, body map[string]any) error {
+			mutated = provider
+			return nil
+		})
+	client, err := NewXAIClient("xai-key", "grok-2-vision", config)
+	require.NoError(t, err)
+
+	_, err = client.DryRun(imageConversation(600, 1), false)
+	require.NoError(t, err, "xAI publishes no limit on the number of images")
+	assert.Equal(t, ProviderXAI, mutated, "request mutators see the xAI provider")
+
+	_, err = client.DryRun(imageConversation(1, 20<<20+1), false)
+	assert.ErrorContains(t, err, "xai accepts at most")
+
+	client.config.SetStopSequences("a", "b", "c", "d", "e")
+	_, err = client.DryRun(promptConversation("hi"), false)
+	assert.ErrorContains(t, err, "exceed the xai limit of 4")
+}
+
+func TestXAIClient_AvailableFromEnv(t *testing.T) {
+	t.Setenv("XAI_API_KEY", "")
+	assert.NotContains(t, GetAvailableProviders(), "xai")
+	_, err := CreateClient("xai", "", "", nil)
+	assert.Error(t, err)
+
+	t.Setenv("XAI_API_KEY", "xai-key")
+	assert.Contains(t, GetAvailableProviders(), "xai")
+	client, err := CreateClient("xai", "", "grok-beta", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "grok-beta", client.Model())
+}